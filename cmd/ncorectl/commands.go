@@ -0,0 +1,529 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/client"
+	"gopkg.in/yaml.v3"
+)
+
+// parseArgs parses the flags of fset and checks there are n arguments left.
+func parseArgs(fset *flag.FlagSet, args []string, n int) error {
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() != n {
+		fset.Usage()
+		return fmt.Errorf("%s takes %d argument(s), got %d", fset.Name(), n, fset.NArg())
+	}
+	return nil
+}
+
+// imageFlags are the -tag, -type and -channel flags of the commands setting an image.
+type imageFlags struct {
+	tag, typ, channel *string
+}
+
+func addImageFlags(fset *flag.FlagSet) *imageFlags {
+	return &imageFlags{
+		tag:     fset.String("tag", "", "image_tag of the image"),
+		typ:     fset.String("type", "", "image_type of the image"),
+		channel: fset.String("channel", "", "Image channel to follow instead of a fixed image"),
+	}
+}
+
+func (f *imageFlags) validate() error {
+	if *f.channel != "" && (*f.tag != "" || *f.typ != "") {
+		return errors.New("-channel and -tag/-type are mutually exclusive")
+	}
+	if *f.channel == "" && (*f.tag == "" || *f.typ == "") {
+		return errors.New("-tag and -type, or -channel, are required")
+	}
+	return nil
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func runNodeStatus(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("node status")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	var views []*client.NodeView
+	switch fset.NArg() {
+	case 0:
+		var err error
+		if views, err = e.client.GetNodeViews(ctx); err != nil {
+			return err
+		}
+	case 1:
+		view, err := e.client.GetNodeView(ctx, fset.Arg(0))
+		if err != nil {
+			return err
+		}
+		views = append(views, view)
+	default:
+		fset.Usage()
+		return errors.New("node status takes at most one mac_address")
+	}
+	var rows [][]string
+	for _, v := range views {
+		rows = append(rows, []string{
+			v.MacAddress, orDash(v.Hostname), orDash(v.IpAddress),
+			orDash(v.ImageTag), orDash(v.ImageType), orDash(v.ImageChannel),
+			orDash(strings.Join(v.PayloadIds, ",")), formatTime(v.LastSeen),
+		})
+	}
+	var v any = views
+	if fset.NArg() == 1 {
+		v = views[0]
+	}
+	return e.out.print(v, []string{"MAC ADDRESS", "HOSTNAME", "IP ADDRESS", "IMAGE TAG", "IMAGE TYPE", "CHANNEL", "PAYLOADS", "LAST SEEN"}, rows)
+}
+
+func runNodeSetImage(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("node set-image")
+	image := addImageFlags(fset)
+	if err := parseArgs(fset, args, 1); err != nil {
+		return err
+	}
+	if err := image.validate(); err != nil {
+		return err
+	}
+	config, err := e.client.PutNodeIpxe(ctx, &client.IpxeNodeDbConfig{
+		MacAddress:   fset.Arg(0),
+		ImageTag:     *image.tag,
+		ImageType:    *image.typ,
+		ImageChannel: *image.channel,
+	})
+	if err != nil {
+		return err
+	}
+	return e.out.print(config, []string{"MAC ADDRESS", "IMAGE TAG", "IMAGE TYPE", "CHANNEL"}, [][]string{
+		{config.MacAddress, orDash(config.ImageTag), orDash(config.ImageType), orDash(config.ImageChannel)},
+	})
+}
+
+func runNodeSetPayload(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("node set-payload")
+	if err := parseArgs(fset, args, 2); err != nil {
+		return err
+	}
+	payloads, err := e.client.PutNodePayload(ctx, fset.Arg(0), fset.Arg(1))
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, p := range payloads {
+		rows = append(rows, []string{p.MacAddress, p.PayloadId, p.PayloadDirectory})
+	}
+	return e.out.print(payloads, []string{"MAC ADDRESS", "PAYLOAD", "DIRECTORY"}, rows)
+}
+
+// runNodeIpxe prints the iPXE script of a node as served to it, whatever the output format.
+func runNodeIpxe(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("node ipxe")
+	ip := fset.String("ip", "", "Look subnet defaults up with this ip_address, honored for -http.trustedDhcp callers only")
+	if err := parseArgs(fset, args, 1); err != nil {
+		return err
+	}
+	script, err := e.client.GetNodeIpxeTemplate(ctx, fset.Arg(0), *ip)
+	if err != nil {
+		return err
+	}
+	_, err = io.WriteString(e.stdout, script)
+	return err
+}
+
+func runImageList(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("image list")
+	state := fset.String("state", "", "Only list images in this state: staged, active, deprecated or retired")
+	if err := parseArgs(fset, args, 0); err != nil {
+		return err
+	}
+	all, err := e.client.GetIpxeImages(ctx)
+	if err != nil {
+		return err
+	}
+	images := []*client.IpxeDbConfig{}
+	var rows [][]string
+	for _, i := range all {
+		if *state != "" && i.ImageState != *state {
+			continue
+		}
+		images = append(images, i)
+		rows = append(rows, []string{i.ImageTag, i.ImageType, i.ImageName, i.ImageBucket, i.ImageState})
+	}
+	return e.out.print(images, []string{"IMAGE TAG", "IMAGE TYPE", "NAME", "BUCKET", "STATE"}, rows)
+}
+
+// imageManifest is the file registered by image register, yaml or json:
+//
+//	name: ubuntu-22.04-develop
+//	bucket: ncore-images
+//	tag: develop
+//	type: ubuntu-22.04
+//	cmdline: console=ttyS0
+//	state: staged
+type imageManifest struct {
+	Name    string `yaml:"name"`
+	Bucket  string `yaml:"bucket"`
+	Tag     string `yaml:"tag"`
+	Type    string `yaml:"type"`
+	Cmdline string `yaml:"cmdline"`
+	State   string `yaml:"state"`
+	Channel string `yaml:"channel"`
+}
+
+func readImageManifest(path string) (*imageManifest, error) {
+	var b []byte
+	var err error
+	if path == "-" {
+		b, err = io.ReadAll(os.Stdin)
+	} else {
+		b, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, err
+	}
+	m := &imageManifest{}
+	dec := yaml.NewDecoder(strings.NewReader(string(b)))
+	dec.KnownFields(true)
+	if err := dec.Decode(m); err != nil {
+		return nil, fmt.Errorf("cannot parse manifest %s: %w", path, err)
+	}
+	return m, nil
+}
+
+func runImageRegister(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("image register")
+	file := fset.String("f", "", "Image manifest, - for stdin")
+	if err := parseArgs(fset, args, 0); err != nil {
+		return err
+	}
+	if *file == "" {
+		fset.Usage()
+		return errors.New("-f is required")
+	}
+	m, err := readImageManifest(*file)
+	if err != nil {
+		return err
+	}
+	image, err := e.client.PutIpxeImage(ctx, &client.IpxeDbConfig{
+		ImageName:    m.Name,
+		ImageBucket:  m.Bucket,
+		ImageTag:     m.Tag,
+		ImageType:    m.Type,
+		ImageCmdline: m.Cmdline,
+		ImageState:   m.State,
+		ImageChannel: m.Channel,
+	})
+	if err != nil {
+		return err
+	}
+	return e.out.print(image, []string{"IMAGE TAG", "IMAGE TYPE", "NAME", "BUCKET", "STATE"}, [][]string{
+		{image.ImageTag, image.ImageType, image.ImageName, image.ImageBucket, image.ImageState},
+	})
+}
+
+func subnetRows(subnets ...*client.SubnetDefaultImage) [][]string {
+	var rows [][]string
+	for _, s := range subnets {
+		rows = append(rows, []string{s.Subnet, orDash(s.ImageTag), orDash(s.ImageType), orDash(s.ImageChannel)})
+	}
+	return rows
+}
+
+var subnetHeader = []string{"SUBNET", "IMAGE TAG", "IMAGE TYPE", "CHANNEL"}
+
+func runSubnetList(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("subnet list")
+	if err := parseArgs(fset, args, 0); err != nil {
+		return err
+	}
+	subnets, err := e.client.GetSubnetDefaultImages(ctx)
+	if err != nil {
+		return err
+	}
+	return e.out.print(subnets, subnetHeader, subnetRows(subnets...))
+}
+
+func runSubnetSet(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("subnet set")
+	image := addImageFlags(fset)
+	if err := parseArgs(fset, args, 1); err != nil {
+		return err
+	}
+	if err := image.validate(); err != nil {
+		return err
+	}
+	subnet, err := e.client.PutSubnetDefaultImage(ctx, &client.SubnetDefaultImage{
+		Subnet:       fset.Arg(0),
+		ImageTag:     *image.tag,
+		ImageType:    *image.typ,
+		ImageChannel: *image.channel,
+	})
+	if err != nil {
+		return err
+	}
+	return e.out.print(subnet, subnetHeader, subnetRows(subnet))
+}
+
+func runSubnetDelete(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("subnet delete")
+	if err := parseArgs(fset, args, 1); err != nil {
+		return err
+	}
+	subnet, err := e.client.DeleteSubnetDefaultImage(ctx, &client.SubnetDefaultImage{Subnet: fset.Arg(0)})
+	if err != nil {
+		return err
+	}
+	return e.out.print(subnet, subnetHeader, subnetRows(subnet))
+}
+
+var registrationHeader = []string{"MAC ADDRESS", "IP ADDRESS", "STATE", "FIRST SEEN", "LAST SEEN"}
+
+func registrationRow(r *client.Registration) []string {
+	return []string{r.MacAddress, r.IpAddress, r.State, formatTime(r.FirstSeen), formatTime(r.LastSeen)}
+}
+
+func runNodeRegistrations(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("node registrations")
+	state := fset.String("state", "pending", "Only list registrations in this state, every one when empty")
+	if err := parseArgs(fset, args, 0); err != nil {
+		return err
+	}
+	registrations, err := e.client.GetRegistrations(ctx, *state)
+	if err != nil {
+		return err
+	}
+	var rows [][]string
+	for _, r := range registrations {
+		rows = append(rows, registrationRow(r))
+	}
+	return e.out.print(registrations, registrationHeader, rows)
+}
+
+func runNodeApprove(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("node approve")
+	image := addImageFlags(fset)
+	payload := fset.String("payload", "", "payload_id assigned to the node, its default when empty")
+	if err := parseArgs(fset, args, 1); err != nil {
+		return err
+	}
+	// without an image the node's default is pinned on its next boot
+	if *image.tag != "" || *image.typ != "" || *image.channel != "" {
+		if err := image.validate(); err != nil {
+			return err
+		}
+	}
+	r, err := e.client.ApproveRegistration(ctx, fset.Arg(0), &client.RegistrationApproval{
+		ImageTag:     *image.tag,
+		ImageType:    *image.typ,
+		ImageChannel: *image.channel,
+		PayloadId:    *payload,
+	})
+	if err != nil {
+		return err
+	}
+	return e.out.print(r, registrationHeader, [][]string{registrationRow(r)})
+}
+
+func runNodeReject(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("node reject")
+	if err := parseArgs(fset, args, 1); err != nil {
+		return err
+	}
+	r, err := e.client.RejectRegistration(ctx, fset.Arg(0))
+	if err != nil {
+		return err
+	}
+	return e.out.print(r, registrationHeader, [][]string{registrationRow(r)})
+}
+
+var inventoryHeader = []string{"MAC ADDRESS", "HARDWARE CLASS", "SERIAL", "BIOS", "REPORTED", "CHANGED"}
+
+func inventoryRow(ni *client.NodeInventory) []string {
+	serial, bios := "-", "-"
+	if ni.Inventory != nil {
+		serial, bios = orDash(ni.Inventory.Serial), orDash(ni.Inventory.BiosVersion)
+	}
+	return []string{ni.MacAddress, ni.HardwareClass, serial, bios, formatTime(ni.ReportedAt), formatTime(ni.ChangedAt)}
+}
+
+func runNodeInventory(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("node inventory")
+	class := fset.String("class", "", "Only list nodes in this hardware class")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	var inventories []*client.NodeInventory
+	switch {
+	case fset.NArg() == 0:
+		var err error
+		if inventories, err = e.client.GetNodeInventories(ctx, *class); err != nil {
+			return err
+		}
+	case fset.NArg() == 1 && *class == "":
+		ni, err := e.client.GetNodeInventory(ctx, fset.Arg(0))
+		if err != nil {
+			return err
+		}
+		inventories = append(inventories, ni)
+	default:
+		fset.Usage()
+		return errors.New("node inventory takes either -class or one mac_address")
+	}
+	var rows [][]string
+	for _, ni := range inventories {
+		rows = append(rows, inventoryRow(ni))
+	}
+	var v any = inventories
+	if fset.NArg() == 1 {
+		v = inventories[0]
+	}
+	return e.out.print(v, inventoryHeader, rows)
+}
+
+var interfaceHeader = []string{"NODE ID", "NODE", "INTERFACE", "NAME", "ATTACHED"}
+
+func interfaceRows(identity *client.NodeIdentity) [][]string {
+	var rows [][]string
+	for _, iface := range identity.Interfaces {
+		rows = append(rows, []string{
+			strconv.FormatInt(identity.NodeId, 10), identity.MacAddress, iface.MacAddress, orDash(iface.Name), formatTime(iface.AttachedAt),
+		})
+	}
+	return rows
+}
+
+func runNodeInterfaces(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("node interfaces")
+	if err := parseArgs(fset, args, 1); err != nil {
+		return err
+	}
+	identity, err := e.client.GetNodeInterfaces(ctx, fset.Arg(0))
+	if err != nil {
+		return err
+	}
+	return e.out.print(identity, interfaceHeader, interfaceRows(identity))
+}
+
+func runNodeAttach(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("node attach")
+	name := fset.String("name", "", "Name of the interface, e.g. eth1")
+	if err := parseArgs(fset, args, 2); err != nil {
+		return err
+	}
+	identity, err := e.client.PutNodeInterface(ctx, fset.Arg(0), &client.NodeInterface{MacAddress: fset.Arg(1), Name: *name})
+	if err != nil {
+		return err
+	}
+	return e.out.print(identity, interfaceHeader, interfaceRows(identity))
+}
+
+func runNodeDetach(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("node detach")
+	if err := parseArgs(fset, args, 2); err != nil {
+		return err
+	}
+	identity, err := e.client.DeleteNodeInterface(ctx, fset.Arg(0), fset.Arg(1))
+	if err != nil {
+		return err
+	}
+	return e.out.print(identity, interfaceHeader, interfaceRows(identity))
+}
+
+var hostnameHeader = []string{"MAC ADDRESS", "HOSTNAME", "SOURCE", "ASSIGNED"}
+
+func hostnameRow(h *client.NodeHostname) []string {
+	return []string{h.MacAddress, h.Hostname, orDash(h.Source), formatTime(h.AssignedAt)}
+}
+
+func runNodeHostname(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("node hostname")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	var h *client.NodeHostname
+	var err error
+	switch fset.NArg() {
+	case 1:
+		h, err = e.client.GetNodeHostname(ctx, fset.Arg(0))
+	case 2:
+		h, err = e.client.PutNodeHostname(ctx, fset.Arg(0), &client.NodeHostname{Hostname: fset.Arg(1)})
+	default:
+		fset.Usage()
+		return errors.New("node hostname takes a mac_address and an optional hostname")
+	}
+	if err != nil {
+		return err
+	}
+	return e.out.print(h, hostnameHeader, [][]string{hostnameRow(h)})
+}
+
+var hostnamePolicyHeader = []string{"POLICY ID", "SUBNET", "TEMPLATE", "COUNTER", "CREATED"}
+
+func hostnamePolicyRows(policies ...*client.HostnamePolicy) [][]string {
+	var rows [][]string
+	for _, p := range policies {
+		rows = append(rows, []string{
+			strconv.FormatInt(p.PolicyId, 10), orDash(p.Subnet), p.Template, strconv.FormatInt(p.Counter, 10), formatTime(p.CreatedAt),
+		})
+	}
+	return rows
+}
+
+func runHostnamePolicies(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("hostname policies")
+	if err := parseArgs(fset, args, 0); err != nil {
+		return err
+	}
+	policies, err := e.client.GetHostnamePolicies(ctx)
+	if err != nil {
+		return err
+	}
+	return e.out.print(policies, hostnamePolicyHeader, hostnamePolicyRows(policies...))
+}
+
+func runHostnameAddPolicy(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("hostname add-policy")
+	subnet := fset.String("subnet", "", "Subnet of the nodes named by the policy, any subnet by default")
+	if err := parseArgs(fset, args, 1); err != nil {
+		return err
+	}
+	policy, err := e.client.PutHostnamePolicy(ctx, &client.HostnamePolicy{Subnet: *subnet, Template: fset.Arg(0)})
+	if err != nil {
+		return err
+	}
+	return e.out.print(policy, hostnamePolicyHeader, hostnamePolicyRows(policy))
+}
+
+func runHostnameDeletePolicy(ctx context.Context, e *env, args []string) error {
+	fset := newFlagSet("hostname delete-policy")
+	if err := parseArgs(fset, args, 1); err != nil {
+		return err
+	}
+	policyId, err := strconv.ParseInt(fset.Arg(0), 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid policy_id %q", fset.Arg(0))
+	}
+	policy, err := e.client.DeleteHostnamePolicy(ctx, policyId)
+	if err != nil {
+		return err
+	}
+	return e.out.print(policy, hostnamePolicyHeader, hostnamePolicyRows(policy))
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v3"
+)
+
+// config is read from the -config file, $NCORECTL_CONFIG or ~/.config/ncorectl/config.yaml.
+//
+//	endpoint: http://ncore-api:8080
+//	token: <bearer token of the proxy in front of the api>
+//	output: table
+type config struct {
+	Endpoint string `yaml:"endpoint"`
+	// Token is sent as a bearer token, Username and Password as basic auth, for proxies in front of the api.
+	Token    string `yaml:"token"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	Output   string `yaml:"output"`
+}
+
+const defaultEndpoint = "http://localhost:8080"
+
+func defaultConfigPath() string {
+	if path := os.Getenv("NCORECTL_CONFIG"); path != "" {
+		return path
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "ncorectl", "config.yaml")
+}
+
+// loadConfig reads path, a missing file is an empty config unless explicit is set.
+func loadConfig(path string, explicit bool) (*config, error) {
+	c := &config{}
+	if path == "" {
+		return c, nil
+	}
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && !explicit {
+		return c, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := yaml.Unmarshal(b, c); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// httpClient returns an http.Client sending the credentials of c.
+func (c *config) httpClient() *http.Client {
+	if c.Token == "" && c.Username == "" {
+		return http.DefaultClient
+	}
+	return &http.Client{Transport: &credentialsTransport{config: c, next: http.DefaultTransport}}
+}
+
+type credentialsTransport struct {
+	config *config
+	next   http.RoundTripper
+}
+
+func (t *credentialsTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	if t.config.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+t.config.Token)
+	} else {
+		req.SetBasicAuth(t.config.Username, t.config.Password)
+	}
+	return t.next.RoundTrip(req)
+}
@@ -0,0 +1,150 @@
+// Command ncorectl manages the nodes, images and subnets of an ncore-api.
+//
+//	ncorectl [-config file] [-endpoint url] [-o table|json|yaml] <command> [flags] [args]
+//
+// Run ncorectl -h for the commands.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/client"
+)
+
+// env is what commands run with.
+type env struct {
+	client *client.Client
+	out    *printer
+	stdout io.Writer
+}
+
+type command struct {
+	name  string
+	usage string
+	run   func(ctx context.Context, e *env, args []string) error
+}
+
+// commands is set by init, the commands refer to it for their usage.
+var commands []command
+
+func init() {
+	commands = []command{
+		{"node status", "[mac_address]", runNodeStatus},
+		{"node set-image", "[-tag tag -type type | -channel channel] mac_address", runNodeSetImage},
+		{"node set-payload", "mac_address payload_id", runNodeSetPayload},
+		{"node ipxe", "[-ip ip_address] mac_address", runNodeIpxe},
+		{"node registrations", "[-state state]", runNodeRegistrations},
+		{"node approve", "[-tag tag -type type | -channel channel] [-payload payload_id] mac_address", runNodeApprove},
+		{"node reject", "mac_address", runNodeReject},
+		{"node inventory", "[-class hardware_class | mac_address]", runNodeInventory},
+		{"node interfaces", "mac_address", runNodeInterfaces},
+		{"node attach", "[-name name] mac_address interface_mac_address", runNodeAttach},
+		{"node detach", "mac_address interface_mac_address", runNodeDetach},
+		{"node hostname", "mac_address [hostname]", runNodeHostname},
+		{"hostname policies", "", runHostnamePolicies},
+		{"hostname add-policy", "[-subnet subnet] template", runHostnameAddPolicy},
+		{"hostname delete-policy", "policy_id", runHostnameDeletePolicy},
+		{"image list", "[-state state]", runImageList},
+		{"image register", "-f manifest.yaml", runImageRegister},
+		{"subnet list", "", runSubnetList},
+		{"subnet set", "[-tag tag -type type | -channel channel] subnet", runSubnetSet},
+		{"subnet delete", "subnet", runSubnetDelete},
+	}
+}
+
+func main() {
+	err := run(context.Background(), os.Args[1:], os.Stdout, os.Stderr)
+	if errors.Is(err, flag.ErrHelp) {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ncorectl: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	fset := flag.NewFlagSet("ncorectl", flag.ContinueOnError)
+	fset.SetOutput(stderr)
+	configPath := fset.String("config", "", "Config file with endpoint and credentials, $NCORECTL_CONFIG or ~/.config/ncorectl/config.yaml by default")
+	endpoint := fset.String("endpoint", "", "ncore-api url, overrides the endpoint of the config file")
+	output := fset.String("o", "", "Output format: table, json or yaml")
+	timeout := fset.Duration("timeout", 30*time.Second, "Timeout of each command")
+	fset.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: ncorectl [flags] <command> [flags] [args]\n\nCommands:\n")
+		for _, c := range commands {
+			fmt.Fprintf(stderr, "  %s\n", strings.TrimSpace(c.name+" "+c.usage))
+		}
+		fmt.Fprintf(stderr, "\nFlags:\n")
+		fset.PrintDefaults()
+	}
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := loadConfig(firstNonEmpty(*configPath, defaultConfigPath()), *configPath != "")
+	if err != nil {
+		return err
+	}
+	cfg.Endpoint = firstNonEmpty(*endpoint, os.Getenv("NCORECTL_ENDPOINT"), cfg.Endpoint, defaultEndpoint)
+	out, err := newPrinter(stdout, firstNonEmpty(*output, cfg.Output, "table"))
+	if err != nil {
+		return err
+	}
+
+	c, rest := lookupCommand(fset.Args())
+	if c == nil {
+		fset.Usage()
+		return fmt.Errorf("unknown command %q", fset.Args())
+	}
+	ctx, cancel := context.WithTimeout(ctx, *timeout)
+	defer cancel()
+	return c.run(ctx, &env{
+		client: client.NewClient(cfg.Endpoint, cfg.httpClient()),
+		out:    out,
+		stdout: stdout,
+	}, rest)
+}
+
+// lookupCommand returns the command named by the first two args and the rest of args.
+func lookupCommand(args []string) (*command, []string) {
+	if len(args) < 2 {
+		return nil, nil
+	}
+	for i := range commands {
+		if commands[i].name == args[0]+" "+args[1] {
+			return &commands[i], args[2:]
+		}
+	}
+	return nil, nil
+}
+
+// newFlagSet returns the flag set of c, its usage lists its flags and arguments.
+func newFlagSet(name string) *flag.FlagSet {
+	fset := flag.NewFlagSet(name, flag.ContinueOnError)
+	fset.Usage = func() {
+		for _, c := range commands {
+			if c.name == name {
+				fmt.Fprintf(fset.Output(), "Usage: ncorectl %s %s\n", c.name, c.usage)
+			}
+		}
+		fset.PrintDefaults()
+	}
+	return fset
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
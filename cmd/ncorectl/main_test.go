@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/coreweave/ncore-api/pkg/client"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) string {
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	t.Setenv("NCORECTL_CONFIG", filepath.Join(t.TempDir(), "missing.yaml"))
+	return srv.URL
+}
+
+func TestRun_nodeStatus(t *testing.T) {
+	url := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/nodes/", r.URL.Path)
+		json.NewEncoder(w).Encode([]*client.NodeView{
+			{MacAddress: "000000000001", Hostname: "g000001", ImageTag: "develop", ImageType: "ubuntu", PayloadIds: []string{"default"}},
+		})
+	})
+
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), []string{"-endpoint", url, "node", "status"}, &stdout, &stderr)
+
+	require.NoError(t, err)
+	assert.Equal(t, ""+
+		"MAC ADDRESS   HOSTNAME  IP ADDRESS  IMAGE TAG  IMAGE TYPE  CHANNEL  PAYLOADS  LAST SEEN\n"+
+		"000000000001  g000001   -           develop    ubuntu      -        default   -\n", stdout.String())
+}
+
+func TestRun_subnetSetYAML(t *testing.T) {
+	url := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		var s client.SubnetDefaultImage
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&s))
+		json.NewEncoder(w).Encode(s)
+	})
+
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), []string{"-endpoint", url, "-o", "yaml", "subnet", "set", "-channel", "stable", "10.0.0.0/24"}, &stdout, &stderr)
+
+	require.NoError(t, err)
+	assert.Equal(t, "Subnet: 10.0.0.0/24\nImageChannel: stable\n", stdout.String())
+}
+
+func TestRun_imageRegister(t *testing.T) {
+	dir := t.TempDir()
+	manifest := filepath.Join(dir, "image.yaml")
+	require.NoError(t, os.WriteFile(manifest, []byte("name: ubuntu-develop\nbucket: images\ntag: develop\ntype: ubuntu\n"), 0644))
+	config := filepath.Join(dir, "config.yaml")
+	url := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var i client.IpxeDbConfig
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&i))
+		assert.Equal(t, client.IpxeDbConfig{ImageName: "ubuntu-develop", ImageBucket: "images", ImageTag: "develop", ImageType: "ubuntu"}, i)
+		json.NewEncoder(w).Encode(client.IpxeConfig{ImageName: i.ImageName, ImageTag: i.ImageTag, ImageType: i.ImageType, ImageState: "staged"})
+	})
+	require.NoError(t, os.WriteFile(config, []byte("endpoint: "+url+"\ntoken: secret\noutput: json\n"), 0600))
+
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), []string{"-config", config, "image", "register", "-f", manifest}, &stdout, &stderr)
+
+	require.NoError(t, err)
+	var image client.IpxeConfig
+	require.NoError(t, json.Unmarshal(stdout.Bytes(), &image))
+	assert.Equal(t, "staged", image.ImageState)
+}
+
+func TestRun_error(t *testing.T) {
+	url := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"code": "database_unavailable", "errors": ["cannot list node views from database: database unavailable"]}`))
+	})
+
+	var stdout, stderr bytes.Buffer
+	err := run(context.Background(), []string{"-endpoint", url, "node", "status", "000000000001"}, &stdout, &stderr)
+
+	assert.EqualError(t, err, "ncore-api: cannot list node views from database: database unavailable")
+}
@@ -0,0 +1,348 @@
+// Package clientgen generates the Go client of pkg/client from the OpenAPI document of pkg/api.
+//
+// Generate only supports what the document uses: component schemas, path and query parameters
+// of scalar types, JSON request bodies and JSON or text responses.
+// The generated methods call a do method written by hand in the client package.
+package clientgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Generate returns the Go source of the types and methods of the document spec in package pkg.
+// Schemas only used by error responses are left out, the client package decodes errors itself.
+func Generate(specJSON []byte, pkg string) ([]byte, error) {
+	var s spec
+	if err := json.Unmarshal(specJSON, &s); err != nil {
+		return nil, fmt.Errorf("cannot parse spec: %w", err)
+	}
+	g := &generator{spec: &s, used: map[string]bool{}}
+	if err := g.operations(); err != nil {
+		return nil, err
+	}
+	g.types()
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by clientgen from pkg/api/openapi.json. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&out, "package %s\n\n", pkg)
+	fmt.Fprintf(&out, "import (\n")
+	for _, imp := range g.sortedImports() {
+		fmt.Fprintf(&out, "\t%q\n", imp)
+	}
+	fmt.Fprintf(&out, ")\n")
+	out.Write(g.typeBuf.Bytes())
+	out.Write(g.methodBuf.Bytes())
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("cannot format generated source: %w", err)
+	}
+	return src, nil
+}
+
+type generator struct {
+	spec      *spec
+	used      map[string]bool
+	imports   map[string]bool
+	typeBuf   bytes.Buffer
+	methodBuf bytes.Buffer
+}
+
+func (g *generator) use(imp string) {
+	if g.imports == nil {
+		g.imports = map[string]bool{}
+	}
+	g.imports[imp] = true
+}
+
+func (g *generator) sortedImports() []string {
+	var imps []string
+	for imp := range g.imports {
+		imps = append(imps, imp)
+	}
+	sort.Strings(imps)
+	return imps
+}
+
+// markUsed records the components s refers to, transitively.
+func (g *generator) markUsed(s *schema) {
+	if s == nil {
+		return
+	}
+	if name := s.refName(); name != "" {
+		if g.used[name] {
+			return
+		}
+		g.used[name] = true
+		g.markUsed(g.spec.Components.Schemas[name])
+		return
+	}
+	g.markUsed(s.Items)
+	for _, p := range s.Properties {
+		g.markUsed(p.Schema)
+	}
+}
+
+func (g *generator) types() {
+	var names []string
+	for name := range g.used {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s := g.spec.Components.Schemas[name]
+		fmt.Fprintf(&g.typeBuf, "\n")
+		if strings.HasPrefix(s.Description, name+" ") {
+			fmt.Fprintf(&g.typeBuf, "// %s\n", s.Description)
+		} else {
+			fmt.Fprintf(&g.typeBuf, "// %s is the %s schema of the API.\n", name, name)
+		}
+		fmt.Fprintf(&g.typeBuf, "type %s struct {\n", name)
+		for _, p := range s.Properties {
+			if p.Schema.Description != "" {
+				fmt.Fprintf(&g.typeBuf, "\t// %s\n", p.Schema.Description)
+			}
+			tag := p.Name
+			if !s.required(p.Name) {
+				tag += ",omitempty"
+			}
+			fmt.Fprintf(&g.typeBuf, "\t%s %s `json:%q`\n", exported(p.Name), g.goType(p.Schema, true), tag)
+		}
+		fmt.Fprintf(&g.typeBuf, "}\n")
+	}
+}
+
+// goType returns the Go type of s, field reports whether s is a struct field
+// where optional structs and times are pointers.
+func (g *generator) goType(s *schema, field bool) string {
+	if name := s.refName(); name != "" {
+		if field {
+			return "*" + name
+		}
+		return name
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			g.use("time")
+			if field {
+				return "*time.Time"
+			}
+			return "time.Time"
+		}
+		return "string"
+	case "integer":
+		if s.Format == "int64" {
+			return "int64"
+		}
+		return "int"
+	case "number":
+		if s.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(s.Items, true)
+	case "object":
+		return "map[string]any"
+	}
+	return "any"
+}
+
+func (g *generator) operations() error {
+	type op struct {
+		method, path string
+		*operation
+	}
+	var ops []op
+	for path, methods := range g.spec.Paths {
+		for method, o := range methods {
+			if o.OperationId == "" {
+				return fmt.Errorf("%s %s: missing operationId", strings.ToUpper(method), path)
+			}
+			ops = append(ops, op{strings.ToUpper(method), path, o})
+		}
+	}
+	sort.Slice(ops, func(i, j int) bool { return ops[i].OperationId < ops[j].OperationId })
+	for _, o := range ops {
+		if err := g.operation(o.method, o.path, o.operation); err != nil {
+			return fmt.Errorf("%s: %w", o.OperationId, err)
+		}
+	}
+	return nil
+}
+
+func (g *generator) operation(method, path string, o *operation) error {
+	g.use("context")
+	g.use("net/http")
+	name := exported(o.OperationId)
+
+	args := []string{"ctx context.Context"}
+	pathExpr := `"` + path + `"`
+	var query []string
+	for _, p := range o.Parameters {
+		arg := unexported(p.Name)
+		typ := g.goType(p.Schema, false)
+		args = append(args, arg+" "+typ)
+		switch p.In {
+		case "path":
+			g.use("net/url")
+			value := "url.PathEscape(" + arg + ")"
+			if typ == "int64" {
+				g.use("strconv")
+				value = "strconv.FormatInt(" + arg + ", 10)"
+			}
+			pathExpr = strings.Replace(pathExpr, "{"+p.Name+"}", `" + `+value+` + "`, 1)
+		case "query":
+			g.use("net/url")
+			switch typ {
+			case "string":
+				query = append(query, fmt.Sprintf("if %s != \"\" {\n\tq.Set(%q, %s)\n}", arg, p.Name, arg))
+			case "bool":
+				query = append(query, fmt.Sprintf("if %s {\n\tq.Set(%q, \"true\")\n}", arg, p.Name))
+			default:
+				return fmt.Errorf("unsupported query parameter %s of type %s", p.Name, typ)
+			}
+		default:
+			return fmt.Errorf("unsupported parameter %s in %s", p.Name, p.In)
+		}
+	}
+	pathExpr = strings.TrimSuffix(pathExpr, ` + ""`)
+
+	bodyArg := "nil"
+	if o.RequestBody != nil {
+		media, ok := o.RequestBody.Content["application/json"]
+		if !ok {
+			return fmt.Errorf("unsupported request body, only application/json is")
+		}
+		g.markUsed(media.Schema)
+		args = append(args, "body "+pointer(g.goType(media.Schema, false)))
+		bodyArg = "body"
+	}
+
+	ok, found := o.Responses["200"]
+	if !found {
+		return fmt.Errorf("missing 200 response")
+	}
+	var outType string
+	if media, found := ok.Content["application/json"]; found {
+		g.markUsed(media.Schema)
+		outType = g.goType(media.Schema, false)
+	} else if _, found := ok.Content["text/plain"]; found {
+		outType = "string"
+	} else {
+		return fmt.Errorf("unsupported 200 response, only application/json and text/plain are")
+	}
+
+	// Other statuses with the body of the 200 response are results, not errors.
+	var statuses []string
+	var statusDocs []string
+	for code, resp := range o.Responses {
+		if code == "200" || code == "default" {
+			continue
+		}
+		media, found := resp.Content["application/json"]
+		if !found || media.Schema.refName() == "" || media.Schema.refName() != ok.Content["application/json"].Schema.refName() {
+			continue
+		}
+		statuses = append(statuses, statusConst(code))
+		statusDocs = append(statusDocs, fmt.Sprintf("// On %s it returns the %s with a nil error: %s.", code, outType, lowerFirst(resp.Description)))
+	}
+	sort.Strings(statuses)
+	sort.Strings(statusDocs)
+
+	w := &g.methodBuf
+	fmt.Fprintf(w, "\n// %s %s.\n", name, lowerFirst(strings.TrimSuffix(o.Summary, ".")))
+	for _, doc := range statusDocs {
+		fmt.Fprintf(w, "%s\n", doc)
+	}
+	fmt.Fprintf(w, "//\n// %s %s\n", method, path)
+	pointerOut := strings.HasPrefix(outType, "[]") || strings.HasPrefix(outType, "map[") || outType == "string"
+	retType := outType
+	if !pointerOut {
+		retType = "*" + outType
+	}
+	fmt.Fprintf(w, "func (c *Client) %s(%s) (%s, error) {\n", name, strings.Join(args, ", "), retType)
+	queryArg := "nil"
+	if len(query) > 0 {
+		queryArg = "q"
+		fmt.Fprintf(w, "q := url.Values{}\n")
+		for _, q := range query {
+			fmt.Fprintf(w, "%s\n", q)
+		}
+	}
+	fmt.Fprintf(w, "var out %s\n", outType)
+	callArgs := []string{"ctx", methodConst(method), pathExpr, queryArg, bodyArg, "&out"}
+	callArgs = append(callArgs, statuses...)
+	fmt.Fprintf(w, "if err := c.do(%s); err != nil {\n", strings.Join(callArgs, ", "))
+	if pointerOut {
+		zero := "nil"
+		if outType == "string" {
+			zero = `""`
+		}
+		fmt.Fprintf(w, "return %s, err\n}\nreturn out, nil\n}\n", zero)
+	} else {
+		fmt.Fprintf(w, "return nil, err\n}\nreturn &out, nil\n}\n")
+	}
+	return nil
+}
+
+func pointer(typ string) string {
+	if strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") {
+		return typ
+	}
+	return "*" + typ
+}
+
+func methodConst(method string) string {
+	return "http.Method" + string(method[0]) + strings.ToLower(method[1:])
+}
+
+// statusConst returns the net/http constant of the status code, the code itself if there's none.
+func statusConst(code string) string {
+	var status int
+	fmt.Sscanf(code, "%d", &status)
+	text := http.StatusText(status)
+	if text == "" {
+		return code
+	}
+	return "http.Status" + strings.NewReplacer(" ", "", "-", "", "'", "").Replace(text)
+}
+
+// exported converts a camelCase or snake_case name to an exported Go identifier.
+func exported(name string) string {
+	var b strings.Builder
+	upper := true
+	for _, r := range name {
+		if r == '_' || r == '-' || r == '.' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+func unexported(name string) string {
+	return lowerFirst(exported(name))
+}
+
+func lowerFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	return strings.ToLower(s[:1]) + s[1:]
+}
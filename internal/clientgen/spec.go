@@ -0,0 +1,102 @@
+package clientgen
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+)
+
+// spec is the subset of an OpenAPI 3 document used by Generate.
+type spec struct {
+	Paths      map[string]map[string]*operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type operation struct {
+	OperationId string      `json:"operationId"`
+	Summary     string      `json:"summary"`
+	Parameters  []parameter `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]mediaType `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]response `json:"responses"`
+}
+
+type parameter struct {
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Required    bool    `json:"required"`
+	Description string  `json:"description"`
+	Schema      *schema `json:"schema"`
+}
+
+type response struct {
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref                  string      `json:"$ref"`
+	Type                 string      `json:"type"`
+	Format               string      `json:"format"`
+	Description          string      `json:"description"`
+	Items                *schema     `json:"items"`
+	Properties           properties  `json:"properties"`
+	Required             []string    `json:"required"`
+	AdditionalProperties interface{} `json:"additionalProperties"`
+}
+
+// refName returns the name of the component s refers to, empty if s isn't a reference.
+func (s *schema) refName() string {
+	if s == nil {
+		return ""
+	}
+	return strings.TrimPrefix(s.Ref, "#/components/schemas/")
+}
+
+func (s *schema) required(name string) bool {
+	for _, r := range s.Required {
+		if r == name {
+			return true
+		}
+	}
+	return false
+}
+
+type property struct {
+	Name   string
+	Schema *schema
+}
+
+// properties keeps the order of the document so generated structs follow it.
+type properties []property
+
+func (p *properties) UnmarshalJSON(data []byte) error {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if t, err := dec.Token(); err != nil || t != json.Delim('{') {
+		return fmt.Errorf("properties: expected an object")
+	}
+	for dec.More() {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		name, ok := t.(string)
+		if !ok {
+			return fmt.Errorf("properties: expected a name, got %v", t)
+		}
+		var s schema
+		if err := dec.Decode(&s); err != nil {
+			return fmt.Errorf("properties: %s: %w", name, err)
+		}
+		*p = append(*p, property{Name: name, Schema: &s})
+	}
+	return nil
+}
@@ -4,19 +4,31 @@ import (
 	"context"
 	"flag"
 	"fmt"
-	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sort"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/coreweave/ncore-api/pkg/api"
+	"github.com/coreweave/ncore-api/pkg/bulk"
 	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/health"
 	"github.com/coreweave/ncore-api/pkg/ipxe"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/metrics"
 	"github.com/coreweave/ncore-api/pkg/nodes"
 	"github.com/coreweave/ncore-api/pkg/payloads"
 	"github.com/coreweave/ncore-api/pkg/postgres"
+	"github.com/coreweave/ncore-api/pkg/registration"
 	"github.com/coreweave/ncore-api/pkg/s3"
+	"github.com/coreweave/ncore-api/pkg/snapshot"
+	"github.com/coreweave/ncore-api/pkg/tracing"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
 )
 
 type pgConfig struct {
@@ -25,6 +37,7 @@ type pgConfig struct {
 	pghost     string
 	pgport     string
 	pgdatabase string
+	searchPath []string
 }
 
 func newPGConfigFromEnv(envPrefix string) *pgConfig {
@@ -45,98 +58,326 @@ host=%s
 port=%s
 dbname=%s`
 
-	return fmt.Sprintf(formatString,
+	connString := fmt.Sprintf(formatString,
 		p.pguser,
 		p.pgpassword,
 		p.pghost,
 		p.pgport,
 		p.pgdatabase,
 	)
+	if len(p.searchPath) > 0 {
+		connString += "\nsearch_path=" + strings.Join(p.searchPath, ",")
+	}
+	return connString
 }
 
 func main() {
 	var (
 		httpAddr,
 		s3Host,
+		s3Backend,
+		s3LocalRoot,
+		s3LocalURL,
 		ipxeTemplateFile,
 		ipxeDefaultImage,
 		ipxeDefaultImageTag,
 		ipxeDefaultImageType,
 		ipxeDefaultBucket,
 		payloadsDefaultPayloadId,
-		payloadsDefaultPayloadDirectory string
+		payloadsDefaultPayloadDirectory,
+		ipxeSyncSources,
+		ipxeSyncNamePattern string
+		ipxeSyncEnabled,
+		ipxeSyncDryRun,
+		databaseSingle,
+		databaseCheckVersion bool
+		logLevel,
+		logFormat string
+		tracingConfig  tracing.Config
+		healthCritical string
+		healthTimeout,
+		healthSlow time.Duration
+		ipxeSyncInterval,
+		ipxeRolloutInterval time.Duration
+		snapshotDir      string
+		snapshotInterval time.Duration
+		enrollmentPolicy,
+		enrollmentDiscoveryImage,
+		enrollmentDiscoveryPayloadId,
+		enrollmentDiscoveryPayloadDirectory string
+		hostnameTemplate   string
+		httpTrustedProxies string
+		httpTrustedDhcp    string
+		httpProxyProtocol  bool
 	)
 
 	flag.StringVar(&httpAddr, "http", "localhost:8080", "HTTP service address to listen for incoming requests on")
+	flag.StringVar(&httpTrustedProxies, "http.trustedProxies", "", "Comma separated addresses and subnets of the load balancers and ingresses trusted to forward client addresses with X-Forwarded-For, X-Real-IP or the PROXY protocol")
+	flag.StringVar(&httpTrustedDhcp, "http.trustedDhcp", "", "Comma separated addresses and subnets of the DHCP servers trusted to pass the address of a node with the ip query parameter of boot requests")
+	flag.BoolVar(&httpProxyProtocol, "http.proxyProtocol", false, "Read a PROXY protocol header from the connections of http.trustedProxies, which must send one")
+	flag.StringVar(&logLevel, "log.level", "info", "Minimum level of logged lines: debug, info, warn or error")
+	flag.StringVar(&logFormat, "log.format", "json", "Format of logged lines: json or console")
+	flag.StringVar(&tracingConfig.Exporter, "tracing.exporter", tracing.ExporterNone, "OpenTelemetry span exporter: none, stdout or otlp")
+	flag.StringVar(&tracingConfig.Endpoint, "tracing.endpoint", "", "host:port of the OTLP/HTTP collector, OTEL_EXPORTER_OTLP_ENDPOINT or localhost:4318 by default")
+	flag.BoolVar(&tracingConfig.Insecure, "tracing.insecure", false, "Export spans to the OTLP collector over http instead of https")
+	flag.Float64Var(&tracingConfig.SampleRatio, "tracing.sampleRatio", 1, "Fraction of traces started by ncore-api that are sampled, traces propagated by clients keep their sampling decision")
+	flag.BoolVar(&databaseSingle, "database.single", false, "Use one database configured by NCORE_PG* variables with ipxe, payloads and nodes schemas instead of PAYLOADS_, IPXE_ and NODES_ databases")
+	flag.BoolVar(&databaseCheckVersion, "database.checkVersion", true, "Refuse to start unless every database is at the schema version of the embedded migrations")
+	flag.StringVar(&healthCritical, "health.critical", "ncore,ipxe,payloads", "Comma separated dependencies failing /readyz when unavailable, among ncore, ipxe, payloads, nodes and s3. Others only degrade it")
+	flag.DurationVar(&healthTimeout, "health.timeout", 2*time.Second, "Timeout of each /readyz dependency check")
+	flag.DurationVar(&healthSlow, "health.slow", 500*time.Millisecond, "Latency above which a /readyz dependency check is degraded")
 	flag.StringVar(&s3Host, "s3.host", "https://accel-object.ord1.coreweave.com", "S3 Storage endpoint")
+	flag.StringVar(&s3Backend, "s3.backend", "s3", "Object storage backend used for images: s3 or local")
+	flag.StringVar(&s3LocalRoot, "s3.local.root", "", "Directory holding <bucket>/<image>/ files when s3.backend is local")
+	flag.StringVar(&s3LocalURL, "s3.local.url", "", "Base http or https url s3.local.root is served at, required when s3.backend is local")
 	flag.StringVar(&ipxeTemplateFile, "ipxe.template", "pkg/ipxe/templates/template_https.ipxe", "Relative path to ipxe template file")
-	flag.StringVar(&ipxeDefaultImage, "ipxe.default.image", "default", "Default image used when database is unavailable or no entry found for macAddress")
+	flag.StringVar(&ipxeDefaultImage, "ipxe.default.image", "default", "Default image used when neither the database nor a snapshot has an entry for macAddress")
 	flag.StringVar(&ipxeDefaultImageTag, "ipxe.default.imageTag", "default", "Default image_tag entry added for node when no entry found for macAddress")
 	flag.StringVar(&ipxeDefaultImageType, "ipxe.default.imageType", "default", "Default image_type entry added for node when no entry found for macAddress")
-	flag.StringVar(&ipxeDefaultBucket, "ipxe.default.bucket", "default", "Default image used when database is unavailable or no entry found for macAddress")
+	flag.StringVar(&ipxeDefaultBucket, "ipxe.default.bucket", "default", "Bucket of the default image used when neither the database nor a snapshot has an entry for macAddress")
+	flag.BoolVar(&ipxeSyncEnabled, "ipxe.sync.enabled", false, "Discover complete image directories in object storage and register them in ipxe.images")
+	flag.StringVar(&ipxeSyncSources, "ipxe.sync.sources", "", "Comma separated bucket or bucket/prefix list scanned for image directories")
+	flag.StringVar(&ipxeSyncNamePattern, "ipxe.sync.namePattern", ipxe.DefaultImageSyncNamePattern, "Regexp with named groups tag and type matched against image directory names")
+	flag.DurationVar(&ipxeSyncInterval, "ipxe.sync.interval", 5*time.Minute, "Interval between image sync runs")
+	flag.BoolVar(&ipxeSyncDryRun, "ipxe.sync.dryRun", true, "Only log the images the sync would register")
+	flag.DurationVar(&ipxeRolloutInterval, "ipxe.rollouts.interval", time.Minute, "Interval between heartbeat checks of in progress image rollouts")
+	flag.DurationVar(&snapshotInterval, "snapshot.interval", time.Minute, "Interval between refreshes of the ipxe and payloads snapshots answering boot requests while the database is unavailable, 0 disables them")
+	flag.StringVar(&snapshotDir, "snapshot.dir", "", "Directory the snapshots are written to and read back from at startup, snapshots are only kept in memory when empty")
+	flag.StringVar(&enrollmentPolicy, "enrollment.policy", string(enrollment.PolicyPin), "How nodes booting without a node_images or node_payloads entry are enrolled: pin records the default applying on first boot, follow records the node but resolves its default on every boot until it is assigned, register refuses to boot nodes not assigned through the api, approve boots them the discovery image and payload until they are approved")
+	flag.StringVar(&enrollmentDiscoveryImage, "enrollment.discovery.image", "discovery", "Image in ipxe.default.bucket booted by pending nodes under the approve enrollment policy")
+	flag.StringVar(&enrollmentDiscoveryPayloadId, "enrollment.discovery.payloadId", "discovery", "PayloadId booted by pending nodes under the approve enrollment policy")
+	flag.StringVar(&enrollmentDiscoveryPayloadDirectory, "enrollment.discovery.payloadDirectory", "discovery", "PayloadDirectory booted by pending nodes under the approve enrollment policy")
+	flag.StringVar(&hostnameTemplate, "hostname.template", nodes.DefaultHostnameTemplate, "text/template naming nodes on first boot when no hostname policy matches them, with .MacAddress, .MacSuffix, .Rack and .Slot")
 	flag.StringVar(&payloadsDefaultPayloadId, "payloads.default.payloadId", "default", "Default PayloadId assigned when no entry found for macAddress")
 	flag.StringVar(&payloadsDefaultPayloadDirectory, "payloads.default.payloadDirectory", "default", "Default PayloadDirectory assigned when no entry found for macAddress")
 
 	flag.Parse()
-	pgxLogLevel, err := database.LogLevelFromEnv()
+	logger, err := logging.New(logLevel, logFormat)
 	if err != nil {
-		log.Fatal(err)
+		fmt.Fprintf(os.Stderr, "ncore-api: %v\n", err)
+		os.Exit(2)
 	}
+	defer logger.Sync()
+	zap.ReplaceGlobals(logger)
+	// libraries logging with the standard logger go through logger too
+	zap.RedirectStdLog(logger)
 
-	payloadsPGConfig := newPGConfigFromEnv("PAYLOADS")
-	pgPoolPayloads, err := database.NewPGXPool(context.Background(), payloadsPGConfig.connString(), &database.PGXStdLogger{}, pgxLogLevel)
+	shutdownTracing, err := tracing.Setup(context.Background(), tracingConfig)
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal("cannot set up tracing", zap.Error(err))
 	}
-	defer pgPoolPayloads.Close()
 
-	ipxePGConfig := newPGConfigFromEnv("IPXE")
-	pgPoolIpxe, err := database.NewPGXPool(context.Background(), ipxePGConfig.connString(), &database.PGXStdLogger{}, pgxLogLevel)
+	pgxLogLevel, err := database.LogLevelFromEnv()
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal("invalid PGX_LOG_LEVEL", zap.Error(err))
+	}
+
+	var payloadsDB, ipxeDB, nodesDB *postgres.DB
+	var domains []*domainDB
+	// pools are labeled by database in the pgxpool metrics
+	pools := map[string]*pgxpool.Pool{}
+	if databaseSingle {
+		ncorePGConfig := newPGConfigFromEnv("NCORE")
+		ncorePGConfig.searchPath = postgres.Schemas
+		pgPool, err := database.NewPGXPool(context.Background(), ncorePGConfig.connString(), &database.PGXLogger{}, pgxLogLevel)
+		if err != nil {
+			logger.Fatal("cannot create ncore database pool", zap.Error(err))
+		}
+		defer pgPool.Close()
+		db := &postgres.DB{
+			Postgres: pgPool,
+			Single:   true,
+		}
+		payloadsDB, ipxeDB, nodesDB = db, db, db
+		pools["ncore"] = pgPool
+		for _, schema := range postgres.Schemas {
+			domains = append(domains, &domainDB{name: schema, config: ncorePGConfig, pool: pgPool, schema: schema})
+		}
+	} else {
+		payloadsPGConfig := newPGConfigFromEnv("PAYLOADS")
+		pgPoolPayloads, err := database.NewPGXPool(context.Background(), payloadsPGConfig.connString(), &database.PGXLogger{}, pgxLogLevel)
+		if err != nil {
+			logger.Fatal("cannot create payloads database pool", zap.Error(err))
+		}
+		defer pgPoolPayloads.Close()
+		payloadsDB = &postgres.DB{
+			Postgres: pgPoolPayloads,
+		}
+
+		ipxePGConfig := newPGConfigFromEnv("IPXE")
+		pgPoolIpxe, err := database.NewPGXPool(context.Background(), ipxePGConfig.connString(), &database.PGXLogger{}, pgxLogLevel)
+		if err != nil {
+			logger.Fatal("cannot create ipxe database pool", zap.Error(err))
+		}
+		defer pgPoolIpxe.Close()
+		ipxeDB = &postgres.DB{
+			Postgres: pgPoolIpxe,
+		}
+
+		nodesPGConfig := newPGConfigFromEnv("NODES")
+		pgPoolNodes, err := database.NewPGXPool(context.Background(), nodesPGConfig.connString(), &database.PGXLogger{}, pgxLogLevel)
+		if err != nil {
+			logger.Fatal("cannot create nodes database pool", zap.Error(err))
+		}
+		defer pgPoolNodes.Close()
+		nodesDB = &postgres.DB{
+			Postgres: pgPoolNodes,
+		}
+		domains = []*domainDB{
+			{name: "ipxe", config: ipxePGConfig, pool: pgPoolIpxe},
+			{name: "payloads", config: payloadsPGConfig, pool: pgPoolPayloads},
+			{name: "nodes", config: nodesPGConfig, pool: pgPoolNodes},
+		}
+		for _, d := range domains {
+			pools[d.name] = d.pool
+		}
 	}
-	defer pgPoolIpxe.Close()
 
-	nodesPGConfig := newPGConfigFromEnv("NODES")
-	pgPoolNodes, err := database.NewPGXPool(context.Background(), nodesPGConfig.connString(), &database.PGXStdLogger{}, pgxLogLevel)
+	if flag.Arg(0) == "migrate" {
+		if err := runMigrate(context.Background(), domains, flag.Args()[1:]); err != nil {
+			logger.Fatal("migrate failed", zap.Error(err))
+		}
+		return
+	}
+	if databaseCheckVersion {
+		if err := checkSchemaVersions(context.Background(), domains); err != nil {
+			// a restarted process can still boot nodes from the snapshots it wrote
+			if !snapshotFilesExist(snapshotDir) || databasesReachable(pools) {
+				logger.Fatal("database schema out of date", zap.Error(err))
+			}
+			logger.Error("cannot check database schema versions, starting with the snapshots in snapshot.dir", zap.Error(err))
+		}
+	}
+
+	var objectStore s3.ObjectStore
+	switch s3Backend {
+	case "s3":
+		logger.Info("using s3 object storage", zap.String("host", s3Host))
+		s3Svc := s3.NewClient(s3Host)
+		if s3Svc == nil {
+			logger.Fatal("cannot create s3 client")
+		}
+		objectStore = s3Svc
+	case "local":
+		logger.Info("using local object storage", zap.String("root", s3LocalRoot))
+		objectStore, err = s3.NewLocalStore(s3LocalRoot, s3LocalURL)
+		if err != nil {
+			logger.Fatal("cannot create local object storage", zap.Error(err))
+		}
+	default:
+		logger.Fatal("unknown s3.backend", zap.String("backend", s3Backend))
+	}
+	objectStore = metrics.InstrumentObjectStore(s3Backend, tracing.InstrumentObjectStore(s3Backend, objectStore))
+
+	policy, err := enrollment.ParsePolicy(enrollmentPolicy)
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal("invalid -enrollment.policy", zap.Error(err))
+	}
+
+	ipxeSvc := ipxe.NewService(
+		ipxeDB,
+		objectStore,
+		ipxeTemplateFile,
+		ipxeDefaultImage,
+		ipxeDefaultImageTag,
+		ipxeDefaultImageType,
+		ipxeDefaultBucket,
+	)
+
+	ipxeSvc.SetEnrollmentPolicy(policy)
+
+	nodesSvc := nodes.NewService(nodesDB)
+	if err := nodesSvc.SetDefaultHostnameTemplate(hostnameTemplate); err != nil {
+		logger.Fatal("invalid -hostname.template", zap.Error(err))
+	}
+	ipxeSvc.SetHeartbeatSource(nodesSvc)
+	ipxeSvc.SetNodeResolver(nodesSvc)
+	ipxeSvc.SetHostnameSource(nodesSvc)
+	metrics.Registry.MustRegister(
+		metrics.NewPGXPoolCollector(pools),
+		metrics.NewHeartbeatCollector(nodesSvc.CountNodesLastSeen, 5*time.Second),
+	)
+
+	payloadsSvc := payloads.NewService(
+		payloadsDB,
+		payloadsDefaultPayloadId,
+		payloadsDefaultPayloadDirectory,
+	)
+	payloadsSvc.SetEnrollmentPolicy(policy)
+	payloadsSvc.SetNodeResolver(nodesSvc)
+	nodesSvc.SetViewSources(ipxeSvc, payloadsSvc)
+
+	// unknown nodes are registered as pending under the approve policy
+	bulkSvc := bulk.NewService(ipxeSvc, payloadsSvc, nodesSvc)
+	bulkSvc.SetSingleDatabase(databaseSingle)
+	registrationSvc := registration.NewService(ipxeSvc, payloadsSvc, nodesSvc, bulkSvc)
+	ipxeSvc.SetRegistrar(registrationSvc)
+	ipxeSvc.SetDiscoveryImage(enrollmentDiscoveryImage)
+	payloadsSvc.SetRegistrar(registrationSvc)
+	payloadsSvc.SetDiscoveryPayload(enrollmentDiscoveryPayloadId, enrollmentDiscoveryPayloadDirectory)
+
+	// boot requests are answered from these snapshots while the database is unavailable
+	var ipxeSnapshots *snapshot.Store[*ipxe.Snapshot]
+	var payloadsSnapshots *snapshot.Store[*payloads.Snapshot]
+	if snapshotInterval > 0 {
+		ipxeSnapshots = snapshot.NewStore(ipxe.SnapshotName, snapshotPath(snapshotDir, ipxe.SnapshotName), ipxeSvc.TakeSnapshot)
+		payloadsSnapshots = snapshot.NewStore(payloads.SnapshotName, snapshotPath(snapshotDir, payloads.SnapshotName), payloadsSvc.TakeSnapshot)
+		readSnapshotFile(logger, ipxeSnapshots)
+		readSnapshotFile(logger, payloadsSnapshots)
+		ipxeSvc.SetSnapshots(ipxeSnapshots)
+		payloadsSvc.SetSnapshots(payloadsSnapshots)
+	}
+	fallbacks := map[string]func() bool{
+		"ipxe":     func() bool { return ipxeSnapshots.Get() != nil },
+		"payloads": func() bool { return payloadsSnapshots.Get() != nil },
+		"ncore":    func() bool { return ipxeSnapshots.Get() != nil && payloadsSnapshots.Get() != nil },
 	}
-	defer pgPoolNodes.Close()
 
-	log.Printf("s3Host: %v", s3Host)
-	s3Svc := s3.NewClient(s3Host)
-	presignClient := s3.NewPresigner(*s3Svc)
+	critical := map[string]bool{}
+	for _, name := range strings.Split(healthCritical, ",") {
+		critical[strings.TrimSpace(name)] = true
+	}
+	var checks []health.Check
+	for _, name := range sortedKeys(pools) {
+		checks = append(checks, health.Check{Name: name, Critical: critical[name], Check: health.PingPool(pools[name]), Fallback: fallbacks[name]})
+	}
+	checks = append(checks, health.Check{Name: "s3", Critical: critical["s3"], Check: health.HeadBucket(objectStore, ipxeDefaultBucket)})
+
+	trustedProxies, err := api.ParsePrefixes(httpTrustedProxies)
+	if err != nil {
+		logger.Fatal("invalid -http.trustedProxies", zap.Error(err))
+	}
+	trustedDhcp, err := api.ParsePrefixes(httpTrustedDhcp)
+	if err != nil {
+		logger.Fatal("invalid -http.trustedDhcp", zap.Error(err))
+	}
 
 	s := &api.Server{
-		Payloads: payloads.NewService(
-			&postgres.DB{
-				Postgres: pgPoolPayloads,
-			},
-			payloadsDefaultPayloadId,
-			payloadsDefaultPayloadDirectory,
-		),
-		Ipxe: ipxe.NewService(
-			&postgres.DB{
-				Postgres: pgPoolIpxe,
-			},
-			*s3Svc,
-			presignClient,
-			ipxeTemplateFile,
-			ipxeDefaultImage,
-			ipxeDefaultImageTag,
-			ipxeDefaultImageType,
-			ipxeDefaultBucket,
-		),
-		Nodes: nodes.NewService(
-			&postgres.DB{
-				Postgres: pgPoolNodes,
-			},
-		),
-		HTTPAddress: httpAddr,
+		Payloads:       payloadsSvc,
+		Ipxe:           ipxeSvc,
+		Nodes:          nodesSvc,
+		Registration:   registrationSvc,
+		Bulk:           bulkSvc,
+		Health:         health.NewChecker(healthTimeout, healthSlow, checks...),
+		HTTPAddress:    httpAddr,
+		TrustedProxies: trustedProxies,
+		TrustedDhcp:    trustedDhcp,
+		ProxyProtocol:  httpProxyProtocol,
 	}
 	ec := make(chan error, 1)
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	if ipxeSyncEnabled {
+		syncConfig, err := ipxe.NewImageSyncConfig(ipxeSyncSources, ipxeSyncNamePattern, ipxeSyncInterval, ipxeSyncDryRun)
+		if err != nil {
+			logger.Fatal("invalid image sync configuration", zap.Error(err))
+		}
+		go ipxe.NewImageSyncer(ipxeSvc, *syncConfig).Run(ctx)
+	}
+	go ipxe.NewRolloutWatcher(ipxeSvc, ipxeRolloutInterval).Run(ctx)
+	if snapshotInterval > 0 {
+		go ipxeSnapshots.Run(ctx, snapshotInterval)
+		go payloadsSnapshots.Run(ctx, snapshotInterval)
+	}
 	go func() {
 		ec <- s.Run(context.Background())
 	}()
@@ -153,7 +394,62 @@ func main() {
 		stop()
 		err = <-ec
 	}
+	flushCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		logger.Error("cannot flush spans", zap.Error(err))
+	}
 	if err != nil {
-		log.Fatal(err)
+		logger.Fatal("server failed", zap.Error(err))
+	}
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// snapshotPath returns the file of the snapshot name in dir, empty when dir is.
+func snapshotPath(dir string, name string) string {
+	if dir == "" {
+		return ""
+	}
+	return filepath.Join(dir, name+".json")
+}
+
+// snapshotFilesExist reports whether dir holds the ipxe and payloads snapshots.
+func snapshotFilesExist(dir string) bool {
+	if dir == "" {
+		return false
+	}
+	for _, name := range []string{ipxe.SnapshotName, payloads.SnapshotName} {
+		if _, err := os.Stat(snapshotPath(dir, name)); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+// databasesReachable reports whether every pool answers a ping.
+func databasesReachable(pools map[string]*pgxpool.Pool) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, pool := range pools {
+		if err := health.PingPool(pool)(ctx); err != nil {
+			return false
+		}
+	}
+	return true
+}
+
+func readSnapshotFile[T any](logger *zap.Logger, store *snapshot.Store[T]) {
+	if err := store.ReadFile(); err != nil {
+		logger.Error("cannot read snapshot file", zap.String("snapshot", store.Name()), zap.Error(err))
+	} else if snap := store.Get(); snap != nil {
+		logger.Info("read snapshot file", zap.String("snapshot", store.Name()), zap.Time("taken_at", snap.TakenAt))
 	}
 }
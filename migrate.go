@@ -0,0 +1,153 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+
+	"github.com/coreweave/ncore-api/migrations"
+	"github.com/coreweave/ncore-api/pkg/migrate"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// domainDB is the database, or the schema in the single database mode, of ipxe, payloads or nodes.
+type domainDB struct {
+	name   string
+	config *pgConfig
+	pool   *pgxpool.Pool
+	schema string
+}
+
+// migrator returns a Migrator for the migrations of d, the _test migrations when test is set and they exist.
+func (d *domainDB) migrator(test bool) (*migrate.Migrator, error) {
+	ms, err := d.migrations(test)
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewMigrator(d.pool, d.schema, ms), nil
+}
+
+// migrations loads the migrations of d. In the single database mode the _single migrations replace the ones
+// granting read_only on the ipxe, payloads and nodes databases with grants on the schemas.
+func (d *domainDB) migrations(test bool) ([]*migrate.Migration, error) {
+	dir := d.name
+	if _, err := fs.Stat(migrations.FS, d.name+"_test"); test && err == nil {
+		dir = d.name + "_test"
+	}
+	ms, err := migrate.Load(migrations.FS, dir, os.Getenv)
+	if err != nil || d.schema == "" {
+		return ms, err
+	}
+	return migrate.Replace(ms, migrations.FS, d.name+"_single", os.Getenv)
+}
+
+// checkSchemaVersions returns an error unless every database is at the version of the embedded migrations.
+func checkSchemaVersions(ctx context.Context, domains []*domainDB) error {
+	for _, d := range domains {
+		m, err := d.migrator(false)
+		if err != nil {
+			return err
+		}
+		if err := m.Check(ctx); err != nil {
+			return fmt.Errorf("%s: %w, run ncore-api migrate up", d.name, err)
+		}
+	}
+	return nil
+}
+
+// runMigrate implements ncore-api migrate [-only ipxe,payloads,nodes] [-test] [-steps n] up|down|status.
+func runMigrate(ctx context.Context, domains []*domainDB, args []string) error {
+	fset := flag.NewFlagSet("migrate", flag.ExitOnError)
+	only := fset.String("only", "", "Comma separated databases to migrate, all of ipxe, payloads and nodes by default")
+	test := fset.Bool("test", false, "Use the _test migrations with test values instead of read_only grants")
+	steps := fset.Int("steps", 1, "Number of migrations reverted by down")
+	fset.Usage = func() {
+		fmt.Fprintf(fset.Output(), "Usage: ncore-api [flags] migrate [-only ipxe,payloads,nodes] [-test] [-steps n] up|down|status\n")
+		fset.PrintDefaults()
+	}
+	fset.Parse(args)
+	if fset.NArg() != 1 {
+		fset.Usage()
+		return errors.New("migrate needs one of up, down or status")
+	}
+	command := fset.Arg(0)
+
+	selected := domains
+	if *only != "" {
+		selected = nil
+		for _, name := range strings.Split(*only, ",") {
+			found := false
+			for _, d := range domains {
+				if d.name == strings.TrimSpace(name) {
+					selected = append(selected, d)
+					found = true
+				}
+			}
+			if !found {
+				return fmt.Errorf("unknown database: %s", name)
+			}
+		}
+	}
+
+	for _, d := range selected {
+		m, err := d.migrator(*test)
+		if err != nil {
+			return err
+		}
+		switch command {
+		case "up":
+			if err := ensureDatabase(ctx, d.config); err != nil {
+				return err
+			}
+			err = m.Up(ctx)
+		case "down":
+			err = m.Down(ctx, *steps)
+		case "status":
+			var status *migrate.Status
+			status, err = m.Status(ctx)
+			if status != nil {
+				fmt.Printf("%s: version %d of %d in %s\n", d.name, status.CurrentVersion, status.LatestVersion, status.VersionTable)
+				for _, name := range status.Pending {
+					fmt.Printf("%s: pending %s\n", d.name, name)
+				}
+			}
+		default:
+			fset.Usage()
+			return fmt.Errorf("unknown migrate command: %s", command)
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", d.name, err)
+		}
+	}
+	return nil
+}
+
+// ensureDatabase creates the database of config unless it exists, connecting to the postgres database.
+func ensureDatabase(ctx context.Context, config *pgConfig) error {
+	admin := *config
+	admin.pgdatabase = "postgres"
+	admin.searchPath = nil
+	conn, err := pgx.Connect(ctx, admin.connString())
+	if err != nil {
+		return fmt.Errorf("cannot connect to the postgres database: %w", err)
+	}
+	defer conn.Close(ctx)
+	var exists bool
+	if err := conn.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM pg_database WHERE datname = $1)`, config.pgdatabase).Scan(&exists); err != nil {
+		return fmt.Errorf("cannot check database %s: %w", config.pgdatabase, err)
+	}
+	if exists {
+		return nil
+	}
+	zap.L().Info("creating database", zap.String("database", config.pgdatabase))
+	if _, err := conn.Exec(ctx, `CREATE DATABASE `+pgx.Identifier{config.pgdatabase}.Sanitize()); err != nil {
+		return fmt.Errorf("cannot create database %s: %w", config.pgdatabase, err)
+	}
+	return nil
+}
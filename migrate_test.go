@@ -0,0 +1,49 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/coreweave/ncore-api/migrations"
+	"github.com/coreweave/ncore-api/pkg/migrate"
+	"github.com/coreweave/ncore-api/pkg/postgres"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRenderMigrations(t *testing.T) {
+	t.Setenv("READ_ONLY_PASSWORD", "secret")
+
+	var domains []*domainDB
+	for _, schema := range postgres.Schemas {
+		domains = append(domains,
+			&domainDB{name: schema, config: &pgConfig{pgdatabase: schema}},
+			&domainDB{name: schema, config: &pgConfig{pgdatabase: "ncore"}, schema: schema},
+		)
+	}
+	for _, d := range domains {
+		for _, dir := range []string{d.name, d.name + "_test"} {
+			env := func(name string) string {
+				v := os.Getenv(name)
+				if v == "" {
+					t.Errorf("%s renders empty in %s of %s", name, dir, d.config.pgdatabase)
+				}
+				return v
+			}
+			if _, err := migrations.FS.Open(dir); err != nil {
+				continue
+			}
+			ms, err := migrate.Load(migrations.FS, dir, env)
+			assert.NoError(t, err, dir)
+			assert.NotEmpty(t, ms, dir)
+			if d.schema != "" {
+				// the schemas of a fresh single database are granted instead of the ipxe, payloads and nodes databases
+				ms, err = migrate.Replace(ms, migrations.FS, d.name+"_single", env)
+				assert.NoError(t, err, dir)
+				for _, m := range ms {
+					assert.NotContains(t, m.Up, "ON DATABASE "+d.name, "%s/%s", dir, m.Name)
+					assert.NotContains(t, m.Up, "SCHEMA public", "%s/%s", dir, m.Name)
+				}
+			}
+		}
+	}
+}
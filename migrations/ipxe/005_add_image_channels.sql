@@ -0,0 +1,59 @@
+CREATE TABLE image_channels (
+    channel text PRIMARY KEY CONSTRAINT channel CHECK (channel != ''),
+    image_tag text NOT NULL CHECK (image_tag != ''),
+    image_type text NOT NULL CHECK (image_type != ''),
+    previous_image_tag text CHECK (previous_image_tag != ''),
+    previous_image_type text CHECK (previous_image_type != ''),
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    modified_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+-- Nodes and subnets either follow a channel or point at a fixed (image_tag, image_type).
+ALTER TABLE node_images
+    ALTER COLUMN image_tag DROP NOT NULL,
+    ALTER COLUMN image_type DROP NOT NULL,
+    ADD COLUMN image_channel text REFERENCES image_channels (channel),
+    ADD CONSTRAINT node_images_image CHECK (
+        (image_channel IS NULL AND image_tag IS NOT NULL AND image_type IS NOT NULL)
+        OR
+        (image_channel IS NOT NULL AND image_tag IS NULL AND image_type IS NULL)
+    );
+
+ALTER TABLE subnet_default_images
+    ALTER COLUMN image_tag DROP NOT NULL,
+    ALTER COLUMN image_type DROP NOT NULL,
+    ADD COLUMN image_channel text REFERENCES image_channels (channel),
+    ADD CONSTRAINT subnet_default_images_image CHECK (
+        (image_channel IS NULL AND image_tag IS NOT NULL AND image_type IS NOT NULL)
+        OR
+        (image_channel IS NOT NULL AND image_tag IS NULL AND image_type IS NULL)
+    );
+
+GRANT SELECT ON image_channels TO read_only;
+
+---- create above / drop below ----
+
+-- Pin everything following a channel to the channel's current image.
+UPDATE node_images
+SET image_tag = image_channels.image_tag, image_type = image_channels.image_type
+FROM image_channels
+WHERE node_images.image_channel = image_channels.channel;
+
+UPDATE subnet_default_images
+SET image_tag = image_channels.image_tag, image_type = image_channels.image_type
+FROM image_channels
+WHERE subnet_default_images.image_channel = image_channels.channel;
+
+ALTER TABLE subnet_default_images
+    DROP CONSTRAINT subnet_default_images_image,
+    DROP COLUMN image_channel,
+    ALTER COLUMN image_tag SET NOT NULL,
+    ALTER COLUMN image_type SET NOT NULL;
+
+ALTER TABLE node_images
+    DROP CONSTRAINT node_images_image,
+    DROP COLUMN image_channel,
+    ALTER COLUMN image_tag SET NOT NULL,
+    ALTER COLUMN image_type SET NOT NULL;
+
+DROP TABLE image_channels;
@@ -0,0 +1,23 @@
+-- Replaces ipxe/003_read_only_user.sql in the ipxe schema of the single database mode:
+-- read_only is granted the database of the schema and the schema instead of the ipxe database.
+DO
+$do$
+BEGIN
+IF EXISTS (
+  SELECT FROM pg_catalog.pg_roles
+  WHERE rolname = 'read_only'
+) THEN RAISE NOTICE 'read_only already exists';
+ELSE
+  CREATE USER read_only WITH PASSWORD '{{ env "READ_ONLY_PASSWORD" }}';
+END IF;
+EXECUTE format('GRANT CONNECT ON DATABASE %I TO read_only', current_database());
+END $do$;
+
+GRANT USAGE ON SCHEMA ipxe TO read_only;
+
+GRANT SELECT ON ALL TABLES IN SCHEMA ipxe TO read_only;
+
+---- create above / drop below ----
+REVOKE SELECT ON ALL TABLES IN SCHEMA ipxe FROM read_only;
+
+REVOKE USAGE ON SCHEMA ipxe FROM read_only;
@@ -1,2 +1,7 @@
 insert into images (image_bucket, image_name, image_cmdline, image_tag, image_type) values ('test-bucket', 'test-image-name', 'test-cmdline', 'test-tag', 'test-type');
 
+-- IPv6 subnet defaults, the /64 is more specific than the /32 containing it.
+insert into subnet_default_images (subnet, image_tag, image_type) values ('2001:db8::/32', 'test-tag', 'test-type');
+
+insert into subnet_default_images (subnet, image_tag, image_type) values ('2001:db8:0:17::/64', 'test-tag', 'test-type');
+
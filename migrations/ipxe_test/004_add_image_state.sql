@@ -0,0 +1,7 @@
+ALTER TABLE images
+    ADD COLUMN image_state text NOT NULL DEFAULT 'active'
+    CONSTRAINT image_state CHECK (image_state IN ('staged', 'active', 'deprecated', 'retired'));
+
+---- create above / drop below ----
+
+ALTER TABLE images DROP COLUMN image_state;
@@ -0,0 +1,38 @@
+CREATE TABLE image_rollouts (
+    rollout_id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    -- nodes tracking (source_image_tag, source_image_type) when the rollout was created, NULL for explicit mac_address lists
+    source_image_tag text CHECK (source_image_tag != ''),
+    source_image_type text CHECK (source_image_type != ''),
+    image_tag text NOT NULL CHECK (image_tag != ''),
+    image_type text NOT NULL CHECK (image_type != ''),
+    -- cumulative percentages of the rollout nodes updated by each wave, e.g. {10,50,100}
+    waves integer[] NOT NULL CONSTRAINT waves CHECK (cardinality(waves) > 0 AND waves[cardinality(waves)] = 100),
+    current_wave integer NOT NULL DEFAULT 0,
+    rollout_state text NOT NULL DEFAULT 'pending'
+        CONSTRAINT rollout_state CHECK (rollout_state IN ('pending', 'in_progress', 'wave_complete', 'completed', 'halted', 'rolled_back')),
+    heartbeat_deadline_secs bigint NOT NULL CHECK (heartbeat_deadline_secs > 0),
+    max_failures integer NOT NULL DEFAULT 0 CHECK (max_failures >= 0),
+    halt_reason text NOT NULL DEFAULT '',
+    wave_started_at timestamp with time zone,
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    modified_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE TABLE image_rollout_nodes (
+    rollout_id bigint NOT NULL REFERENCES image_rollouts (rollout_id) ON DELETE CASCADE,
+    mac_address text NOT NULL CHECK (mac_address != ''),
+    wave integer NOT NULL CHECK (wave > 0),
+    -- node_images assignment before the rollout, restored by rollback
+    previous_image_tag text,
+    previous_image_type text,
+    previous_image_channel text,
+    node_state text NOT NULL DEFAULT 'pending'
+        CONSTRAINT node_state CHECK (node_state IN ('pending', 'updated', 'healthy', 'failed', 'rolled_back')),
+    updated_at timestamp with time zone,
+    PRIMARY KEY (rollout_id, mac_address)
+);
+
+---- create above / drop below ----
+
+DROP TABLE image_rollout_nodes;
+DROP TABLE image_rollouts;
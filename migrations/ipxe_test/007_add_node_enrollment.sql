@@ -0,0 +1,10 @@
+-- How each node got its node_images entry: pinned to the default of its first boot,
+-- following the default on every boot until assigned, or assigned through the api.
+-- Existing entries were all added or overwritten without distinction and stay pinned.
+ALTER TABLE node_images
+    ADD COLUMN enrollment text NOT NULL DEFAULT 'pinned'
+        CONSTRAINT node_images_enrollment CHECK (enrollment IN ('pinned', 'following', 'assigned'));
+
+---- create above / drop below ----
+
+ALTER TABLE node_images DROP COLUMN enrollment;
@@ -0,0 +1,12 @@
+-- Mac addresses were stored as sent to the api, they become macaddr columns in canonical form.
+{{ template "shared/normalize_mac_addresses.sql" "ipxe_mac_address_conflicts" }}
+SELECT pg_temp.normalize_mac_addresses('node_images', '');
+SELECT pg_temp.normalize_mac_addresses('image_rollout_nodes', 'rollout_id,');
+
+---- create above / drop below ----
+
+ALTER TABLE node_images ALTER COLUMN mac_address TYPE text USING replace(mac_address::text, ':', '');
+ALTER TABLE image_rollout_nodes ALTER COLUMN mac_address TYPE text USING replace(mac_address::text, ':', '');
+ALTER TABLE node_images ADD CONSTRAINT node_images_mac_address_check CHECK (mac_address != '');
+ALTER TABLE image_rollout_nodes ADD CONSTRAINT image_rollout_nodes_mac_address_check CHECK (mac_address != '');
+DROP TABLE ipxe_mac_address_conflicts;
@@ -0,0 +1,9 @@
+-- node_images enrollment before the rollout, restored by rollback so following and pinned nodes keep tracking
+-- their default. NULL for nodes of rollouts created before, which are restored as assigned.
+ALTER TABLE image_rollout_nodes
+    ADD COLUMN previous_enrollment text
+        CONSTRAINT image_rollout_nodes_previous_enrollment CHECK (previous_enrollment IN ('pinned', 'following', 'assigned'));
+
+---- create above / drop below ----
+
+ALTER TABLE image_rollout_nodes DROP COLUMN previous_enrollment;
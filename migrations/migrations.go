@@ -0,0 +1,15 @@
+// Package migrations embeds the SQL migrations of the ipxe, payloads and nodes databases.
+//
+// Each directory holds tern style NNN_name.sql files, see pkg/migrate.
+// The _test directories replace the read_only grants with test values.
+// The _single directories replace the migrations granting read_only on the ipxe, payloads and nodes databases
+// with grants on the schemas of the single database mode, see pkg/migrate.Replace.
+// The shared directory holds templates used by the migrations of several directories.
+package migrations
+
+import "embed"
+
+// FS holds one directory of migrations per database.
+//
+//go:embed ipxe payloads nodes ipxe_test payloads_test ipxe_single payloads_single nodes_single shared
+var FS embed.FS
@@ -17,4 +17,4 @@ CREATE TABLE node_heartbeat (
 );
 
 ---- create above / drop below ----
-DROP TABLE nodes;
\ No newline at end of file
+DROP TABLE node_heartbeat;
\ No newline at end of file
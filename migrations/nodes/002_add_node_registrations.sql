@@ -0,0 +1,33 @@
+CREATE TABLE registration_rules (
+    rule_id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    subnet cidr,
+    -- first three octets of the mac_address, e.g. 0c42a1
+    mac_oui text CONSTRAINT mac_oui CHECK (mac_oui ~ '^[0-9a-f]{6}$'),
+    -- assigned to approved nodes, their subnet or api default when NULL
+    image_tag text CHECK (image_tag != ''),
+    image_type text CHECK (image_type != ''),
+    image_channel text CHECK (image_channel != ''),
+    payload_id text CHECK (payload_id != ''),
+    created_at timestamp with time zone NOT NULL DEFAULT now(),
+    CONSTRAINT registration_rules_match CHECK (subnet IS NOT NULL OR mac_oui IS NOT NULL)
+);
+
+CREATE TABLE node_registrations (
+    mac_address text PRIMARY KEY CHECK (mac_address != ''),
+    ip_address text NOT NULL CHECK (ip_address != ''),
+    -- DHCP options relayed by the node's boot requests, e.g. {"uuid": "...", "dhcp-server": "..."}
+    hints jsonb NOT NULL DEFAULT '{}',
+    registration_state text NOT NULL DEFAULT 'pending'
+        CONSTRAINT registration_state CHECK (registration_state IN ('pending', 'approved', 'rejected')),
+    rule_id bigint REFERENCES registration_rules (rule_id) ON DELETE SET NULL,
+    first_seen timestamp with time zone NOT NULL DEFAULT now(),
+    last_seen timestamp with time zone NOT NULL DEFAULT now(),
+    decided_at timestamp with time zone
+);
+
+CREATE INDEX node_registrations_state ON node_registrations (registration_state);
+
+---- create above / drop below ----
+
+DROP TABLE node_registrations;
+DROP TABLE registration_rules;
@@ -0,0 +1,28 @@
+CREATE TABLE node_inventory (
+    mac_address text PRIMARY KEY CHECK (mac_address != ''),
+    -- vendor/model+<count>x<accelerator model>, e.g. supermicro/sys-821ge-tnhr+8xnvidia-h100-80gb-hbm3
+    hardware_class text NOT NULL CHECK (hardware_class != ''),
+    inventory jsonb NOT NULL,
+    reported_at timestamp with time zone NOT NULL DEFAULT now(),
+    changed_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX node_inventory_hardware_class ON node_inventory (hardware_class);
+
+CREATE TABLE node_inventory_changes (
+    change_id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    mac_address text NOT NULL CHECK (mac_address != ''),
+    changed_at timestamp with time zone NOT NULL DEFAULT now(),
+    -- top-level inventory fields differing from previous
+    changed_fields text[] NOT NULL,
+    -- NULL for the first report of mac_address
+    previous jsonb,
+    inventory jsonb NOT NULL
+);
+
+CREATE INDEX node_inventory_changes_mac_address ON node_inventory_changes (mac_address, changed_at);
+
+---- create above / drop below ----
+
+DROP TABLE node_inventory_changes;
+DROP TABLE node_inventory;
@@ -0,0 +1,20 @@
+CREATE TABLE nodes (
+    node_id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    -- keys the node_images, node_payloads and node_heartbeat entries of the node, whichever interface it boots from
+    mac_address text NOT NULL UNIQUE CHECK (mac_address != ''),
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE TABLE node_interfaces (
+    mac_address text PRIMARY KEY CHECK (mac_address != ''),
+    node_id bigint NOT NULL REFERENCES nodes (node_id) ON DELETE CASCADE,
+    name text,
+    attached_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE INDEX node_interfaces_node_id ON node_interfaces (node_id);
+
+---- create above / drop below ----
+
+DROP TABLE node_interfaces;
+DROP TABLE nodes;
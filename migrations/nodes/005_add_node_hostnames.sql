@@ -0,0 +1,24 @@
+CREATE TABLE hostname_policies (
+    policy_id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    -- NULL for the site policy, used by nodes in no policy subnet
+    subnet cidr UNIQUE,
+    -- text/template executed with MacAddress, MacSuffix, Rack, Slot and Counter
+    template text NOT NULL CHECK (template != ''),
+    counter bigint NOT NULL DEFAULT 0,
+    created_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE UNIQUE INDEX hostname_policies_site ON hostname_policies ((subnet IS NULL)) WHERE subnet IS NULL;
+
+CREATE TABLE node_hostnames (
+    mac_address text PRIMARY KEY CHECK (mac_address != ''),
+    hostname text NOT NULL UNIQUE CHECK (hostname != ''),
+    source text NOT NULL CONSTRAINT hostname_source CHECK (source IN ('explicit', 'generated')),
+    policy_id bigint REFERENCES hostname_policies (policy_id) ON DELETE SET NULL,
+    assigned_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+---- create above / drop below ----
+
+DROP TABLE node_hostnames;
+DROP TABLE hostname_policies;
@@ -0,0 +1,27 @@
+-- Mac addresses were stored as sent to the api, they become macaddr columns in canonical form.
+{{ template "shared/normalize_mac_addresses.sql" "nodes_mac_address_conflicts" }}
+SELECT pg_temp.normalize_mac_addresses('node_heartbeat', '');
+SELECT pg_temp.normalize_mac_addresses('node_registrations', '');
+SELECT pg_temp.normalize_mac_addresses('node_inventory', '');
+SELECT pg_temp.normalize_mac_addresses('node_inventory_changes', NULL);
+SELECT pg_temp.normalize_mac_addresses('nodes', '');
+SELECT pg_temp.normalize_mac_addresses('node_interfaces', '');
+SELECT pg_temp.normalize_mac_addresses('node_hostnames', '');
+
+---- create above / drop below ----
+
+ALTER TABLE node_heartbeat ALTER COLUMN mac_address TYPE text USING replace(mac_address::text, ':', '');
+ALTER TABLE node_registrations ALTER COLUMN mac_address TYPE text USING replace(mac_address::text, ':', '');
+ALTER TABLE node_inventory ALTER COLUMN mac_address TYPE text USING replace(mac_address::text, ':', '');
+ALTER TABLE node_inventory_changes ALTER COLUMN mac_address TYPE text USING replace(mac_address::text, ':', '');
+ALTER TABLE nodes ALTER COLUMN mac_address TYPE text USING replace(mac_address::text, ':', '');
+ALTER TABLE node_interfaces ALTER COLUMN mac_address TYPE text USING replace(mac_address::text, ':', '');
+ALTER TABLE node_hostnames ALTER COLUMN mac_address TYPE text USING replace(mac_address::text, ':', '');
+ALTER TABLE node_heartbeat ADD CONSTRAINT node_heartbeat_mac_address_check CHECK (mac_address != '');
+ALTER TABLE node_registrations ADD CONSTRAINT node_registrations_mac_address_check CHECK (mac_address != '');
+ALTER TABLE node_inventory ADD CONSTRAINT node_inventory_mac_address_check CHECK (mac_address != '');
+ALTER TABLE node_inventory_changes ADD CONSTRAINT node_inventory_changes_mac_address_check CHECK (mac_address != '');
+ALTER TABLE nodes ADD CONSTRAINT nodes_mac_address_check CHECK (mac_address != '');
+ALTER TABLE node_interfaces ADD CONSTRAINT node_interfaces_mac_address_check CHECK (mac_address != '');
+ALTER TABLE node_hostnames ADD CONSTRAINT node_hostnames_mac_address_check CHECK (mac_address != '');
+DROP TABLE nodes_mac_address_conflicts;
@@ -0,0 +1,56 @@
+-- Heartbeat ip addresses were stored as sent by nodes, they become an inet column holding the primary address
+-- and an inet[] of every address of the node, e.g. both addresses of dual-stack nodes.
+-- Heartbeats whose ip_address isn't an ip address are kept without address, ip_address NULL and ip_addresses empty,
+-- until the next heartbeat of the node, and the address is recorded in nodes_ip_address_conflicts for an operator
+-- to review. The table is named after the schema it ends up in with -database.single.
+CREATE TABLE nodes_ip_address_conflicts (
+    conflict_id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    table_name text NOT NULL,
+    reason text NOT NULL CONSTRAINT ip_address_conflict_reason CHECK (reason IN ('invalid')),
+    mac_address macaddr NOT NULL,
+    ip_address text NOT NULL,
+    flagged_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+CREATE FUNCTION pg_temp.is_inet(s text) RETURNS boolean AS $$
+BEGIN
+    PERFORM s::inet;
+    RETURN true;
+EXCEPTION WHEN invalid_text_representation THEN
+    RETURN false;
+END
+$$ LANGUAGE plpgsql;
+
+INSERT INTO nodes_ip_address_conflicts (table_name, reason, mac_address, ip_address)
+SELECT 'node_heartbeat', 'invalid', mac_address, ip_address
+FROM node_heartbeat
+WHERE NOT pg_temp.is_inet(ip_address);
+
+ALTER TABLE node_heartbeat
+    DROP CONSTRAINT IF EXISTS node_heartbeat_ip_address_check,
+    ALTER COLUMN ip_address DROP NOT NULL,
+    ALTER COLUMN ip_address TYPE inet USING CASE WHEN pg_temp.is_inet(ip_address) THEN host(ip_address::inet)::inet END,
+    ADD COLUMN ip_addresses inet[] NOT NULL DEFAULT '{}';
+
+UPDATE node_heartbeat SET ip_addresses = ARRAY[ip_address] WHERE ip_address IS NOT NULL;
+
+---- create above / drop below ----
+
+ALTER TABLE node_heartbeat
+    DROP COLUMN ip_addresses,
+    ALTER COLUMN ip_address TYPE text USING host(ip_address);
+
+UPDATE node_heartbeat
+SET ip_address = c.ip_address
+FROM (
+    SELECT DISTINCT ON (mac_address) mac_address, ip_address
+    FROM nodes_ip_address_conflicts
+    WHERE table_name = 'node_heartbeat'
+    ORDER BY mac_address, conflict_id DESC
+) c
+WHERE node_heartbeat.ip_address IS NULL AND node_heartbeat.mac_address = c.mac_address;
+
+ALTER TABLE node_heartbeat
+    ALTER COLUMN ip_address SET NOT NULL,
+    ADD CONSTRAINT node_heartbeat_ip_address_check CHECK (ip_address != '');
+DROP TABLE nodes_ip_address_conflicts;
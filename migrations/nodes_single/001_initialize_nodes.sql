@@ -0,0 +1,32 @@
+-- Replaces nodes/001_initialize_nodes.sql in the nodes schema of the single database mode:
+-- read_only is granted the database of the schema and the schema instead of the nodes database,
+-- and may already exist from the ipxe and payloads schemas.
+DO
+$do$
+BEGIN
+IF EXISTS (
+  SELECT FROM pg_catalog.pg_roles
+  WHERE rolname = 'read_only'
+) THEN RAISE NOTICE 'read_only already exists';
+ELSE
+  CREATE USER read_only WITH PASSWORD '{{ env "READ_ONLY_PASSWORD" }}';
+END IF;
+EXECUTE format('GRANT CONNECT ON DATABASE %I TO read_only', current_database());
+END $do$;
+
+GRANT USAGE ON SCHEMA nodes TO read_only;
+GRANT SELECT ON ALL TABLES IN SCHEMA nodes TO read_only;
+
+ALTER DEFAULT PRIVILEGES IN SCHEMA nodes GRANT SELECT ON TABLES TO read_only;
+
+CREATE TABLE node_heartbeat (
+    mac_address text PRIMARY KEY CHECK (mac_address != '') NOT NULL,
+    hostname text NOT NULL CHECK (hostname != ''),
+    ip_address text NOT NULL CHECK (ip_address != ''),
+    first_seen timestamp with time zone NOT NULL DEFAULT now(),
+    last_seen timestamp with time zone NOT NULL DEFAULT now()
+    -- TODO: add ncore_systems_history table to keep track of each change here.
+);
+
+---- create above / drop below ----
+DROP TABLE node_heartbeat;
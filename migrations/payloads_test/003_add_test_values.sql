@@ -4,3 +4,8 @@ insert into payload_schemas (payload_schema_id, parameter_name) values ('test-sc
 
 insert into payload_parameters (payload_id, parameter_name, parameter_value) values ('test-payload','test-parameter', 'test-value');
 
+-- IPv6 subnet defaults, the /64 is more specific than the /32 containing it.
+insert into subnet_default_payloads (subnet, payload_id) values ('2001:db8::/32', 'test-payload');
+
+insert into subnet_default_payloads (subnet, payload_id) values ('2001:db8:0:17::/64', 'test-payload');
+
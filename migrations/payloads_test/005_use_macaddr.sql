@@ -0,0 +1,9 @@
+-- Mac addresses were stored as sent to the api, they become macaddr columns in canonical form.
+{{ template "shared/normalize_mac_addresses.sql" "payloads_mac_address_conflicts" }}
+SELECT pg_temp.normalize_mac_addresses('node_payloads', 'payload_id,');
+
+---- create above / drop below ----
+
+ALTER TABLE node_payloads ALTER COLUMN mac_address TYPE text USING replace(mac_address::text, ':', '');
+ALTER TABLE node_payloads ADD CONSTRAINT node_payloads_mac_address_check CHECK (mac_address != '');
+DROP TABLE payloads_mac_address_conflicts;
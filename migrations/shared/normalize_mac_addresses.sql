@@ -0,0 +1,55 @@
+-- Creates the {{ . }} table and the normalize_mac_addresses function of the use_macaddr migrations.
+-- Rows whose mac_address isn't a mac address, or that duplicate another row once normalized, are moved to the
+-- table for an operator to review instead of failing the migration. The table is named after the schema it ends up
+-- in with -database.single. Of duplicate rows the one already in canonical form is kept.
+CREATE TABLE {{ . }} (
+    conflict_id bigint GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    table_name text NOT NULL,
+    reason text NOT NULL CONSTRAINT mac_address_conflict_reason CHECK (reason IN ('invalid', 'duplicate')),
+    mac_address text NOT NULL,
+    row_data jsonb NOT NULL,
+    flagged_at timestamp with time zone NOT NULL DEFAULT now()
+);
+
+-- normalize_mac_addresses moves the invalid and duplicate rows of table_name, whose unique key is
+-- key_columns and mac_address, to {{ . }} and converts its mac_address column to macaddr.
+-- Tables without a unique key on mac_address pass NULL key_columns.
+CREATE FUNCTION pg_temp.normalize_mac_addresses(table_name text, key_columns text) RETURNS void AS $$
+BEGIN
+    EXECUTE format($sql$
+        WITH moved AS (
+            DELETE FROM %1$I
+            WHERE regexp_replace(mac_address, '[:.-]', '', 'g') !~* '^[0-9a-f]{12}$'
+            RETURNING *
+        )
+        INSERT INTO {{ . }} (table_name, reason, mac_address, row_data)
+        SELECT %1$L, 'invalid', moved.mac_address, to_jsonb(moved) FROM moved
+    $sql$, table_name);
+
+    IF key_columns IS NOT NULL THEN
+        EXECUTE format($sql$
+            WITH ranked AS (
+                SELECT
+                    ctid AS row_id,
+                    row_number() OVER (
+                        PARTITION BY %2$s lower(regexp_replace(mac_address, '[:.-]', '', 'g'))
+                        ORDER BY mac_address = lower(regexp_replace(mac_address, '[:.-]', '', 'g')) DESC, ctid
+                    ) AS n
+                FROM %1$I
+            ), moved AS (
+                DELETE FROM %1$I
+                WHERE ctid IN (SELECT row_id FROM ranked WHERE n > 1)
+                RETURNING *
+            )
+            INSERT INTO {{ . }} (table_name, reason, mac_address, row_data)
+            SELECT %1$L, 'duplicate', moved.mac_address, to_jsonb(moved) FROM moved
+        $sql$, table_name, key_columns);
+    END IF;
+
+    EXECUTE format($sql$
+        ALTER TABLE %1$I
+            DROP CONSTRAINT IF EXISTS %2$I,
+            ALTER COLUMN mac_address TYPE macaddr USING regexp_replace(mac_address, '[:.-]', '', 'g')::macaddr
+    $sql$, table_name, table_name || '_mac_address_check');
+END
+$$ LANGUAGE plpgsql;
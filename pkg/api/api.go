@@ -4,15 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
+	"net/netip"
 	"strings"
 	sync "sync"
 	"time"
 
+	"github.com/coreweave/ncore-api/pkg/bulk"
+	"github.com/coreweave/ncore-api/pkg/health"
 	"github.com/coreweave/ncore-api/pkg/ipxe"
 	"github.com/coreweave/ncore-api/pkg/nodes"
 	"github.com/coreweave/ncore-api/pkg/payloads"
+	"github.com/coreweave/ncore-api/pkg/proxyproto"
+	"github.com/coreweave/ncore-api/pkg/registration"
+	"go.uber.org/zap"
 )
 
 // Server for the API.
@@ -21,8 +27,20 @@ type Server struct {
 	Payloads    *payloads.Service
 	Ipxe        *ipxe.Service
 	Nodes       *nodes.Service
-	http        *httpServer
-	stopFn      sync.Once
+	// Registration is the enrollment.Registrar of Ipxe and Payloads, approving nodes with Bulk.
+	Registration *registration.Service
+	Bulk         *bulk.Service
+	// Health checks the dependencies reported by /readyz.
+	Health *health.Checker
+	// TrustedProxies may forward requests for other clients, with X-Forwarded-For, X-Real-IP or a PROXY protocol
+	// header.
+	TrustedProxies []netip.Prefix
+	// TrustedDhcp may pass the ip_address of boot requests, see bootIP.
+	TrustedDhcp []netip.Prefix
+	// ProxyProtocol reads a PROXY protocol header from the connections of TrustedProxies.
+	ProxyProtocol bool
+	http          *httpServer
+	stopFn        sync.Once
 }
 
 func middleware(handler http.Handler) http.Handler {
@@ -36,9 +54,15 @@ func (s *Server) Run(ctx context.Context) (err error) {
 	var ec = make(chan error, 1)
 	ctx, cancel := context.WithCancel(ctx)
 	s.http = &httpServer{
-		ipxe:     s.Ipxe,
-		payloads: s.Payloads,
-		nodes:    s.Nodes,
+		ipxe:           s.Ipxe,
+		payloads:       s.Payloads,
+		nodes:          s.Nodes,
+		registration:   s.Registration,
+		bulk:           s.Bulk,
+		health:         s.Health,
+		trustedProxies: s.TrustedProxies,
+		proxyProtocol:  s.ProxyProtocol,
+		middleware:     clientAddress(s.TrustedProxies, s.TrustedDhcp),
 	}
 	go func() {
 		err := s.http.Run(ctx, s.HTTPAddress)
@@ -73,19 +97,24 @@ func (s *Server) Shutdown(ctx context.Context) {
 }
 
 type httpServer struct {
-	ipxe       *ipxe.Service
-	payloads   *payloads.Service
-	nodes      *nodes.Service
-	middleware func(http.Handler) http.Handler
-	http       *http.Server
+	ipxe           *ipxe.Service
+	payloads       *payloads.Service
+	nodes          *nodes.Service
+	registration   *registration.Service
+	bulk           *bulk.Service
+	health         *health.Checker
+	trustedProxies prefixes
+	proxyProtocol  bool
+	middleware     func(http.Handler) http.Handler
+	http           *http.Server
 }
 
 // Run HTTP server.
 func (s *httpServer) Run(ctx context.Context, address string) error {
-	handler := NewHTTPServer(s.ipxe, s.payloads, s.nodes)
+	handler := NewHTTPServer(s.ipxe, s.payloads, s.nodes, s.registration, s.bulk, s.health)
 
 	if s.middleware != nil {
-		log.Printf("Using middleware")
+		zap.L().Info("using middleware")
 		handler = s.middleware(handler)
 	}
 
@@ -94,8 +123,15 @@ func (s *httpServer) Run(ctx context.Context, address string) error {
 		Handler:           handler,
 		ReadHeaderTimeout: 5 * time.Second,
 	}
-	log.Printf("HTTP server listening at %s\n", address)
-	if err := s.http.ListenAndServe(); err != http.ErrServerClosed {
+	ln, err := net.Listen("tcp", address)
+	if err != nil {
+		return err
+	}
+	if s.proxyProtocol {
+		ln = &proxyproto.Listener{Listener: ln, Trusted: s.trustedProxies.contains, HeaderTimeout: s.http.ReadHeaderTimeout}
+	}
+	zap.L().Info("HTTP server listening", zap.String("address", address), zap.Bool("proxy_protocol", s.proxyProtocol))
+	if err := s.http.Serve(ln); err != http.ErrServerClosed {
 		return err
 	}
 	return nil
@@ -103,10 +139,10 @@ func (s *httpServer) Run(ctx context.Context, address string) error {
 
 // Shutdown HTTP server.
 func (s *httpServer) Shutdown(ctx context.Context) {
-	log.Println("shutting down HTTP server")
+	zap.L().Info("shutting down HTTP server")
 	if s.http != nil {
 		if err := s.http.Shutdown(ctx); err != nil {
-			log.Println("graceful shutdown of HTTP server failed")
+			zap.L().Error("graceful shutdown of HTTP server failed", zap.Error(err))
 		}
 	}
 }
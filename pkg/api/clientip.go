@@ -0,0 +1,134 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+)
+
+// ParsePrefixes parses a comma separated list of addresses and subnets, e.g. 10.0.0.0/8,fd00::/8,192.168.1.10,
+// such as Server.TrustedProxies or Server.TrustedDhcp.
+func ParsePrefixes(s string) ([]netip.Prefix, error) {
+	var prefixes []netip.Prefix
+	for _, field := range strings.Split(s, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		if !strings.Contains(field, "/") {
+			addr, err := netip.ParseAddr(field)
+			if err != nil {
+				return nil, fmt.Errorf("invalid address %q: %w", field, err)
+			}
+			addr = addr.Unmap()
+			prefixes = append(prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(field)
+		if err != nil {
+			return nil, fmt.Errorf("invalid subnet %q: %w", field, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// prefixes are trusted callers, the load balancers and ingresses forwarding requests of other clients
+// or the DHCP servers asking for nodes.
+type prefixes []netip.Prefix
+
+func (t prefixes) contains(addr netip.Addr) bool {
+	for _, prefix := range t {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// parseIP parses an address in any notation net/http reports, e.g. 10.1.2.3, [2001:db8::17]:8080 or
+// ::ffff:10.1.2.3, without its port and zone and with IPv4-mapped IPv6 addresses as IPv4.
+func parseIP(s string) (netip.Addr, bool) {
+	s = strings.TrimSpace(s)
+	if addrPort, err := netip.ParseAddrPort(s); err == nil {
+		return addrPort.Addr().Unmap().WithZone(""), true
+	}
+	addr, err := netip.ParseAddr(strings.TrimSuffix(strings.TrimPrefix(s, "["), "]"))
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap().WithZone(""), true
+}
+
+// forwardedFor returns the client address trusted proxies forwarded r for: the last address of X-Forwarded-For
+// that isn't a trusted proxy, or X-Real-IP without X-Forwarded-For.
+func (t prefixes) forwardedFor(r *http.Request) (netip.Addr, bool) {
+	var hops []string
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		hops = append(hops, strings.Split(header, ",")...)
+	}
+	if len(hops) == 0 {
+		return parseIP(r.Header.Get("X-Real-IP"))
+	}
+	var client netip.Addr
+	for i := len(hops) - 1; i >= 0; i-- {
+		addr, ok := parseIP(hops[i])
+		if !ok {
+			break
+		}
+		client = addr
+		if !t.contains(addr) {
+			break
+		}
+	}
+	return client, client.IsValid()
+}
+
+type trustedDhcpKey struct{}
+
+// clientAddress sets the RemoteAddr of requests from trusted proxies to the client address they forwarded,
+// see forwardedFor, and records whether the client is a trusted DHCP server, see bootIP.
+func clientAddress(proxies, dhcp []netip.Prefix) func(http.Handler) http.Handler {
+	trustedProxies, trustedDhcp := prefixes(proxies), prefixes(dhcp)
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			addr, ok := parseIP(r.RemoteAddr)
+			if ok && trustedProxies.contains(addr) {
+				if client, ok := trustedProxies.forwardedFor(r); ok {
+					addr = client
+					r.RemoteAddr = netip.AddrPortFrom(client, 0).String()
+				}
+			}
+			if ok && trustedDhcp.contains(addr) {
+				r = r.WithContext(context.WithValue(r.Context(), trustedDhcpKey{}, true))
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// requestIP returns the ip_address of the client of r, empty when unknown.
+func requestIP(r *http.Request) string {
+	if addr, ok := parseIP(r.RemoteAddr); ok {
+		return addr.String()
+	}
+	return ""
+}
+
+// bootIP returns the ip_address the subnet defaults of boot request r are looked up with: the ip query parameter
+// of trusted DHCP servers asking for a node before it boots, or the address of the client.
+func bootIP(r *http.Request) (string, error) {
+	ip := r.URL.Query().Get("ip")
+	if ip == "" || r.Context().Value(trustedDhcpKey{}) == nil {
+		return requestIP(r), nil
+	}
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return "", errdefs.Invalid("invalid_ip_address", "invalid ip query parameter: %q", ip)
+	}
+	return addr.Unmap().WithZone("").String(), nil
+}
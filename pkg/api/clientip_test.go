@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePrefixes(t *testing.T) {
+	proxies, err := ParsePrefixes(" 10.0.0.0/8, fd00::/8,192.168.1.10,,::ffff:172.16.0.1")
+	require.NoError(t, err)
+	assert.Equal(t, "[10.0.0.0/8 fd00::/8 192.168.1.10/32 172.16.0.1/32]", fmt.Sprint(proxies))
+
+	for _, s := range []string{"10.0.0.0/33", "proxy.local"} {
+		_, err := ParsePrefixes(s)
+		assert.Error(t, err, s)
+	}
+}
+
+func TestClientAddress(t *testing.T) {
+	proxies, err := ParsePrefixes("10.0.0.0/8,fd00::/8")
+	require.NoError(t, err)
+	dhcp, err := ParsePrefixes("192.168.1.2,fd01::/64")
+	require.NoError(t, err)
+
+	tests := []struct {
+		name, remoteAddr, forwardedFor, realIP, query, ip string
+	}{
+		{"direct client", "192.168.1.20:51234", "", "", "", "192.168.1.20"},
+		{"direct IPv6 client", "[2001:db8::17%eth0]:51234", "", "", "", "2001:db8::17"},
+		{"IPv4-mapped client", "[::ffff:192.168.1.20]:51234", "", "", "", "192.168.1.20"},
+		{"untrusted peer forwarding", "192.168.1.20:51234", "192.168.1.30", "192.168.1.40", "", "192.168.1.20"},
+		{"trusted proxy", "10.0.0.1:51234", "192.168.1.30", "", "", "192.168.1.30"},
+		{"trusted proxy chain", "10.0.0.1:51234", "6.6.6.6, 192.168.1.30, 10.0.0.2", "", "", "192.168.1.30"},
+		{"trusted IPv6 proxy", "[fd00::1]:51234", "2001:db8::17", "", "", "2001:db8::17"},
+		{"real ip", "10.0.0.1:51234", "", "192.168.1.40", "", "192.168.1.40"},
+		{"dhcp caller ip", "192.168.1.2:51234", "", "", "?ip=192.168.1.50", "192.168.1.50"},
+		{"dhcp IPv6 caller ip", "[fd01::2]:51234", "", "", "?ip=2001:db8::17", "2001:db8::17"},
+		{"proxy caller ip", "10.0.0.1:51234", "", "", "?ip=192.168.1.50", "10.0.0.1"},
+		{"untrusted caller ip", "192.168.1.20:51234", "", "", "?ip=192.168.1.50", "192.168.1.20"},
+		{"forwarded client ip", "10.0.0.1:51234", "192.168.1.30", "", "?ip=192.168.1.50", "192.168.1.30"},
+		{"forwarded dhcp ip", "10.0.0.1:51234", "192.168.1.2", "", "?ip=192.168.1.50", "192.168.1.50"},
+		{"dhcp forwarding", "192.168.1.2:51234", "192.168.1.30", "", "", "192.168.1.2"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ip string
+			h := clientAddress(proxies, dhcp)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				ip, err = bootIP(r)
+			}))
+			r := httptest.NewRequest(http.MethodGet, "/api/v2/ipxe/template/0c42a1b2c3d4"+tt.query, nil)
+			r.RemoteAddr = tt.remoteAddr
+			if tt.forwardedFor != "" {
+				r.Header.Set("X-Forwarded-For", tt.forwardedFor)
+			}
+			if tt.realIP != "" {
+				r.Header.Set("X-Real-IP", tt.realIP)
+			}
+			h.ServeHTTP(httptest.NewRecorder(), r)
+			require.NoError(t, err)
+			assert.Equal(t, tt.ip, ip)
+		})
+	}
+
+	h := clientAddress(proxies, dhcp)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, err = bootIP(r)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/api/v2/payload/0c42a1b2c3d4?ip=node-1", nil)
+	r.RemoteAddr = "192.168.1.2:51234"
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	assert.ErrorIs(t, err, errdefs.ErrInvalid)
+	assert.Equal(t, "invalid_ip_address", errdefs.Code(err))
+}
@@ -0,0 +1,39 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/coreweave/ncore-api/pkg/health"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// handleGetHealthz is the liveness probe, it only reports the process serves requests
+// so failing dependencies don't get pods restarted.
+func (s *HTTPServer) handleGetHealthz(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, &health.Report{Status: health.StatusOK, Checks: []*health.CheckResult{}})
+}
+
+// handleGetReadyz is the readiness probe, it fails with 503 while a critical dependency is unavailable
+// and reports the status and latency of every dependency.
+func (s *HTTPServer) handleGetReadyz(w http.ResponseWriter, r *http.Request) {
+	report := s.health.Ready(r.Context())
+	statusCode := http.StatusOK
+	if report.Status == health.StatusUnavailable {
+		statusCode = http.StatusServiceUnavailable
+	}
+	if report.Status != health.StatusOK {
+		logger := logging.FromContext(r.Context())
+		for _, check := range report.Checks {
+			if check.Status != health.StatusOK {
+				logger.Warn("dependency not ready",
+					zap.String("check", check.Name),
+					zap.String("status", check.Status),
+					zap.Bool("critical", check.Critical),
+					zap.Float64("latency_ms", check.LatencyMs),
+					zap.String("error", check.Error))
+			}
+		}
+	}
+	writeJSON(w, statusCode, report)
+}
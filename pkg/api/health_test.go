@@ -0,0 +1,48 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/health"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHandleGetReadyz(t *testing.T) {
+	up := func(ctx context.Context) error { return nil }
+	down := func(ctx context.Context) error { return errors.New("connection refused") }
+	tests := []struct {
+		name       string
+		checks     []health.Check
+		statusCode int
+		status     string
+	}{
+		{"ok", []health.Check{{Name: "ipxe", Critical: true, Check: up}, {Name: "s3", Check: up}}, http.StatusOK, health.StatusOK},
+		{"degraded", []health.Check{{Name: "ipxe", Critical: true, Check: up}, {Name: "s3", Check: down}}, http.StatusOK, health.StatusDegraded},
+		{"ipxe down", []health.Check{{Name: "ipxe", Critical: true, Check: down}, {Name: "s3", Check: up}}, http.StatusServiceUnavailable, health.StatusUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			checker := health.NewChecker(time.Second, time.Second, tt.checks...)
+			w := httptest.NewRecorder()
+			NewHTTPServer(nil, nil, nil, nil, nil, checker).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+
+			var report health.Report
+			require.NoError(t, json.NewDecoder(w.Body).Decode(&report))
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Equal(t, tt.status, report.Status)
+			assert.Len(t, report.Checks, len(tt.checks))
+
+			// liveness doesn't depend on the dependencies
+			w = httptest.NewRecorder()
+			NewHTTPServer(nil, nil, nil, nil, nil, checker).ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+			assert.Equal(t, http.StatusOK, w.Code)
+		})
+	}
+}
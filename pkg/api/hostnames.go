@@ -0,0 +1,120 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/coreweave/ncore-api/pkg/nodes"
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *HTTPServer) handleGetNodeHostname(w http.ResponseWriter, r *http.Request) {
+	macAddress, ok := pathMacAddress(w, r, "macAddress")
+	if !ok {
+		return
+	}
+	nh, err := s.nodes.GetNodeHostname(r.Context(), macAddress)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nh)
+}
+
+// handlePutNodeHostname assigns the hostname in the body to a node, replacing its generated one.
+func (s *HTTPServer) handlePutNodeHostname(w http.ResponseWriter, r *http.Request) {
+	var errors []string
+	if r.Header.Get("Content-type") != "application/json" {
+		var e = formatHttpErrors(http.StatusUnsupportedMediaType, errors)
+		e.writeErrors(w)
+		return
+	}
+	macAddress, ok := pathMacAddress(w, r, "macAddress")
+	if !ok {
+		return
+	}
+	defer r.Body.Close()
+	body := &nodes.NodeHostname{}
+	if err := json.NewDecoder(r.Body).Decode(body); err != nil {
+		errors = append(errors, err.Error())
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
+		e.writeErrors(w)
+		return
+	}
+	nh, err := s.nodes.SetNodeHostname(r.Context(), macAddress, body.Hostname)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nh)
+}
+
+func (s *HTTPServer) handleDeleteNodeHostname(w http.ResponseWriter, r *http.Request) {
+	macAddress, ok := pathMacAddress(w, r, "macAddress")
+	if !ok {
+		return
+	}
+	nh, err := s.nodes.DeleteNodeHostname(r.Context(), macAddress)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nh)
+}
+
+func (s *HTTPServer) handleGetHostname(w http.ResponseWriter, r *http.Request) {
+	nh, err := s.nodes.LookupHostname(r.Context(), chi.URLParam(r, "hostname"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, nh)
+}
+
+func (s *HTTPServer) handleGetHostnamePolicies(w http.ResponseWriter, r *http.Request) {
+	policies, err := s.nodes.ListHostnamePolicies(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, policies)
+}
+
+func (s *HTTPServer) handlePutHostnamePolicy(w http.ResponseWriter, r *http.Request) {
+	var errors []string
+	if r.Header.Get("Content-type") != "application/json" {
+		var e = formatHttpErrors(http.StatusUnsupportedMediaType, errors)
+		e.writeErrors(w)
+		return
+	}
+	defer r.Body.Close()
+	policy := &nodes.HostnamePolicy{}
+	if err := json.NewDecoder(r.Body).Decode(policy); err != nil {
+		errors = append(errors, err.Error())
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
+		e.writeErrors(w)
+		return
+	}
+	created, err := s.nodes.CreateHostnamePolicy(r.Context(), policy)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, created)
+}
+
+func (s *HTTPServer) handleDeleteHostnamePolicy(w http.ResponseWriter, r *http.Request) {
+	policyId, err := strconv.ParseInt(chi.URLParam(r, "policyId"), 10, 64)
+	if err != nil {
+		var e = formatHttpErrors(http.StatusBadRequest, []string{"Invalid policyId"})
+		e.writeErrors(w)
+		return
+	}
+	deleted, err := s.nodes.DeleteHostnamePolicy(r.Context(), policyId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deleted)
+}
@@ -3,20 +3,34 @@ package api
 import (
 	"context"
 	"encoding/json"
+	goerrors "errors"
 	"fmt"
-	"log"
 	"net/http"
+	"net/url"
+	"path"
+	"strconv"
 	"strings"
 
+	"github.com/coreweave/ncore-api/pkg/bulk"
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/health"
 	"github.com/coreweave/ncore-api/pkg/ipxe"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/macaddr"
+	"github.com/coreweave/ncore-api/pkg/metrics"
 	"github.com/coreweave/ncore-api/pkg/nodes"
 	"github.com/coreweave/ncore-api/pkg/payloads"
+	"github.com/coreweave/ncore-api/pkg/registration"
 	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
 )
 
 type httpErrors struct {
-	StatusCode int      `json:"-"`
-	Errors     []string `json:"errors"`
+	StatusCode int `json:"-"`
+	// Code is a machine readable errdefs code, e.g. image_exists, or the code of StatusCode.
+	Code   string   `json:"code"`
+	Errors []string `json:"errors"`
 }
 
 func (e *httpErrors) writeErrors(w http.ResponseWriter) {
@@ -32,38 +46,91 @@ func (e *httpErrors) writeErrors(w http.ResponseWriter) {
 func formatHttpErrors(statusCode int, messages []string) *httpErrors {
 	return &httpErrors{
 		StatusCode: statusCode,
+		Code:       statusErrorCodes[statusCode],
 		Errors:     messages,
 	}
 }
 
-func contains(s []string, str string) bool {
-	for _, v := range s {
-		if v == str {
-			return true
-		}
+// statusErrorCodes are the codes of errors written with formatHttpErrors, checked by the handlers themselves.
+var statusErrorCodes = map[int]string{
+	http.StatusBadRequest:           errdefs.CodeInvalid,
+	http.StatusNotFound:             errdefs.CodeNotFound,
+	http.StatusConflict:             errdefs.CodeConflict,
+	http.StatusUnsupportedMediaType: "unsupported_media_type",
+	http.StatusInternalServerError:  errdefs.CodeInternal,
+	http.StatusServiceUnavailable:   errdefs.CodeUnavailable,
+}
+
+// errorStatus returns the http status of err from its errdefs kind.
+func errorStatus(err error) int {
+	switch {
+	case errdefs.IsNotFound(err):
+		return http.StatusNotFound
+	case errdefs.IsConflict(err):
+		return http.StatusConflict
+	case errdefs.IsInvalid(err):
+		return http.StatusBadRequest
+	case errdefs.IsUnavailable(err):
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusInternalServerError
+}
+
+// writeError writes err with the status and code of its errdefs kind, followed by details.
+// Nothing is written once the request is canceled.
+func writeError(w http.ResponseWriter, err error, details ...string) {
+	if goerrors.Is(err, context.Canceled) || goerrors.Is(err, context.DeadlineExceeded) {
+		return
+	}
+	e := &httpErrors{
+		StatusCode: errorStatus(err),
+		Code:       errdefs.Code(err),
+		Errors:     append([]string{err.Error()}, details...),
 	}
+	e.writeErrors(w)
+}
 
-	return false
+// writeJSON writes v as an indented json response with statusCode.
+func writeJSON(w http.ResponseWriter, statusCode int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(v); err != nil {
+		zap.L().Error("cannot json encode response", zap.Error(err))
+	}
 }
 
-func containsImageTagType(images []ipxe.IpxeImageTagType, iitt ipxe.IpxeImageTagType) bool {
-	for _, a := range images {
-		if a == iitt {
+func contains(s []string, str string) bool {
+	for _, v := range s {
+		if v == str {
 			return true
 		}
 	}
+
 	return false
 }
 
 // NewHTTPServer creates an HTTPServer for the API.
-func NewHTTPServer(i *ipxe.Service, p *payloads.Service, n *nodes.Service) http.Handler {
+func NewHTTPServer(i *ipxe.Service, p *payloads.Service, n *nodes.Service, r *registration.Service, b *bulk.Service, h *health.Checker) http.Handler {
 	s := &HTTPServer{
-		ipxe:     i,
-		payloads: p,
-		nodes:    n,
-		router:   chi.NewRouter(),
-	}
+		ipxe:         i,
+		payloads:     p,
+		nodes:        n,
+		health:       h,
+		bulk:         b,
+		registration: r,
+		router:       chi.NewRouter(),
+	}
+	s.router.Use(requestTracer)
+	s.router.Use(staleResponses)
+	s.router.Use(requestLogger)
+	s.router.Use(requestMetrics)
 	s.router.Get("/", s.handleGetRoot)
+	s.router.Method(http.MethodGet, "/metrics", metrics.Handler())
+	s.router.Get("/healthz", s.handleGetHealthz)
+	s.router.Get("/readyz", s.handleGetReadyz)
+	s.router.Get("/api/openapi.json", s.handleGetOpenAPI)
 	s.router.Route("/api/v2/payload", func(r chi.Router) {
 		r.Get("/{macAddress}", s.handleGetNodePayload)
 		r.Put("/{macAddress}/{payloadId}", s.handlePutNodePayload)
@@ -75,106 +142,147 @@ func NewHTTPServer(i *ipxe.Service, p *payloads.Service, n *nodes.Service) http.
 		r.Put("/", s.handlePutNodeIpxe)
 		r.Get("/template/{macAddress}", s.handleGetNodeIpxeTemplate)
 		r.Get("/images/", s.handleGetIpxeImages)
+		r.Get("/images/usage", s.handleGetIpxeImageUsage)
+		r.Put("/images/state", s.handlePutIpxeImageState)
 		r.Put("/images/", s.handlePutIpxeImages)
 		r.Put("/images/{imageName}", s.handlePutIpxeImages)
 		r.Delete("/images/", s.handleDeleteIpxeImages)
 		r.Get("/s3/{imageName}", s.handleGetIpxeImagePresignedUrls)
+		r.Get("/channels/", s.handleGetImageChannels)
+		r.Put("/channels/promote", s.handlePutImageChannelPromote)
+		r.Put("/channels/{channel}/rollback", s.handlePutImageChannelRollback)
+		r.Delete("/channels/{channel}", s.handleDeleteImageChannel)
+		r.Get("/subnets/", s.handleGetSubnetDefaultImages)
+		r.Put("/subnets/", s.handlePutSubnetDefaultImage)
+		r.Delete("/subnets/", s.handleDeleteSubnetDefaultImage)
+		r.Get("/rollouts/", s.handleGetImageRollouts)
+		r.Put("/rollouts/", s.handlePutImageRollout)
+		r.Get("/rollouts/{rolloutId}", s.handleGetImageRollout)
+		r.Put("/rollouts/{rolloutId}/advance", s.handlePutImageRolloutAction)
+		r.Put("/rollouts/{rolloutId}/halt", s.handlePutImageRolloutAction)
+		r.Put("/rollouts/{rolloutId}/rollback", s.handlePutImageRolloutAction)
 	})
 	s.router.Route("/api/v2/nodes", func(r chi.Router) {
+		r.Get("/", s.handleGetNodeViews)
+		r.Get("/{macAddress}", s.handleGetNodeView)
+		r.Put("/bulk", s.handlePutNodesBulk)
+		r.Get("/registrations/", s.handleGetRegistrations)
+		r.Get("/registrations/{macAddress}", s.handleGetRegistration)
+		r.Put("/registrations/{macAddress}/approve", s.handlePutRegistrationApprove)
+		r.Put("/registrations/{macAddress}/reject", s.handlePutRegistrationReject)
+		r.Get("/registrations/rules/", s.handleGetRegistrationRules)
+		r.Put("/registrations/rules/", s.handlePutRegistrationRule)
+		r.Delete("/registrations/rules/{ruleId}", s.handleDeleteRegistrationRule)
 		r.Put("/{macAddress}/heartbeat", s.handlePutNodesHeartbeat)
+		r.Get("/inventory/", s.handleGetNodeInventories)
+		r.Get("/{macAddress}/inventory", s.handleGetNodeInventory)
+		r.Put("/{macAddress}/inventory", s.handlePutNodeInventory)
+		r.Get("/{macAddress}/inventory/changes", s.handleGetNodeInventoryChanges)
+		r.Get("/{macAddress}/interfaces", s.handleGetNodeInterfaces)
+		r.Put("/{macAddress}/interfaces", s.handlePutNodeInterface)
+		r.Delete("/{macAddress}/interfaces/{interfaceMacAddress}", s.handleDeleteNodeInterface)
+		r.Get("/{macAddress}/hostname", s.handleGetNodeHostname)
+		r.Put("/{macAddress}/hostname", s.handlePutNodeHostname)
+		r.Delete("/{macAddress}/hostname", s.handleDeleteNodeHostname)
+		r.Get("/hostnames/{hostname}", s.handleGetHostname)
+		r.Get("/hostnames/policies/", s.handleGetHostnamePolicies)
+		r.Put("/hostnames/policies/", s.handlePutHostnamePolicy)
+		r.Delete("/hostnames/policies/{policyId}", s.handleDeleteHostnamePolicy)
 	})
 	return s.router
 }
 
 // HTTPServer exposes payloads.Service via HTTP.
 type HTTPServer struct {
-	ipxe     *ipxe.Service
-	payloads *payloads.Service
-	nodes    *nodes.Service
-	router   *chi.Mux
+	ipxe         *ipxe.Service
+	payloads     *payloads.Service
+	nodes        *nodes.Service
+	health       *health.Checker
+	bulk         *bulk.Service
+	registration *registration.Service
+	router       *chi.Mux
 }
 
 func (s *HTTPServer) handleGetRoot(w http.ResponseWriter, r *http.Request) {
 	w.Write([]byte("ncore-api"))
 }
 
-func (s *HTTPServer) handleGetNodePayload(w http.ResponseWriter, r *http.Request) {
-	var errors []string
-	macAddress := chi.URLParam(r, "macAddress")
-	macAddress = strings.Replace(strings.ToLower(macAddress), ":", "", -1)
+// hintNames are the query parameters of boot requests recorded as enrollment.Hints,
+// the iPXE settings a chainloading script can pass, e.g. ?uuid=${uuid}&serial=${serial}.
+var hintNames = []string{"hostname", "uuid", "serial", "manufacturer", "product", "asset", "platform", "buildarch", "user-class", "dhcp-server", "next-server", "rack", "slot"}
+
+// bootHints returns the hints passed in the query of boot request r.
+func bootHints(r *http.Request) enrollment.Hints {
+	hints := enrollment.Hints{}
+	query := r.URL.Query()
+	for _, name := range hintNames {
+		if v := strings.TrimSpace(query.Get(name)); v != "" && len(v) <= 256 {
+			hints[name] = v
+		}
+	}
+	return hints
+}
 
-	// if query includes a hostname instead of full macAddress
-	if len(macAddress) == 7 {
-		macAddress = "%" + macAddress[1:]
+// pathMacAddress returns the mac_address path parameter name of r in canonical form,
+// writing a 400 error when it is invalid. The escaped separators of some iPXE clients are accepted.
+func pathMacAddress(w http.ResponseWriter, r *http.Request, name string) (string, bool) {
+	param := chi.URLParam(r, name)
+	if unescaped, err := url.PathUnescape(param); err == nil {
+		param = unescaped
+	}
+	mac, err := macaddr.Parse(param)
+	if err != nil {
+		writeError(w, err)
+		return "", false
 	}
+	return mac.String(), true
+}
 
-	if len(macAddress) != 12 && len(macAddress) != 7 {
-		errors = append(errors, "Invalid mac_address")
-		var e = formatHttpErrors(http.StatusBadRequest, errors)
-		e.writeErrors(w)
-		return
+// nodeMacAddress returns the mac_address of the node named by nameOrMacAddress, a mac_address in any form
+// accepted by macaddr.Parse or a hostname, and whether it was a hostname. Unknown hostnames are an errdefs.ErrNotFound error.
+func (s *HTTPServer) nodeMacAddress(ctx context.Context, nameOrMacAddress string) (string, bool, error) {
+	if unescaped, err := url.PathUnescape(nameOrMacAddress); err == nil {
+		nameOrMacAddress = unescaped
+	}
+	if mac, err := macaddr.Parse(nameOrMacAddress); err == nil {
+		return mac.String(), false, nil
+	}
+	nh, err := s.nodes.LookupHostname(ctx, nameOrMacAddress)
+	if err != nil {
+		return "", true, err
 	}
+	return nh.MacAddress, true, nil
+}
 
-	log.Printf("Checking node_payloads for macAddress: %s", macAddress)
-	assignedNodePayloads, err := s.payloads.GetNodePayloads(r.Context(), macAddress)
+func (s *HTTPServer) handleGetNodePayload(w http.ResponseWriter, r *http.Request) {
+	var errors []string
+	// the path may name the node by hostname instead of macAddress
+	macAddress, byHostname, err := s.nodeMacAddress(r.Context(), chi.URLParam(r, "macAddress"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
 
+	var assignedNodePayloads []*payloads.NodePayload
+	if byHostname {
+		assignedNodePayloads, err = s.payloads.GetNodePayloads(r.Context(), macAddress)
+	} else {
+		var requestIp string
+		if requestIp, err = bootIP(r); err == nil {
+			assignedNodePayloads, err = s.payloads.BootNodePayload(r.Context(), macAddress, requestIp, bootHints(r))
+		}
+	}
 	switch {
-	case err == context.Canceled, err == context.DeadlineExceeded:
-		// TODO: Add warning log
-		return
 	case err != nil:
-		errors = append(errors, err.Error())
-		var e = formatHttpErrors(http.StatusInternalServerError, errors)
-		e.writeErrors(w)
+		writeError(w, err)
 		return
-	case assignedNodePayloads == nil && len(macAddress) == 7:
-		errors = append(errors, fmt.Sprintf("payload not found for hostname: %s", macAddress))
+	case assignedNodePayloads == nil:
+		errors = append(errors, fmt.Sprintf("payload not found for hostname: %s", chi.URLParam(r, "macAddress")))
 		var e = formatHttpErrors(http.StatusBadRequest, errors)
 		e.writeErrors(w)
 		return
-	case assignedNodePayloads == nil:
-		// PayloadId, MacAddress missing from payloads.node_payloads
-		// or PayloadId, PayloadDirectory, PayloadSchema missing from payloads.payloads
-		var defaultNodePayload *payloads.NodePayload
-
-		requestIp := strings.Split(r.RemoteAddr, ":")[0]
-		log.Printf("Checking subnet_default_payloads for requestIp: %s", requestIp)
-		subnetPayload, err := s.payloads.GetSubnetDefaultPayload(r.Context(), requestIp)
-
-		switch {
-		case err != nil:
-			errors = append(errors, err.Error())
-			var e = formatHttpErrors(http.StatusInternalServerError, errors)
-			e.writeErrors(w)
-			return
-		case subnetPayload != nil:
-			log.Printf("Using subnetPayload for macAddress: %s", macAddress)
-			defaultNodePayload = &payloads.NodePayload{
-				PayloadId:        subnetPayload.PayloadId,
-				PayloadDirectory: subnetPayload.PayloadDirectory,
-				MacAddress:       macAddress,
-			}
-		case subnetPayload == nil:
-			log.Printf("Using defaultPayload for macAddress: %s", macAddress)
-			defaultPayload := s.payloads.GetDefaultPayload(r.Context())
-			defaultNodePayload = &payloads.NodePayload{
-				PayloadId:        defaultPayload.PayloadId,
-				PayloadDirectory: defaultPayload.PayloadDirectory,
-				MacAddress:       macAddress,
-			}
-		}
-		defaultNodePayloadDb := &payloads.NodePayloadDb{
-			PayloadId:  defaultNodePayload.PayloadId,
-			MacAddress: macAddress,
-		}
-		log.Printf("Adding defaulted node_payloads entry: %v", defaultNodePayloadDb)
-
-		if assignedNodePayloads, err = s.payloads.AddNodePayload(r.Context(), defaultNodePayloadDb); err != nil {
-			errors = append(errors, err.Error())
-			var e = formatHttpErrors(http.StatusInternalServerError, errors)
-			e.writeErrors(w)
-			return
-		}
+	case byHostname:
+		metrics.BootResolutions.WithLabelValues(metrics.KindPayload, metrics.SourceNode).Inc()
 	}
 
 	w.Header().Set("Content-Type", "application/json")
@@ -208,70 +316,22 @@ func (s *HTTPServer) handlePutNodePayload(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	npd.MacAddress = strings.Replace(strings.ToLower(npd.MacAddress), ":", "", -1)
-
-	// if query includes a hostname instead of full macAddress
-	if len(npd.MacAddress) == 7 {
-		npd.MacAddress = "%" + npd.MacAddress[1:]
-	}
-
-	if len(npd.MacAddress) != 12 && len(npd.MacAddress) != 7 {
-		errors = append(errors, "Invalid mac_address")
-		var e = formatHttpErrors(http.StatusBadRequest, errors)
-		e.writeErrors(w)
-		return
-	}
-
-	payloads := s.payloads.GetAvailablePayloads(r.Context())
-
-	if !contains(payloads, npd.PayloadId) {
-		errors = append(errors, "PayloadId doesn't exist")
-		errors = append(errors, fmt.Sprintf(`Available Payloads: %v`, payloads))
-		var e = formatHttpErrors(http.StatusBadRequest, errors)
-		e.writeErrors(w)
+	// the path may name the node by hostname instead of macAddress
+	macAddress, _, err := s.nodeMacAddress(r.Context(), npd.MacAddress)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
+	npd.MacAddress = macAddress
 
-	assignedNodePayloads, err := s.payloads.GetNodePayloads(r.Context(), npd.MacAddress)
-
+	assignedNodePayloads, err := s.payloads.AssignNodePayload(r.Context(), &npd)
 	switch {
-	case err == context.Canceled, err == context.DeadlineExceeded:
-		// TODO: Add warning log
+	case errdefs.IsInvalid(err):
+		writeError(w, err, fmt.Sprintf(`Available Payloads: %v`, s.payloads.GetAvailablePayloads(r.Context())))
 		return
 	case err != nil:
-		errors = append(errors, err.Error())
-		var e = formatHttpErrors(http.StatusInternalServerError, errors)
-		e.writeErrors(w)
+		writeError(w, err)
 		return
-	case assignedNodePayloads == nil:
-		log.Printf("Adding node_payloads entry: %v", npd)
-		assignedNodePayloads, err = s.payloads.AddNodePayload(r.Context(), &npd)
-		if err != nil {
-			errors = append(errors, err.Error())
-			var e = formatHttpErrors(http.StatusInternalServerError, errors)
-			e.writeErrors(w)
-			return
-		}
-	default:
-		found := false
-		for _, payload := range assignedNodePayloads {
-			if payload.PayloadId == npd.PayloadId {
-				log.Printf("Found node_payloads entry: %v", npd)
-				found = true
-				break
-			}
-		}
-		if !found {
-			log.Printf("Updating node_payloads entry: %v", npd)
-			// TODO: update to use s.payloads.AddNodePayload when we decide to allow multiple payloads per node
-			assignedNodePayloads, err = s.payloads.UpdateNodePayload(r.Context(), &npd)
-			if err != nil {
-				errors = append(errors, err.Error())
-				var e = formatHttpErrors(http.StatusInternalServerError, errors)
-				e.writeErrors(w)
-				return
-			}
-		}
 	}
 
 	w.WriteHeader(http.StatusOK)
@@ -305,19 +365,13 @@ func (s *HTTPServer) handleDeleteNodePayload(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	npd.MacAddress = strings.Replace(strings.ToLower(npd.MacAddress), ":", "", -1)
-
-	// if query includes a hostname instead of full macAddress
-	if len(npd.MacAddress) == 7 {
-		npd.MacAddress = "%" + npd.MacAddress[1:]
-	}
-
-	if len(npd.MacAddress) != 12 && len(npd.MacAddress) != 7 {
-		errors = append(errors, "Invalid mac_address")
-		var e = formatHttpErrors(http.StatusBadRequest, errors)
-		e.writeErrors(w)
+	// the path may name the node by hostname instead of macAddress
+	macAddress, _, err := s.nodeMacAddress(r.Context(), npd.MacAddress)
+	if err != nil {
+		writeError(w, err)
 		return
 	}
+	npd.MacAddress = macAddress
 
 	payloads := s.payloads.GetAvailablePayloads(r.Context())
 
@@ -329,13 +383,10 @@ func (s *HTTPServer) handleDeleteNodePayload(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	log.Printf("Deleting node_payloads entry: %v", npd)
+	logging.FromContext(r.Context()).Info("deleting node_payloads entry", zap.String("mac_address", npd.MacAddress), zap.String("payload_id", npd.PayloadId))
 	assignedNodePayloads, err := s.payloads.DeleteNodePayload(r.Context(), &npd)
-
 	if err != nil {
-		errors = append(errors, err.Error())
-		var e = formatHttpErrors(http.StatusNotFound, errors)
-		e.writeErrors(w)
+		writeError(w, err)
 		return
 	}
 
@@ -357,23 +408,10 @@ func (s *HTTPServer) handleGetPayloadParameters(w http.ResponseWriter, r *http.R
 		http.NotFound(w, r)
 		return
 	}
-	log.Printf("Request Host: %s", r.Host)
-	log.Printf("Request RemoteAddr: %s", r.RemoteAddr)
-	log.Printf("Request RequestURI: %s", r.RequestURI)
-
 	parameters, err := s.payloads.GetPayloadParameters(r.Context(), payloadId)
 	switch {
-	case err == context.Canceled, err == context.DeadlineExceeded:
-		// TODO: Add warning log
-		return
 	case err != nil:
-		errors = append(errors, err.Error())
-		var e = formatHttpErrors(http.StatusInternalServerError, errors)
-		e.writeErrors(w)
-		return
-	case parameters == nil:
-		var e = formatHttpErrors(http.StatusNotFound, errors)
-		e.writeErrors(w)
+		writeError(w, err)
 		return
 	default:
 		w.Header().Set("Content-Type", "application/json")
@@ -390,34 +428,22 @@ func (s *HTTPServer) handleGetPayloadParameters(w http.ResponseWriter, r *http.R
 
 func (s *HTTPServer) handleGetNodeIpxe(w http.ResponseWriter, r *http.Request) {
 	var errors []string
-	macAddress := chi.URLParam(r, "macAddress")
-	macAddress = strings.Replace(strings.ToLower(macAddress), ":", "", -1)
-
-	// if query includes a hostname instead of full macAddress
-	if len(macAddress) == 7 {
-		macAddress = "%" + macAddress[1:]
-	}
-
-	if len(macAddress) != 12 && len(macAddress) != 7 {
-		errors = append(errors, "Invalid mac_address")
-		var e = formatHttpErrors(http.StatusBadRequest, errors)
-		e.writeErrors(w)
+	// the path may name the node by hostname instead of macAddress
+	macAddress, _, err := s.nodeMacAddress(r.Context(), chi.URLParam(r, "macAddress"))
+	if err != nil {
+		writeError(w, err)
 		return
 	}
 
-	log.Printf("Request Host: %s", r.Host)
-	log.Printf("Request RemoteAddr: %s", r.RemoteAddr)
-	log.Printf("Request RequestURI: %s", r.RequestURI)
-
 	parameters, err := s.ipxe.GetNodeIpxeConfig(r.Context(), macAddress)
 	switch {
 	case err == context.Canceled, err == context.DeadlineExceeded:
-		// TODO: Add warning log
+		logging.FromContext(r.Context()).Warn("request ended before the node image was read", zap.String("mac_address", macAddress), zap.Error(err))
 		return
 	case err != nil || parameters == nil:
-		log.Printf("Getting API Ipxe default image for macAddress: %s", macAddress)
-		parameters := s.ipxe.GetIpxeApiDefault()
-		s.ipxe.SetHostname(r.Context(), parameters, macAddress)
+		logging.FromContext(r.Context()).Info("using api default image", zap.String("mac_address", macAddress))
+		parameters := s.ipxe.GetIpxeApiDefault(r.Context())
+		s.ipxe.SetHostname(r.Context(), parameters, macAddress, "", nil)
 		w.Header().Set("Content-Type", "application/json")
 		enc := json.NewEncoder(w)
 		enc.SetIndent("", "\t")
@@ -450,7 +476,6 @@ func (s *HTTPServer) handlePutNodeIpxe(w http.ResponseWriter, r *http.Request) {
 
 	defer r.Body.Close()
 	var indc *ipxe.IpxeNodeDbConfig
-	var iitt *ipxe.IpxeImageTagType
 	dec := json.NewDecoder(r.Body)
 	if err := dec.Decode(&indc); err != nil {
 		errors = append(errors, err.Error())
@@ -459,12 +484,15 @@ func (s *HTTPServer) handlePutNodeIpxe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if indc.ImageTag == "" {
+	if indc.ImageChannel == "" && indc.ImageTag == "" {
 		errors = append(errors, "ImageTag is missing.")
 	}
-	if indc.ImageType == "" {
+	if indc.ImageChannel == "" && indc.ImageType == "" {
 		errors = append(errors, "ImageType is missing.")
 	}
+	if indc.ImageChannel != "" && (indc.ImageTag != "" || indc.ImageType != "") {
+		errors = append(errors, "ImageChannel and ImageTag/ImageType are mutually exclusive.")
+	}
 	if indc.MacAddress == "" {
 		errors = append(errors, "MacAddress is missing.")
 	}
@@ -474,65 +502,112 @@ func (s *HTTPServer) handlePutNodeIpxe(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	indc.MacAddress = strings.Replace(strings.ToLower(indc.MacAddress), ":", "", -1)
-
-	// if query includes a hostname instead of full macAddress
-	if len(indc.MacAddress) == 7 {
-		indc.MacAddress = "%" + indc.MacAddress[1:]
+	// MacAddress may name the node by hostname
+	macAddress, _, err := s.nodeMacAddress(r.Context(), indc.MacAddress)
+	if err != nil {
+		writeError(w, err)
+		return
 	}
+	indc.MacAddress = macAddress
 
-	if len(indc.MacAddress) != 12 && len(indc.MacAddress) != 7 {
-		errors = append(errors, "Invalid mac_address")
-		var e = formatHttpErrors(http.StatusBadRequest, errors)
+	config, err := s.ipxe.UpdateNodeImage(r.Context(), indc)
+	if err != nil {
+		writeImageLifecycleError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Header().Set("Content-Type", "application/json")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	if err := enc.Encode(config); err != nil {
+		errors = append(errors, err.Error())
+		var e = formatHttpErrors(http.StatusInternalServerError, errors)
 		e.writeErrors(w)
 		return
 	}
+}
 
-	images := s.ipxe.GetAvailableImages(r.Context())
+func (s *HTTPServer) handleGetIpxeImages(w http.ResponseWriter, r *http.Request) {
+	images, err := s.ipxe.ListIpxeImages(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, images)
+}
 
-	iitt = &ipxe.IpxeImageTagType{
-		ImageTag:  indc.ImageTag,
-		ImageType: indc.ImageType,
+// handleGetIpxeImageUsage lists the nodes and subnets using each image.
+// The state query parameter limits the list to images in that state, e.g. ?state=deprecated.
+func (s *HTTPServer) handleGetIpxeImageUsage(w http.ResponseWriter, r *http.Request) {
+	usage, err := s.ipxe.ListIpxeImageUsage(r.Context(), r.URL.Query().Get("state"))
+	if err != nil {
+		writeError(w, err)
+		return
 	}
+	writeJSON(w, http.StatusOK, usage)
+}
 
-	if !containsImageTagType(images, *iitt) {
-		errors = append(errors, "Image doesn't exist")
-		errors = append(errors, fmt.Sprintf(`Available Images: %v`, images))
-		var e = formatHttpErrors(http.StatusBadRequest, errors)
+func (s *HTTPServer) handlePutIpxeImageState(w http.ResponseWriter, r *http.Request) {
+	var errors []string
+	if r.Header.Get("Content-type") != "application/json" {
+		var e = formatHttpErrors(http.StatusUnsupportedMediaType, errors)
 		e.writeErrors(w)
 		return
 	}
-
-	config, err := s.ipxe.UpdateNodeImage(r.Context(), indc)
-	if err != nil {
+	defer r.Body.Close()
+	var isc *ipxe.IpxeImageStateConfig
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&isc); err != nil {
 		errors = append(errors, err.Error())
-		var e = formatHttpErrors(http.StatusInternalServerError, errors)
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
 		e.writeErrors(w)
 		return
 	}
-	w.WriteHeader(http.StatusOK)
-	w.Header().Set("Content-Type", "application/json")
-	enc := json.NewEncoder(w)
-	enc.SetIndent("", "\t")
-	if err := enc.Encode(config); err != nil {
-		errors = append(errors, err.Error())
-		var e = formatHttpErrors(http.StatusInternalServerError, errors)
+
+	if isc.ImageTag == "" {
+		errors = append(errors, "ImageTag is missing.")
+	}
+	if isc.ImageType == "" {
+		errors = append(errors, "ImageType is missing.")
+	}
+	if isc.ImageState == "" {
+		errors = append(errors, "ImageState is missing.")
+	}
+	if len(errors) > 0 {
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
 		e.writeErrors(w)
 		return
 	}
+
+	config, err := s.ipxe.SetIpxeImageState(r.Context(), isc)
+	if err != nil {
+		writeImageLifecycleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, config)
 }
 
-func (s *HTTPServer) handleGetIpxeImages(w http.ResponseWriter, r *http.Request) {
-	// TODO: List all images
-	http.NotFound(w, r)
+// writeImageLifecycleError writes errors from image, channel and subnet default changes with writeError.
+// Images still in use are listed with the nodes, subnets and channels using them.
+func writeImageLifecycleError(w http.ResponseWriter, err error) {
+	var details []string
+	var inUseErr ipxe.ImageInUseError
+	if goerrors.As(err, &inUseErr) {
+		for _, macAddress := range inUseErr.Usage.MacAddresses {
+			details = append(details, fmt.Sprintf("used by mac_address: %s", macAddress))
+		}
+		for _, subnet := range inUseErr.Usage.Subnets {
+			details = append(details, fmt.Sprintf("used by subnet: %s", subnet))
+		}
+		for _, channel := range inUseErr.Usage.Channels {
+			details = append(details, fmt.Sprintf("used by channel: %s", channel))
+		}
+	}
+	writeError(w, err, details...)
 }
 
 func (s *HTTPServer) handlePutIpxeImages(w http.ResponseWriter, r *http.Request) {
 	var errors []string
-	log.Printf("Request Host: %s", r.Host)
-	log.Printf("Request RemoteAddr: %s", r.RemoteAddr)
-	log.Printf("Request RequestURI: %s", r.RequestURI)
-
 	if r.Header.Get("Content-type") != "application/json" {
 		var e = formatHttpErrors(http.StatusUnsupportedMediaType, errors)
 		e.writeErrors(w)
@@ -573,9 +648,7 @@ func (s *HTTPServer) handlePutIpxeImages(w http.ResponseWriter, r *http.Request)
 
 	config, err := s.ipxe.CreateIpxeImage(r.Context(), ic)
 	if err != nil {
-		errors = append(errors, err.Error())
-		var e = formatHttpErrors(http.StatusInternalServerError, errors)
-		e.writeErrors(w)
+		writeError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -592,17 +665,13 @@ func (s *HTTPServer) handlePutIpxeImages(w http.ResponseWriter, r *http.Request)
 
 func (s *HTTPServer) handleDeleteIpxeImages(w http.ResponseWriter, r *http.Request) {
 	var errors []string
-	log.Printf("Request Host: %s", r.Host)
-	log.Printf("Request RemoteAddr: %s", r.RemoteAddr)
-	log.Printf("Request RequestURI: %s", r.RequestURI)
-
 	if r.Header.Get("Content-type") != "application/json" {
 		var e = formatHttpErrors(http.StatusUnsupportedMediaType, errors)
 		e.writeErrors(w)
 		return
 	}
 	defer r.Body.Close()
-	var iddc *ipxe.IpxeImageTagType
+	var iddc *ipxe.IpxeImageDeleteConfig
 	dec := json.NewDecoder(r.Body)
 	if err := dec.Decode(&iddc); err != nil {
 		errors = append(errors, err.Error())
@@ -625,9 +694,7 @@ func (s *HTTPServer) handleDeleteIpxeImages(w http.ResponseWriter, r *http.Reque
 
 	config, err := s.ipxe.DeleteIpxeImage(r.Context(), iddc)
 	if err != nil {
-		errors = append(errors, err.Error())
-		var e = formatHttpErrors(http.StatusInternalServerError, errors)
-		e.writeErrors(w)
+		writeImageLifecycleError(w, err)
 		return
 	}
 
@@ -643,65 +710,268 @@ func (s *HTTPServer) handleDeleteIpxeImages(w http.ResponseWriter, r *http.Reque
 	}
 }
 
-func (s *HTTPServer) handleGetNodeIpxeTemplate(w http.ResponseWriter, r *http.Request) {
+func (s *HTTPServer) handleGetImageChannels(w http.ResponseWriter, r *http.Request) {
+	channels, err := s.ipxe.ListImageChannels(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, channels)
+}
+
+// handlePutImageChannelPromote points a channel at an image, creating the channel if needed.
+func (s *HTTPServer) handlePutImageChannelPromote(w http.ResponseWriter, r *http.Request) {
+	var errors []string
+	if r.Header.Get("Content-type") != "application/json" {
+		var e = formatHttpErrors(http.StatusUnsupportedMediaType, errors)
+		e.writeErrors(w)
+		return
+	}
+	defer r.Body.Close()
+	var icpc *ipxe.ImageChannelPromoteConfig
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&icpc); err != nil {
+		errors = append(errors, err.Error())
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
+		e.writeErrors(w)
+		return
+	}
+
+	if icpc.Channel == "" {
+		errors = append(errors, "Channel is missing.")
+	}
+	if icpc.ImageTag == "" {
+		errors = append(errors, "ImageTag is missing.")
+	}
+	if icpc.ImageType == "" {
+		errors = append(errors, "ImageType is missing.")
+	}
+	if len(errors) > 0 {
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
+		e.writeErrors(w)
+		return
+	}
+
+	channel, err := s.ipxe.PromoteImageChannel(r.Context(), icpc)
+	if err != nil {
+		writeImageLifecycleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, channel)
+}
+
+// handlePutImageChannelRollback points a channel back at the image it pointed at before the last promotion.
+func (s *HTTPServer) handlePutImageChannelRollback(w http.ResponseWriter, r *http.Request) {
+	channel, err := s.ipxe.RollbackImageChannel(r.Context(), chi.URLParam(r, "channel"))
+	if err != nil {
+		writeImageLifecycleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, channel)
+}
+
+func (s *HTTPServer) handleDeleteImageChannel(w http.ResponseWriter, r *http.Request) {
+	channel, err := s.ipxe.DeleteImageChannel(r.Context(), chi.URLParam(r, "channel"))
+	if err != nil {
+		writeImageLifecycleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, channel)
+}
+
+func (s *HTTPServer) handleGetSubnetDefaultImages(w http.ResponseWriter, r *http.Request) {
+	subnets, err := s.ipxe.ListSubnetDefaultImages(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, subnets)
+}
+
+// handlePutSubnetDefaultImage sets the image or channel booted by unassigned nodes in a subnet.
+func (s *HTTPServer) handlePutSubnetDefaultImage(w http.ResponseWriter, r *http.Request) {
 	var errors []string
+	if r.Header.Get("Content-type") != "application/json" {
+		var e = formatHttpErrors(http.StatusUnsupportedMediaType, errors)
+		e.writeErrors(w)
+		return
+	}
+	defer r.Body.Close()
+	var sdi *ipxe.SubnetDefaultImage
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&sdi); err != nil {
+		errors = append(errors, err.Error())
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
+		e.writeErrors(w)
+		return
+	}
 
-	macAddress := chi.URLParam(r, "macAddress")
-	macAddress = strings.ToLower(macAddress)
-	macAddress = strings.Replace(macAddress, ":", "", -1)
-	macAddress = strings.Replace(macAddress, "%3a", "", -1)
+	if sdi.Subnet == "" {
+		errors = append(errors, "Subnet is missing.")
+	}
+	if len(errors) > 0 {
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
+		e.writeErrors(w)
+		return
+	}
 
-	if len(macAddress) != 12 {
-		errors = append(errors, "Invalid mac_address")
+	config, err := s.ipxe.SetSubnetDefaultImage(r.Context(), sdi)
+	if err != nil {
+		writeImageLifecycleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, config)
+}
+
+func (s *HTTPServer) handleDeleteSubnetDefaultImage(w http.ResponseWriter, r *http.Request) {
+	var errors []string
+	if r.Header.Get("Content-type") != "application/json" {
+		var e = formatHttpErrors(http.StatusUnsupportedMediaType, errors)
+		e.writeErrors(w)
+		return
+	}
+	defer r.Body.Close()
+	var sdi *ipxe.SubnetDefaultImage
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&sdi); err != nil {
+		errors = append(errors, err.Error())
 		var e = formatHttpErrors(http.StatusBadRequest, errors)
 		e.writeErrors(w)
 		return
 	}
 
-	log.Printf("Request Host: %s", r.Host)
-	log.Printf("Request RemoteAddr: %s", r.RemoteAddr)
-	log.Printf("Request RequestURI: %s", r.RequestURI)
-	ipxeTemplate, err := s.ipxe.GetIpxeConfigTemplate(r.Context())
+	if sdi.Subnet == "" {
+		errors = append(errors, "Subnet is missing.")
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
+		e.writeErrors(w)
+		return
+	}
+
+	config, err := s.ipxe.DeleteSubnetDefaultImage(r.Context(), sdi.Subnet)
 	if err != nil {
+		writeImageLifecycleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, config)
+}
+
+func (s *HTTPServer) handleGetImageRollouts(w http.ResponseWriter, r *http.Request) {
+	rollouts, err := s.ipxe.ListImageRollouts(r.Context(), r.URL.Query().Get("state"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rollouts)
+}
+
+// handlePutImageRollout creates a rollout, no node is updated before the first advance.
+func (s *HTTPServer) handlePutImageRollout(w http.ResponseWriter, r *http.Request) {
+	var errors []string
+	if r.Header.Get("Content-type") != "application/json" {
+		var e = formatHttpErrors(http.StatusUnsupportedMediaType, errors)
+		e.writeErrors(w)
+		return
+	}
+	defer r.Body.Close()
+	var irc *ipxe.ImageRolloutConfig
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&irc); err != nil {
 		errors = append(errors, err.Error())
-		var e = formatHttpErrors(http.StatusInternalServerError, errors)
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
 		e.writeErrors(w)
 		return
 	}
 
-	assignedIpxeConfig, err := s.ipxe.GetNodeIpxeConfig(r.Context(), macAddress)
-	switch {
-	case assignedIpxeConfig == nil || err != nil:
-		// image_tag, image_type, mac_address missing from ipxe.node_images
-		var defaultNodeIpxeConfig *ipxe.IpxeConfig
-
-		requestIp := strings.Split(r.RemoteAddr, ":")[0]
-		log.Printf("Checking subnet_default_images for requestIp: %s", requestIp)
-		subnetIpxeConfig := s.ipxe.GetSubnetDefaultIpxeConfig(r.Context(), requestIp)
-
-		switch {
-		case subnetIpxeConfig != nil:
-			log.Printf("Using subnetIpxeConfig for macAddress: %s", macAddress)
-			defaultNodeIpxeConfig = subnetIpxeConfig
-		case subnetIpxeConfig == nil:
-			log.Printf("Using API default IpxeConfig for macAddress: %s", macAddress)
-			defaultNodeIpxeConfig = s.ipxe.GetIpxeApiDefault()
-		}
-		defaultNodeIpxeDbConfig := &ipxe.IpxeNodeDbConfig{
-			ImageTag:   defaultNodeIpxeConfig.ImageTag,
-			ImageType:  defaultNodeIpxeConfig.ImageType,
-			MacAddress: macAddress,
-		}
-		log.Printf("Adding defaulted node_images entry: %v", defaultNodeIpxeDbConfig)
-		if err := s.ipxe.CreateNodeIpxeConfig(r.Context(), defaultNodeIpxeDbConfig); err != nil {
-			log.Printf("CreateNodeIpxeConfig: %s", err.Error())
+	for i, macAddress := range irc.MacAddresses {
+		mac, err := macaddr.Parse(macAddress)
+		if err != nil {
+			errors = append(errors, err.Error())
 		}
-		s.ipxe.SetHostname(r.Context(), defaultNodeIpxeConfig, macAddress)
-		ipxeTemplate.Execute(w, defaultNodeIpxeConfig)
+		irc.MacAddresses[i] = mac.String()
+	}
+	if len(errors) > 0 {
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
+		e.writeErrors(w)
+		return
+	}
+
+	rollout, err := s.ipxe.CreateImageRollout(r.Context(), irc)
+	if err != nil {
+		writeImageLifecycleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rollout)
+}
+
+func (s *HTTPServer) handleGetImageRollout(w http.ResponseWriter, r *http.Request) {
+	var errors []string
+	rolloutId, err := strconv.ParseInt(chi.URLParam(r, "rolloutId"), 10, 64)
+	if err != nil {
+		errors = append(errors, "Invalid rolloutId")
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
+		e.writeErrors(w)
+		return
+	}
+	rollout, err := s.ipxe.GetImageRollout(r.Context(), rolloutId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rollout)
+}
+
+// handlePutImageRolloutAction advances, halts or rolls back a rollout depending on the last path element.
+func (s *HTTPServer) handlePutImageRolloutAction(w http.ResponseWriter, r *http.Request) {
+	var errors []string
+	rolloutId, err := strconv.ParseInt(chi.URLParam(r, "rolloutId"), 10, 64)
+	if err != nil {
+		errors = append(errors, "Invalid rolloutId")
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
+		e.writeErrors(w)
+		return
+	}
+	var rollout *ipxe.ImageRollout
+	switch path.Base(r.URL.Path) {
+	case "advance":
+		rollout, err = s.ipxe.AdvanceImageRollout(r.Context(), rolloutId)
+	case "halt":
+		rollout, err = s.ipxe.HaltImageRollout(r.Context(), rolloutId, r.URL.Query().Get("reason"))
+	case "rollback":
+		rollout, err = s.ipxe.RollbackImageRollout(r.Context(), rolloutId)
 	default:
-		ipxeTemplate.Execute(w, assignedIpxeConfig)
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		writeImageLifecycleError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rollout)
+}
 
+func (s *HTTPServer) handleGetNodeIpxeTemplate(w http.ResponseWriter, r *http.Request) {
+	macAddress, ok := pathMacAddress(w, r, "macAddress")
+	if !ok {
+		return
 	}
+
+	ipxeTemplate, err := s.ipxe.GetIpxeConfigTemplate(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	requestIp, err := bootIP(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ipxeConfig, err := s.ipxe.BootNodeIpxeConfig(r.Context(), macAddress, requestIp, bootHints(r))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	ipxeTemplate.Execute(w, ipxeConfig)
 }
 
 // Accepts an imageName
@@ -715,18 +985,10 @@ func (s *HTTPServer) handleGetIpxeImagePresignedUrls(w http.ResponseWriter, r *h
 		http.NotFound(w, r)
 		return
 	}
-	log.Printf("Request Host: %s", r.Host)
-	log.Printf("Request RemoteAddr: %s", r.RemoteAddr)
-	log.Printf("Request RequestURI: %s", r.RequestURI)
-
-	imageInitrdUrlHttps, imageKernelUrlHttps, imageRootFsUrlHttps, err := s.ipxe.GetIpxeImagePresignedUrls(bucket, imageName, lifetimeSecs)
+	imageInitrdUrlHttps, imageKernelUrlHttps, imageRootFsUrlHttps, err := s.ipxe.GetIpxeImagePresignedUrls(r.Context(), bucket, imageName, lifetimeSecs)
 	switch {
-	case err == context.Canceled, err == context.DeadlineExceeded:
-		return
 	case err != nil:
-		errors = append(errors, err.Error())
-		var e = formatHttpErrors(http.StatusInternalServerError, errors)
-		e.writeErrors(w)
+		writeError(w, err)
 		return
 	default:
 		if imageInitrdUrlHttps == "" {
@@ -759,14 +1021,8 @@ func (s *HTTPServer) handlePutNodesHeartbeat(w http.ResponseWriter, r *http.Requ
 		return
 	}
 	var errors []string
-	macAddress := chi.URLParam(r, "macAddress")
-
-	macAddress = strings.Replace(strings.ToLower(macAddress), ":", "", -1)
-
-	if len(macAddress) != 12 {
-		errors = append(errors, "Invalid mac_address")
-		var e = formatHttpErrors(http.StatusBadRequest, errors)
-		e.writeErrors(w)
+	macAddress, ok := pathMacAddress(w, r, "macAddress")
+	if !ok {
 		return
 	}
 
@@ -791,9 +1047,7 @@ func (s *HTTPServer) handlePutNodesHeartbeat(w http.ResponseWriter, r *http.Requ
 
 	n, err := s.nodes.UpdateNodeStats(r.Context(), node)
 	if err != nil {
-		errors = append(errors, err.Error())
-		var e = formatHttpErrors(http.StatusInternalServerError, errors)
-		e.writeErrors(w)
+		writeError(w, err)
 		return
 	}
 	w.WriteHeader(http.StatusOK)
@@ -801,7 +1055,72 @@ func (s *HTTPServer) handlePutNodesHeartbeat(w http.ResponseWriter, r *http.Requ
 	enc := json.NewEncoder(w)
 	enc.SetIndent("", "\t")
 	if err := enc.Encode(n); err != nil {
-		log.Printf("cannot json encode payload response: %v", err)
+		logging.FromContext(r.Context()).Error("cannot json encode response", zap.Error(err))
+	}
+
+}
+
+// handlePutNodesBulk assigns an image, a payload or both to many nodes, all or nothing.
+// With ?dry_run=true the changes are computed and rolled back.
+func (s *HTTPServer) handlePutNodesBulk(w http.ResponseWriter, r *http.Request) {
+	var errors []string
+	if r.Header.Get("Content-type") != "application/json" {
+		var e = formatHttpErrors(http.StatusUnsupportedMediaType, errors)
+		e.writeErrors(w)
+		return
+	}
+	dryRun := false
+	if v := r.URL.Query().Get("dry_run"); v != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			errors = append(errors, "Invalid dry_run")
+			var e = formatHttpErrors(http.StatusBadRequest, errors)
+			e.writeErrors(w)
+			return
+		}
+	}
+	defer r.Body.Close()
+	var a *bulk.Assignment
+	dec := json.NewDecoder(r.Body)
+	if err := dec.Decode(&a); err != nil {
+		errors = append(errors, err.Error())
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
+		e.writeErrors(w)
+		return
 	}
 
+	result, err := s.bulk.Assign(r.Context(), a, dryRun)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if !result.Committed && !result.DryRun {
+		writeJSON(w, http.StatusConflict, result)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *HTTPServer) handleGetNodeViews(w http.ResponseWriter, r *http.Request) {
+	views, err := s.nodes.ListNodeViews(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, views)
+}
+
+func (s *HTTPServer) handleGetNodeView(w http.ResponseWriter, r *http.Request) {
+	// the path may name the node by hostname instead of macAddress
+	macAddress, _, err := s.nodeMacAddress(r.Context(), chi.URLParam(r, "macAddress"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	view, err := s.nodes.GetNodeView(r.Context(), macAddress)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, view)
 }
@@ -0,0 +1,70 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/ipxe"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWriteError(t *testing.T) {
+	tests := []struct {
+		err        error
+		statusCode int
+		code       string
+	}{
+		{errdefs.NotFound("node_not_found", "mac_address not in database"), http.StatusNotFound, "node_not_found"},
+		{fmt.Errorf("create: %w", errdefs.Conflict("image_exists", "(image_tag, image_type) exists")), http.StatusConflict, "image_exists"},
+		{ipxe.ImageInUseError{Usage: &ipxe.IpxeImageUsage{}}, http.StatusConflict, "image_in_use"},
+		{errdefs.Unavailable("database_unavailable", "database unavailable"), http.StatusServiceUnavailable, "database_unavailable"},
+		{errors.New("cannot list images from database"), http.StatusInternalServerError, errdefs.CodeInternal},
+	}
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			writeError(w, tt.err, "detail")
+
+			var e httpErrors
+			assert.NoError(t, json.NewDecoder(w.Body).Decode(&e))
+			assert.Equal(t, tt.statusCode, w.Code)
+			assert.Equal(t, tt.code, e.Code)
+			assert.Equal(t, []string{tt.err.Error(), "detail"}, e.Errors)
+		})
+	}
+}
+
+func TestWriteError_canceled(t *testing.T) {
+	w := httptest.NewRecorder()
+	writeError(w, fmt.Errorf("query: %w", context.Canceled))
+
+	assert.Zero(t, w.Body.Len())
+}
+
+func TestPathMacAddress(t *testing.T) {
+	r := chi.NewRouter()
+	r.Get("/{macAddress}", func(w http.ResponseWriter, r *http.Request) {
+		if macAddress, ok := pathMacAddress(w, r, "macAddress"); ok {
+			w.Write([]byte(macAddress))
+		}
+	})
+	for _, path := range []string{"/0C:42:A1:B2:C3:D4", "/0c%3a42%3aa1%3ab2%3ac3%3ad4", "/0c-42-a1-b2-c3-d4", "/0c42.a1b2.c3d4"} {
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		assert.Equal(t, "0c42a1b2c3d4", w.Body.String(), path)
+	}
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/0c42a1b2c3zz", nil))
+	var e httpErrors
+	assert.NoError(t, json.NewDecoder(w.Body).Decode(&e))
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, "invalid_mac_address", e.Code)
+}
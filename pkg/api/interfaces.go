@@ -0,0 +1,66 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/coreweave/ncore-api/pkg/nodes"
+)
+
+func (s *HTTPServer) handleGetNodeInterfaces(w http.ResponseWriter, r *http.Request) {
+	macAddress, ok := pathMacAddress(w, r, "macAddress")
+	if !ok {
+		return
+	}
+	identity, err := s.nodes.GetNodeIdentity(r.Context(), macAddress)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, identity)
+}
+
+// handlePutNodeInterface attaches the interface in the body to the node of macAddress, e.g. a replaced NIC.
+func (s *HTTPServer) handlePutNodeInterface(w http.ResponseWriter, r *http.Request) {
+	var errors []string
+	if r.Header.Get("Content-type") != "application/json" {
+		var e = formatHttpErrors(http.StatusUnsupportedMediaType, errors)
+		e.writeErrors(w)
+		return
+	}
+	macAddress, ok := pathMacAddress(w, r, "macAddress")
+	if !ok {
+		return
+	}
+	defer r.Body.Close()
+	iface := &nodes.Interface{}
+	if err := json.NewDecoder(r.Body).Decode(iface); err != nil {
+		errors = append(errors, err.Error())
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
+		e.writeErrors(w)
+		return
+	}
+	identity, err := s.nodes.AttachInterface(r.Context(), macAddress, iface)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, identity)
+}
+
+func (s *HTTPServer) handleDeleteNodeInterface(w http.ResponseWriter, r *http.Request) {
+	macAddress, ok := pathMacAddress(w, r, "macAddress")
+	if !ok {
+		return
+	}
+	interfaceMacAddress, ok := pathMacAddress(w, r, "interfaceMacAddress")
+	if !ok {
+		return
+	}
+	identity, err := s.nodes.DetachInterface(r.Context(), macAddress, interfaceMacAddress)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, identity)
+}
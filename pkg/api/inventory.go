@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/coreweave/ncore-api/pkg/nodes"
+)
+
+func (s *HTTPServer) handleGetNodeInventories(w http.ResponseWriter, r *http.Request) {
+	inventories, err := s.nodes.ListNodeInventories(r.Context(), r.URL.Query().Get("hardware_class"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, inventories)
+}
+
+func (s *HTTPServer) handleGetNodeInventory(w http.ResponseWriter, r *http.Request) {
+	macAddress, ok := pathMacAddress(w, r, "macAddress")
+	if !ok {
+		return
+	}
+	ni, err := s.nodes.GetNodeInventory(r.Context(), macAddress)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ni)
+}
+
+func (s *HTTPServer) handleGetNodeInventoryChanges(w http.ResponseWriter, r *http.Request) {
+	macAddress, ok := pathMacAddress(w, r, "macAddress")
+	if !ok {
+		return
+	}
+	changes, err := s.nodes.ListInventoryChanges(r.Context(), macAddress)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, changes)
+}
+
+// handlePutNodeInventory records the hardware report of a node, usually sent by the agent next to its heartbeat.
+func (s *HTTPServer) handlePutNodeInventory(w http.ResponseWriter, r *http.Request) {
+	var errors []string
+	if r.Header.Get("Content-type") != "application/json" {
+		var e = formatHttpErrors(http.StatusUnsupportedMediaType, errors)
+		e.writeErrors(w)
+		return
+	}
+	macAddress, ok := pathMacAddress(w, r, "macAddress")
+	if !ok {
+		return
+	}
+	defer r.Body.Close()
+	var inv *nodes.Inventory
+	if err := json.NewDecoder(r.Body).Decode(&inv); err != nil {
+		errors = append(errors, err.Error())
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
+		e.writeErrors(w)
+		return
+	}
+	ni, err := s.nodes.ReportInventory(r.Context(), macAddress, inv)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, ni)
+}
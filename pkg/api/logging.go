@@ -0,0 +1,72 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/snapshot"
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// maxRequestIDLength bounds the X-Request-ID accepted from clients, longer ones are replaced.
+const maxRequestIDLength = 128
+
+// requestLogger carries the request ID, taken from X-Request-ID or generated, and a logger with it
+// and the trace ID in the context of the request, returns it in X-Request-ID and writes one access line per request.
+func requestLogger(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := r.Header.Get(logging.RequestIDHeader)
+		if !validRequestID(id) {
+			id = logging.NewRequestID()
+		}
+		ctx := logging.WithRequestID(r.Context(), id)
+		if sc := trace.SpanContextFromContext(ctx); sc.HasTraceID() {
+			ctx = logging.WithLogger(ctx, logging.FromContext(ctx).With(zap.String("trace_id", sc.TraceID().String())))
+		}
+		w.Header().Set(logging.RequestIDHeader, id)
+
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r.WithContext(ctx))
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		route := ""
+		if rctx := chi.RouteContext(ctx); rctx != nil {
+			route = rctx.RoutePattern()
+		}
+		fields := []zap.Field{
+			zap.String("method", r.Method),
+			zap.String("uri", r.RequestURI),
+			zap.String("route", route),
+			zap.Int("status", status),
+			zap.Int("bytes", ww.BytesWritten()),
+			zap.Duration("duration", time.Since(start)),
+			zap.String("host", r.Host),
+			zap.String("remote_addr", r.RemoteAddr),
+			zap.String("user_agent", r.UserAgent()),
+		}
+		if takenAt, ok := snapshot.Stale(ctx); ok {
+			fields = append(fields, zap.Time("stale_snapshot", takenAt))
+		}
+		logging.FromContext(ctx).Info("request", fields...)
+	})
+}
+
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for _, c := range id {
+		if c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
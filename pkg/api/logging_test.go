@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestRequestLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	defer zap.ReplaceGlobals(zap.New(core))()
+
+	tests := []struct {
+		name      string
+		requestID string
+		generated bool
+	}{
+		{"from header", "abc-123", false},
+		{"generated", "", true},
+		{"invalid header", "has spaces", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs.TakeAll()
+			r := httptest.NewRequest(http.MethodGet, "/api/v2/ipxe/rollouts/x", nil)
+			if tt.requestID != "" {
+				r.Header.Set(logging.RequestIDHeader, tt.requestID)
+			}
+			w := httptest.NewRecorder()
+			NewHTTPServer(nil, nil, nil, nil, nil, nil).ServeHTTP(w, r)
+
+			id := w.Header().Get(logging.RequestIDHeader)
+			if tt.generated {
+				assert.Len(t, id, 32)
+			} else {
+				assert.Equal(t, tt.requestID, id)
+			}
+			lines := logs.FilterMessage("request").All()
+			require.Len(t, lines, 1)
+			fields := lines[0].ContextMap()
+			assert.Equal(t, id, fields["request_id"])
+			assert.Equal(t, "/api/v2/ipxe/rollouts/{rolloutId}", fields["route"])
+			assert.Equal(t, int64(http.StatusBadRequest), fields["status"])
+		})
+	}
+}
@@ -0,0 +1,49 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/metrics"
+	"github.com/go-chi/chi/v5"
+	chimiddleware "github.com/go-chi/chi/v5/middleware"
+)
+
+// unmatchedRoute labels requests matching no route, so unknown paths don't add label values.
+const unmatchedRoute = "unmatched"
+
+// requestMetrics records metrics.HTTPRequests and metrics.HTTPRequestDuration by route pattern.
+func requestMetrics(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ww := chimiddleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+		route, ok := routePattern(r.Context())
+		if !ok {
+			route = unmatchedRoute
+		}
+		labels := []string{r.Method, route, strconv.Itoa(status)}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
+
+// routePattern returns the pattern of the route ctx was routed to, false when no route matched.
+func routePattern(ctx context.Context) (string, bool) {
+	rctx := chi.RouteContext(ctx)
+	if rctx == nil || len(rctx.RoutePatterns) == 0 {
+		return "", false
+	}
+	// RoutePattern trims the trailing slash, of the root route too
+	if route := rctx.RoutePattern(); route != "" {
+		return route, true
+	}
+	return "/", true
+}
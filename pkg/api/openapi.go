@@ -0,0 +1,17 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+// OpenAPISpec is the OpenAPI 3 document of the routes of NewHTTPServer,
+// pkg/client is generated from it.
+//
+//go:embed openapi.json
+var OpenAPISpec []byte
+
+func (s *HTTPServer) handleGetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(OpenAPISpec)
+}
@@ -0,0 +1,126 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/coreweave/ncore-api/pkg/nodes"
+	"github.com/coreweave/ncore-api/pkg/registration"
+	"github.com/go-chi/chi/v5"
+)
+
+func (s *HTTPServer) handleGetRegistrations(w http.ResponseWriter, r *http.Request) {
+	registrations, err := s.registration.ListRegistrations(r.Context(), r.URL.Query().Get("state"))
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, registrations)
+}
+
+func (s *HTTPServer) handleGetRegistration(w http.ResponseWriter, r *http.Request) {
+	macAddress, ok := pathMacAddress(w, r, "macAddress")
+	if !ok {
+		return
+	}
+	reg, err := s.registration.GetRegistration(r.Context(), macAddress)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reg)
+}
+
+// handlePutRegistrationApprove approves a node with the image and payload in the optional body,
+// its defaults are pinned on its next boot otherwise.
+func (s *HTTPServer) handlePutRegistrationApprove(w http.ResponseWriter, r *http.Request) {
+	var errors []string
+	macAddress, ok := pathMacAddress(w, r, "macAddress")
+	if !ok {
+		return
+	}
+	approval := &registration.Approval{}
+	if r.ContentLength != 0 {
+		if r.Header.Get("Content-type") != "application/json" {
+			var e = formatHttpErrors(http.StatusUnsupportedMediaType, errors)
+			e.writeErrors(w)
+			return
+		}
+		defer r.Body.Close()
+		if err := json.NewDecoder(r.Body).Decode(approval); err != nil {
+			errors = append(errors, err.Error())
+			var e = formatHttpErrors(http.StatusBadRequest, errors)
+			e.writeErrors(w)
+			return
+		}
+	}
+	reg, err := s.registration.Approve(r.Context(), macAddress, approval)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reg)
+}
+
+func (s *HTTPServer) handlePutRegistrationReject(w http.ResponseWriter, r *http.Request) {
+	macAddress, ok := pathMacAddress(w, r, "macAddress")
+	if !ok {
+		return
+	}
+	reg, err := s.registration.Reject(r.Context(), macAddress)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, reg)
+}
+
+func (s *HTTPServer) handleGetRegistrationRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := s.registration.ListRules(r.Context())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, rules)
+}
+
+func (s *HTTPServer) handlePutRegistrationRule(w http.ResponseWriter, r *http.Request) {
+	var errors []string
+	if r.Header.Get("Content-type") != "application/json" {
+		var e = formatHttpErrors(http.StatusUnsupportedMediaType, errors)
+		e.writeErrors(w)
+		return
+	}
+	defer r.Body.Close()
+	var rule *nodes.RegistrationRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		errors = append(errors, err.Error())
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
+		e.writeErrors(w)
+		return
+	}
+	created, err := s.registration.CreateRule(r.Context(), rule)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, created)
+}
+
+func (s *HTTPServer) handleDeleteRegistrationRule(w http.ResponseWriter, r *http.Request) {
+	var errors []string
+	ruleId, err := strconv.ParseInt(chi.URLParam(r, "ruleId"), 10, 64)
+	if err != nil {
+		errors = append(errors, "Invalid ruleId")
+		var e = formatHttpErrors(http.StatusBadRequest, errors)
+		e.writeErrors(w)
+		return
+	}
+	deleted, err := s.registration.DeleteRule(r.Context(), ruleId)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, deleted)
+}
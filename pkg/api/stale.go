@@ -0,0 +1,51 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/snapshot"
+)
+
+// staleWarning is the Warning header of responses answered from a snapshot, see RFC 7234 section 5.5.1.
+const staleWarning = `110 ncore-api "Response is Stale"`
+
+// staleResponses lets services record in the context of the request that they answered from a snapshot,
+// and marks such responses with a Warning header and an Age header holding the age of the snapshot.
+func staleResponses(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = r.WithContext(snapshot.WithStaleness(r.Context()))
+		next.ServeHTTP(&staleResponseWriter{ResponseWriter: w, r: r}, r)
+	})
+}
+
+// staleResponseWriter adds the stale headers when the response headers are written.
+type staleResponseWriter struct {
+	http.ResponseWriter
+	r           *http.Request
+	wroteHeader bool
+}
+
+func (w *staleResponseWriter) WriteHeader(statusCode int) {
+	if !w.wroteHeader {
+		w.wroteHeader = true
+		if takenAt, ok := snapshot.Stale(w.r.Context()); ok {
+			w.Header().Set("Warning", staleWarning)
+			w.Header().Set("Age", fmt.Sprint(int64(time.Since(takenAt).Seconds())))
+		}
+	}
+	w.ResponseWriter.WriteHeader(statusCode)
+}
+
+func (w *staleResponseWriter) Write(b []byte) (int, error) {
+	if !w.wroteHeader {
+		w.WriteHeader(http.StatusOK)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap lets http.ResponseController reach the underlying http.ResponseWriter.
+func (w *staleResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
@@ -0,0 +1,32 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/snapshot"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStaleResponses(t *testing.T) {
+	store := snapshot.NewStore("test", "", func(ctx context.Context) (string, error) { return "", nil })
+	handler := staleResponses(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/stale" {
+			snapshot.MarkStale(r.Context(), store, &snapshot.Snapshot[string]{TakenAt: time.Now().Add(-time.Minute)})
+		}
+		w.Write([]byte("ok"))
+	}))
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/stale", nil))
+	assert.Equal(t, staleWarning, rr.Header().Get("Warning"))
+	assert.Equal(t, "60", rr.Header().Get("Age"))
+
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/fresh", nil))
+	assert.Empty(t, rr.Header().Get("Warning"))
+	assert.Empty(t, rr.Header().Get("Age"))
+}
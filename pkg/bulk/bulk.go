@@ -0,0 +1,411 @@
+// Package bulk assigns images and payloads to many nodes at once.
+package bulk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/ipxe"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/macaddr"
+	"github.com/coreweave/ncore-api/pkg/nodes"
+	"github.com/coreweave/ncore-api/pkg/payloads"
+	"go.uber.org/zap"
+)
+
+// Node result statuses reported in NodeResult.
+const (
+	StatusUpdated   = "updated"
+	StatusUnchanged = "unchanged"
+	StatusFailed    = "failed"
+)
+
+// Assignment steps reported in StepResult, each one is a transaction.
+const (
+	StepImage        = "image"
+	StepPayload      = "payload"
+	StepImagePayload = "image_payload"
+)
+
+// errDryRun rolls back the transactions of a dry run.
+var errDryRun = errors.New("dry run")
+
+// Selector matches nodes by subnet, hardware class, current image and current payload. Set fields are combined with AND.
+type Selector struct {
+	// Subnet matches the ip_address of the last node heartbeat.
+	Subnet string
+	// HardwareClass matches the last inventory reported by the node, see nodes.Inventory.HardwareClass.
+	HardwareClass string
+	ImageTag      string
+	ImageType     string
+	PayloadId     string
+}
+
+func (s *Selector) empty() bool {
+	return s.Subnet == "" && s.HardwareClass == "" && s.ImageTag == "" && s.ImageType == "" && s.PayloadId == ""
+}
+
+// Assignment changes the image, the payload or both of the nodes in MacAddresses or matching Selector.
+type Assignment struct {
+	MacAddresses []string
+	Selector     *Selector
+	ImageTag     string
+	ImageType    string
+	ImageChannel string
+	PayloadId    string
+}
+
+func (a *Assignment) image() bool {
+	return a.ImageTag != "" || a.ImageType != "" || a.ImageChannel != ""
+}
+
+// NodeResult is the outcome of an Assignment for one node.
+type NodeResult struct {
+	MacAddress           string
+	Status               string
+	PreviousImageTag     string `json:",omitempty"`
+	PreviousImageType    string `json:",omitempty"`
+	PreviousImageChannel string `json:",omitempty"`
+	PreviousPayloadId    string `json:",omitempty"`
+	Error                string `json:",omitempty"`
+}
+
+// StepResult is the outcome of one step of an Assignment, nothing of the step is written unless Committed is set.
+type StepResult struct {
+	Step      string
+	Committed bool
+	Nodes     []*NodeResult
+	Error     string `json:",omitempty"`
+}
+
+// Result of an Assignment, with the steps that ran in order. Committed is set when every step committed.
+type Result struct {
+	DryRun    bool
+	Committed bool
+	Steps     []*StepResult
+}
+
+// step is an Assignment restricted to what one transaction changes.
+type step struct {
+	name   string
+	a      *Assignment
+	withTx func(ctx context.Context, txOptions database.TxOptions, fn func(ctx context.Context) error) error
+}
+
+//go:generate mockgen --build_flags=--mod=mod -package bulk -destination mock_ipxe_db_test.go -mock_names DB=MockIpxeDB github.com/coreweave/ncore-api/pkg/ipxe DB
+//go:generate mockgen --build_flags=--mod=mod -package bulk -destination mock_payloads_db_test.go -mock_names DB=MockPayloadsDB github.com/coreweave/ncore-api/pkg/payloads DB
+//go:generate mockgen --build_flags=--mod=mod -package bulk -destination mock_nodes_db_test.go -mock_names DB=MockNodesDB github.com/coreweave/ncore-api/pkg/nodes DB
+
+// NewService creates a bulk assignment service on top of the ipxe, payloads and nodes services.
+func NewService(ipxeSvc *ipxe.Service, payloadsSvc *payloads.Service, nodesSvc *nodes.Service) *Service {
+	return &Service{
+		ipxe:     ipxeSvc,
+		payloads: payloadsSvc,
+		nodes:    nodesSvc,
+	}
+}
+
+// Service for bulk assignments.
+type Service struct {
+	ipxe     *ipxe.Service
+	payloads *payloads.Service
+	nodes    *nodes.Service
+	// singleDatabase is set when ipxe and payloads share one database, see SetSingleDatabase.
+	singleDatabase bool
+}
+
+// SetSingleDatabase tells whether the ipxe and payloads services share one database, the image and the payload
+// of an Assignment are then changed in one transaction instead of two steps.
+func (s *Service) SetSingleDatabase(single bool) {
+	s.singleDatabase = single
+}
+
+// ValidationError is returned when there is an invalid parameter received.
+type ValidationError struct {
+	s string
+}
+
+func (e ValidationError) Error() string {
+	return e.s
+}
+
+// Is makes ValidationError an errdefs.ErrInvalid error.
+func (e ValidationError) Is(target error) bool {
+	return target == errdefs.ErrInvalid
+}
+
+// Assign applies a to every selected node in steps, each one a transaction that stops at the first failed node and
+// rolls back, StepResult.Nodes then ends with it.
+// The image and the payload are one step in the single database mode. With separate ipxe and payloads databases
+// they are two steps: the image step commits before the payload step runs, a failed payload step leaves the images
+// assigned and the payloads unchanged. No step runs after a failed one.
+// A dry run runs every step and rolls each one back.
+func (s *Service) Assign(ctx context.Context, a *Assignment, dryRun bool) (*Result, error) {
+	if err := s.validate(ctx, a); err != nil {
+		return nil, err
+	}
+	macAddresses, err := s.resolve(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	if len(macAddresses) == 0 {
+		return nil, ValidationError{"no nodes selected"}
+	}
+
+	result := &Result{DryRun: dryRun}
+	for _, st := range s.steps(a) {
+		sr, err := s.runStep(ctx, st, macAddresses, dryRun)
+		if err != nil && len(result.Steps) == 0 {
+			return nil, err
+		}
+		if err != nil {
+			// an earlier step committed, report it rather than the error alone
+			sr.Error = err.Error()
+		}
+		result.Steps = append(result.Steps, sr)
+		if !sr.Committed && !dryRun {
+			logging.FromContext(ctx).Warn("bulk assignment step rolled back", zap.String("step", st.name),
+				zap.Int("committed_steps", len(result.Steps)-1), zap.Int("nodes", len(macAddresses)), zap.String("error", sr.Error))
+			return result, nil
+		}
+	}
+	result.Committed = !dryRun
+	if result.Committed {
+		logging.FromContext(ctx).Info("bulk assignment committed", zap.Int("nodes", len(macAddresses)))
+	}
+	return result, nil
+}
+
+// steps splits a into the transactions applying it.
+func (s *Service) steps(a *Assignment) []*step {
+	image, payload := *a, *a
+	image.PayloadId = ""
+	payload.ImageTag, payload.ImageType, payload.ImageChannel = "", "", ""
+	switch {
+	case !a.image():
+		return []*step{{name: StepPayload, a: &payload, withTx: s.payloads.WithTx}}
+	case a.PayloadId == "":
+		return []*step{{name: StepImage, a: &image, withTx: s.ipxe.WithTx}}
+	case s.singleDatabase:
+		// both services run on the same pool, the nested transaction is the outer one
+		withTx := func(ctx context.Context, txOptions database.TxOptions, fn func(ctx context.Context) error) error {
+			return s.payloads.WithTx(ctx, txOptions, func(ctx context.Context) error {
+				return s.ipxe.WithTx(ctx, txOptions, fn)
+			})
+		}
+		return []*step{{name: StepImagePayload, a: a, withTx: withTx}}
+	default:
+		return []*step{
+			{name: StepImage, a: &image, withTx: s.ipxe.WithTx},
+			{name: StepPayload, a: &payload, withTx: s.payloads.WithTx},
+		}
+	}
+}
+
+// runStep assigns st to macAddresses in one transaction. The returned error is set when the transaction itself failed.
+func (s *Service) runStep(ctx context.Context, st *step, macAddresses []string, dryRun bool) (*StepResult, error) {
+	sr := &StepResult{Step: st.name}
+	errFailed := errors.New("assignment failed for some nodes")
+	err := st.withTx(ctx, database.DefaultTxOptions, func(ctx context.Context) error {
+		sr.Nodes = make([]*NodeResult, 0, len(macAddresses))
+		for _, macAddress := range macAddresses {
+			nr := s.assignNode(ctx, st.a, macAddress)
+			sr.Nodes = append(sr.Nodes, nr)
+			// A failed statement aborts the transaction, the remaining nodes would fail as well.
+			if nr.Status == StatusFailed {
+				sr.Error = nr.Error
+				return errFailed
+			}
+		}
+		if dryRun {
+			return errDryRun
+		}
+		return nil
+	})
+	switch {
+	case errors.Is(err, errDryRun), errors.Is(err, errFailed):
+		return sr, nil
+	case err != nil:
+		return sr, err
+	}
+	sr.Committed = true
+	return sr, nil
+}
+
+func (s *Service) validate(ctx context.Context, a *Assignment) error {
+	if len(a.MacAddresses) > 0 && a.Selector != nil && !a.Selector.empty() {
+		return ValidationError{"MacAddresses and Selector are mutually exclusive"}
+	}
+	if len(a.MacAddresses) == 0 && (a.Selector == nil || a.Selector.empty()) {
+		return ValidationError{"missing MacAddresses or Selector"}
+	}
+	if a.Selector != nil && (a.Selector.ImageTag == "") != (a.Selector.ImageType == "") {
+		return ValidationError{"Selector needs both ImageTag and ImageType"}
+	}
+	if !a.image() && a.PayloadId == "" {
+		return ValidationError{"missing image or PayloadId to assign"}
+	}
+	if a.image() {
+		if err := s.ipxe.CheckImageTarget(ctx, a.ImageTag, a.ImageType, a.ImageChannel); err != nil {
+			return err
+		}
+	}
+	if a.PayloadId != "" {
+		available := false
+		for _, payloadId := range s.payloads.GetAvailablePayloads(ctx) {
+			if payloadId == a.PayloadId {
+				available = true
+				break
+			}
+		}
+		if !available {
+			return ValidationError{fmt.Sprintf("PayloadId doesn't exist: %s", a.PayloadId)}
+		}
+	}
+	return nil
+}
+
+// resolve returns the sorted mac_address list selected by a.
+func (s *Service) resolve(ctx context.Context, a *Assignment) ([]string, error) {
+	if len(a.MacAddresses) > 0 {
+		seen := map[string]bool{}
+		var macAddresses []string
+		for _, macAddress := range a.MacAddresses {
+			mac, err := macaddr.Parse(macAddress)
+			if err != nil {
+				return nil, err
+			}
+			// any interface of a node selects the node
+			macAddress, err := s.nodes.ResolveMacAddress(ctx, mac.String())
+			if err != nil {
+				return nil, err
+			}
+			if !seen[macAddress] {
+				seen[macAddress] = true
+				macAddresses = append(macAddresses, macAddress)
+			}
+		}
+		sort.Strings(macAddresses)
+		return macAddresses, nil
+	}
+
+	var sets [][]string
+	if a.Selector.Subnet != "" {
+		macAddresses, err := s.nodes.ListNodesInSubnet(ctx, a.Selector.Subnet)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, macAddresses)
+	}
+	if a.Selector.HardwareClass != "" {
+		macAddresses, err := s.nodes.ListNodesWithHardwareClass(ctx, a.Selector.HardwareClass)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, macAddresses)
+	}
+	if a.Selector.ImageTag != "" {
+		macAddresses, err := s.ipxe.ListNodeImageMacAddresses(ctx, ipxe.IpxeImageTagType{ImageTag: a.Selector.ImageTag, ImageType: a.Selector.ImageType})
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, macAddresses)
+	}
+	if a.Selector.PayloadId != "" {
+		macAddresses, err := s.payloads.ListNodePayloadMacAddresses(ctx, a.Selector.PayloadId)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, macAddresses)
+	}
+	return intersect(sets), nil
+}
+
+// intersect returns the sorted mac_addresses present in every set.
+func intersect(sets [][]string) []string {
+	counts := map[string]int{}
+	for _, set := range sets {
+		seen := map[string]bool{}
+		for _, macAddress := range set {
+			if !seen[macAddress] {
+				seen[macAddress] = true
+				counts[macAddress]++
+			}
+		}
+	}
+	var macAddresses []string
+	for macAddress, count := range counts {
+		if count == len(sets) {
+			macAddresses = append(macAddresses, macAddress)
+		}
+	}
+	sort.Strings(macAddresses)
+	return macAddresses
+}
+
+// assignNode applies a to macAddress with the transactions carried by ctx.
+func (s *Service) assignNode(ctx context.Context, a *Assignment, macAddress string) *NodeResult {
+	nr := &NodeResult{MacAddress: macAddress, Status: StatusUnchanged}
+	fail := func(err error) *NodeResult {
+		nr.Status = StatusFailed
+		nr.Error = err.Error()
+		return nr
+	}
+
+	if a.image() {
+		current, err := s.ipxe.GetNodeImage(ctx, macAddress)
+		if err != nil && !errdefs.IsNotFound(err) {
+			return fail(err)
+		}
+		config := &ipxe.IpxeNodeDbConfig{
+			ImageTag:     a.ImageTag,
+			ImageType:    a.ImageType,
+			ImageChannel: a.ImageChannel,
+			MacAddress:   macAddress,
+		}
+		switch {
+		case current == nil:
+			if err := s.ipxe.CreateNodeIpxeConfig(ctx, config); err != nil {
+				return fail(err)
+			}
+			nr.Status = StatusUpdated
+		case current.ImageTag != config.ImageTag || current.ImageType != config.ImageType || current.ImageChannel != config.ImageChannel,
+			current.Enrollment == enrollment.Following:
+			// following nodes become assigned so they keep the image
+			nr.PreviousImageTag = current.ImageTag
+			nr.PreviousImageType = current.ImageType
+			nr.PreviousImageChannel = current.ImageChannel
+			if _, err := s.ipxe.UpdateNodeImage(ctx, config); err != nil {
+				return fail(err)
+			}
+			nr.Status = StatusUpdated
+		}
+	}
+
+	if a.PayloadId != "" {
+		current, err := s.payloads.GetNodePayloads(ctx, macAddress)
+		if err != nil {
+			return fail(err)
+		}
+		npd := &payloads.NodePayloadDb{PayloadId: a.PayloadId, MacAddress: macAddress}
+		switch {
+		case len(current) == 0:
+			if _, err := s.payloads.AddNodePayload(ctx, npd); err != nil {
+				return fail(err)
+			}
+			nr.Status = StatusUpdated
+		case current[0].PayloadId != a.PayloadId, current[0].Enrollment == enrollment.Following:
+			nr.PreviousPayloadId = current[0].PayloadId
+			if _, err := s.payloads.UpdateNodePayload(ctx, npd); err != nil {
+				return fail(err)
+			}
+			nr.Status = StatusUpdated
+		}
+	}
+	return nr
+}
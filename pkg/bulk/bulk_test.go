@@ -0,0 +1,221 @@
+package bulk
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/ipxe"
+	"github.com/coreweave/ncore-api/pkg/nodes"
+	"github.com/coreweave/ncore-api/pkg/payloads"
+	"github.com/coreweave/ncore-api/pkg/s3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testDBs are the databases of a test Service, with the outcome of every transaction they ran.
+type testDBs struct {
+	ipxe      *MockIpxeDB
+	payloads  *MockPayloadsDB
+	nodes     *MockNodesDB
+	commits   []string
+	rollbacks []string
+}
+
+type txKey string
+
+// withTx runs fn in a transaction of the pool database, nested transactions run in the outer one like postgres.DB.WithTx.
+func (dbs *testDBs) withTx(pool string) func(ctx context.Context, _ database.TxOptions, fn func(ctx context.Context) error) error {
+	return func(ctx context.Context, _ database.TxOptions, fn func(ctx context.Context) error) error {
+		if ctx.Value(txKey(pool)) != nil {
+			return fn(ctx)
+		}
+		err := fn(context.WithValue(ctx, txKey(pool), true))
+		if err != nil {
+			dbs.rollbacks = append(dbs.rollbacks, pool)
+		} else {
+			dbs.commits = append(dbs.commits, pool)
+		}
+		return err
+	}
+}
+
+// newTestService returns a Service on mock databases, ipxe and payloads share one database when single is set.
+// Image (t, t) and payload p can be assigned, every mac_address resolves to itself.
+func newTestService(t *testing.T, single bool) (*Service, *testDBs) {
+	ctrl := gomock.NewController(t)
+	dbs := &testDBs{ipxe: NewMockIpxeDB(ctrl), payloads: NewMockPayloadsDB(ctrl), nodes: NewMockNodesDB(ctrl)}
+	ipxePool, payloadsPool := "ipxe", "payloads"
+	if single {
+		ipxePool, payloadsPool = "ncore", "ncore"
+	}
+	dbs.ipxe.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(dbs.withTx(ipxePool)).AnyTimes()
+	dbs.payloads.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(dbs.withTx(payloadsPool)).AnyTimes()
+	dbs.ipxe.EXPECT().GetAvailableImages(gomock.Any()).Return([]ipxe.IpxeImageTagType{{ImageTag: "t", ImageType: "t"}}).AnyTimes()
+	dbs.payloads.EXPECT().GetAvailablePayloads(gomock.Any()).Return([]string{"p"}).AnyTimes()
+	dbs.nodes.EXPECT().ResolveMacAddress(gomock.Any(), gomock.Any()).DoAndReturn(
+		func(_ context.Context, macAddress string) (string, error) {
+			return macAddress, nil
+		}).AnyTimes()
+
+	ipxeSvc := ipxe.NewService(dbs.ipxe, s3.NewMemoryStore("https://objects.test"), "", "default-image", "default-tag", "default-type", "default-bucket")
+	payloadsSvc := payloads.NewService(dbs.payloads, "default", "/payloads/default")
+	s := NewService(ipxeSvc, payloadsSvc, nodes.NewService(dbs.nodes))
+	s.SetSingleDatabase(single)
+	return s, dbs
+}
+
+// expectImage expects macAddress to be read with current and, unless nil, assigned (t, t) with update.
+func (dbs *testDBs) expectImage(macAddress string, current *ipxe.IpxeNodeDbConfig, update error) {
+	config := &ipxe.IpxeNodeDbConfig{ImageTag: "t", ImageType: "t", MacAddress: macAddress}
+	if current == nil {
+		dbs.ipxe.EXPECT().GetNodeImage(gomock.Any(), macAddress).Return(nil, errdefs.NotFound(ipxe.CodeNodeImageNotFound, "no image"))
+		dbs.ipxe.EXPECT().CreateNodeIpxeConfig(gomock.Any(), config).Return(update)
+		return
+	}
+	dbs.ipxe.EXPECT().GetNodeImage(gomock.Any(), macAddress).Return(current, nil)
+	dbs.ipxe.EXPECT().UpdateNodeImage(gomock.Any(), config).Return(config, update)
+}
+
+// expectPayload expects macAddress to be read with current and assigned p with write.
+func (dbs *testDBs) expectPayload(macAddress string, current *payloads.NodePayload, write error) {
+	npd := &payloads.NodePayloadDb{PayloadId: "p", MacAddress: macAddress}
+	if current == nil {
+		dbs.payloads.EXPECT().GetNodePayloads(gomock.Any(), macAddress).Return(nil, nil)
+		dbs.payloads.EXPECT().AddNodePayload(gomock.Any(), npd).Return(nil, write)
+		return
+	}
+	dbs.payloads.EXPECT().GetNodePayloads(gomock.Any(), macAddress).Return([]*payloads.NodePayload{current}, nil)
+	dbs.payloads.EXPECT().UpdateNodePayload(gomock.Any(), npd).Return(nil, write)
+}
+
+func TestIntersect(t *testing.T) {
+	sets := [][]string{
+		{"000000000003", "000000000001", "000000000002", "000000000001"},
+		{"000000000002", "000000000003", "000000000004"},
+	}
+	assert.Equal(t, []string{"000000000002", "000000000003"}, intersect(sets))
+	assert.Nil(t, intersect([][]string{{"000000000001"}, {}}))
+}
+
+func TestAssignValidation(t *testing.T) {
+	s := &Service{}
+	tests := []struct {
+		name string
+		a    *Assignment
+	}{
+		{"no nodes", &Assignment{PayloadId: "p"}},
+		{"empty selector", &Assignment{Selector: &Selector{}, PayloadId: "p"}},
+		{"nodes and selector", &Assignment{MacAddresses: []string{"000000000001"}, Selector: &Selector{Subnet: "10.0.0.0/24"}, PayloadId: "p"}},
+		{"selector image tag only", &Assignment{Selector: &Selector{ImageTag: "t"}, PayloadId: "p"}},
+		{"nothing to assign", &Assignment{MacAddresses: []string{"000000000001"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := s.Assign(context.Background(), tt.a, false)
+			assert.IsType(t, ValidationError{}, err)
+		})
+	}
+}
+
+func TestAssign(t *testing.T) {
+	s, dbs := newTestService(t, true)
+	dbs.expectImage("000000000001", nil, nil)
+	dbs.expectPayload("000000000001", nil, nil)
+	// a following node becomes assigned to the image and the payload it already has
+	dbs.expectImage("000000000002", &ipxe.IpxeNodeDbConfig{ImageTag: "t", ImageType: "t", MacAddress: "000000000002", Enrollment: enrollment.Following}, nil)
+	dbs.expectPayload("000000000002", &payloads.NodePayload{PayloadId: "p", MacAddress: "000000000002", Enrollment: enrollment.Following}, nil)
+	dbs.expectImage("000000000003", &ipxe.IpxeNodeDbConfig{ImageTag: "old", ImageType: "t", MacAddress: "000000000003"}, nil)
+	dbs.payloads.EXPECT().GetNodePayloads(gomock.Any(), "000000000003").Return([]*payloads.NodePayload{{PayloadId: "p", MacAddress: "000000000003"}}, nil)
+
+	result, err := s.Assign(context.Background(), &Assignment{
+		MacAddresses: []string{"00:00:00:00:00:03", "00:00:00:00:00:01", "00:00:00:00:00:02"},
+		ImageTag:     "t",
+		ImageType:    "t",
+		PayloadId:    "p",
+	}, false)
+
+	require.NoError(t, err)
+	assert.True(t, result.Committed)
+	require.Len(t, result.Steps, 1)
+	assert.Equal(t, StepImagePayload, result.Steps[0].Step)
+	assert.True(t, result.Steps[0].Committed)
+	assert.Equal(t, []*NodeResult{
+		{MacAddress: "000000000001", Status: StatusUpdated},
+		{MacAddress: "000000000002", Status: StatusUpdated, PreviousImageTag: "t", PreviousImageType: "t", PreviousPayloadId: "p"},
+		{MacAddress: "000000000003", Status: StatusUpdated, PreviousImageTag: "old", PreviousImageType: "t"},
+	}, result.Steps[0].Nodes)
+	assert.Equal(t, []string{"ncore"}, dbs.commits)
+	assert.Empty(t, dbs.rollbacks)
+}
+
+func TestAssignDryRun(t *testing.T) {
+	s, dbs := newTestService(t, false)
+	dbs.expectImage("000000000001", nil, nil)
+	dbs.expectPayload("000000000001", &payloads.NodePayload{PayloadId: "q", MacAddress: "000000000001"}, nil)
+
+	result, err := s.Assign(context.Background(), &Assignment{MacAddresses: []string{"000000000001"}, ImageTag: "t", ImageType: "t", PayloadId: "p"}, true)
+
+	require.NoError(t, err)
+	assert.True(t, result.DryRun)
+	assert.False(t, result.Committed)
+	require.Len(t, result.Steps, 2)
+	assert.Equal(t, StepImage, result.Steps[0].Step)
+	assert.False(t, result.Steps[0].Committed)
+	assert.Equal(t, []*NodeResult{{MacAddress: "000000000001", Status: StatusUpdated}}, result.Steps[0].Nodes)
+	assert.Equal(t, StepPayload, result.Steps[1].Step)
+	assert.False(t, result.Steps[1].Committed)
+	assert.Equal(t, []*NodeResult{{MacAddress: "000000000001", Status: StatusUpdated, PreviousPayloadId: "q"}}, result.Steps[1].Nodes)
+	assert.Empty(t, dbs.commits)
+	assert.Equal(t, []string{"ipxe", "payloads"}, dbs.rollbacks)
+}
+
+func TestAssignStopsAtFirstFailure(t *testing.T) {
+	s, dbs := newTestService(t, true)
+	dbs.expectImage("000000000001", nil, nil)
+	dbs.expectImage("000000000002", &ipxe.IpxeNodeDbConfig{ImageTag: "old", ImageType: "t", MacAddress: "000000000002"}, errors.New("update failed"))
+	// 000000000003 is never read
+
+	result, err := s.Assign(context.Background(), &Assignment{MacAddresses: []string{"000000000001", "000000000002", "000000000003"}, ImageTag: "t", ImageType: "t"}, false)
+
+	require.NoError(t, err)
+	assert.False(t, result.Committed)
+	require.Len(t, result.Steps, 1)
+	assert.Equal(t, StepImage, result.Steps[0].Step)
+	assert.False(t, result.Steps[0].Committed)
+	assert.Equal(t, "update failed", result.Steps[0].Error)
+	assert.Equal(t, []*NodeResult{
+		{MacAddress: "000000000001", Status: StatusUpdated},
+		{MacAddress: "000000000002", Status: StatusFailed, PreviousImageTag: "old", PreviousImageType: "t", Error: "update failed"},
+	}, result.Steps[0].Nodes)
+	assert.Empty(t, dbs.commits)
+	assert.Equal(t, []string{"ncore"}, dbs.rollbacks)
+}
+
+func TestAssignSteps(t *testing.T) {
+	s, dbs := newTestService(t, false)
+	dbs.expectImage("000000000001", nil, nil)
+	dbs.expectImage("000000000002", nil, nil)
+	dbs.expectPayload("000000000001", &payloads.NodePayload{PayloadId: "p", MacAddress: "000000000001", Enrollment: enrollment.Following}, errors.New("update failed"))
+
+	result, err := s.Assign(context.Background(), &Assignment{MacAddresses: []string{"000000000001", "000000000002"}, ImageTag: "t", ImageType: "t", PayloadId: "p"}, false)
+
+	// the image step committed before the payload step failed
+	require.NoError(t, err)
+	assert.False(t, result.Committed)
+	require.Len(t, result.Steps, 2)
+	assert.Equal(t, StepImage, result.Steps[0].Step)
+	assert.True(t, result.Steps[0].Committed)
+	assert.Len(t, result.Steps[0].Nodes, 2)
+	assert.Equal(t, StepPayload, result.Steps[1].Step)
+	assert.False(t, result.Steps[1].Committed)
+	assert.Equal(t, []*NodeResult{
+		{MacAddress: "000000000001", Status: StatusFailed, PreviousPayloadId: "p", Error: "update failed"},
+	}, result.Steps[1].Nodes)
+	assert.Equal(t, []string{"ipxe"}, dbs.commits)
+	assert.Equal(t, []string{"payloads"}, dbs.rollbacks)
+}
@@ -0,0 +1,497 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/coreweave/ncore-api/pkg/ipxe (interfaces: DB)
+
+// Package bulk is a generated GoMock package.
+package bulk
+
+import (
+	context "context"
+	reflect "reflect"
+
+	database "github.com/coreweave/ncore-api/pkg/database"
+	ipxe "github.com/coreweave/ncore-api/pkg/ipxe"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockIpxeDB is a mock of DB interface.
+type MockIpxeDB struct {
+	ctrl     *gomock.Controller
+	recorder *MockIpxeDBMockRecorder
+}
+
+// MockIpxeDBMockRecorder is the mock recorder for MockIpxeDB.
+type MockIpxeDBMockRecorder struct {
+	mock *MockIpxeDB
+}
+
+// NewMockIpxeDB creates a new mock instance.
+func NewMockIpxeDB(ctrl *gomock.Controller) *MockIpxeDB {
+	mock := &MockIpxeDB{ctrl: ctrl}
+	mock.recorder = &MockIpxeDBMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIpxeDB) EXPECT() *MockIpxeDBMockRecorder {
+	return m.recorder
+}
+
+// CreateImageRollout mocks base method.
+func (m *MockIpxeDB) CreateImageRollout(arg0 context.Context, arg1 *ipxe.ImageRollout, arg2 map[string]int) (*ipxe.ImageRollout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateImageRollout", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*ipxe.ImageRollout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateImageRollout indicates an expected call of CreateImageRollout.
+func (mr *MockIpxeDBMockRecorder) CreateImageRollout(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImageRollout", reflect.TypeOf((*MockIpxeDB)(nil).CreateImageRollout), arg0, arg1, arg2)
+}
+
+// CreateIpxeImage mocks base method.
+func (m *MockIpxeDB) CreateIpxeImage(arg0 context.Context, arg1 *ipxe.IpxeDbConfig) (*ipxe.IpxeConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIpxeImage", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.IpxeConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateIpxeImage indicates an expected call of CreateIpxeImage.
+func (mr *MockIpxeDBMockRecorder) CreateIpxeImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIpxeImage", reflect.TypeOf((*MockIpxeDB)(nil).CreateIpxeImage), arg0, arg1)
+}
+
+// CreateNodeIpxeConfig mocks base method.
+func (m *MockIpxeDB) CreateNodeIpxeConfig(arg0 context.Context, arg1 *ipxe.IpxeNodeDbConfig) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateNodeIpxeConfig", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateNodeIpxeConfig indicates an expected call of CreateNodeIpxeConfig.
+func (mr *MockIpxeDBMockRecorder) CreateNodeIpxeConfig(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNodeIpxeConfig", reflect.TypeOf((*MockIpxeDB)(nil).CreateNodeIpxeConfig), arg0, arg1)
+}
+
+// DeleteImageChannel mocks base method.
+func (m *MockIpxeDB) DeleteImageChannel(arg0 context.Context, arg1 string) (*ipxe.ImageChannel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteImageChannel", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.ImageChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteImageChannel indicates an expected call of DeleteImageChannel.
+func (mr *MockIpxeDBMockRecorder) DeleteImageChannel(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImageChannel", reflect.TypeOf((*MockIpxeDB)(nil).DeleteImageChannel), arg0, arg1)
+}
+
+// DeleteIpxeImage mocks base method.
+func (m *MockIpxeDB) DeleteIpxeImage(arg0 context.Context, arg1 *ipxe.IpxeImageDeleteConfig) (*ipxe.IpxeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIpxeImage", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.IpxeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteIpxeImage indicates an expected call of DeleteIpxeImage.
+func (mr *MockIpxeDBMockRecorder) DeleteIpxeImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIpxeImage", reflect.TypeOf((*MockIpxeDB)(nil).DeleteIpxeImage), arg0, arg1)
+}
+
+// DeleteSubnetDefaultImage mocks base method.
+func (m *MockIpxeDB) DeleteSubnetDefaultImage(arg0 context.Context, arg1 string) (*ipxe.SubnetDefaultImage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSubnetDefaultImage", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.SubnetDefaultImage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteSubnetDefaultImage indicates an expected call of DeleteSubnetDefaultImage.
+func (mr *MockIpxeDBMockRecorder) DeleteSubnetDefaultImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSubnetDefaultImage", reflect.TypeOf((*MockIpxeDB)(nil).DeleteSubnetDefaultImage), arg0, arg1)
+}
+
+// GetAvailableImages mocks base method.
+func (m *MockIpxeDB) GetAvailableImages(arg0 context.Context) []ipxe.IpxeImageTagType {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAvailableImages", arg0)
+	ret0, _ := ret[0].([]ipxe.IpxeImageTagType)
+	return ret0
+}
+
+// GetAvailableImages indicates an expected call of GetAvailableImages.
+func (mr *MockIpxeDBMockRecorder) GetAvailableImages(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAvailableImages", reflect.TypeOf((*MockIpxeDB)(nil).GetAvailableImages), arg0)
+}
+
+// GetImageChannel mocks base method.
+func (m *MockIpxeDB) GetImageChannel(arg0 context.Context, arg1 string) (*ipxe.ImageChannel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImageChannel", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.ImageChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImageChannel indicates an expected call of GetImageChannel.
+func (mr *MockIpxeDBMockRecorder) GetImageChannel(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageChannel", reflect.TypeOf((*MockIpxeDB)(nil).GetImageChannel), arg0, arg1)
+}
+
+// GetImageRollout mocks base method.
+func (m *MockIpxeDB) GetImageRollout(arg0 context.Context, arg1 int64) (*ipxe.ImageRollout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImageRollout", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.ImageRollout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImageRollout indicates an expected call of GetImageRollout.
+func (mr *MockIpxeDBMockRecorder) GetImageRollout(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageRollout", reflect.TypeOf((*MockIpxeDB)(nil).GetImageRollout), arg0, arg1)
+}
+
+// GetIpxeDbConfig mocks base method.
+func (m *MockIpxeDB) GetIpxeDbConfig(arg0 context.Context, arg1 string) (*ipxe.IpxeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIpxeDbConfig", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.IpxeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIpxeDbConfig indicates an expected call of GetIpxeDbConfig.
+func (mr *MockIpxeDBMockRecorder) GetIpxeDbConfig(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIpxeDbConfig", reflect.TypeOf((*MockIpxeDB)(nil).GetIpxeDbConfig), arg0, arg1)
+}
+
+// GetIpxeImageUsage mocks base method.
+func (m *MockIpxeDB) GetIpxeImageUsage(arg0 context.Context, arg1 *ipxe.IpxeImageTagType) (*ipxe.IpxeImageUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIpxeImageUsage", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.IpxeImageUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIpxeImageUsage indicates an expected call of GetIpxeImageUsage.
+func (mr *MockIpxeDBMockRecorder) GetIpxeImageUsage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIpxeImageUsage", reflect.TypeOf((*MockIpxeDB)(nil).GetIpxeImageUsage), arg0, arg1)
+}
+
+// GetNodeImage mocks base method.
+func (m *MockIpxeDB) GetNodeImage(arg0 context.Context, arg1 string) (*ipxe.IpxeNodeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNodeImage", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.IpxeNodeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNodeImage indicates an expected call of GetNodeImage.
+func (mr *MockIpxeDBMockRecorder) GetNodeImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeImage", reflect.TypeOf((*MockIpxeDB)(nil).GetNodeImage), arg0, arg1)
+}
+
+// GetSubnetDefaultIpxeDbConfig mocks base method.
+func (m *MockIpxeDB) GetSubnetDefaultIpxeDbConfig(arg0 context.Context, arg1 string) (*ipxe.IpxeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubnetDefaultIpxeDbConfig", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.IpxeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubnetDefaultIpxeDbConfig indicates an expected call of GetSubnetDefaultIpxeDbConfig.
+func (mr *MockIpxeDBMockRecorder) GetSubnetDefaultIpxeDbConfig(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubnetDefaultIpxeDbConfig", reflect.TypeOf((*MockIpxeDB)(nil).GetSubnetDefaultIpxeDbConfig), arg0, arg1)
+}
+
+// ListImageChannels mocks base method.
+func (m *MockIpxeDB) ListImageChannels(arg0 context.Context) ([]*ipxe.ImageChannel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImageChannels", arg0)
+	ret0, _ := ret[0].([]*ipxe.ImageChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImageChannels indicates an expected call of ListImageChannels.
+func (mr *MockIpxeDBMockRecorder) ListImageChannels(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImageChannels", reflect.TypeOf((*MockIpxeDB)(nil).ListImageChannels), arg0)
+}
+
+// ListImageRollouts mocks base method.
+func (m *MockIpxeDB) ListImageRollouts(arg0 context.Context, arg1 string) ([]*ipxe.ImageRollout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImageRollouts", arg0, arg1)
+	ret0, _ := ret[0].([]*ipxe.ImageRollout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImageRollouts indicates an expected call of ListImageRollouts.
+func (mr *MockIpxeDBMockRecorder) ListImageRollouts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImageRollouts", reflect.TypeOf((*MockIpxeDB)(nil).ListImageRollouts), arg0, arg1)
+}
+
+// ListIpxeImageUsage mocks base method.
+func (m *MockIpxeDB) ListIpxeImageUsage(arg0 context.Context, arg1 string) ([]*ipxe.IpxeImageUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListIpxeImageUsage", arg0, arg1)
+	ret0, _ := ret[0].([]*ipxe.IpxeImageUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListIpxeImageUsage indicates an expected call of ListIpxeImageUsage.
+func (mr *MockIpxeDBMockRecorder) ListIpxeImageUsage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIpxeImageUsage", reflect.TypeOf((*MockIpxeDB)(nil).ListIpxeImageUsage), arg0, arg1)
+}
+
+// ListIpxeImages mocks base method.
+func (m *MockIpxeDB) ListIpxeImages(arg0 context.Context) ([]*ipxe.IpxeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListIpxeImages", arg0)
+	ret0, _ := ret[0].([]*ipxe.IpxeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListIpxeImages indicates an expected call of ListIpxeImages.
+func (mr *MockIpxeDBMockRecorder) ListIpxeImages(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIpxeImages", reflect.TypeOf((*MockIpxeDB)(nil).ListIpxeImages), arg0)
+}
+
+// ListNodeImageMacAddresses mocks base method.
+func (m *MockIpxeDB) ListNodeImageMacAddresses(arg0 context.Context, arg1 *ipxe.IpxeImageTagType, arg2 []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNodeImageMacAddresses", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNodeImageMacAddresses indicates an expected call of ListNodeImageMacAddresses.
+func (mr *MockIpxeDBMockRecorder) ListNodeImageMacAddresses(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodeImageMacAddresses", reflect.TypeOf((*MockIpxeDB)(nil).ListNodeImageMacAddresses), arg0, arg1, arg2)
+}
+
+// ListNodeImages mocks base method.
+func (m *MockIpxeDB) ListNodeImages(arg0 context.Context) ([]*ipxe.IpxeNodeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNodeImages", arg0)
+	ret0, _ := ret[0].([]*ipxe.IpxeNodeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNodeImages indicates an expected call of ListNodeImages.
+func (mr *MockIpxeDBMockRecorder) ListNodeImages(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodeImages", reflect.TypeOf((*MockIpxeDB)(nil).ListNodeImages), arg0)
+}
+
+// ListSubnetDefaultImages mocks base method.
+func (m *MockIpxeDB) ListSubnetDefaultImages(arg0 context.Context) ([]*ipxe.SubnetDefaultImage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSubnetDefaultImages", arg0)
+	ret0, _ := ret[0].([]*ipxe.SubnetDefaultImage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSubnetDefaultImages indicates an expected call of ListSubnetDefaultImages.
+func (mr *MockIpxeDBMockRecorder) ListSubnetDefaultImages(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSubnetDefaultImages", reflect.TypeOf((*MockIpxeDB)(nil).ListSubnetDefaultImages), arg0)
+}
+
+// PromoteImageChannel mocks base method.
+func (m *MockIpxeDB) PromoteImageChannel(arg0 context.Context, arg1 *ipxe.ImageChannelPromoteConfig) (*ipxe.ImageChannel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PromoteImageChannel", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.ImageChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PromoteImageChannel indicates an expected call of PromoteImageChannel.
+func (mr *MockIpxeDBMockRecorder) PromoteImageChannel(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PromoteImageChannel", reflect.TypeOf((*MockIpxeDB)(nil).PromoteImageChannel), arg0, arg1)
+}
+
+// RollbackImageChannel mocks base method.
+func (m *MockIpxeDB) RollbackImageChannel(arg0 context.Context, arg1 string) (*ipxe.ImageChannel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RollbackImageChannel", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.ImageChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RollbackImageChannel indicates an expected call of RollbackImageChannel.
+func (mr *MockIpxeDBMockRecorder) RollbackImageChannel(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RollbackImageChannel", reflect.TypeOf((*MockIpxeDB)(nil).RollbackImageChannel), arg0, arg1)
+}
+
+// SetImageRolloutNodeStates mocks base method.
+func (m *MockIpxeDB) SetImageRolloutNodeStates(arg0 context.Context, arg1 int64, arg2 map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetImageRolloutNodeStates", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetImageRolloutNodeStates indicates an expected call of SetImageRolloutNodeStates.
+func (mr *MockIpxeDBMockRecorder) SetImageRolloutNodeStates(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetImageRolloutNodeStates", reflect.TypeOf((*MockIpxeDB)(nil).SetImageRolloutNodeStates), arg0, arg1, arg2)
+}
+
+// SetImageRolloutState mocks base method.
+func (m *MockIpxeDB) SetImageRolloutState(arg0 context.Context, arg1 int64, arg2 []string, arg3, arg4 string) (*ipxe.ImageRollout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetImageRolloutState", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(*ipxe.ImageRollout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetImageRolloutState indicates an expected call of SetImageRolloutState.
+func (mr *MockIpxeDBMockRecorder) SetImageRolloutState(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetImageRolloutState", reflect.TypeOf((*MockIpxeDB)(nil).SetImageRolloutState), arg0, arg1, arg2, arg3, arg4)
+}
+
+// SetIpxeImageState mocks base method.
+func (m *MockIpxeDB) SetIpxeImageState(arg0 context.Context, arg1 *ipxe.IpxeImageStateConfig) (*ipxe.IpxeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetIpxeImageState", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.IpxeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetIpxeImageState indicates an expected call of SetIpxeImageState.
+func (mr *MockIpxeDBMockRecorder) SetIpxeImageState(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIpxeImageState", reflect.TypeOf((*MockIpxeDB)(nil).SetIpxeImageState), arg0, arg1)
+}
+
+// SetSubnetDefaultImage mocks base method.
+func (m *MockIpxeDB) SetSubnetDefaultImage(arg0 context.Context, arg1 *ipxe.SubnetDefaultImage) (*ipxe.SubnetDefaultImage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSubnetDefaultImage", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.SubnetDefaultImage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetSubnetDefaultImage indicates an expected call of SetSubnetDefaultImage.
+func (mr *MockIpxeDBMockRecorder) SetSubnetDefaultImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSubnetDefaultImage", reflect.TypeOf((*MockIpxeDB)(nil).SetSubnetDefaultImage), arg0, arg1)
+}
+
+// StartImageRolloutWave mocks base method.
+func (m *MockIpxeDB) StartImageRolloutWave(arg0 context.Context, arg1 int64, arg2 string) ([]*ipxe.ImageRolloutNode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartImageRolloutWave", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*ipxe.ImageRolloutNode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartImageRolloutWave indicates an expected call of StartImageRolloutWave.
+func (mr *MockIpxeDBMockRecorder) StartImageRolloutWave(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartImageRolloutWave", reflect.TypeOf((*MockIpxeDB)(nil).StartImageRolloutWave), arg0, arg1, arg2)
+}
+
+// UpdateIpxeImage mocks base method.
+func (m *MockIpxeDB) UpdateIpxeImage(arg0 context.Context, arg1 *ipxe.IpxeDbConfig) (*ipxe.IpxeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateIpxeImage", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.IpxeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateIpxeImage indicates an expected call of UpdateIpxeImage.
+func (mr *MockIpxeDBMockRecorder) UpdateIpxeImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateIpxeImage", reflect.TypeOf((*MockIpxeDB)(nil).UpdateIpxeImage), arg0, arg1)
+}
+
+// UpdateNodeImage mocks base method.
+func (m *MockIpxeDB) UpdateNodeImage(arg0 context.Context, arg1 *ipxe.IpxeNodeDbConfig) (*ipxe.IpxeNodeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateNodeImage", arg0, arg1)
+	ret0, _ := ret[0].(*ipxe.IpxeNodeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateNodeImage indicates an expected call of UpdateNodeImage.
+func (mr *MockIpxeDBMockRecorder) UpdateNodeImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateNodeImage", reflect.TypeOf((*MockIpxeDB)(nil).UpdateNodeImage), arg0, arg1)
+}
+
+// WithAcquire mocks base method.
+func (m *MockIpxeDB) WithAcquire(arg0 context.Context, arg1 func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithAcquire", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithAcquire indicates an expected call of WithAcquire.
+func (mr *MockIpxeDBMockRecorder) WithAcquire(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithAcquire", reflect.TypeOf((*MockIpxeDB)(nil).WithAcquire), arg0, arg1)
+}
+
+// WithTx mocks base method.
+func (m *MockIpxeDB) WithTx(arg0 context.Context, arg1 database.TxOptions, arg2 func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockIpxeDBMockRecorder) WithTx(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockIpxeDB)(nil).WithTx), arg0, arg1, arg2)
+}
@@ -0,0 +1,531 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/coreweave/ncore-api/pkg/nodes (interfaces: DB)
+
+// Package bulk is a generated GoMock package.
+package bulk
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	database "github.com/coreweave/ncore-api/pkg/database"
+	nodes "github.com/coreweave/ncore-api/pkg/nodes"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockNodesDB is a mock of DB interface.
+type MockNodesDB struct {
+	ctrl     *gomock.Controller
+	recorder *MockNodesDBMockRecorder
+}
+
+// MockNodesDBMockRecorder is the mock recorder for MockNodesDB.
+type MockNodesDBMockRecorder struct {
+	mock *MockNodesDB
+}
+
+// NewMockNodesDB creates a new mock instance.
+func NewMockNodesDB(ctrl *gomock.Controller) *MockNodesDB {
+	mock := &MockNodesDB{ctrl: ctrl}
+	mock.recorder = &MockNodesDBMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockNodesDB) EXPECT() *MockNodesDBMockRecorder {
+	return m.recorder
+}
+
+// AttachInterface mocks base method.
+func (m *MockNodesDB) AttachInterface(arg0 context.Context, arg1 int64, arg2 *nodes.Interface) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AttachInterface", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AttachInterface indicates an expected call of AttachInterface.
+func (mr *MockNodesDBMockRecorder) AttachInterface(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AttachInterface", reflect.TypeOf((*MockNodesDB)(nil).AttachInterface), arg0, arg1, arg2)
+}
+
+// CountNodesLastSeen mocks base method.
+func (m *MockNodesDB) CountNodesLastSeen(arg0 context.Context, arg1 []time.Duration) ([]int, int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CountNodesLastSeen", arg0, arg1)
+	ret0, _ := ret[0].([]int)
+	ret1, _ := ret[1].(int)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// CountNodesLastSeen indicates an expected call of CountNodesLastSeen.
+func (mr *MockNodesDBMockRecorder) CountNodesLastSeen(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CountNodesLastSeen", reflect.TypeOf((*MockNodesDB)(nil).CountNodesLastSeen), arg0, arg1)
+}
+
+// CreateHostnamePolicy mocks base method.
+func (m *MockNodesDB) CreateHostnamePolicy(arg0 context.Context, arg1 *nodes.HostnamePolicy) (*nodes.HostnamePolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateHostnamePolicy", arg0, arg1)
+	ret0, _ := ret[0].(*nodes.HostnamePolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateHostnamePolicy indicates an expected call of CreateHostnamePolicy.
+func (mr *MockNodesDBMockRecorder) CreateHostnamePolicy(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateHostnamePolicy", reflect.TypeOf((*MockNodesDB)(nil).CreateHostnamePolicy), arg0, arg1)
+}
+
+// CreateNodeIdentity mocks base method.
+func (m *MockNodesDB) CreateNodeIdentity(arg0 context.Context, arg1 string) (*nodes.NodeIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateNodeIdentity", arg0, arg1)
+	ret0, _ := ret[0].(*nodes.NodeIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateNodeIdentity indicates an expected call of CreateNodeIdentity.
+func (mr *MockNodesDBMockRecorder) CreateNodeIdentity(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNodeIdentity", reflect.TypeOf((*MockNodesDB)(nil).CreateNodeIdentity), arg0, arg1)
+}
+
+// CreateRegistrationRule mocks base method.
+func (m *MockNodesDB) CreateRegistrationRule(arg0 context.Context, arg1 *nodes.RegistrationRule) (*nodes.RegistrationRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRegistrationRule", arg0, arg1)
+	ret0, _ := ret[0].(*nodes.RegistrationRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateRegistrationRule indicates an expected call of CreateRegistrationRule.
+func (mr *MockNodesDBMockRecorder) CreateRegistrationRule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRegistrationRule", reflect.TypeOf((*MockNodesDB)(nil).CreateRegistrationRule), arg0, arg1)
+}
+
+// DeleteHostnamePolicy mocks base method.
+func (m *MockNodesDB) DeleteHostnamePolicy(arg0 context.Context, arg1 int64) (*nodes.HostnamePolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteHostnamePolicy", arg0, arg1)
+	ret0, _ := ret[0].(*nodes.HostnamePolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteHostnamePolicy indicates an expected call of DeleteHostnamePolicy.
+func (mr *MockNodesDBMockRecorder) DeleteHostnamePolicy(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteHostnamePolicy", reflect.TypeOf((*MockNodesDB)(nil).DeleteHostnamePolicy), arg0, arg1)
+}
+
+// DeleteNodeHostname mocks base method.
+func (m *MockNodesDB) DeleteNodeHostname(arg0 context.Context, arg1 string) (*nodes.NodeHostname, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteNodeHostname", arg0, arg1)
+	ret0, _ := ret[0].(*nodes.NodeHostname)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteNodeHostname indicates an expected call of DeleteNodeHostname.
+func (mr *MockNodesDBMockRecorder) DeleteNodeHostname(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNodeHostname", reflect.TypeOf((*MockNodesDB)(nil).DeleteNodeHostname), arg0, arg1)
+}
+
+// DeleteRegistrationRule mocks base method.
+func (m *MockNodesDB) DeleteRegistrationRule(arg0 context.Context, arg1 int64) (*nodes.RegistrationRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRegistrationRule", arg0, arg1)
+	ret0, _ := ret[0].(*nodes.RegistrationRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteRegistrationRule indicates an expected call of DeleteRegistrationRule.
+func (mr *MockNodesDBMockRecorder) DeleteRegistrationRule(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRegistrationRule", reflect.TypeOf((*MockNodesDB)(nil).DeleteRegistrationRule), arg0, arg1)
+}
+
+// DetachInterface mocks base method.
+func (m *MockNodesDB) DetachInterface(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DetachInterface", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DetachInterface indicates an expected call of DetachInterface.
+func (mr *MockNodesDBMockRecorder) DetachInterface(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DetachInterface", reflect.TypeOf((*MockNodesDB)(nil).DetachInterface), arg0, arg1)
+}
+
+// GetNodeHostname mocks base method.
+func (m *MockNodesDB) GetNodeHostname(arg0 context.Context, arg1 string) (*nodes.NodeHostname, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNodeHostname", arg0, arg1)
+	ret0, _ := ret[0].(*nodes.NodeHostname)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNodeHostname indicates an expected call of GetNodeHostname.
+func (mr *MockNodesDBMockRecorder) GetNodeHostname(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeHostname", reflect.TypeOf((*MockNodesDB)(nil).GetNodeHostname), arg0, arg1)
+}
+
+// GetNodeIdentity mocks base method.
+func (m *MockNodesDB) GetNodeIdentity(arg0 context.Context, arg1 string) (*nodes.NodeIdentity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNodeIdentity", arg0, arg1)
+	ret0, _ := ret[0].(*nodes.NodeIdentity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNodeIdentity indicates an expected call of GetNodeIdentity.
+func (mr *MockNodesDBMockRecorder) GetNodeIdentity(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeIdentity", reflect.TypeOf((*MockNodesDB)(nil).GetNodeIdentity), arg0, arg1)
+}
+
+// GetNodeInventory mocks base method.
+func (m *MockNodesDB) GetNodeInventory(arg0 context.Context, arg1 string) (*nodes.NodeInventory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNodeInventory", arg0, arg1)
+	ret0, _ := ret[0].(*nodes.NodeInventory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNodeInventory indicates an expected call of GetNodeInventory.
+func (mr *MockNodesDBMockRecorder) GetNodeInventory(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeInventory", reflect.TypeOf((*MockNodesDB)(nil).GetNodeInventory), arg0, arg1)
+}
+
+// GetNodesLastSeen mocks base method.
+func (m *MockNodesDB) GetNodesLastSeen(arg0 context.Context, arg1 []string) (map[string]time.Time, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNodesLastSeen", arg0, arg1)
+	ret0, _ := ret[0].(map[string]time.Time)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNodesLastSeen indicates an expected call of GetNodesLastSeen.
+func (mr *MockNodesDBMockRecorder) GetNodesLastSeen(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodesLastSeen", reflect.TypeOf((*MockNodesDB)(nil).GetNodesLastSeen), arg0, arg1)
+}
+
+// GetRegistration mocks base method.
+func (m *MockNodesDB) GetRegistration(arg0 context.Context, arg1 string) (*nodes.Registration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetRegistration", arg0, arg1)
+	ret0, _ := ret[0].(*nodes.Registration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetRegistration indicates an expected call of GetRegistration.
+func (mr *MockNodesDBMockRecorder) GetRegistration(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetRegistration", reflect.TypeOf((*MockNodesDB)(nil).GetRegistration), arg0, arg1)
+}
+
+// IncrementHostnamePolicyCounter mocks base method.
+func (m *MockNodesDB) IncrementHostnamePolicyCounter(arg0 context.Context, arg1 int64) (int64, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementHostnamePolicyCounter", arg0, arg1)
+	ret0, _ := ret[0].(int64)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementHostnamePolicyCounter indicates an expected call of IncrementHostnamePolicyCounter.
+func (mr *MockNodesDBMockRecorder) IncrementHostnamePolicyCounter(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementHostnamePolicyCounter", reflect.TypeOf((*MockNodesDB)(nil).IncrementHostnamePolicyCounter), arg0, arg1)
+}
+
+// ListHostnamePolicies mocks base method.
+func (m *MockNodesDB) ListHostnamePolicies(arg0 context.Context) ([]*nodes.HostnamePolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListHostnamePolicies", arg0)
+	ret0, _ := ret[0].([]*nodes.HostnamePolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListHostnamePolicies indicates an expected call of ListHostnamePolicies.
+func (mr *MockNodesDBMockRecorder) ListHostnamePolicies(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListHostnamePolicies", reflect.TypeOf((*MockNodesDB)(nil).ListHostnamePolicies), arg0)
+}
+
+// ListInventoryChanges mocks base method.
+func (m *MockNodesDB) ListInventoryChanges(arg0 context.Context, arg1 string) ([]*nodes.InventoryChange, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListInventoryChanges", arg0, arg1)
+	ret0, _ := ret[0].([]*nodes.InventoryChange)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListInventoryChanges indicates an expected call of ListInventoryChanges.
+func (mr *MockNodesDBMockRecorder) ListInventoryChanges(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListInventoryChanges", reflect.TypeOf((*MockNodesDB)(nil).ListInventoryChanges), arg0, arg1)
+}
+
+// ListNodeHeartbeatViews mocks base method.
+func (m *MockNodesDB) ListNodeHeartbeatViews(arg0 context.Context, arg1 string) ([]*nodes.NodeView, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNodeHeartbeatViews", arg0, arg1)
+	ret0, _ := ret[0].([]*nodes.NodeView)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNodeHeartbeatViews indicates an expected call of ListNodeHeartbeatViews.
+func (mr *MockNodesDBMockRecorder) ListNodeHeartbeatViews(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodeHeartbeatViews", reflect.TypeOf((*MockNodesDB)(nil).ListNodeHeartbeatViews), arg0, arg1)
+}
+
+// ListNodeInventories mocks base method.
+func (m *MockNodesDB) ListNodeInventories(arg0 context.Context, arg1 string) ([]*nodes.NodeInventory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNodeInventories", arg0, arg1)
+	ret0, _ := ret[0].([]*nodes.NodeInventory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNodeInventories indicates an expected call of ListNodeInventories.
+func (mr *MockNodesDBMockRecorder) ListNodeInventories(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodeInventories", reflect.TypeOf((*MockNodesDB)(nil).ListNodeInventories), arg0, arg1)
+}
+
+// ListNodeViews mocks base method.
+func (m *MockNodesDB) ListNodeViews(arg0 context.Context, arg1 string) ([]*nodes.NodeView, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNodeViews", arg0, arg1)
+	ret0, _ := ret[0].([]*nodes.NodeView)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNodeViews indicates an expected call of ListNodeViews.
+func (mr *MockNodesDBMockRecorder) ListNodeViews(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodeViews", reflect.TypeOf((*MockNodesDB)(nil).ListNodeViews), arg0, arg1)
+}
+
+// ListNodesInSubnet mocks base method.
+func (m *MockNodesDB) ListNodesInSubnet(arg0 context.Context, arg1 string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNodesInSubnet", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNodesInSubnet indicates an expected call of ListNodesInSubnet.
+func (mr *MockNodesDBMockRecorder) ListNodesInSubnet(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodesInSubnet", reflect.TypeOf((*MockNodesDB)(nil).ListNodesInSubnet), arg0, arg1)
+}
+
+// ListRegistrationRules mocks base method.
+func (m *MockNodesDB) ListRegistrationRules(arg0 context.Context) ([]*nodes.RegistrationRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRegistrationRules", arg0)
+	ret0, _ := ret[0].([]*nodes.RegistrationRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRegistrationRules indicates an expected call of ListRegistrationRules.
+func (mr *MockNodesDBMockRecorder) ListRegistrationRules(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRegistrationRules", reflect.TypeOf((*MockNodesDB)(nil).ListRegistrationRules), arg0)
+}
+
+// ListRegistrations mocks base method.
+func (m *MockNodesDB) ListRegistrations(arg0 context.Context, arg1 string) ([]*nodes.Registration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListRegistrations", arg0, arg1)
+	ret0, _ := ret[0].([]*nodes.Registration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListRegistrations indicates an expected call of ListRegistrations.
+func (mr *MockNodesDBMockRecorder) ListRegistrations(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListRegistrations", reflect.TypeOf((*MockNodesDB)(nil).ListRegistrations), arg0, arg1)
+}
+
+// LookupHostname mocks base method.
+func (m *MockNodesDB) LookupHostname(arg0 context.Context, arg1 string) (*nodes.NodeHostname, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LookupHostname", arg0, arg1)
+	ret0, _ := ret[0].(*nodes.NodeHostname)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// LookupHostname indicates an expected call of LookupHostname.
+func (mr *MockNodesDBMockRecorder) LookupHostname(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LookupHostname", reflect.TypeOf((*MockNodesDB)(nil).LookupHostname), arg0, arg1)
+}
+
+// MatchHostnamePolicy mocks base method.
+func (m *MockNodesDB) MatchHostnamePolicy(arg0 context.Context, arg1 string) (*nodes.HostnamePolicy, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MatchHostnamePolicy", arg0, arg1)
+	ret0, _ := ret[0].(*nodes.HostnamePolicy)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MatchHostnamePolicy indicates an expected call of MatchHostnamePolicy.
+func (mr *MockNodesDBMockRecorder) MatchHostnamePolicy(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MatchHostnamePolicy", reflect.TypeOf((*MockNodesDB)(nil).MatchHostnamePolicy), arg0, arg1)
+}
+
+// MatchRegistrationRule mocks base method.
+func (m *MockNodesDB) MatchRegistrationRule(arg0 context.Context, arg1, arg2 string) (*nodes.RegistrationRule, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MatchRegistrationRule", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*nodes.RegistrationRule)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// MatchRegistrationRule indicates an expected call of MatchRegistrationRule.
+func (mr *MockNodesDBMockRecorder) MatchRegistrationRule(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MatchRegistrationRule", reflect.TypeOf((*MockNodesDB)(nil).MatchRegistrationRule), arg0, arg1, arg2)
+}
+
+// RegisterNode mocks base method.
+func (m *MockNodesDB) RegisterNode(arg0 context.Context, arg1 *nodes.Registration) (*nodes.Registration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RegisterNode", arg0, arg1)
+	ret0, _ := ret[0].(*nodes.Registration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RegisterNode indicates an expected call of RegisterNode.
+func (mr *MockNodesDBMockRecorder) RegisterNode(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RegisterNode", reflect.TypeOf((*MockNodesDB)(nil).RegisterNode), arg0, arg1)
+}
+
+// ResolveMacAddress mocks base method.
+func (m *MockNodesDB) ResolveMacAddress(arg0 context.Context, arg1 string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ResolveMacAddress", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ResolveMacAddress indicates an expected call of ResolveMacAddress.
+func (mr *MockNodesDBMockRecorder) ResolveMacAddress(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ResolveMacAddress", reflect.TypeOf((*MockNodesDB)(nil).ResolveMacAddress), arg0, arg1)
+}
+
+// SetNodeHostname mocks base method.
+func (m *MockNodesDB) SetNodeHostname(arg0 context.Context, arg1 *nodes.NodeHostname) (*nodes.NodeHostname, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetNodeHostname", arg0, arg1)
+	ret0, _ := ret[0].(*nodes.NodeHostname)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetNodeHostname indicates an expected call of SetNodeHostname.
+func (mr *MockNodesDBMockRecorder) SetNodeHostname(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNodeHostname", reflect.TypeOf((*MockNodesDB)(nil).SetNodeHostname), arg0, arg1)
+}
+
+// SetNodeInventory mocks base method.
+func (m *MockNodesDB) SetNodeInventory(arg0 context.Context, arg1 *nodes.NodeInventory, arg2 *nodes.Inventory, arg3 []string) (*nodes.NodeInventory, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetNodeInventory", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*nodes.NodeInventory)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetNodeInventory indicates an expected call of SetNodeInventory.
+func (mr *MockNodesDBMockRecorder) SetNodeInventory(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetNodeInventory", reflect.TypeOf((*MockNodesDB)(nil).SetNodeInventory), arg0, arg1, arg2, arg3)
+}
+
+// SetRegistrationState mocks base method.
+func (m *MockNodesDB) SetRegistrationState(arg0 context.Context, arg1, arg2 string, arg3 *int64) (*nodes.Registration, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetRegistrationState", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*nodes.Registration)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetRegistrationState indicates an expected call of SetRegistrationState.
+func (mr *MockNodesDBMockRecorder) SetRegistrationState(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetRegistrationState", reflect.TypeOf((*MockNodesDB)(nil).SetRegistrationState), arg0, arg1, arg2, arg3)
+}
+
+// UpdateNodeStats mocks base method.
+func (m *MockNodesDB) UpdateNodeStats(arg0 context.Context, arg1 *nodes.Node) (*nodes.Node, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateNodeStats", arg0, arg1)
+	ret0, _ := ret[0].(*nodes.Node)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateNodeStats indicates an expected call of UpdateNodeStats.
+func (mr *MockNodesDBMockRecorder) UpdateNodeStats(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateNodeStats", reflect.TypeOf((*MockNodesDB)(nil).UpdateNodeStats), arg0, arg1)
+}
+
+// WithTx mocks base method.
+func (m *MockNodesDB) WithTx(arg0 context.Context, arg1 database.TxOptions, arg2 func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockNodesDBMockRecorder) WithTx(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockNodesDB)(nil).WithTx), arg0, arg1, arg2)
+}
@@ -0,0 +1,200 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/coreweave/ncore-api/pkg/payloads (interfaces: DB)
+
+// Package bulk is a generated GoMock package.
+package bulk
+
+import (
+	context "context"
+	reflect "reflect"
+
+	database "github.com/coreweave/ncore-api/pkg/database"
+	payloads "github.com/coreweave/ncore-api/pkg/payloads"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockPayloadsDB is a mock of DB interface.
+type MockPayloadsDB struct {
+	ctrl     *gomock.Controller
+	recorder *MockPayloadsDBMockRecorder
+}
+
+// MockPayloadsDBMockRecorder is the mock recorder for MockPayloadsDB.
+type MockPayloadsDBMockRecorder struct {
+	mock *MockPayloadsDB
+}
+
+// NewMockPayloadsDB creates a new mock instance.
+func NewMockPayloadsDB(ctrl *gomock.Controller) *MockPayloadsDB {
+	mock := &MockPayloadsDB{ctrl: ctrl}
+	mock.recorder = &MockPayloadsDBMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPayloadsDB) EXPECT() *MockPayloadsDBMockRecorder {
+	return m.recorder
+}
+
+// AddNodePayload mocks base method.
+func (m *MockPayloadsDB) AddNodePayload(arg0 context.Context, arg1 *payloads.NodePayloadDb) ([]*payloads.NodePayload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddNodePayload", arg0, arg1)
+	ret0, _ := ret[0].([]*payloads.NodePayload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AddNodePayload indicates an expected call of AddNodePayload.
+func (mr *MockPayloadsDBMockRecorder) AddNodePayload(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNodePayload", reflect.TypeOf((*MockPayloadsDB)(nil).AddNodePayload), arg0, arg1)
+}
+
+// DeleteNodePayload mocks base method.
+func (m *MockPayloadsDB) DeleteNodePayload(arg0 context.Context, arg1 *payloads.NodePayloadDb) ([]*payloads.NodePayload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteNodePayload", arg0, arg1)
+	ret0, _ := ret[0].([]*payloads.NodePayload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteNodePayload indicates an expected call of DeleteNodePayload.
+func (mr *MockPayloadsDBMockRecorder) DeleteNodePayload(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNodePayload", reflect.TypeOf((*MockPayloadsDB)(nil).DeleteNodePayload), arg0, arg1)
+}
+
+// GetAvailablePayloads mocks base method.
+func (m *MockPayloadsDB) GetAvailablePayloads(arg0 context.Context) []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAvailablePayloads", arg0)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// GetAvailablePayloads indicates an expected call of GetAvailablePayloads.
+func (mr *MockPayloadsDBMockRecorder) GetAvailablePayloads(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAvailablePayloads", reflect.TypeOf((*MockPayloadsDB)(nil).GetAvailablePayloads), arg0)
+}
+
+// GetNodePayloads mocks base method.
+func (m *MockPayloadsDB) GetNodePayloads(arg0 context.Context, arg1 string) ([]*payloads.NodePayload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNodePayloads", arg0, arg1)
+	ret0, _ := ret[0].([]*payloads.NodePayload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNodePayloads indicates an expected call of GetNodePayloads.
+func (mr *MockPayloadsDBMockRecorder) GetNodePayloads(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodePayloads", reflect.TypeOf((*MockPayloadsDB)(nil).GetNodePayloads), arg0, arg1)
+}
+
+// GetPayloadParameters mocks base method.
+func (m *MockPayloadsDB) GetPayloadParameters(arg0 context.Context, arg1 string) (interface{}, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetPayloadParameters", arg0, arg1)
+	ret0, _ := ret[0].(interface{})
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetPayloadParameters indicates an expected call of GetPayloadParameters.
+func (mr *MockPayloadsDBMockRecorder) GetPayloadParameters(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPayloadParameters", reflect.TypeOf((*MockPayloadsDB)(nil).GetPayloadParameters), arg0, arg1)
+}
+
+// GetSubnetDefaultPayload mocks base method.
+func (m *MockPayloadsDB) GetSubnetDefaultPayload(arg0 context.Context, arg1 string) (*payloads.Payload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubnetDefaultPayload", arg0, arg1)
+	ret0, _ := ret[0].(*payloads.Payload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubnetDefaultPayload indicates an expected call of GetSubnetDefaultPayload.
+func (mr *MockPayloadsDBMockRecorder) GetSubnetDefaultPayload(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubnetDefaultPayload", reflect.TypeOf((*MockPayloadsDB)(nil).GetSubnetDefaultPayload), arg0, arg1)
+}
+
+// ListNodePayloadMacAddresses mocks base method.
+func (m *MockPayloadsDB) ListNodePayloadMacAddresses(arg0 context.Context, arg1 string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNodePayloadMacAddresses", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNodePayloadMacAddresses indicates an expected call of ListNodePayloadMacAddresses.
+func (mr *MockPayloadsDBMockRecorder) ListNodePayloadMacAddresses(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodePayloadMacAddresses", reflect.TypeOf((*MockPayloadsDB)(nil).ListNodePayloadMacAddresses), arg0, arg1)
+}
+
+// ListNodePayloads mocks base method.
+func (m *MockPayloadsDB) ListNodePayloads(arg0 context.Context) ([]*payloads.NodePayload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNodePayloads", arg0)
+	ret0, _ := ret[0].([]*payloads.NodePayload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNodePayloads indicates an expected call of ListNodePayloads.
+func (mr *MockPayloadsDBMockRecorder) ListNodePayloads(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodePayloads", reflect.TypeOf((*MockPayloadsDB)(nil).ListNodePayloads), arg0)
+}
+
+// ListSubnetDefaultPayloads mocks base method.
+func (m *MockPayloadsDB) ListSubnetDefaultPayloads(arg0 context.Context) ([]*payloads.SubnetDefaultPayload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSubnetDefaultPayloads", arg0)
+	ret0, _ := ret[0].([]*payloads.SubnetDefaultPayload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSubnetDefaultPayloads indicates an expected call of ListSubnetDefaultPayloads.
+func (mr *MockPayloadsDBMockRecorder) ListSubnetDefaultPayloads(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSubnetDefaultPayloads", reflect.TypeOf((*MockPayloadsDB)(nil).ListSubnetDefaultPayloads), arg0)
+}
+
+// UpdateNodePayload mocks base method.
+func (m *MockPayloadsDB) UpdateNodePayload(arg0 context.Context, arg1 *payloads.NodePayloadDb) ([]*payloads.NodePayload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateNodePayload", arg0, arg1)
+	ret0, _ := ret[0].([]*payloads.NodePayload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateNodePayload indicates an expected call of UpdateNodePayload.
+func (mr *MockPayloadsDBMockRecorder) UpdateNodePayload(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateNodePayload", reflect.TypeOf((*MockPayloadsDB)(nil).UpdateNodePayload), arg0, arg1)
+}
+
+// WithTx mocks base method.
+func (m *MockPayloadsDB) WithTx(arg0 context.Context, arg1 database.TxOptions, arg2 func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockPayloadsDBMockRecorder) WithTx(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockPayloadsDB)(nil).WithTx), arg0, arg1, arg2)
+}
@@ -0,0 +1,1044 @@
+// Code generated by clientgen from pkg/api/openapi.json. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// BulkAssignment is the image, payload or both assigned to MacAddresses or to the nodes matching Selector.
+type BulkAssignment struct {
+	MacAddresses []string      `json:"MacAddresses,omitempty"`
+	Selector     *BulkSelector `json:"Selector,omitempty"`
+	ImageTag     string        `json:"ImageTag,omitempty"`
+	ImageType    string        `json:"ImageType,omitempty"`
+	ImageChannel string        `json:"ImageChannel,omitempty"`
+	PayloadId    string        `json:"PayloadId,omitempty"`
+}
+
+// BulkNodeResult is the BulkNodeResult schema of the API.
+type BulkNodeResult struct {
+	MacAddress           string `json:"MacAddress"`
+	Status               string `json:"Status"`
+	PreviousImageTag     string `json:"PreviousImageTag,omitempty"`
+	PreviousImageType    string `json:"PreviousImageType,omitempty"`
+	PreviousImageChannel string `json:"PreviousImageChannel,omitempty"`
+	PreviousPayloadId    string `json:"PreviousPayloadId,omitempty"`
+	Error                string `json:"Error,omitempty"`
+}
+
+// BulkResult is the BulkResult schema of the API.
+type BulkResult struct {
+	DryRun bool `json:"DryRun"`
+	// Committed is set when every step committed.
+	Committed bool              `json:"Committed"`
+	Steps     []*BulkStepResult `json:"Steps"`
+}
+
+// BulkSelector is the BulkSelector schema of the API.
+type BulkSelector struct {
+	// Subnet matches any of the ip_addresses of the last node heartbeat, IPv4 or IPv6.
+	Subnet string `json:"Subnet,omitempty"`
+	// HardwareClass matches the last inventory reported by the node.
+	HardwareClass string `json:"HardwareClass,omitempty"`
+	ImageTag      string `json:"ImageTag,omitempty"`
+	ImageType     string `json:"ImageType,omitempty"`
+	PayloadId     string `json:"PayloadId,omitempty"`
+}
+
+// BulkStepResult is one transaction of a bulk assignment, the image and the payload are two steps without the single database mode.
+type BulkStepResult struct {
+	Step      string            `json:"Step"`
+	Committed bool              `json:"Committed"`
+	Nodes     []*BulkNodeResult `json:"Nodes"`
+	Error     string            `json:"Error,omitempty"`
+}
+
+// HealthCheckResult is the HealthCheckResult schema of the API.
+type HealthCheckResult struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	Critical  bool    `json:"critical"`
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+	Fallback  bool    `json:"fallback,omitempty"`
+}
+
+// HealthReport is unavailable when a critical dependency is, degraded when another one is or a check was slow.
+type HealthReport struct {
+	Status string               `json:"status"`
+	Checks []*HealthCheckResult `json:"checks"`
+}
+
+// HostnamePolicy is the HostnamePolicy schema of the API.
+type HostnamePolicy struct {
+	PolicyId int64 `json:"policy_id,omitempty"`
+	// Nodes first booting from this subnet, any subnet when empty.
+	Subnet string `json:"subnet,omitempty"`
+	// text/template executed with .MacAddress, .MacSuffix, .Rack, .Slot and .Counter, e.g. gpu-{{.Rack}}-{{.Slot}}.
+	Template  string     `json:"template"`
+	Counter   int64      `json:"counter,omitempty"`
+	CreatedAt *time.Time `json:"created_at,omitempty"`
+}
+
+// ImageChannel is the ImageChannel schema of the API.
+type ImageChannel struct {
+	Channel           string `json:"Channel"`
+	ImageTag          string `json:"ImageTag"`
+	ImageType         string `json:"ImageType"`
+	PreviousImageTag  string `json:"PreviousImageTag"`
+	PreviousImageType string `json:"PreviousImageType"`
+}
+
+// ImageChannelPromoteConfig is the ImageChannelPromoteConfig schema of the API.
+type ImageChannelPromoteConfig struct {
+	Channel   string `json:"Channel,omitempty"`
+	ImageTag  string `json:"ImageTag,omitempty"`
+	ImageType string `json:"ImageType,omitempty"`
+}
+
+// ImageRollout is the ImageRollout schema of the API.
+type ImageRollout struct {
+	RolloutId             int64               `json:"RolloutId"`
+	SourceImageTag        string              `json:"SourceImageTag,omitempty"`
+	SourceImageType       string              `json:"SourceImageType,omitempty"`
+	ImageTag              string              `json:"ImageTag"`
+	ImageType             string              `json:"ImageType"`
+	Waves                 []int               `json:"Waves"`
+	CurrentWave           int                 `json:"CurrentWave"`
+	RolloutState          string              `json:"RolloutState"`
+	HeartbeatDeadlineSecs int64               `json:"HeartbeatDeadlineSecs"`
+	MaxFailures           int                 `json:"MaxFailures"`
+	HaltReason            string              `json:"HaltReason,omitempty"`
+	WaveStartedAt         *time.Time          `json:"WaveStartedAt,omitempty"`
+	Nodes                 []*ImageRolloutNode `json:"Nodes,omitempty"`
+}
+
+// ImageRolloutConfig is the ImageRolloutConfig schema of the API.
+type ImageRolloutConfig struct {
+	SourceImageTag  string   `json:"SourceImageTag,omitempty"`
+	SourceImageType string   `json:"SourceImageType,omitempty"`
+	MacAddresses    []string `json:"MacAddresses,omitempty"`
+	ImageTag        string   `json:"ImageTag,omitempty"`
+	ImageType       string   `json:"ImageType,omitempty"`
+	// Waves are cumulative percentages of the rollout nodes, the last wave must be 100.
+	Waves                 []int `json:"Waves,omitempty"`
+	HeartbeatDeadlineSecs int64 `json:"HeartbeatDeadlineSecs,omitempty"`
+	MaxFailures           int   `json:"MaxFailures,omitempty"`
+}
+
+// ImageRolloutNode is the ImageRolloutNode schema of the API.
+type ImageRolloutNode struct {
+	MacAddress           string     `json:"MacAddress"`
+	Wave                 int        `json:"Wave"`
+	NodeState            string     `json:"NodeState"`
+	UpdatedAt            *time.Time `json:"UpdatedAt,omitempty"`
+	PreviousImageTag     string     `json:"PreviousImageTag,omitempty"`
+	PreviousImageType    string     `json:"PreviousImageType,omitempty"`
+	PreviousImageChannel string     `json:"PreviousImageChannel,omitempty"`
+	// Enrollment restored by rollback with the previous image.
+	PreviousEnrollment string `json:"PreviousEnrollment,omitempty"`
+}
+
+// Inventory is the hardware report of a node.
+type Inventory struct {
+	Serial       string                  `json:"serial,omitempty"`
+	Vendor       string                  `json:"vendor,omitempty"`
+	Model        string                  `json:"model,omitempty"`
+	BiosVersion  string                  `json:"bios_version,omitempty"`
+	Cpu          *InventoryCpu           `json:"cpu,omitempty"`
+	MemoryBytes  int64                   `json:"memory_bytes,omitempty"`
+	Nics         []*InventoryNic         `json:"nics,omitempty"`
+	Disks        []*InventoryDisk        `json:"disks,omitempty"`
+	Accelerators []*InventoryAccelerator `json:"accelerators,omitempty"`
+}
+
+// InventoryAccelerator is the InventoryAccelerator schema of the API.
+type InventoryAccelerator struct {
+	Vendor      string `json:"vendor,omitempty"`
+	Model       string `json:"model"`
+	Serial      string `json:"serial,omitempty"`
+	PciAddress  string `json:"pci_address,omitempty"`
+	MemoryBytes int64  `json:"memory_bytes,omitempty"`
+}
+
+// InventoryChange is the InventoryChange schema of the API.
+type InventoryChange struct {
+	ChangeId      int64      `json:"change_id"`
+	MacAddress    string     `json:"mac_address"`
+	ChangedAt     *time.Time `json:"changed_at"`
+	ChangedFields []string   `json:"changed_fields"`
+	Previous      *Inventory `json:"previous,omitempty"`
+	Inventory     *Inventory `json:"inventory"`
+}
+
+// InventoryCpu is the InventoryCpu schema of the API.
+type InventoryCpu struct {
+	Model   string `json:"model,omitempty"`
+	Sockets int    `json:"sockets,omitempty"`
+	Cores   int    `json:"cores,omitempty"`
+	Threads int    `json:"threads,omitempty"`
+}
+
+// InventoryDisk is the InventoryDisk schema of the API.
+type InventoryDisk struct {
+	Name      string `json:"name"`
+	Model     string `json:"model,omitempty"`
+	Serial    string `json:"serial,omitempty"`
+	SizeBytes int64  `json:"size_bytes,omitempty"`
+	Type      string `json:"type,omitempty"`
+}
+
+// InventoryNic is the InventoryNic schema of the API.
+type InventoryNic struct {
+	Name       string `json:"name"`
+	MacAddress string `json:"mac_address"`
+	SpeedMbps  int64  `json:"speed_mbps,omitempty"`
+}
+
+// IpxeConfig is the IpxeConfig schema of the API.
+type IpxeConfig struct {
+	ImageName           string `json:"ImageName"`
+	ImageBucket         string `json:"ImageBucket"`
+	ImageTag            string `json:"ImageTag"`
+	ImageType           string `json:"ImageType"`
+	ImageInitrdUrlHttp  string `json:"ImageInitrdUrlHttp"`
+	ImageInitrdUrlHttps string `json:"ImageInitrdUrlHttps"`
+	ImageKernelUrlHttp  string `json:"ImageKernelUrlHttp"`
+	ImageKernelUrlHttps string `json:"ImageKernelUrlHttps"`
+	ImageRootFsUrlHttp  string `json:"ImageRootFsUrlHttp"`
+	ImageRootFsUrlHttps string `json:"ImageRootFsUrlHttps"`
+	ImageCmdline        string `json:"ImageCmdline"`
+	ImageState          string `json:"ImageState"`
+	ImageWarning        string `json:"ImageWarning,omitempty"`
+	ImageChannel        string `json:"ImageChannel,omitempty"`
+	Hostname            string `json:"Hostname"`
+	// How the node got its assignment: pinned, following or assigned.
+	Enrollment string `json:"Enrollment,omitempty"`
+}
+
+// IpxeDbConfig is the IpxeDbConfig schema of the API.
+type IpxeDbConfig struct {
+	ImageName    string `json:"ImageName,omitempty"`
+	ImageBucket  string `json:"ImageBucket,omitempty"`
+	ImageTag     string `json:"ImageTag,omitempty"`
+	ImageType    string `json:"ImageType,omitempty"`
+	ImageCmdline string `json:"ImageCmdline,omitempty"`
+	ImageState   string `json:"ImageState,omitempty"`
+	ImageChannel string `json:"ImageChannel,omitempty"`
+	// How the node got its assignment: pinned, following or assigned.
+	Enrollment string `json:"Enrollment,omitempty"`
+}
+
+// IpxeImageDeleteConfig is the IpxeImageDeleteConfig schema of the API.
+type IpxeImageDeleteConfig struct {
+	ImageTag          string `json:"ImageTag,omitempty"`
+	ImageType         string `json:"ImageType,omitempty"`
+	Cascade           bool   `json:"Cascade,omitempty"`
+	ReassignImageTag  string `json:"ReassignImageTag,omitempty"`
+	ReassignImageType string `json:"ReassignImageType,omitempty"`
+}
+
+// IpxeImageStateConfig is the IpxeImageStateConfig schema of the API.
+type IpxeImageStateConfig struct {
+	ImageTag   string `json:"ImageTag,omitempty"`
+	ImageType  string `json:"ImageType,omitempty"`
+	ImageState string `json:"ImageState,omitempty"`
+}
+
+// IpxeImageUsage is the IpxeImageUsage schema of the API.
+type IpxeImageUsage struct {
+	ImageTag     string   `json:"ImageTag"`
+	ImageType    string   `json:"ImageType"`
+	ImageName    string   `json:"ImageName"`
+	ImageState   string   `json:"ImageState"`
+	MacAddresses []string `json:"MacAddresses"`
+	Subnets      []string `json:"Subnets"`
+	Channels     []string `json:"Channels"`
+}
+
+// IpxeNodeDbConfig assigns a node either a fixed (ImageTag, ImageType) or an ImageChannel.
+type IpxeNodeDbConfig struct {
+	ImageTag     string `json:"ImageTag,omitempty"`
+	ImageType    string `json:"ImageType,omitempty"`
+	ImageChannel string `json:"ImageChannel,omitempty"`
+	MacAddress   string `json:"MacAddress,omitempty"`
+	// How the node got its assignment: pinned, following or assigned.
+	Enrollment string `json:"Enrollment,omitempty"`
+}
+
+// Node is the Node schema of the API.
+type Node struct {
+	MacAddress string `json:"mac_address,omitempty"`
+	Hostname   string `json:"hostname,omitempty"`
+	// Primary IPv4 or IPv6 address of the node, the first of ip_addresses when unset.
+	IpAddress string `json:"ip_address,omitempty"`
+	// Every address of the node, e.g. both addresses of dual-stack nodes.
+	IpAddresses []string `json:"ip_addresses,omitempty"`
+}
+
+// NodeHostname is the hostname of a node, unique among nodes.
+type NodeHostname struct {
+	MacAddress string     `json:"mac_address,omitempty"`
+	Hostname   string     `json:"hostname"`
+	Source     string     `json:"source,omitempty"`
+	PolicyId   int64      `json:"policy_id,omitempty"`
+	AssignedAt *time.Time `json:"assigned_at,omitempty"`
+}
+
+// NodeIdentity is a node booting from any of its interfaces.
+type NodeIdentity struct {
+	NodeId int64 `json:"node_id"`
+	// Keys the image, payloads and heartbeat of the node whichever interface it boots from.
+	MacAddress string           `json:"mac_address"`
+	Interfaces []*NodeInterface `json:"interfaces"`
+	CreatedAt  *time.Time       `json:"created_at"`
+}
+
+// NodeInterface is the NodeInterface schema of the API.
+type NodeInterface struct {
+	MacAddress string     `json:"mac_address"`
+	Name       string     `json:"name,omitempty"`
+	AttachedAt *time.Time `json:"attached_at,omitempty"`
+}
+
+// NodeInventory is the NodeInventory schema of the API.
+type NodeInventory struct {
+	MacAddress string `json:"mac_address"`
+	// vendor/model followed by +<count>x<model> per accelerator model.
+	HardwareClass string     `json:"hardware_class"`
+	Inventory     *Inventory `json:"inventory"`
+	ReportedAt    *time.Time `json:"reported_at"`
+	// When the last report differing from the previous one was received.
+	ChangedAt *time.Time `json:"changed_at"`
+}
+
+// NodePayload is the NodePayload schema of the API.
+type NodePayload struct {
+	PayloadId        string `json:"PayloadId"`
+	PayloadDirectory string `json:"PayloadDirectory"`
+	MacAddress       string `json:"MacAddress"`
+	// How the node got its assignment: pinned, following or assigned.
+	Enrollment string `json:"Enrollment,omitempty"`
+}
+
+// NodeView is the NodeView schema of the API.
+type NodeView struct {
+	MacAddress   string     `json:"mac_address"`
+	Hostname     string     `json:"hostname,omitempty"`
+	IpAddress    string     `json:"ip_address,omitempty"`
+	IpAddresses  []string   `json:"ip_addresses,omitempty"`
+	ImageTag     string     `json:"image_tag,omitempty"`
+	ImageType    string     `json:"image_type,omitempty"`
+	ImageChannel string     `json:"image_channel,omitempty"`
+	PayloadIds   []string   `json:"payload_ids"`
+	LastSeen     *time.Time `json:"last_seen,omitempty"`
+}
+
+// Registration of a node that booted without a node_images entry under the approve enrollment policy.
+type Registration struct {
+	MacAddress string `json:"mac_address"`
+	IpAddress  string `json:"ip_address"`
+	// DHCP options passed as query parameters of the boot requests, e.g. uuid or dhcp-server.
+	Hints map[string]any `json:"hints"`
+	State string         `json:"state"`
+	// Registration rule that approved the node.
+	RuleId    int64      `json:"rule_id,omitempty"`
+	FirstSeen *time.Time `json:"first_seen"`
+	LastSeen  *time.Time `json:"last_seen"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+}
+
+// RegistrationApproval assigns an approved node an image, a payload or both, unset ones are pinned to the node's defaults on its next boot.
+type RegistrationApproval struct {
+	ImageTag     string `json:"image_tag,omitempty"`
+	ImageType    string `json:"image_type,omitempty"`
+	ImageChannel string `json:"image_channel,omitempty"`
+	PayloadId    string `json:"payload_id,omitempty"`
+}
+
+// RegistrationRule approves pending nodes booting from subnet, with a mac address in mac_oui, or both.
+type RegistrationRule struct {
+	RuleId int64  `json:"rule_id,omitempty"`
+	Subnet string `json:"subnet,omitempty"`
+	// First three octets of the mac address, e.g. 0c:42:a1.
+	MacOui       string     `json:"mac_oui,omitempty"`
+	ImageTag     string     `json:"image_tag,omitempty"`
+	ImageType    string     `json:"image_type,omitempty"`
+	ImageChannel string     `json:"image_channel,omitempty"`
+	PayloadId    string     `json:"payload_id,omitempty"`
+	CreatedAt    *time.Time `json:"created_at,omitempty"`
+}
+
+// SubnetDefaultImage is the image or channel booted by unassigned nodes in Subnet.
+type SubnetDefaultImage struct {
+	Subnet       string `json:"Subnet,omitempty"`
+	ImageTag     string `json:"ImageTag,omitempty"`
+	ImageType    string `json:"ImageType,omitempty"`
+	ImageChannel string `json:"ImageChannel,omitempty"`
+}
+
+// AdvanceImageRollout starts the next wave of a rollout.
+//
+// PUT /api/v2/ipxe/rollouts/{rolloutId}/advance
+func (c *Client) AdvanceImageRollout(ctx context.Context, rolloutId int64) (*ImageRollout, error) {
+	var out ImageRollout
+	if err := c.do(ctx, http.MethodPut, "/api/v2/ipxe/rollouts/"+strconv.FormatInt(rolloutId, 10)+"/advance", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ApproveRegistration approves a pending or rejected node with an image and payload.
+//
+// PUT /api/v2/nodes/registrations/{macAddress}/approve
+func (c *Client) ApproveRegistration(ctx context.Context, macAddress string, body *RegistrationApproval) (*Registration, error) {
+	var out Registration
+	if err := c.do(ctx, http.MethodPut, "/api/v2/nodes/registrations/"+url.PathEscape(macAddress)+"/approve", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteHostnamePolicy deletes a hostname policy, the hostnames it generated are kept.
+//
+// DELETE /api/v2/nodes/hostnames/policies/{policyId}
+func (c *Client) DeleteHostnamePolicy(ctx context.Context, policyId int64) (*HostnamePolicy, error) {
+	var out HostnamePolicy
+	if err := c.do(ctx, http.MethodDelete, "/api/v2/nodes/hostnames/policies/"+strconv.FormatInt(policyId, 10), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteImageChannel deletes a channel no node or subnet follows.
+//
+// DELETE /api/v2/ipxe/channels/{channel}
+func (c *Client) DeleteImageChannel(ctx context.Context, channel string) (*ImageChannel, error) {
+	var out ImageChannel
+	if err := c.do(ctx, http.MethodDelete, "/api/v2/ipxe/channels/"+url.PathEscape(channel), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteIpxeImage deletes an image, failing with 409 while it is referenced unless Cascade or Reassign is set.
+//
+// DELETE /api/v2/ipxe/images/
+func (c *Client) DeleteIpxeImage(ctx context.Context, body *IpxeImageDeleteConfig) (*IpxeDbConfig, error) {
+	var out IpxeDbConfig
+	if err := c.do(ctx, http.MethodDelete, "/api/v2/ipxe/images/", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteNodeHostname removes the hostname of a node, a new one is generated on its next boot.
+//
+// DELETE /api/v2/nodes/{macAddress}/hostname
+func (c *Client) DeleteNodeHostname(ctx context.Context, macAddress string) (*NodeHostname, error) {
+	var out NodeHostname
+	if err := c.do(ctx, http.MethodDelete, "/api/v2/nodes/"+url.PathEscape(macAddress)+"/hostname", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteNodeInterface detaches an interface from the node of a mac address.
+//
+// DELETE /api/v2/nodes/{macAddress}/interfaces/{interfaceMacAddress}
+func (c *Client) DeleteNodeInterface(ctx context.Context, macAddress string, interfaceMacAddress string) (*NodeIdentity, error) {
+	var out NodeIdentity
+	if err := c.do(ctx, http.MethodDelete, "/api/v2/nodes/"+url.PathEscape(macAddress)+"/interfaces/"+url.PathEscape(interfaceMacAddress), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteNodePayload removes a payload from a node.
+//
+// DELETE /api/v2/payload/{macAddress}/{payloadId}
+func (c *Client) DeleteNodePayload(ctx context.Context, macAddress string, payloadId string) ([]*NodePayload, error) {
+	var out []*NodePayload
+	if err := c.do(ctx, http.MethodDelete, "/api/v2/payload/"+url.PathEscape(macAddress)+"/"+url.PathEscape(payloadId), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DeleteRegistrationRule deletes a registration rule.
+//
+// DELETE /api/v2/nodes/registrations/rules/{ruleId}
+func (c *Client) DeleteRegistrationRule(ctx context.Context, ruleId int64) (*RegistrationRule, error) {
+	var out RegistrationRule
+	if err := c.do(ctx, http.MethodDelete, "/api/v2/nodes/registrations/rules/"+strconv.FormatInt(ruleId, 10), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteSubnetDefaultImage deletes the default image of a subnet.
+//
+// DELETE /api/v2/ipxe/subnets/
+func (c *Client) DeleteSubnetDefaultImage(ctx context.Context, body *SubnetDefaultImage) (*SubnetDefaultImage, error) {
+	var out SubnetDefaultImage
+	if err := c.do(ctx, http.MethodDelete, "/api/v2/ipxe/subnets/", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetHealthz reports ok while the process serves requests, the liveness probe.
+//
+// GET /healthz
+func (c *Client) GetHealthz(ctx context.Context) (*HealthReport, error) {
+	var out HealthReport
+	if err := c.do(ctx, http.MethodGet, "/healthz", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetHostname returns the node named hostname.
+//
+// GET /api/v2/nodes/hostnames/{hostname}
+func (c *Client) GetHostname(ctx context.Context, hostname string) (*NodeHostname, error) {
+	var out NodeHostname
+	if err := c.do(ctx, http.MethodGet, "/api/v2/nodes/hostnames/"+url.PathEscape(hostname), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetHostnamePolicies lists hostname policies.
+//
+// GET /api/v2/nodes/hostnames/policies/
+func (c *Client) GetHostnamePolicies(ctx context.Context) ([]*HostnamePolicy, error) {
+	var out []*HostnamePolicy
+	if err := c.do(ctx, http.MethodGet, "/api/v2/nodes/hostnames/policies/", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetImageChannels lists every image channel.
+//
+// GET /api/v2/ipxe/channels/
+func (c *Client) GetImageChannels(ctx context.Context) ([]*ImageChannel, error) {
+	var out []*ImageChannel
+	if err := c.do(ctx, http.MethodGet, "/api/v2/ipxe/channels/", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetImageRollout returns an image rollout with its nodes.
+//
+// GET /api/v2/ipxe/rollouts/{rolloutId}
+func (c *Client) GetImageRollout(ctx context.Context, rolloutId int64) (*ImageRollout, error) {
+	var out ImageRollout
+	if err := c.do(ctx, http.MethodGet, "/api/v2/ipxe/rollouts/"+strconv.FormatInt(rolloutId, 10), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetImageRollouts lists image rollouts.
+//
+// GET /api/v2/ipxe/rollouts/
+func (c *Client) GetImageRollouts(ctx context.Context, state string) ([]*ImageRollout, error) {
+	q := url.Values{}
+	if state != "" {
+		q.Set("state", state)
+	}
+	var out []*ImageRollout
+	if err := c.do(ctx, http.MethodGet, "/api/v2/ipxe/rollouts/", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetIpxeImagePresignedUrls returns presigned urls of the files of an image.
+//
+// GET /api/v2/ipxe/s3/{imageName}
+func (c *Client) GetIpxeImagePresignedUrls(ctx context.Context, imageName string) (string, error) {
+	var out string
+	if err := c.do(ctx, http.MethodGet, "/api/v2/ipxe/s3/"+url.PathEscape(imageName), nil, nil, &out); err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// GetIpxeImageUsage lists the nodes, subnets and channels using each image.
+//
+// GET /api/v2/ipxe/images/usage
+func (c *Client) GetIpxeImageUsage(ctx context.Context, state string) ([]*IpxeImageUsage, error) {
+	q := url.Values{}
+	if state != "" {
+		q.Set("state", state)
+	}
+	var out []*IpxeImageUsage
+	if err := c.do(ctx, http.MethodGet, "/api/v2/ipxe/images/usage", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetIpxeImages lists every image.
+//
+// GET /api/v2/ipxe/images/
+func (c *Client) GetIpxeImages(ctx context.Context) ([]*IpxeDbConfig, error) {
+	var out []*IpxeDbConfig
+	if err := c.do(ctx, http.MethodGet, "/api/v2/ipxe/images/", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetMetrics returns the Prometheus metrics.
+//
+// GET /metrics
+func (c *Client) GetMetrics(ctx context.Context) (string, error) {
+	var out string
+	if err := c.do(ctx, http.MethodGet, "/metrics", nil, nil, &out); err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// GetNodeHostname returns the hostname of a node.
+//
+// GET /api/v2/nodes/{macAddress}/hostname
+func (c *Client) GetNodeHostname(ctx context.Context, macAddress string) (*NodeHostname, error) {
+	var out NodeHostname
+	if err := c.do(ctx, http.MethodGet, "/api/v2/nodes/"+url.PathEscape(macAddress)+"/hostname", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetNodeInterfaces returns the node a mac address is attached to with its interfaces.
+//
+// GET /api/v2/nodes/{macAddress}/interfaces
+func (c *Client) GetNodeInterfaces(ctx context.Context, macAddress string) (*NodeIdentity, error) {
+	var out NodeIdentity
+	if err := c.do(ctx, http.MethodGet, "/api/v2/nodes/"+url.PathEscape(macAddress)+"/interfaces", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetNodeInventories lists the last inventory reported by every node.
+//
+// GET /api/v2/nodes/inventory/
+func (c *Client) GetNodeInventories(ctx context.Context, hardwareClass string) ([]*NodeInventory, error) {
+	q := url.Values{}
+	if hardwareClass != "" {
+		q.Set("hardware_class", hardwareClass)
+	}
+	var out []*NodeInventory
+	if err := c.do(ctx, http.MethodGet, "/api/v2/nodes/inventory/", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetNodeInventory returns the last inventory reported by a node.
+//
+// GET /api/v2/nodes/{macAddress}/inventory
+func (c *Client) GetNodeInventory(ctx context.Context, macAddress string) (*NodeInventory, error) {
+	var out NodeInventory
+	if err := c.do(ctx, http.MethodGet, "/api/v2/nodes/"+url.PathEscape(macAddress)+"/inventory", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetNodeInventoryChanges lists the inventory changes of a node, the latest first.
+//
+// GET /api/v2/nodes/{macAddress}/inventory/changes
+func (c *Client) GetNodeInventoryChanges(ctx context.Context, macAddress string) ([]*InventoryChange, error) {
+	var out []*InventoryChange
+	if err := c.do(ctx, http.MethodGet, "/api/v2/nodes/"+url.PathEscape(macAddress)+"/inventory/changes", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetNodeIpxe returns the image of a node, the api default for unknown nodes.
+//
+// GET /api/v2/ipxe/config/{macAddress}
+func (c *Client) GetNodeIpxe(ctx context.Context, macAddress string) (*IpxeConfig, error) {
+	var out IpxeConfig
+	if err := c.do(ctx, http.MethodGet, "/api/v2/ipxe/config/"+url.PathEscape(macAddress), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetNodeIpxeTemplate returns the iPXE menu of a node, query parameters such as uuid or dhcp-server are recorded as hints of pending nodes.
+//
+// GET /api/v2/ipxe/template/{macAddress}
+func (c *Client) GetNodeIpxeTemplate(ctx context.Context, macAddress string, ip string) (string, error) {
+	q := url.Values{}
+	if ip != "" {
+		q.Set("ip", ip)
+	}
+	var out string
+	if err := c.do(ctx, http.MethodGet, "/api/v2/ipxe/template/"+url.PathEscape(macAddress), q, nil, &out); err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// GetNodePayload returns the payload of a node, enrolling unknown nodes with the subnet or api default according to the enrollment policy.
+//
+// GET /api/v2/payload/{macAddress}
+func (c *Client) GetNodePayload(ctx context.Context, macAddress string, ip string) (*NodePayload, error) {
+	q := url.Values{}
+	if ip != "" {
+		q.Set("ip", ip)
+	}
+	var out NodePayload
+	if err := c.do(ctx, http.MethodGet, "/api/v2/payload/"+url.PathEscape(macAddress), q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetNodeView returns the image, payloads and last heartbeat of a node.
+//
+// GET /api/v2/nodes/{macAddress}
+func (c *Client) GetNodeView(ctx context.Context, macAddress string) (*NodeView, error) {
+	var out NodeView
+	if err := c.do(ctx, http.MethodGet, "/api/v2/nodes/"+url.PathEscape(macAddress), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetNodeViews lists every node with its image, payloads and last heartbeat.
+//
+// GET /api/v2/nodes/
+func (c *Client) GetNodeViews(ctx context.Context) ([]*NodeView, error) {
+	var out []*NodeView
+	if err := c.do(ctx, http.MethodGet, "/api/v2/nodes/", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetOpenAPI returns this document.
+//
+// GET /api/openapi.json
+func (c *Client) GetOpenAPI(ctx context.Context) (map[string]any, error) {
+	var out map[string]any
+	if err := c.do(ctx, http.MethodGet, "/api/openapi.json", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetPayloadParameters returns the parameters of a payload.
+//
+// GET /api/v2/payload/config/{payloadId}
+func (c *Client) GetPayloadParameters(ctx context.Context, payloadId string) (map[string]any, error) {
+	var out map[string]any
+	if err := c.do(ctx, http.MethodGet, "/api/v2/payload/config/"+url.PathEscape(payloadId), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetReadyz reports the status and latency of each database pool and of the default bucket, the readiness probe.
+// On 503 it returns the HealthReport with a nil error: a critical dependency is unavailable.
+//
+// GET /readyz
+func (c *Client) GetReadyz(ctx context.Context) (*HealthReport, error) {
+	var out HealthReport
+	if err := c.do(ctx, http.MethodGet, "/readyz", nil, nil, &out, http.StatusServiceUnavailable); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetRegistration returns the registration of a node.
+//
+// GET /api/v2/nodes/registrations/{macAddress}
+func (c *Client) GetRegistration(ctx context.Context, macAddress string) (*Registration, error) {
+	var out Registration
+	if err := c.do(ctx, http.MethodGet, "/api/v2/nodes/registrations/"+url.PathEscape(macAddress), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetRegistrationRules lists registration rules in the order they are matched.
+//
+// GET /api/v2/nodes/registrations/rules/
+func (c *Client) GetRegistrationRules(ctx context.Context) ([]*RegistrationRule, error) {
+	var out []*RegistrationRule
+	if err := c.do(ctx, http.MethodGet, "/api/v2/nodes/registrations/rules/", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetRegistrations lists node registrations.
+//
+// GET /api/v2/nodes/registrations/
+func (c *Client) GetRegistrations(ctx context.Context, state string) ([]*Registration, error) {
+	q := url.Values{}
+	if state != "" {
+		q.Set("state", state)
+	}
+	var out []*Registration
+	if err := c.do(ctx, http.MethodGet, "/api/v2/nodes/registrations/", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetRoot returns ncore-api.
+//
+// GET /
+func (c *Client) GetRoot(ctx context.Context) (string, error) {
+	var out string
+	if err := c.do(ctx, http.MethodGet, "/", nil, nil, &out); err != nil {
+		return "", err
+	}
+	return out, nil
+}
+
+// GetSubnetDefaultImages lists the subnet default images.
+//
+// GET /api/v2/ipxe/subnets/
+func (c *Client) GetSubnetDefaultImages(ctx context.Context) ([]*SubnetDefaultImage, error) {
+	var out []*SubnetDefaultImage
+	if err := c.do(ctx, http.MethodGet, "/api/v2/ipxe/subnets/", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// HaltImageRollout halts a rollout.
+//
+// PUT /api/v2/ipxe/rollouts/{rolloutId}/halt
+func (c *Client) HaltImageRollout(ctx context.Context, rolloutId int64, reason string) (*ImageRollout, error) {
+	q := url.Values{}
+	if reason != "" {
+		q.Set("reason", reason)
+	}
+	var out ImageRollout
+	if err := c.do(ctx, http.MethodPut, "/api/v2/ipxe/rollouts/"+strconv.FormatInt(rolloutId, 10)+"/halt", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PromoteImageChannel points a channel at an image, creating the channel if needed.
+//
+// PUT /api/v2/ipxe/channels/promote
+func (c *Client) PromoteImageChannel(ctx context.Context, body *ImageChannelPromoteConfig) (*ImageChannel, error) {
+	var out ImageChannel
+	if err := c.do(ctx, http.MethodPut, "/api/v2/ipxe/channels/promote", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutHostnamePolicy adds a hostname policy naming nodes on their first boot.
+//
+// PUT /api/v2/nodes/hostnames/policies/
+func (c *Client) PutHostnamePolicy(ctx context.Context, body *HostnamePolicy) (*HostnamePolicy, error) {
+	var out HostnamePolicy
+	if err := c.do(ctx, http.MethodPut, "/api/v2/nodes/hostnames/policies/", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutImageRollout creates an image rollout, no node is updated before the first advance.
+//
+// PUT /api/v2/ipxe/rollouts/
+func (c *Client) PutImageRollout(ctx context.Context, body *ImageRolloutConfig) (*ImageRollout, error) {
+	var out ImageRollout
+	if err := c.do(ctx, http.MethodPut, "/api/v2/ipxe/rollouts/", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutIpxeImage creates an image.
+//
+// PUT /api/v2/ipxe/images/
+func (c *Client) PutIpxeImage(ctx context.Context, body *IpxeDbConfig) (*IpxeConfig, error) {
+	var out IpxeConfig
+	if err := c.do(ctx, http.MethodPut, "/api/v2/ipxe/images/", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutIpxeImageByName creates an image, imageName is informational.
+//
+// PUT /api/v2/ipxe/images/{imageName}
+func (c *Client) PutIpxeImageByName(ctx context.Context, imageName string, body *IpxeDbConfig) (*IpxeConfig, error) {
+	var out IpxeConfig
+	if err := c.do(ctx, http.MethodPut, "/api/v2/ipxe/images/"+url.PathEscape(imageName), nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutIpxeImageState moves an image to another state, retiring fails with 409 while it is referenced.
+//
+// PUT /api/v2/ipxe/images/state
+func (c *Client) PutIpxeImageState(ctx context.Context, body *IpxeImageStateConfig) (*IpxeDbConfig, error) {
+	var out IpxeDbConfig
+	if err := c.do(ctx, http.MethodPut, "/api/v2/ipxe/images/state", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutNodeHeartbeat records the hostname and ip_address of a node.
+//
+// PUT /api/v2/nodes/{macAddress}/heartbeat
+func (c *Client) PutNodeHeartbeat(ctx context.Context, macAddress string, body *Node) (*Node, error) {
+	var out Node
+	if err := c.do(ctx, http.MethodPut, "/api/v2/nodes/"+url.PathEscape(macAddress)+"/heartbeat", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutNodeHostname assigns a hostname to a node, unique among nodes.
+//
+// PUT /api/v2/nodes/{macAddress}/hostname
+func (c *Client) PutNodeHostname(ctx context.Context, macAddress string, body *NodeHostname) (*NodeHostname, error) {
+	var out NodeHostname
+	if err := c.do(ctx, http.MethodPut, "/api/v2/nodes/"+url.PathEscape(macAddress)+"/hostname", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutNodeInterface attaches an interface to the node of a mac address, creating the node when unknown.
+//
+// PUT /api/v2/nodes/{macAddress}/interfaces
+func (c *Client) PutNodeInterface(ctx context.Context, macAddress string, body *NodeInterface) (*NodeIdentity, error) {
+	var out NodeIdentity
+	if err := c.do(ctx, http.MethodPut, "/api/v2/nodes/"+url.PathEscape(macAddress)+"/interfaces", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutNodeInventory records the hardware inventory of a node, tracking the fields changed since its previous report.
+//
+// PUT /api/v2/nodes/{macAddress}/inventory
+func (c *Client) PutNodeInventory(ctx context.Context, macAddress string, body *Inventory) (*NodeInventory, error) {
+	var out NodeInventory
+	if err := c.do(ctx, http.MethodPut, "/api/v2/nodes/"+url.PathEscape(macAddress)+"/inventory", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutNodeIpxe assigns a node a fixed image or a channel.
+//
+// PUT /api/v2/ipxe/
+func (c *Client) PutNodeIpxe(ctx context.Context, body *IpxeNodeDbConfig) (*IpxeNodeDbConfig, error) {
+	var out IpxeNodeDbConfig
+	if err := c.do(ctx, http.MethodPut, "/api/v2/ipxe/", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutNodePayload assigns a payload to a node.
+//
+// PUT /api/v2/payload/{macAddress}/{payloadId}
+func (c *Client) PutNodePayload(ctx context.Context, macAddress string, payloadId string) ([]*NodePayload, error) {
+	var out []*NodePayload
+	if err := c.do(ctx, http.MethodPut, "/api/v2/payload/"+url.PathEscape(macAddress)+"/"+url.PathEscape(payloadId), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// PutNodesBulk assigns an image, a payload or both to many nodes, each step all or nothing.
+// On 409 it returns the BulkResult with a nil error: a step failed and changed nothing, the steps before it committed, the failed nodes have an Error.
+//
+// PUT /api/v2/nodes/bulk
+func (c *Client) PutNodesBulk(ctx context.Context, dryRun bool, body *BulkAssignment) (*BulkResult, error) {
+	q := url.Values{}
+	if dryRun {
+		q.Set("dry_run", "true")
+	}
+	var out BulkResult
+	if err := c.do(ctx, http.MethodPut, "/api/v2/nodes/bulk", q, body, &out, http.StatusConflict); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutRegistrationRule adds a registration rule approving pending nodes on their next boot.
+//
+// PUT /api/v2/nodes/registrations/rules/
+func (c *Client) PutRegistrationRule(ctx context.Context, body *RegistrationRule) (*RegistrationRule, error) {
+	var out RegistrationRule
+	if err := c.do(ctx, http.MethodPut, "/api/v2/nodes/registrations/rules/", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PutSubnetDefaultImage sets the image or channel of a subnet.
+//
+// PUT /api/v2/ipxe/subnets/
+func (c *Client) PutSubnetDefaultImage(ctx context.Context, body *SubnetDefaultImage) (*SubnetDefaultImage, error) {
+	var out SubnetDefaultImage
+	if err := c.do(ctx, http.MethodPut, "/api/v2/ipxe/subnets/", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RejectRegistration rejects a pending node, which is refused to boot until it is approved.
+//
+// PUT /api/v2/nodes/registrations/{macAddress}/reject
+func (c *Client) RejectRegistration(ctx context.Context, macAddress string) (*Registration, error) {
+	var out Registration
+	if err := c.do(ctx, http.MethodPut, "/api/v2/nodes/registrations/"+url.PathEscape(macAddress)+"/reject", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RollbackImageChannel points a channel back at its previous image.
+//
+// PUT /api/v2/ipxe/channels/{channel}/rollback
+func (c *Client) RollbackImageChannel(ctx context.Context, channel string) (*ImageChannel, error) {
+	var out ImageChannel
+	if err := c.do(ctx, http.MethodPut, "/api/v2/ipxe/channels/"+url.PathEscape(channel)+"/rollback", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// RollbackImageRollout puts the updated nodes of a rollout back on their previous image.
+//
+// PUT /api/v2/ipxe/rollouts/{rolloutId}/rollback
+func (c *Client) RollbackImageRollout(ctx context.Context, rolloutId int64) (*ImageRollout, error) {
+	var out ImageRollout
+	if err := c.do(ctx, http.MethodPut, "/api/v2/ipxe/rollouts/"+strconv.FormatInt(rolloutId, 10)+"/rollback", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
@@ -0,0 +1,134 @@
+// Package client is a Go client of the ncore-api HTTP API.
+//
+// The types and methods of client.gen.go are generated from pkg/api/openapi.json,
+// run go generate ./pkg/client after changing it.
+package client
+
+//go:generate go run gen.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+)
+
+// Client calls the ncore-api, see NewClient.
+type Client struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+// NewClient returns a Client of the ncore-api at baseURL, e.g. http://ncore-api:8080,
+// http.DefaultClient is used when httpClient is nil.
+func NewClient(baseURL string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: httpClient,
+	}
+}
+
+// Error is returned for a response with an unexpected status.
+// It matches the errdefs kind of StatusCode with errors.Is.
+type Error struct {
+	StatusCode int
+	Code       string   `json:"code"`
+	Errors     []string `json:"errors"`
+}
+
+func (e *Error) Error() string {
+	if len(e.Errors) == 0 {
+		return fmt.Sprintf("ncore-api: %s", http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("ncore-api: %s", strings.Join(e.Errors, ", "))
+}
+
+// Is reports whether target is the errdefs kind of e.StatusCode.
+func (e *Error) Is(target error) bool {
+	switch e.StatusCode {
+	case http.StatusNotFound:
+		return target == errdefs.ErrNotFound
+	case http.StatusConflict:
+		return target == errdefs.ErrConflict
+	case http.StatusBadRequest:
+		return target == errdefs.ErrInvalid
+	case http.StatusServiceUnavailable:
+		return target == errdefs.ErrUnavailable
+	}
+	return false
+}
+
+// ErrorCode returns e.Code, see errdefs.Code.
+func (e *Error) ErrorCode() string {
+	return e.Code
+}
+
+// do sends body as JSON and decodes the response into out, a *string for text responses.
+// Statuses other than 200 and statuses are returned as an *Error.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out any, statuses ...int) error {
+	var reqBody io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("cannot encode request: %w", err)
+		}
+		reqBody = bytes.NewReader(b)
+	}
+	u := c.baseURL + path
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reqBody)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-type", "application/json")
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if !expected(resp.StatusCode, statuses) {
+		e := &Error{StatusCode: resp.StatusCode}
+		// the body is informational, e keeps StatusCode if it isn't an error document
+		json.NewDecoder(resp.Body).Decode(e)
+		return e
+	}
+	if s, ok := out.(*string); ok {
+		b, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return err
+		}
+		*s = string(b)
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("cannot decode %s %s response: %w", method, path, err)
+	}
+	return nil
+}
+
+func expected(status int, statuses []int) bool {
+	if status == http.StatusOK {
+		return true
+	}
+	for _, s := range statuses {
+		if status == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,67 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/coreweave/ncore-api/internal/clientgen"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGenerated_upToDate(t *testing.T) {
+	spec, err := os.ReadFile("../api/openapi.json")
+	require.NoError(t, err)
+	want, err := clientgen.Generate(spec, "client")
+	require.NoError(t, err)
+	got, err := os.ReadFile("client.gen.go")
+	require.NoError(t, err)
+
+	assert.Equal(t, string(want), string(got), "client.gen.go is stale, run go generate ./pkg/client")
+}
+
+func TestClient_PutNodesBulk(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+		assert.Equal(t, "/api/v2/nodes/bulk", r.URL.Path)
+		assert.Equal(t, "true", r.URL.Query().Get("dry_run"))
+		assert.Equal(t, "application/json", r.Header.Get("Content-type"))
+
+		var a BulkAssignment
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&a))
+		w.WriteHeader(http.StatusConflict)
+		json.NewEncoder(w).Encode(BulkResult{DryRun: true, Steps: []*BulkStepResult{{Step: "payload", Error: "unknown payload", Nodes: []*BulkNodeResult{
+			{MacAddress: a.MacAddresses[0], Status: "failed", Error: "unknown payload"},
+		}}}})
+	}))
+	defer srv.Close()
+
+	result, err := NewClient(srv.URL, nil).PutNodesBulk(context.Background(), true, &BulkAssignment{
+		MacAddresses: []string{"000000000001"},
+		PayloadId:    "missing",
+	})
+
+	require.NoError(t, err)
+	assert.False(t, result.Committed)
+	assert.Equal(t, "unknown payload", result.Steps[0].Nodes[0].Error)
+}
+
+func TestClient_error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/api/v2/ipxe/rollouts/42", r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code": "image_rollout_not_found", "errors": ["image rollout not found"]}`))
+	}))
+	defer srv.Close()
+
+	_, err := NewClient(srv.URL+"/", nil).GetImageRollout(context.Background(), 42)
+
+	assert.True(t, errdefs.IsNotFound(err))
+	assert.Equal(t, "image_rollout_not_found", errdefs.Code(err))
+	assert.EqualError(t, err, "ncore-api: image rollout not found")
+}
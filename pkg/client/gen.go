@@ -0,0 +1,25 @@
+//go:build ignore
+
+// gen.go writes client.gen.go from pkg/api/openapi.json, see go:generate in client.go.
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/coreweave/ncore-api/internal/clientgen"
+)
+
+func main() {
+	spec, err := os.ReadFile("../api/openapi.json")
+	if err != nil {
+		log.Fatal(err)
+	}
+	src, err := clientgen.Generate(spec, "client")
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile("client.gen.go", src, 0644); err != nil {
+		log.Fatal(err)
+	}
+}
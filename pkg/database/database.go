@@ -4,18 +4,22 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
 	"os"
+	"sort"
 
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/macaddr"
+	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
 	"github.com/jackc/pgx/v5/tracelog"
+	"go.uber.org/zap"
 )
 
 // NewPGXPool is a PostgreSQL connection pool for pgx.
 //
 // Usage:
-// pgPool := database.NewPGXPool(context.Background(), "", &PGXStdLogger{}, tracelog.LogLevelInfo)
+// pgPool := database.NewPGXPool(context.Background(), "", &PGXLogger{}, tracelog.LogLevelInfo)
 // defer pgPool.Close() // Close any remaining connections before shutting down your application.
 //
 // Instead of passing a configuration explictly with a connString,
@@ -28,9 +32,18 @@ func NewPGXPool(ctx context.Context, connString string, logger tracelog.Logger,
 		return nil, err
 	}
 
-	conf.ConnConfig.Tracer = &tracelog.TraceLog{
-		Logger:   logger,
-		LogLevel: logLevel,
+	conf.ConnConfig.Tracer = multiTracer{
+		&tracelog.TraceLog{
+			Logger:   logger,
+			LogLevel: logLevel,
+		},
+		&PGXTracer{},
+	}
+
+	// macaddr columns are read as canonical mac addresses and written from any form, see macaddr.Parse.
+	conf.AfterConnect = func(ctx context.Context, conn *pgx.Conn) error {
+		macaddr.Register(conn.TypeMap())
+		return nil
 	}
 
 	// pgxpool default max number of connections is the number of CPUs on your machine returned by runtime.NumCPU().
@@ -58,17 +71,32 @@ func LogLevelFromEnv() (tracelog.LogLevel, error) {
 	return tracelog.LogLevelInfo, nil
 }
 
-// PGXStdLogger prints pgx logs to the standard logger.
-// os.Stderr by default.
-type PGXStdLogger struct{}
+// PGXLogger writes pgx logs with the logger of the context, see logging.FromContext,
+// so the queries of a request are logged with its request ID.
+type PGXLogger struct{}
+
+func (l *PGXLogger) Log(ctx context.Context, level tracelog.LogLevel, msg string, data map[string]any) {
+	keys := make([]string, 0, len(data))
+	for k := range data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	fields := make([]zap.Field, 0, len(data))
+	for _, k := range keys {
+		fields = append(fields, zap.Any(k, data[k]))
+	}
 
-func (l *PGXStdLogger) Log(ctx context.Context, level tracelog.LogLevel, msg string, data map[string]any) {
-	args := make([]any, 0, len(data)+2) // making space for arguments + level + msg
-	args = append(args, level, msg)
-	for k, v := range data {
-		args = append(args, fmt.Sprintf("%s=%v", k, v))
+	logger := logging.FromContext(ctx).Named("pgx")
+	switch level {
+	case tracelog.LogLevelTrace, tracelog.LogLevelDebug:
+		logger.Debug(msg, fields...)
+	case tracelog.LogLevelInfo:
+		logger.Info(msg, fields...)
+	case tracelog.LogLevelWarn:
+		logger.Warn(msg, fields...)
+	default:
+		logger.Error(msg, fields...)
 	}
-	log.Println(args...)
 }
 
 // PgErrors returns a multi-line error printing more information from *pgconn.PgError to make debugging faster.
@@ -0,0 +1,28 @@
+package database
+
+// IsoLevel is a transaction isolation level.
+// Reference: https://www.postgresql.org/docs/current/transaction-iso.html
+type IsoLevel string
+
+// Transaction isolation levels.
+const (
+	ReadCommitted  IsoLevel = "read committed"
+	RepeatableRead IsoLevel = "repeatable read"
+	Serializable   IsoLevel = "serializable"
+)
+
+// TxOptions configures a transaction started by WithTx.
+type TxOptions struct {
+	// IsoLevel defaults to the server default, read committed unless configured otherwise.
+	IsoLevel IsoLevel
+
+	// MaxRetries is how many times the transaction is run again after a serialization failure (40001)
+	// or a deadlock (40P01). The function run in the transaction must be safe to run more than once.
+	MaxRetries int
+}
+
+// DefaultTxOptions runs a transaction once at the server default isolation level.
+var DefaultTxOptions = TxOptions{}
+
+// SerializableTxOptions is used by operations that read and then write, retried a few times on serialization failures.
+var SerializableTxOptions = TxOptions{IsoLevel: Serializable, MaxRetries: 3}
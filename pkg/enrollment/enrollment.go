@@ -0,0 +1,82 @@
+// Package enrollment defines what happens to nodes booting without a node_images or node_payloads entry,
+// and how the enrollment of each node is recorded.
+package enrollment
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+)
+
+// Policy decides how unknown nodes are enrolled when they first boot.
+type Policy string
+
+const (
+	// PolicyPin records the subnet or api default that applies on first boot as the node's assignment.
+	PolicyPin Policy = "pin"
+	// PolicyFollow records the node but resolves its subnet or api default on every boot until it is assigned.
+	PolicyFollow Policy = "follow"
+	// PolicyRegister refuses to boot nodes that were not assigned through the api beforehand.
+	PolicyRegister Policy = "register"
+	// PolicyApprove records unknown nodes as pending registrations booting the discovery image
+	// until they are approved, see Registrar.
+	PolicyApprove Policy = "approve"
+)
+
+// Policies lists the valid policies.
+var Policies = []Policy{PolicyPin, PolicyFollow, PolicyRegister, PolicyApprove}
+
+// Enrollment of a node_images or node_payloads entry, recording how the node got its assignment.
+const (
+	// Pinned entries were added with the default that applied on first boot.
+	Pinned = "pinned"
+	// Following entries are updated to the default that applies on every boot.
+	Following = "following"
+	// Assigned entries were set through the api.
+	Assigned = "assigned"
+)
+
+// ParsePolicy returns the Policy named s.
+func ParsePolicy(s string) (Policy, error) {
+	for _, p := range Policies {
+		if string(p) == s {
+			return p, nil
+		}
+	}
+	return "", fmt.Errorf("invalid enrollment policy %q, must be one of %v", s, Policies)
+}
+
+// States of a node registration under PolicyApprove.
+const (
+	// StatePending registrations boot the discovery image until they are approved or rejected.
+	StatePending = "pending"
+	// StateApproved registrations boot their assigned image and payloads, or the defaults pinned on their next boot.
+	StateApproved = "approved"
+	// StateRejected registrations are refused like unknown nodes under PolicyRegister.
+	StateRejected = "rejected"
+)
+
+// Hints are the DHCP options relayed by a booting node, e.g. its uuid or the dhcp-server that answered it.
+type Hints map[string]string
+
+// Registrar records the nodes booting without an entry under PolicyApprove, see registration.Service.
+type Registrar interface {
+	// RegisterNode records a boot of macAddress from ipAddress, and returns the state of its registration.
+	RegisterNode(ctx context.Context, macAddress string, ipAddress string, hints Hints) (string, error)
+}
+
+// Enrollment returns the enrollment recorded for nodes enrolled on first boot under p.
+// The zero Policy behaves like PolicyPin, approved nodes are pinned under PolicyApprove.
+func (p Policy) Enrollment() string {
+	if p == PolicyFollow {
+		return Following
+	}
+	return Pinned
+}
+
+// NotRegistered returns the errdefs.ErrNotFound error of unknown nodes under PolicyRegister,
+// and of rejected nodes under PolicyApprove.
+func NotRegistered(macAddress string) error {
+	return errdefs.NotFound("node_not_registered", "node not registered: %s", macAddress)
+}
@@ -0,0 +1,124 @@
+// Package errdefs defines the kinds of errors returned by pkg/postgres and the services.
+//
+// Callers classify an error with errors.Is against ErrNotFound, ErrConflict, ErrInvalid or ErrUnavailable,
+// whatever package created it. Code returns a machine readable code, e.g. image_exists, more specific than the kind.
+package errdefs
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Kinds of errors, an error of no kind is internal.
+var (
+	ErrNotFound    = errors.New("not found")
+	ErrConflict    = errors.New("conflict")
+	ErrInvalid     = errors.New("invalid")
+	ErrUnavailable = errors.New("unavailable")
+)
+
+// Codes of the kinds, used by Code when an error has no code of its own.
+const (
+	CodeNotFound    = "not_found"
+	CodeConflict    = "conflict"
+	CodeInvalid     = "invalid"
+	CodeUnavailable = "unavailable"
+	CodeInternal    = "internal"
+)
+
+// Error is an error of Kind with a machine readable Code.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	// Err is the cause, wrapped with %w in the format of the constructors.
+	Err error
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Is reports whether target is the kind of e.
+func (e *Error) Is(target error) bool {
+	return target == e.Kind
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// ErrorCode returns e.Code, see Code.
+func (e *Error) ErrorCode() string {
+	return e.Code
+}
+
+func newError(kind error, code string, format string, args ...any) error {
+	err := fmt.Errorf(format, args...)
+	return &Error{
+		Kind:    kind,
+		Code:    code,
+		Message: err.Error(),
+		Err:     errors.Unwrap(err),
+	}
+}
+
+// NotFound returns an ErrNotFound error with code, formatted like fmt.Errorf.
+func NotFound(code string, format string, args ...any) error {
+	return newError(ErrNotFound, code, format, args...)
+}
+
+// Conflict returns an ErrConflict error with code, formatted like fmt.Errorf.
+func Conflict(code string, format string, args ...any) error {
+	return newError(ErrConflict, code, format, args...)
+}
+
+// Invalid returns an ErrInvalid error with code, formatted like fmt.Errorf.
+func Invalid(code string, format string, args ...any) error {
+	return newError(ErrInvalid, code, format, args...)
+}
+
+// Unavailable returns an ErrUnavailable error with code, formatted like fmt.Errorf.
+func Unavailable(code string, format string, args ...any) error {
+	return newError(ErrUnavailable, code, format, args...)
+}
+
+// IsNotFound reports whether err is an ErrNotFound error.
+func IsNotFound(err error) bool {
+	return errors.Is(err, ErrNotFound)
+}
+
+// IsConflict reports whether err is an ErrConflict error.
+func IsConflict(err error) bool {
+	return errors.Is(err, ErrConflict)
+}
+
+// IsInvalid reports whether err is an ErrInvalid error.
+func IsInvalid(err error) bool {
+	return errors.Is(err, ErrInvalid)
+}
+
+// IsUnavailable reports whether err is an ErrUnavailable error.
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrUnavailable)
+}
+
+// Code returns the code of the first error in the chain of err with an ErrorCode method,
+// falling back to the code of its kind and CodeInternal for errors of no kind.
+func Code(err error) string {
+	var coder interface{ ErrorCode() string }
+	if errors.As(err, &coder) && coder.ErrorCode() != "" {
+		return coder.ErrorCode()
+	}
+	switch {
+	case IsNotFound(err):
+		return CodeNotFound
+	case IsConflict(err):
+		return CodeConflict
+	case IsInvalid(err):
+		return CodeInvalid
+	case IsUnavailable(err):
+		return CodeUnavailable
+	}
+	return CodeInternal
+}
@@ -0,0 +1,31 @@
+package errdefs
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type validationError struct{}
+
+func (validationError) Error() string        { return "invalid parameter" }
+func (validationError) Is(target error) bool { return target == ErrInvalid }
+
+func TestError(t *testing.T) {
+	cause := errors.New("duplicate key")
+	err := fmt.Errorf("cannot create image: %w", Conflict("image_exists", "image (%s, %s) exists: %w", "develop", "ci-test", cause))
+
+	assert.True(t, IsConflict(err))
+	assert.False(t, IsNotFound(err))
+	assert.ErrorIs(t, err, cause)
+	assert.Equal(t, "image_exists", Code(err))
+	assert.Equal(t, "cannot create image: image (develop, ci-test) exists: duplicate key", err.Error())
+}
+
+func TestCode(t *testing.T) {
+	assert.Equal(t, CodeInvalid, Code(validationError{}))
+	assert.Equal(t, CodeNotFound, Code(fmt.Errorf("lookup: %w", ErrNotFound)))
+	assert.Equal(t, CodeInternal, Code(errors.New("boom")))
+}
@@ -0,0 +1,131 @@
+// Package health checks the dependencies of ncore-api for the readiness endpoint.
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/s3"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Statuses of a CheckResult and of a Report.
+const (
+	StatusOK          = "ok"
+	StatusDegraded    = "degraded"
+	StatusUnavailable = "unavailable"
+)
+
+// Check is a dependency checked by Checker.Ready.
+type Check struct {
+	Name string
+	// Critical checks failing make the Report unavailable, other checks failing only degrade it.
+	Critical bool
+	// Check returns nil when the dependency is reachable.
+	Check func(ctx context.Context) error
+	// Fallback, if set, reports whether requests can be answered without the dependency,
+	// a critical check failing while it does only degrades the Report.
+	Fallback func() bool
+}
+
+// PingPool checks pool can run a query.
+func PingPool(pool *pgxpool.Pool) func(ctx context.Context) error {
+	return pool.Ping
+}
+
+// HeadBucket checks bucketName of store exists and is accessible.
+func HeadBucket(store s3.ObjectStore, bucketName string) func(ctx context.Context) error {
+	return func(ctx context.Context) error {
+		return store.HeadBucket(ctx, bucketName)
+	}
+}
+
+// CheckResult is the outcome of a Check.
+type CheckResult struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	// LatencyMs is the duration of the check in milliseconds.
+	LatencyMs float64 `json:"latency_ms"`
+	Error     string  `json:"error,omitempty"`
+	// Fallback is set when the check failed and requests are answered without the dependency.
+	Fallback bool `json:"fallback,omitempty"`
+}
+
+// Report is the outcome of every Check: unavailable when a critical check failed,
+// degraded when another check failed or a check was slow, ok otherwise.
+type Report struct {
+	Status string         `json:"status"`
+	Checks []*CheckResult `json:"checks"`
+}
+
+// Checker runs its checks concurrently on each call to Ready.
+type Checker struct {
+	checks  []Check
+	timeout time.Duration
+	slow    time.Duration
+}
+
+// NewChecker returns a Checker failing checks taking longer than timeout
+// and degrading checks taking longer than slow.
+func NewChecker(timeout time.Duration, slow time.Duration, checks ...Check) *Checker {
+	return &Checker{
+		checks:  checks,
+		timeout: timeout,
+		slow:    slow,
+	}
+}
+
+// Ready runs every check and returns their results in the order of the checks.
+// A nil Checker has no dependencies and is always ok.
+func (c *Checker) Ready(ctx context.Context) *Report {
+	report := &Report{Status: StatusOK, Checks: []*CheckResult{}}
+	if c == nil {
+		return report
+	}
+	report.Checks = make([]*CheckResult, len(c.checks))
+	var wg sync.WaitGroup
+	for i, check := range c.checks {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx, check)
+		}(i, check)
+	}
+	wg.Wait()
+
+	for _, result := range report.Checks {
+		switch {
+		case result.Status == StatusUnavailable && result.Critical && !result.Fallback:
+			report.Status = StatusUnavailable
+		case result.Status != StatusOK && report.Status == StatusOK:
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, check Check) *CheckResult {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	start := time.Now()
+	err := check.Check(ctx)
+	latency := time.Since(start)
+
+	result := &CheckResult{
+		Name:      check.Name,
+		Status:    StatusOK,
+		Critical:  check.Critical,
+		LatencyMs: float64(latency.Microseconds()) / 1000,
+	}
+	switch {
+	case err != nil:
+		result.Status = StatusUnavailable
+		result.Error = err.Error()
+		result.Fallback = check.Fallback != nil && check.Fallback()
+	case latency > c.slow:
+		result.Status = StatusDegraded
+	}
+	return result
+}
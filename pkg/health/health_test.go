@@ -0,0 +1,65 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/s3"
+	"github.com/stretchr/testify/assert"
+)
+
+func ok(ctx context.Context) error { return nil }
+
+func down(ctx context.Context) error { return errors.New("connection refused") }
+
+func slow(ctx context.Context) error {
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestChecker_Ready(t *testing.T) {
+	store := s3.NewMemoryStore("")
+	store.PutObject("images", "cmdline", nil)
+
+	tests := []struct {
+		name   string
+		checks []Check
+		status string
+	}{
+		{"ok", []Check{{Name: "ipxe", Critical: true, Check: ok}, {Name: "s3", Check: HeadBucket(store, "images")}}, StatusOK},
+		{"non critical down", []Check{{Name: "ipxe", Critical: true, Check: ok}, {Name: "s3", Check: HeadBucket(store, "missing")}}, StatusDegraded},
+		{"critical down", []Check{{Name: "ipxe", Critical: true, Check: down}, {Name: "nodes", Check: ok}}, StatusUnavailable},
+		{"critical timeout", []Check{{Name: "ipxe", Critical: true, Check: slow}}, StatusUnavailable},
+		{"critical down with fallback", []Check{{Name: "ipxe", Critical: true, Check: down, Fallback: func() bool { return true }}}, StatusDegraded},
+		{"critical down without fallback", []Check{{Name: "ipxe", Critical: true, Check: down, Fallback: func() bool { return false }}}, StatusUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			report := NewChecker(10*time.Millisecond, time.Second, tt.checks...).Ready(context.Background())
+			assert.Equal(t, tt.status, report.Status)
+			assert.Len(t, report.Checks, len(tt.checks))
+			for i, check := range tt.checks {
+				assert.Equal(t, check.Name, report.Checks[i].Name)
+			}
+		})
+	}
+}
+
+func TestChecker_Ready_slow(t *testing.T) {
+	wait := func(ctx context.Context) error {
+		time.Sleep(5 * time.Millisecond)
+		return nil
+	}
+	report := NewChecker(time.Second, time.Millisecond, Check{Name: "payloads", Critical: true, Check: wait}).Ready(context.Background())
+
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, StatusDegraded, report.Checks[0].Status)
+	assert.GreaterOrEqual(t, report.Checks[0].LatencyMs, 5.0)
+}
+
+func TestChecker_Ready_nil(t *testing.T) {
+	var c *Checker
+	assert.Equal(t, &Report{Status: StatusOK, Checks: []*CheckResult{}}, c.Ready(context.Background()))
+}
@@ -0,0 +1,134 @@
+package ipxe
+
+import (
+	"context"
+
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/metrics"
+	"github.com/coreweave/ncore-api/pkg/snapshot"
+	"go.uber.org/zap"
+)
+
+// Tag and type of the discovery image, which has no images entry.
+const (
+	DiscoveryImageTag  = "discovery"
+	DiscoveryImageType = "discovery"
+)
+
+// SetEnrollmentPolicy sets how nodes booting without a node_images entry are enrolled, enrollment.PolicyPin by default.
+func (s *Service) SetEnrollmentPolicy(policy enrollment.Policy) {
+	s.enrollmentPolicy = policy
+}
+
+// SetRegistrar sets where unknown nodes are registered under enrollment.PolicyApprove.
+func (s *Service) SetRegistrar(registrar enrollment.Registrar) {
+	s.registrar = registrar
+}
+
+// SetDiscoveryImage sets the image in the default bucket that pending nodes boot under enrollment.PolicyApprove.
+func (s *Service) SetDiscoveryImage(imageName string) {
+	s.discoveryImage = imageName
+}
+
+// BootNodeIpxeConfig returns the image the node of macAddress boots from ipAddress: the image of its node_images entry,
+// or the default of the subnet containing ipAddress or the api default for unknown and following nodes.
+// Unknown nodes are enrolled according to the enrollment policy, an enrollment.NotRegistered error is
+// returned for them under enrollment.PolicyRegister. Under enrollment.PolicyApprove they are registered with hints
+// and boot the discovery image until they are approved.
+func (s *Service) BootNodeIpxeConfig(ctx context.Context, macAddress string, ipAddress string, hints enrollment.Hints) (*IpxeConfig, error) {
+	macAddress = s.bootMacAddress(ctx, macAddress)
+	assigned, err := s.getNodeIpxeConfig(ctx, macAddress)
+	if err == nil && assigned.Enrollment != enrollment.Following {
+		metrics.BootResolutions.WithLabelValues(metrics.KindImage, metrics.SourceNode).Inc()
+		return s.SetHostname(ctx, assigned, macAddress, ipAddress, hints), nil
+	}
+	logger := logging.FromContext(ctx).With(zap.String("mac_address", macAddress))
+	unknown := errdefs.Code(err) == CodeNodeImageNotFound
+	if unknown && s.enrollmentPolicy == enrollment.PolicyRegister {
+		logger.Info("refusing to boot unregistered node", zap.String("request_ip", ipAddress))
+		return nil, enrollment.NotRegistered(macAddress)
+	}
+	if unknown && s.enrollmentPolicy == enrollment.PolicyApprove {
+		switch state, err := s.registrar.RegisterNode(ctx, macAddress, ipAddress, hints); {
+		case err != nil:
+			logger.Error("cannot register node, booting discovery image", zap.Error(err))
+			return s.bootDiscoveryImage(ctx, macAddress, ipAddress, hints), nil
+		case state == enrollment.StateRejected:
+			logger.Info("refusing to boot rejected node", zap.String("request_ip", ipAddress))
+			return nil, enrollment.NotRegistered(macAddress)
+		case state == enrollment.StatePending:
+			return s.bootDiscoveryImage(ctx, macAddress, ipAddress, hints), nil
+		}
+		// approved nodes boot the image assigned by the approval, or are pinned to their default
+		if assigned, err = s.getNodeIpxeConfig(ctx, macAddress); err == nil {
+			metrics.BootResolutions.WithLabelValues(metrics.KindImage, metrics.SourceNode).Inc()
+			return s.SetHostname(ctx, assigned, macAddress, ipAddress, hints), nil
+		}
+	}
+
+	logger.Debug("checking subnet_default_images", zap.String("request_ip", ipAddress))
+	ic := s.GetSubnetDefaultIpxeConfig(ctx, ipAddress)
+	if ic != nil {
+		logger.Info("using subnet default image", zap.String("image_tag", ic.ImageTag), zap.String("image_type", ic.ImageType), zap.String("image_channel", ic.ImageChannel))
+		metrics.BootResolutions.WithLabelValues(metrics.KindImage, metrics.SourceSubnetDefault).Inc()
+	} else {
+		logger.Info("using api default image")
+		metrics.BootResolutions.WithLabelValues(metrics.KindImage, metrics.SourceAPIDefault).Inc()
+		ic = s.GetIpxeApiDefault(ctx)
+	}
+	s.SetHostname(ctx, ic, macAddress, ipAddress, hints)
+
+	config := nodeImageTarget(ic, macAddress)
+	logger = logger.With(
+		zap.String("image_tag", config.ImageTag),
+		zap.String("image_type", config.ImageType),
+		zap.String("image_channel", config.ImageChannel))
+	switch _, stale := snapshot.Stale(ctx); {
+	case stale && (unknown || err == nil):
+		// the database is unavailable, the node is enrolled or updated once it is back
+		logger.Warn("not recording node_images entry from snapshot")
+		if err == nil {
+			ic.Enrollment = assigned.Enrollment
+		}
+	case unknown:
+		config.Enrollment = s.enrollmentPolicy.Enrollment()
+		ic.Enrollment = config.Enrollment
+		logger.Info("adding defaulted node_images entry", zap.String("enrollment", config.Enrollment))
+		if err := s.db.CreateNodeIpxeConfig(ctx, config); err != nil {
+			logger.Error("cannot add defaulted node_images entry", zap.Error(err))
+			break
+		}
+		metrics.DefaultedNodes.WithLabelValues(metrics.KindImage).Inc()
+	case err == nil:
+		ic.Enrollment = enrollment.Following
+		if *nodeImageTarget(assigned, macAddress) == *config {
+			break
+		}
+		config.Enrollment = enrollment.Following
+		logger.Info("updating following node_images entry", zap.String("previous_image_tag", assigned.ImageTag), zap.String("previous_image_type", assigned.ImageType), zap.String("previous_image_channel", assigned.ImageChannel))
+		if _, err := s.db.UpdateNodeImage(ctx, config); err != nil {
+			logger.Error("cannot update following node_images entry", zap.Error(err))
+		}
+	}
+	return ic, nil
+}
+
+// bootDiscoveryImage returns the discovery image booted by pending nodes.
+func (s *Service) bootDiscoveryImage(ctx context.Context, macAddress string, ipAddress string, hints enrollment.Hints) *IpxeConfig {
+	logging.FromContext(ctx).Info("using discovery image", zap.String("mac_address", macAddress), zap.String("image_name", s.discoveryImage))
+	metrics.BootResolutions.WithLabelValues(metrics.KindImage, metrics.SourceDiscovery).Inc()
+	ic := s.GetDiscoveryImage(ctx)
+	s.SetHostname(ctx, ic, macAddress, ipAddress, hints)
+	return ic
+}
+
+// nodeImageTarget returns the node_images entry of macAddress booting ic,
+// nodes defaulted from a subnet following a channel follow the channel too.
+func nodeImageTarget(ic *IpxeConfig, macAddress string) *IpxeNodeDbConfig {
+	if ic.ImageChannel != "" {
+		return &IpxeNodeDbConfig{ImageChannel: ic.ImageChannel, MacAddress: macAddress}
+	}
+	return &IpxeNodeDbConfig{ImageTag: ic.ImageTag, ImageType: ic.ImageType, MacAddress: macAddress}
+}
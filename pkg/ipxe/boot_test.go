@@ -0,0 +1,102 @@
+package ipxe
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var notFound = errdefs.NotFound(CodeNodeImageNotFound, "no node_images entry")
+
+func TestService_BootNodeIpxeConfig_pin(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	db.EXPECT().GetIpxeDbConfig(gomock.Any(), "0c42a1b2c3d4").Return(nil, notFound)
+	db.EXPECT().GetSubnetDefaultIpxeDbConfig(gomock.Any(), "10.1.2.3").Return(&IpxeDbConfig{
+		ImageName: "ncore-release-ci-test", ImageBucket: "ncore-images", ImageTag: "release", ImageType: "ci-test", ImageChannel: "stable",
+	}, nil)
+	db.EXPECT().CreateNodeIpxeConfig(gomock.Any(), &IpxeNodeDbConfig{ImageChannel: "stable", MacAddress: "0c42a1b2c3d4", Enrollment: enrollment.Pinned})
+
+	ic, err := svc.BootNodeIpxeConfig(context.Background(), "0c42a1b2c3d4", "10.1.2.3", nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "ncore-release-ci-test", ic.ImageName)
+	assert.Equal(t, enrollment.Pinned, ic.Enrollment)
+}
+
+func TestService_BootNodeIpxeConfig_register(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	svc.SetEnrollmentPolicy(enrollment.PolicyRegister)
+	db.EXPECT().GetIpxeDbConfig(gomock.Any(), "0c42a1b2c3d4").Return(nil, notFound)
+
+	_, err := svc.BootNodeIpxeConfig(context.Background(), "0c42a1b2c3d4", "10.1.2.3", nil)
+
+	assert.True(t, errdefs.IsNotFound(err))
+	assert.Equal(t, "node_not_registered", errdefs.Code(err))
+}
+
+func TestService_BootNodeIpxeConfig_following(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	db.EXPECT().GetIpxeDbConfig(gomock.Any(), "0c42a1b2c3d4").Return(&IpxeDbConfig{
+		ImageName: "ncore-develop-ci-test", ImageBucket: "ncore-images", ImageTag: "develop", ImageType: "ci-test", Enrollment: enrollment.Following,
+	}, nil)
+	db.EXPECT().GetSubnetDefaultIpxeDbConfig(gomock.Any(), "10.1.2.3").Return(&IpxeDbConfig{
+		ImageName: "ncore-release-ci-test", ImageBucket: "ncore-images", ImageTag: "release", ImageType: "ci-test",
+	}, nil)
+	db.EXPECT().UpdateNodeImage(gomock.Any(), &IpxeNodeDbConfig{ImageTag: "release", ImageType: "ci-test", MacAddress: "0c42a1b2c3d4", Enrollment: enrollment.Following})
+
+	ic, err := svc.BootNodeIpxeConfig(context.Background(), "0c42a1b2c3d4", "10.1.2.3", nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "ncore-release-ci-test", ic.ImageName, "following nodes boot their subnet default")
+	assert.Equal(t, enrollment.Following, ic.Enrollment)
+}
+
+type registrar string
+
+func (r registrar) RegisterNode(ctx context.Context, macAddress string, ipAddress string, hints enrollment.Hints) (string, error) {
+	return string(r), nil
+}
+
+func TestService_BootNodeIpxeConfig_approve(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	svc.SetEnrollmentPolicy(enrollment.PolicyApprove)
+	svc.SetDiscoveryImage("ncore-discovery")
+	db.EXPECT().GetIpxeDbConfig(gomock.Any(), "0c42a1b2c3d4").Return(nil, notFound).Times(2)
+
+	svc.SetRegistrar(registrar(enrollment.StatePending))
+	ic, err := svc.BootNodeIpxeConfig(context.Background(), "0c42a1b2c3d4", "10.1.2.3", enrollment.Hints{"uuid": "4c4c4544"})
+	require.NoError(t, err)
+	assert.Equal(t, "ncore-discovery", ic.ImageName, "pending nodes boot the discovery image")
+
+	svc.SetRegistrar(registrar(enrollment.StateRejected))
+	_, err = svc.BootNodeIpxeConfig(context.Background(), "0c42a1b2c3d4", "10.1.2.3", nil)
+	assert.Equal(t, "node_not_registered", errdefs.Code(err))
+}
+
+type nodeResolver map[string]string
+
+func (r nodeResolver) ResolveMacAddress(ctx context.Context, macAddress string) (string, error) {
+	if resolved, ok := r[macAddress]; ok {
+		return resolved, nil
+	}
+	return macAddress, nil
+}
+
+func TestService_BootNodeIpxeConfig_interface(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	svc.SetNodeResolver(nodeResolver{"0c42a1b2c3d5": "0c42a1b2c3d4"})
+	db.EXPECT().GetIpxeDbConfig(gomock.Any(), "0c42a1b2c3d4").Return(&IpxeDbConfig{
+		ImageName: "ncore-develop-ci-test", ImageBucket: "ncore-images", ImageTag: "develop", ImageType: "ci-test", Enrollment: enrollment.Assigned,
+	}, nil)
+
+	ic, err := svc.BootNodeIpxeConfig(context.Background(), "0c42a1b2c3d5", "10.1.2.3", nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, "ncore-develop-ci-test", ic.ImageName, "interfaces boot the image of their node")
+	assert.Equal(t, "gb2c3d4", ic.Hostname)
+}
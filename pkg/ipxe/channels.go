@@ -0,0 +1,146 @@
+package ipxe
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// ImageChannel is a named alias such as stable or canary pointing at a concrete (image_tag, image_type).
+// Nodes and subnets following a channel boot whatever image the channel points at.
+// PreviousImageTag and PreviousImageType hold the target before the last promotion, used by rollback.
+type ImageChannel struct {
+	Channel           string
+	ImageTag          string
+	ImageType         string
+	PreviousImageTag  string
+	PreviousImageType string
+}
+
+// ImageChannelPromoteConfig points Channel at (ImageTag, ImageType), creating the channel if needed.
+type ImageChannelPromoteConfig struct {
+	Channel   string
+	ImageTag  string
+	ImageType string
+}
+
+// SubnetDefaultImage is the image booted by unassigned nodes in Subnet.
+// Either ImageChannel or ImageTag and ImageType are set.
+type SubnetDefaultImage struct {
+	Subnet       string
+	ImageTag     string `json:",omitempty"`
+	ImageType    string `json:",omitempty"`
+	ImageChannel string `json:",omitempty"`
+}
+
+// validateImageTarget checks that exactly one of imageChannel or (imageTag, imageType) is set.
+func validateImageTarget(imageTag string, imageType string, imageChannel string) error {
+	switch {
+	case imageChannel != "" && (imageTag != "" || imageType != ""):
+		return ValidationError{"ImageChannel and ImageTag/ImageType are mutually exclusive"}
+	case imageChannel == "" && (imageTag == "" || imageType == ""):
+		return ValidationError{"ImageTag and ImageType or ImageChannel are required"}
+	}
+	return nil
+}
+
+// checkImageTarget validates the target of a node or subnet assignment against the database.
+// Fixed images must be assignable, channels must exist.
+func (s *Service) checkImageTarget(ctx context.Context, imageTag string, imageType string, imageChannel string) error {
+	if err := validateImageTarget(imageTag, imageType, imageChannel); err != nil {
+		return err
+	}
+	if imageChannel != "" {
+		_, err := s.db.GetImageChannel(ctx, imageChannel)
+		if errdefs.IsNotFound(err) {
+			return ValidationError{fmt.Sprintf("image channel doesn't exist: %s", imageChannel)}
+		}
+		return err
+	}
+	target := IpxeImageTagType{ImageTag: imageTag, ImageType: imageType}
+	for _, image := range s.db.GetAvailableImages(ctx) {
+		if image == target {
+			return nil
+		}
+	}
+	return ValidationError{fmt.Sprintf("image (%s, %s) doesn't exist or can't be assigned", imageTag, imageType)}
+}
+
+// CheckImageTarget validates an image assignment made outside of this package, such as a bulk assignment.
+func (s *Service) CheckImageTarget(ctx context.Context, imageTag string, imageType string, imageChannel string) error {
+	return s.checkImageTarget(ctx, imageTag, imageType, imageChannel)
+}
+
+// ListImageChannels returns every entry in ipxe.image_channels.
+func (s *Service) ListImageChannels(ctx context.Context) ([]*ImageChannel, error) {
+	return s.db.ListImageChannels(ctx)
+}
+
+// PromoteImageChannel points a channel at another image, keeping the current target for RollbackImageChannel.
+// Only staged and active images can be promoted.
+func (s *Service) PromoteImageChannel(ctx context.Context, config *ImageChannelPromoteConfig) (*ImageChannel, error) {
+	if config.Channel == "" {
+		return nil, ValidationError{"missing Channel"}
+	}
+	return inTx(ctx, s.db, func(ctx context.Context) (*ImageChannel, error) {
+		if err := s.checkImageTarget(ctx, config.ImageTag, config.ImageType, ""); err != nil {
+			return nil, err
+		}
+		logging.FromContext(ctx).Info("promoting image channel", zap.String("channel", config.Channel), zap.String("image_tag", config.ImageTag), zap.String("image_type", config.ImageType))
+		return s.db.PromoteImageChannel(ctx, config)
+	})
+}
+
+// RollbackImageChannel swaps the current and previous target of channel.
+// Rolling back twice returns to the promoted image.
+func (s *Service) RollbackImageChannel(ctx context.Context, channel string) (*ImageChannel, error) {
+	if channel == "" {
+		return nil, ValidationError{"missing Channel"}
+	}
+	logging.FromContext(ctx).Info("rolling back image channel", zap.String("channel", channel))
+	return s.db.RollbackImageChannel(ctx, channel)
+}
+
+// DeleteImageChannel deletes a channel no node or subnet follows anymore.
+func (s *Service) DeleteImageChannel(ctx context.Context, channel string) (*ImageChannel, error) {
+	if channel == "" {
+		return nil, ValidationError{"missing Channel"}
+	}
+	logging.FromContext(ctx).Info("deleting image channel", zap.String("channel", channel))
+	return s.db.DeleteImageChannel(ctx, channel)
+}
+
+// ListSubnetDefaultImages returns every entry in ipxe.subnet_default_images.
+func (s *Service) ListSubnetDefaultImages(ctx context.Context) ([]*SubnetDefaultImage, error) {
+	return s.db.ListSubnetDefaultImages(ctx)
+}
+
+// SetSubnetDefaultImage creates or replaces the default image of a subnet.
+func (s *Service) SetSubnetDefaultImage(ctx context.Context, config *SubnetDefaultImage) (*SubnetDefaultImage, error) {
+	prefix, err := netip.ParsePrefix(config.Subnet)
+	if err != nil {
+		return nil, ValidationError{fmt.Sprintf("invalid Subnet: %s", config.Subnet)}
+	}
+	config.Subnet = prefix.Masked().String()
+	return inTx(ctx, s.db, func(ctx context.Context) (*SubnetDefaultImage, error) {
+		if err := s.checkImageTarget(ctx, config.ImageTag, config.ImageType, config.ImageChannel); err != nil {
+			return nil, err
+		}
+		logging.FromContext(ctx).Info("setting subnet default image", zap.String("subnet", config.Subnet), zap.String("image_tag", config.ImageTag), zap.String("image_type", config.ImageType), zap.String("image_channel", config.ImageChannel))
+		return s.db.SetSubnetDefaultImage(ctx, config)
+	})
+}
+
+// DeleteSubnetDefaultImage deletes the default image of subnet.
+func (s *Service) DeleteSubnetDefaultImage(ctx context.Context, subnet string) (*SubnetDefaultImage, error) {
+	prefix, err := netip.ParsePrefix(subnet)
+	if err != nil {
+		return nil, ValidationError{fmt.Sprintf("invalid Subnet: %s", subnet)}
+	}
+	logging.FromContext(ctx).Info("deleting subnet default image", zap.String("subnet", subnet))
+	return s.db.DeleteSubnetDefaultImage(ctx, prefix.Masked().String())
+}
@@ -0,0 +1,40 @@
+package ipxe
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_PromoteImageChannel_unavailable(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	db.EXPECT().GetAvailableImages(gomock.Any()).Return([]IpxeImageTagType{{ImageTag: "master", ImageType: "ci-test"}})
+
+	_, err := svc.PromoteImageChannel(context.Background(), &ImageChannelPromoteConfig{Channel: "stable", ImageTag: "develop", ImageType: "ci-test"})
+
+	var validationErr ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+}
+
+func TestService_SetSubnetDefaultImage_channel(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	db.EXPECT().GetImageChannel(gomock.Any(), "stable").Return(&ImageChannel{Channel: "stable", ImageTag: "master", ImageType: "ci-test"}, nil)
+	db.EXPECT().SetSubnetDefaultImage(gomock.Any(), &SubnetDefaultImage{Subnet: "10.0.0.0/24", ImageChannel: "stable"}).Return(&SubnetDefaultImage{Subnet: "10.0.0.0/24", ImageChannel: "stable"}, nil)
+
+	sdi, err := svc.SetSubnetDefaultImage(context.Background(), &SubnetDefaultImage{Subnet: "10.0.0.17/24", ImageChannel: "stable"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "stable", sdi.ImageChannel)
+}
+
+func TestService_UpdateNodeImage_channelAndTag(t *testing.T) {
+	svc, _, _ := newTestService(t)
+
+	_, err := svc.UpdateNodeImage(context.Background(), &IpxeNodeDbConfig{MacAddress: "0c42a1b2c3d4", ImageChannel: "stable", ImageTag: "master"})
+
+	var validationErr ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+}
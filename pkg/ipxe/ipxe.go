@@ -3,9 +3,16 @@ package ipxe
 import (
 	"context"
 	"fmt"
-	"log"
+	"net/netip"
 	"strings"
 	"text/template"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/macaddr"
+	"go.uber.org/zap"
 )
 
 type IpxeConfig struct {
@@ -20,13 +27,22 @@ type IpxeConfig struct {
 	ImageRootFsUrlHttp  string
 	ImageRootFsUrlHttps string
 	ImageCmdline        string
+	ImageState          string
+	ImageWarning        string `json:",omitempty"`
+	ImageChannel        string `json:",omitempty"`
 	Hostname            string
+	// Enrollment of the node_images entry of the node, see package enrollment.
+	Enrollment string `json:",omitempty"`
 }
 
+// IpxeNodeDbConfig assigns a node either a fixed (ImageTag, ImageType) or an ImageChannel.
 type IpxeNodeDbConfig struct {
-	ImageTag   string
-	ImageType  string
-	MacAddress string
+	ImageTag     string
+	ImageType    string
+	ImageChannel string `json:",omitempty"`
+	MacAddress   string
+	// Enrollment is assigned unless set, see package enrollment.
+	Enrollment string `json:",omitempty"`
 }
 
 type IpxeDbConfig struct {
@@ -35,6 +51,11 @@ type IpxeDbConfig struct {
 	ImageTag     string
 	ImageType    string
 	ImageCmdline string
+	ImageState   string
+	// ImageChannel is set when the image was resolved through a channel.
+	ImageChannel string `json:",omitempty"`
+	// Enrollment is set when the image was resolved through a node_images entry.
+	Enrollment string `json:",omitempty"`
 }
 
 func (ic *IpxeConfig) dto() *IpxeConfig {
@@ -50,7 +71,11 @@ func (ic *IpxeConfig) dto() *IpxeConfig {
 		ImageRootFsUrlHttp:  strings.Replace(ic.ImageRootFsUrlHttps, "https", "http", 1),
 		ImageRootFsUrlHttps: ic.ImageRootFsUrlHttps,
 		ImageCmdline:        ic.ImageCmdline,
+		ImageState:          ic.ImageState,
+		ImageWarning:        imageWarning(ic.ImageTag, ic.ImageType, ic.ImageState),
+		ImageChannel:        ic.ImageChannel,
 		Hostname:            ic.Hostname,
+		Enrollment:          ic.Enrollment,
 	}
 }
 
@@ -61,6 +86,9 @@ func (idc *IpxeDbConfig) dto() *IpxeDbConfig {
 		ImageTag:     idc.ImageTag,
 		ImageType:    idc.ImageType,
 		ImageCmdline: idc.ImageCmdline,
+		ImageState:   idc.ImageState,
+		ImageChannel: idc.ImageChannel,
+		Enrollment:   idc.Enrollment,
 	}
 }
 
@@ -69,39 +97,130 @@ type IpxeImageTagType struct {
 	ImageType string
 }
 
-func (s *Service) SetHostname(ctx context.Context, ic *IpxeConfig, macAddress string) *IpxeConfig {
-	ic.Hostname = string('g') + macAddress[len(macAddress)-6:]
+// HostnameSource names nodes, see nodes.Service.
+type HostnameSource interface {
+	NodeHostname(ctx context.Context, macAddress string, ipAddress string, hints enrollment.Hints) (string, error)
+}
+
+// SetHostnameSource sets where node hostnames are read from, every node is named g and the last 6 hex digits
+// of its mac_address without one.
+func (s *Service) SetHostnameSource(hostnames HostnameSource) {
+	s.hostnames = hostnames
+}
+
+// SetHostname sets the hostname of macAddress booting from ipAddress on ic, see HostnameSource.
+// Nodes that can't be named, e.g. without ipAddress or while the nodes database is unavailable,
+// are named g and the last 6 hex digits of macAddress.
+func (s *Service) SetHostname(ctx context.Context, ic *IpxeConfig, macAddress string, ipAddress string, hints enrollment.Hints) *IpxeConfig {
+	ic.Hostname = "g" + macaddr.MAC(macAddress).Suffix()
+	if s.hostnames == nil {
+		return ic
+	}
+	hostname, err := s.hostnames.NodeHostname(ctx, macAddress, ipAddress, hints)
+	switch {
+	case err == nil:
+		ic.Hostname = hostname
+	case !errdefs.IsNotFound(err):
+		logging.FromContext(ctx).Warn("cannot name node", zap.String("mac_address", macAddress), zap.String("hostname", ic.Hostname), zap.Error(err))
+	}
 	return ic
 }
 
-// GetAvailableImages returns a list of available {image_tag image_type}
+// GetAvailableImages returns a list of {image_tag image_type} that can be assigned to nodes.
 func (s *Service) GetAvailableImages(ctx context.Context) []IpxeImageTagType {
 	return s.db.GetAvailableImages(ctx)
 }
 
+// UpdateNodeImage assigns a node a fixed image or a channel, under the mac_address keying the node of config.MacAddress.
+// Its Enrollment becomes assigned, or following when set so the node follows its default again.
+// The image or channel is checked in the same transaction as the update.
 func (s *Service) UpdateNodeImage(ctx context.Context, config *IpxeNodeDbConfig) (*IpxeNodeDbConfig, error) {
-	return s.db.UpdateNodeImage(ctx, config)
+	if config.Enrollment != "" && config.Enrollment != enrollment.Assigned && config.Enrollment != enrollment.Following {
+		return nil, ValidationError{fmt.Sprintf("Enrollment must be %s or %s", enrollment.Assigned, enrollment.Following)}
+	}
+	macAddress, err := s.resolveMacAddress(ctx, config.MacAddress)
+	if err != nil {
+		return nil, err
+	}
+	config.MacAddress = macAddress
+	return inTx(ctx, s.db, func(ctx context.Context) (*IpxeNodeDbConfig, error) {
+		if err := s.checkImageTarget(ctx, config.ImageTag, config.ImageType, config.ImageChannel); err != nil {
+			return nil, err
+		}
+		return s.db.UpdateNodeImage(ctx, config)
+	})
 }
 
-// GetIpxe returns an IpxeConfig for macAddress.
+// WithTx runs fn in a transaction on the ipxe database, see postgres.DB.WithTx.
+func (s *Service) WithTx(ctx context.Context, opts database.TxOptions, fn func(ctx context.Context) error) error {
+	return s.db.WithTx(ctx, opts, fn)
+}
+
+// inTx runs fn in a serializable transaction retried on serialization failures, for operations reading then writing.
+func inTx[T any](ctx context.Context, db DB, fn func(ctx context.Context) (T, error)) (T, error) {
+	var v T
+	err := db.WithTx(ctx, database.SerializableTxOptions, func(ctx context.Context) error {
+		var err error
+		v, err = fn(ctx)
+		return err
+	})
+	return v, err
+}
+
+// GetNodeImage returns the node_images entry for macAddress, an errdefs.ErrNotFound error when the node has none.
+func (s *Service) GetNodeImage(ctx context.Context, macAddress string) (*IpxeNodeDbConfig, error) {
+	if macAddress == "" {
+		return nil, ValidationError{"missing macAddress"}
+	}
+	return s.db.GetNodeImage(ctx, macAddress)
+}
+
+// ListNodeImages returns every node_images entry.
+func (s *Service) ListNodeImages(ctx context.Context) ([]*IpxeNodeDbConfig, error) {
+	return s.db.ListNodeImages(ctx)
+}
+
+// ListNodeImageMacAddresses returns the mac_address of the nodes assigned (image.ImageTag, image.ImageType).
+func (s *Service) ListNodeImageMacAddresses(ctx context.Context, image IpxeImageTagType) ([]string, error) {
+	return s.db.ListNodeImageMacAddresses(ctx, &image, nil)
+}
+
+// GetIpxe returns an IpxeConfig for the node of macAddress, an errdefs.ErrNotFound error when the node has no image.
+// When the database is unavailable the image is resolved from the snapshot, see SetSnapshots.
 func (s *Service) GetNodeIpxeConfig(ctx context.Context, macAddress string) (*IpxeConfig, error) {
-	var ic *IpxeConfig
-	var idc *IpxeDbConfig
 	if macAddress == "" {
 		return nil, ValidationError{"missing macAddress"}
 	}
+	macAddress = s.bootMacAddress(ctx, macAddress)
+	ic, err := s.getNodeIpxeConfig(ctx, macAddress)
+	if err != nil {
+		return nil, err
+	}
+	return s.SetHostname(ctx, ic, macAddress, "", nil), nil
+}
+
+// getNodeIpxeConfig is GetNodeIpxeConfig for a resolved macAddress, without hostname.
+func (s *Service) getNodeIpxeConfig(ctx context.Context, macAddress string) (*IpxeConfig, error) {
+	var ic *IpxeConfig
+	var idc *IpxeDbConfig
 	idc, err := s.db.GetIpxeDbConfig(ctx, macAddress)
 	if err != nil {
-		log.Printf("GetNodeIpxeConfig: failed to get IpxeConfig from database. %v", err)
-		return nil, nil
+		idc, err = s.fromSnapshot(ctx, err, func(snap *Snapshot) (*IpxeDbConfig, error) {
+			return snap.GetIpxeDbConfig(macAddress)
+		})
+	}
+	if err != nil {
+		logging.FromContext(ctx).Debug("cannot get node image", zap.String("mac_address", macAddress), zap.Error(err))
+		return nil, err
 	}
 	imageInitrdUrlHttps, imageKernelUrlHttps, imageRootFsUrlHttps, err := s.GetIpxeImagePresignedUrls(
+		ctx,
 		idc.ImageBucket,
 		idc.ImageName,
 		900,
 	)
 	if err != nil {
-		log.Printf("GetIpxe error: %v", err)
+		logging.FromContext(ctx).Error("cannot presign node image", zap.String("mac_address", macAddress), zap.String("image_name", idc.ImageName), zap.Error(err))
 		return nil, err
 	}
 	ic = &IpxeConfig{
@@ -113,8 +232,10 @@ func (s *Service) GetNodeIpxeConfig(ctx context.Context, macAddress string) (*Ip
 		ImageKernelUrlHttps: imageKernelUrlHttps,
 		ImageRootFsUrlHttps: imageRootFsUrlHttps,
 		ImageCmdline:        idc.ImageCmdline,
+		ImageState:          idc.ImageState,
+		ImageChannel:        idc.ImageChannel,
+		Enrollment:          idc.Enrollment,
 	}
-	s.SetHostname(ctx, ic, macAddress)
 	return ic.dto(), nil
 }
 
@@ -126,18 +247,31 @@ func (s *Service) GetSubnetDefaultIpxeConfig(ctx context.Context, ipAddress stri
 	if ipAddress == "" {
 		return nil
 	}
-	idc, err := s.db.GetSubnetDefaultIpxeDbConfig(ctx, ipAddress)
+	addr, err := netip.ParseAddr(ipAddress)
+	if err != nil {
+		logging.FromContext(ctx).Debug("invalid ip_address", zap.String("ip_address", ipAddress))
+		return nil
+	}
+	// IPv4 clients of IPv6 listeners are reported as IPv4-mapped addresses, which IPv4 subnets don't contain
+	ipAddress = addr.Unmap().WithZone("").String()
+	idc, err = s.db.GetSubnetDefaultIpxeDbConfig(ctx, ipAddress)
+	if err != nil {
+		idc, err = s.fromSnapshot(ctx, err, func(snap *Snapshot) (*IpxeDbConfig, error) {
+			return snap.GetSubnetDefaultIpxeDbConfig(ipAddress)
+		})
+	}
 	if err != nil {
-		log.Printf("GetSubnetDefaultIpxeDbConfig: failed to get IpxeConfig from database. %v", err)
+		logging.FromContext(ctx).Debug("cannot get subnet default image", zap.String("ip_address", ipAddress), zap.Error(err))
 		return nil
 	}
 	imageInitrdUrlHttps, imageKernelUrlHttps, imageRootFsUrlHttps, err := s.GetIpxeImagePresignedUrls(
+		ctx,
 		idc.ImageBucket,
 		idc.ImageName,
 		900,
 	)
 	if err != nil {
-		log.Printf("GetSubnetDefaultIpxeConfig error for ipAddress: %s - %v", ipAddress, err)
+		logging.FromContext(ctx).Error("cannot presign subnet default image", zap.String("ip_address", ipAddress), zap.String("image_name", idc.ImageName), zap.Error(err))
 		return nil
 	}
 	ic = &IpxeConfig{
@@ -149,6 +283,8 @@ func (s *Service) GetSubnetDefaultIpxeConfig(ctx context.Context, ipAddress stri
 		ImageKernelUrlHttps: imageKernelUrlHttps,
 		ImageRootFsUrlHttps: imageRootFsUrlHttps,
 		ImageCmdline:        idc.ImageCmdline,
+		ImageState:          idc.ImageState,
+		ImageChannel:        idc.ImageChannel,
 	}
 	return ic.dto()
 }
@@ -172,10 +308,11 @@ func (s *Service) CreateIpxeImage(ctx context.Context, config *IpxeDbConfig) (*I
 
 	ic, err := s.db.CreateIpxeImage(ctx, config)
 	if err != nil {
-		log.Printf("CreateIpxeImage: failed to insert IpxeDbConfig: %v", err)
+		logging.FromContext(ctx).Error("cannot create image", zap.String("image_tag", config.ImageTag), zap.String("image_type", config.ImageType), zap.Error(err))
 		return nil, err
 	}
 	imageInitrdUrlHttps, imageKernelUrlHttps, imageRootFsUrlHttps, err := s.GetIpxeImagePresignedUrls(
+		ctx,
 		ic.ImageBucket,
 		ic.ImageName,
 		900,
@@ -186,18 +323,9 @@ func (s *Service) CreateIpxeImage(ctx context.Context, config *IpxeDbConfig) (*I
 	return ic.dto(), err
 }
 
-// DeleteIpxeImage deletes an entry in ipxe.images matching image_tag and image_type.
-func (s *Service) DeleteIpxeImage(ctx context.Context, config *IpxeImageTagType) (*IpxeDbConfig, error) {
-	idc, err := s.db.DeleteIpxeImage(ctx, config)
-	if err != nil {
-		log.Printf("DeleteIpxeImage: failed to delete IpxeDbDeleteConfig: %v", err)
-		return nil, err
-	}
-	return idc, err
-}
-
 // GetIpxePresignedUrl returns a url string for the given bucket.
 func (s *Service) GetIpxeImagePresignedUrls(
+	ctx context.Context,
 	bucket string,
 	imageName string,
 	lifetimeSecs int64,
@@ -217,50 +345,62 @@ func (s *Service) GetIpxeImagePresignedUrls(
 	imageKernel := fmt.Sprintf(`%s/vmlinuz`, imageName)
 	imageRootFs := fmt.Sprintf(`%s/rootfs.cpio.gz`, imageName)
 
-	imageInitrdReq, err := s.s3Presigner.GetObject(bucket, imageInitrd, lifetimeSecs)
+	logger := logging.FromContext(ctx).With(zap.String("bucket", bucket))
+	imageInitrdUrl, err := s.objectStore.PresignGetObject(ctx, bucket, imageInitrd, lifetimeSecs)
 	if err != nil {
-		log.Printf("GetIpxePresignedUrls error: %v", err)
+		logger.Error("cannot presign object", zap.String("key", imageInitrd), zap.Error(err))
 		return "", "", "", err
 	}
-	imageKernelReq, err := s.s3Presigner.GetObject(bucket, imageKernel, lifetimeSecs)
+	imageKernelUrl, err := s.objectStore.PresignGetObject(ctx, bucket, imageKernel, lifetimeSecs)
 	if err != nil {
-		log.Printf("GetIpxePresignedUrls error: %v", err)
+		logger.Error("cannot presign object", zap.String("key", imageKernel), zap.Error(err))
 		return "", "", "", err
 	}
-	imageRootFsReq, err := s.s3Presigner.GetObject(bucket, imageRootFs, lifetimeSecs)
+	imageRootFsUrl, err := s.objectStore.PresignGetObject(ctx, bucket, imageRootFs, lifetimeSecs)
 	if err != nil {
-		log.Printf("GetIpxePresignedUrls error: %v", err)
+		logger.Error("cannot presign object", zap.String("key", imageRootFs), zap.Error(err))
 		return "", "", "", err
 	}
-	imageInitrdUrl := imageInitrdReq.URL
-	imageKernelUrl := imageKernelReq.URL
-	imageRootFsUrl := imageRootFsReq.URL
 	return imageInitrdUrl, imageKernelUrl, imageRootFsUrl, err
 }
 
-func (s *Service) GetIpxeApiDefault() *IpxeConfig {
+func (s *Service) GetIpxeApiDefault(ctx context.Context) *IpxeConfig {
+	return s.defaultBucketImage(ctx, s.ipxeDefaultImage, s.ipxeDefaultImageTag, s.ipxeDefaultImageType)
+}
+
+// GetDiscoveryImage returns the IpxeConfig of the image pending nodes boot under enrollment.PolicyApprove,
+// see SetDiscoveryImage.
+func (s *Service) GetDiscoveryImage(ctx context.Context) *IpxeConfig {
+	return s.defaultBucketImage(ctx, s.discoveryImage, DiscoveryImageTag, DiscoveryImageType)
+}
+
+// defaultBucketImage returns the IpxeConfig of imageName in the default bucket, read from object storage only.
+func (s *Service) defaultBucketImage(ctx context.Context, imageName string, imageTag string, imageType string) *IpxeConfig {
 	var ic IpxeConfig
+	logger := logging.FromContext(ctx).With(zap.String("image_name", imageName))
 	imageInitrdUrlHttps, imageKernelUrlHttps, imageRootFsUrlHttps, err := s.GetIpxeImagePresignedUrls(
+		ctx,
 		s.ipxeDefaultBucket,
-		s.ipxeDefaultImage,
+		imageName,
 		900,
 	)
 	if err != nil {
-		log.Printf("GetIpxeApiDefault error: %v", err)
+		logger.Error("cannot presign default bucket image", zap.Error(err))
 		imageInitrdUrlHttps = err.Error()
 		imageKernelUrlHttps = err.Error()
 		imageRootFsUrlHttps = err.Error()
 	}
-	defaultCmdline := fmt.Sprintf(`%s/cmdline`, s.ipxeDefaultImage)
-	bytes, err := s.s3Svc.GetObject(s.ipxeDefaultBucket, defaultCmdline)
+	defaultCmdline := fmt.Sprintf(`%s/cmdline`, imageName)
+	bytes, err := s.objectStore.GetObject(ctx, s.ipxeDefaultBucket, defaultCmdline)
 	if err != nil {
-		log.Printf("GetIpxeApiDefault: failed to GetObject for defaultCmdline")
+		logger.Error("cannot get default bucket image cmdline", zap.String("key", defaultCmdline), zap.Error(err))
 	}
 	ic.ImageCmdline = string(bytes)
-	ic.ImageTag = s.ipxeDefaultImageTag
-	ic.ImageType = s.ipxeDefaultImageType
-	ic.ImageName = s.ipxeDefaultImage
+	ic.ImageTag = imageTag
+	ic.ImageType = imageType
+	ic.ImageName = imageName
 	ic.ImageBucket = s.ipxeDefaultBucket
+	ic.ImageState = ImageStateActive
 	ic.ImageInitrdUrlHttps = imageInitrdUrlHttps
 	ic.ImageKernelUrlHttps = imageKernelUrlHttps
 	ic.ImageRootFsUrlHttps = imageRootFsUrlHttps
@@ -0,0 +1,187 @@
+package ipxe
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// Image lifecycle states stored in ipxe.images.image_state.
+//
+// Staged and active images can be assigned to nodes.
+// Deprecated images keep booting the nodes already using them, with a warning in the iPXE menu,
+// but can't be assigned anymore. Retired images don't boot, nodes still referencing them fall
+// back to their subnet default or the API default.
+const (
+	ImageStateStaged     = "staged"
+	ImageStateActive     = "active"
+	ImageStateDeprecated = "deprecated"
+	ImageStateRetired    = "retired"
+)
+
+// ImageStates lists the valid image states.
+var ImageStates = []string{ImageStateStaged, ImageStateActive, ImageStateDeprecated, ImageStateRetired}
+
+// IpxeImageStateConfig changes the image_state of (image_tag, image_type).
+type IpxeImageStateConfig struct {
+	ImageTag   string
+	ImageType  string
+	ImageState string
+}
+
+// IpxeImageDeleteConfig deletes (image_tag, image_type).
+// Images still referenced by node_images or subnet_default_images are only deleted when Cascade is set,
+// which deletes the references too, or when ReassignImageTag and ReassignImageType are set,
+// which moves the references, including image channels pointing at the image, to that image.
+// Cascade never deletes image channels, they have to be promoted to another image first.
+type IpxeImageDeleteConfig struct {
+	ImageTag          string
+	ImageType         string
+	Cascade           bool
+	ReassignImageTag  string
+	ReassignImageType string
+}
+
+// Reassign reports whether references are moved to another image.
+func (c *IpxeImageDeleteConfig) Reassign() bool {
+	return c.ReassignImageTag != "" || c.ReassignImageType != ""
+}
+
+// IpxeImageUsage lists the nodes, subnets and image channels referencing an image.
+// Nodes and subnets following a channel are only listed under the channel.
+type IpxeImageUsage struct {
+	ImageTag     string
+	ImageType    string
+	ImageName    string
+	ImageState   string
+	MacAddresses []string
+	Subnets      []string
+	Channels     []string
+}
+
+// InUse reports whether any node, subnet or image channel references the image.
+func (u *IpxeImageUsage) InUse() bool {
+	return len(u.MacAddresses) > 0 || len(u.Subnets) > 0 || len(u.Channels) > 0
+}
+
+// ImageInUseError is returned when an image can't be deleted or retired because it is still referenced.
+type ImageInUseError struct {
+	Usage *IpxeImageUsage
+}
+
+func (e ImageInUseError) Error() string {
+	return fmt.Sprintf("image (%s, %s) is used by %d nodes, %d subnets and %d channels",
+		e.Usage.ImageTag, e.Usage.ImageType, len(e.Usage.MacAddresses), len(e.Usage.Subnets), len(e.Usage.Channels))
+}
+
+// Is makes ImageInUseError an errdefs.ErrConflict error.
+func (e ImageInUseError) Is(target error) bool {
+	return target == errdefs.ErrConflict
+}
+
+func (e ImageInUseError) ErrorCode() string {
+	return "image_in_use"
+}
+
+// imageWarning returns the iPXE menu warning shown for an image in imageState.
+func imageWarning(imageTag string, imageType string, imageState string) string {
+	if imageState != ImageStateDeprecated {
+		return ""
+	}
+	return fmt.Sprintf("image (%s, %s) is deprecated", imageTag, imageType)
+}
+
+func validImageState(imageState string) bool {
+	for _, state := range ImageStates {
+		if state == imageState {
+			return true
+		}
+	}
+	return false
+}
+
+// ListIpxeImages returns every entry in ipxe.images.
+func (s *Service) ListIpxeImages(ctx context.Context) ([]*IpxeDbConfig, error) {
+	return s.db.ListIpxeImages(ctx)
+}
+
+// ListIpxeImageUsage returns the usage of every image in imageState, or of all images when imageState is empty.
+func (s *Service) ListIpxeImageUsage(ctx context.Context, imageState string) ([]*IpxeImageUsage, error) {
+	if imageState != "" && !validImageState(imageState) {
+		return nil, ValidationError{fmt.Sprintf("invalid image state: %s, expected one of %s", imageState, strings.Join(ImageStates, ", "))}
+	}
+	return s.db.ListIpxeImageUsage(ctx, imageState)
+}
+
+// SetIpxeImageState moves an image to another lifecycle state.
+// Images can only be retired once no node or subnet references them.
+func (s *Service) SetIpxeImageState(ctx context.Context, config *IpxeImageStateConfig) (*IpxeDbConfig, error) {
+	if !validImageState(config.ImageState) {
+		return nil, ValidationError{fmt.Sprintf("invalid image state: %s, expected one of %s", config.ImageState, strings.Join(ImageStates, ", "))}
+	}
+	return inTx(ctx, s.db, func(ctx context.Context) (*IpxeDbConfig, error) {
+		if config.ImageState == ImageStateRetired {
+			usage, err := s.db.GetIpxeImageUsage(ctx, &IpxeImageTagType{ImageTag: config.ImageTag, ImageType: config.ImageType})
+			if err != nil {
+				return nil, err
+			}
+			if usage.InUse() {
+				return nil, ImageInUseError{usage}
+			}
+		}
+		logging.FromContext(ctx).Info("setting image state", zap.String("image_tag", config.ImageTag), zap.String("image_type", config.ImageType), zap.String("image_state", config.ImageState))
+		return s.db.SetIpxeImageState(ctx, config)
+	})
+}
+
+// DeleteIpxeImage deletes an entry in ipxe.images matching image_tag and image_type.
+func (s *Service) DeleteIpxeImage(ctx context.Context, config *IpxeImageDeleteConfig) (*IpxeDbConfig, error) {
+	if config.Cascade && config.Reassign() {
+		return nil, ValidationError{"cascade and reassign are mutually exclusive"}
+	}
+	return inTx(ctx, s.db, func(ctx context.Context) (*IpxeDbConfig, error) {
+		if config.Reassign() {
+			target := IpxeImageTagType{ImageTag: config.ReassignImageTag, ImageType: config.ReassignImageType}
+			if target == (IpxeImageTagType{ImageTag: config.ImageTag, ImageType: config.ImageType}) {
+				return nil, ValidationError{"cannot reassign an image to itself"}
+			}
+			assignable := false
+			for _, image := range s.db.GetAvailableImages(ctx) {
+				if image == target {
+					assignable = true
+					break
+				}
+			}
+			if !assignable {
+				return nil, ValidationError{fmt.Sprintf("reassign image (%s, %s) doesn't exist or can't be assigned", target.ImageTag, target.ImageType)}
+			}
+		}
+		if !config.Reassign() {
+			usage, err := s.db.GetIpxeImageUsage(ctx, &IpxeImageTagType{ImageTag: config.ImageTag, ImageType: config.ImageType})
+			if err != nil {
+				return nil, err
+			}
+			if config.Cascade && len(usage.Channels) > 0 {
+				return nil, ImageInUseError{&IpxeImageUsage{
+					ImageTag:  usage.ImageTag,
+					ImageType: usage.ImageType,
+					ImageName: usage.ImageName,
+					Channels:  usage.Channels,
+				}}
+			}
+			if !config.Cascade && usage.InUse() {
+				return nil, ImageInUseError{usage}
+			}
+		}
+		idc, err := s.db.DeleteIpxeImage(ctx, config)
+		if err != nil {
+			logging.FromContext(ctx).Error("cannot delete image", zap.String("image_tag", config.ImageTag), zap.String("image_type", config.ImageType), zap.Error(err))
+			return nil, err
+		}
+		return idc, err
+	})
+}
@@ -0,0 +1,51 @@
+package ipxe
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestService_DeleteIpxeImage_inUse(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	db.EXPECT().GetIpxeImageUsage(gomock.Any(), &IpxeImageTagType{ImageTag: "develop", ImageType: "ci-test"}).Return(&IpxeImageUsage{
+		ImageTag:     "develop",
+		ImageType:    "ci-test",
+		MacAddresses: []string{"0c42a1b2c3d4"},
+	}, nil)
+
+	_, err := svc.DeleteIpxeImage(context.Background(), &IpxeImageDeleteConfig{ImageTag: "develop", ImageType: "ci-test"})
+
+	var inUseErr ImageInUseError
+	assert.True(t, errors.As(err, &inUseErr))
+	assert.Equal(t, []string{"0c42a1b2c3d4"}, inUseErr.Usage.MacAddresses)
+}
+
+func TestService_DeleteIpxeImage_reassign(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	config := &IpxeImageDeleteConfig{
+		ImageTag:          "develop",
+		ImageType:         "ci-test",
+		ReassignImageTag:  "master",
+		ReassignImageType: "ci-test",
+	}
+	db.EXPECT().GetAvailableImages(gomock.Any()).Return([]IpxeImageTagType{{ImageTag: "master", ImageType: "ci-test"}})
+	db.EXPECT().DeleteIpxeImage(gomock.Any(), config).Return(&IpxeDbConfig{ImageTag: "develop", ImageType: "ci-test"}, nil)
+
+	idc, err := svc.DeleteIpxeImage(context.Background(), config)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "develop", idc.ImageTag)
+}
+
+func TestService_SetIpxeImageState_invalid(t *testing.T) {
+	svc, _, _ := newTestService(t)
+
+	_, err := svc.SetIpxeImageState(context.Background(), &IpxeImageStateConfig{ImageTag: "develop", ImageType: "ci-test", ImageState: "gone"})
+
+	var validationErr ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+}
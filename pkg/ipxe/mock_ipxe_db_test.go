@@ -0,0 +1,496 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/coreweave/ncore-api/pkg/ipxe (interfaces: DB)
+
+// Package ipxe is a generated GoMock package.
+package ipxe
+
+import (
+	context "context"
+	reflect "reflect"
+
+	database "github.com/coreweave/ncore-api/pkg/database"
+	gomock "github.com/golang/mock/gomock"
+)
+
+// MockDB is a mock of DB interface.
+type MockDB struct {
+	ctrl     *gomock.Controller
+	recorder *MockDBMockRecorder
+}
+
+// MockDBMockRecorder is the mock recorder for MockDB.
+type MockDBMockRecorder struct {
+	mock *MockDB
+}
+
+// NewMockDB creates a new mock instance.
+func NewMockDB(ctrl *gomock.Controller) *MockDB {
+	mock := &MockDB{ctrl: ctrl}
+	mock.recorder = &MockDBMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockDB) EXPECT() *MockDBMockRecorder {
+	return m.recorder
+}
+
+// CreateImageRollout mocks base method.
+func (m *MockDB) CreateImageRollout(arg0 context.Context, arg1 *ImageRollout, arg2 map[string]int) (*ImageRollout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateImageRollout", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*ImageRollout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateImageRollout indicates an expected call of CreateImageRollout.
+func (mr *MockDBMockRecorder) CreateImageRollout(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateImageRollout", reflect.TypeOf((*MockDB)(nil).CreateImageRollout), arg0, arg1, arg2)
+}
+
+// CreateIpxeImage mocks base method.
+func (m *MockDB) CreateIpxeImage(arg0 context.Context, arg1 *IpxeDbConfig) (*IpxeConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIpxeImage", arg0, arg1)
+	ret0, _ := ret[0].(*IpxeConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateIpxeImage indicates an expected call of CreateIpxeImage.
+func (mr *MockDBMockRecorder) CreateIpxeImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIpxeImage", reflect.TypeOf((*MockDB)(nil).CreateIpxeImage), arg0, arg1)
+}
+
+// CreateNodeIpxeConfig mocks base method.
+func (m *MockDB) CreateNodeIpxeConfig(arg0 context.Context, arg1 *IpxeNodeDbConfig) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateNodeIpxeConfig", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateNodeIpxeConfig indicates an expected call of CreateNodeIpxeConfig.
+func (mr *MockDBMockRecorder) CreateNodeIpxeConfig(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateNodeIpxeConfig", reflect.TypeOf((*MockDB)(nil).CreateNodeIpxeConfig), arg0, arg1)
+}
+
+// DeleteImageChannel mocks base method.
+func (m *MockDB) DeleteImageChannel(arg0 context.Context, arg1 string) (*ImageChannel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteImageChannel", arg0, arg1)
+	ret0, _ := ret[0].(*ImageChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteImageChannel indicates an expected call of DeleteImageChannel.
+func (mr *MockDBMockRecorder) DeleteImageChannel(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteImageChannel", reflect.TypeOf((*MockDB)(nil).DeleteImageChannel), arg0, arg1)
+}
+
+// DeleteIpxeImage mocks base method.
+func (m *MockDB) DeleteIpxeImage(arg0 context.Context, arg1 *IpxeImageDeleteConfig) (*IpxeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteIpxeImage", arg0, arg1)
+	ret0, _ := ret[0].(*IpxeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteIpxeImage indicates an expected call of DeleteIpxeImage.
+func (mr *MockDBMockRecorder) DeleteIpxeImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteIpxeImage", reflect.TypeOf((*MockDB)(nil).DeleteIpxeImage), arg0, arg1)
+}
+
+// DeleteSubnetDefaultImage mocks base method.
+func (m *MockDB) DeleteSubnetDefaultImage(arg0 context.Context, arg1 string) (*SubnetDefaultImage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteSubnetDefaultImage", arg0, arg1)
+	ret0, _ := ret[0].(*SubnetDefaultImage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteSubnetDefaultImage indicates an expected call of DeleteSubnetDefaultImage.
+func (mr *MockDBMockRecorder) DeleteSubnetDefaultImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteSubnetDefaultImage", reflect.TypeOf((*MockDB)(nil).DeleteSubnetDefaultImage), arg0, arg1)
+}
+
+// GetAvailableImages mocks base method.
+func (m *MockDB) GetAvailableImages(arg0 context.Context) []IpxeImageTagType {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAvailableImages", arg0)
+	ret0, _ := ret[0].([]IpxeImageTagType)
+	return ret0
+}
+
+// GetAvailableImages indicates an expected call of GetAvailableImages.
+func (mr *MockDBMockRecorder) GetAvailableImages(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAvailableImages", reflect.TypeOf((*MockDB)(nil).GetAvailableImages), arg0)
+}
+
+// GetImageChannel mocks base method.
+func (m *MockDB) GetImageChannel(arg0 context.Context, arg1 string) (*ImageChannel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImageChannel", arg0, arg1)
+	ret0, _ := ret[0].(*ImageChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImageChannel indicates an expected call of GetImageChannel.
+func (mr *MockDBMockRecorder) GetImageChannel(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageChannel", reflect.TypeOf((*MockDB)(nil).GetImageChannel), arg0, arg1)
+}
+
+// GetImageRollout mocks base method.
+func (m *MockDB) GetImageRollout(arg0 context.Context, arg1 int64) (*ImageRollout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetImageRollout", arg0, arg1)
+	ret0, _ := ret[0].(*ImageRollout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetImageRollout indicates an expected call of GetImageRollout.
+func (mr *MockDBMockRecorder) GetImageRollout(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetImageRollout", reflect.TypeOf((*MockDB)(nil).GetImageRollout), arg0, arg1)
+}
+
+// GetIpxeDbConfig mocks base method.
+func (m *MockDB) GetIpxeDbConfig(arg0 context.Context, arg1 string) (*IpxeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIpxeDbConfig", arg0, arg1)
+	ret0, _ := ret[0].(*IpxeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIpxeDbConfig indicates an expected call of GetIpxeDbConfig.
+func (mr *MockDBMockRecorder) GetIpxeDbConfig(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIpxeDbConfig", reflect.TypeOf((*MockDB)(nil).GetIpxeDbConfig), arg0, arg1)
+}
+
+// GetIpxeImageUsage mocks base method.
+func (m *MockDB) GetIpxeImageUsage(arg0 context.Context, arg1 *IpxeImageTagType) (*IpxeImageUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetIpxeImageUsage", arg0, arg1)
+	ret0, _ := ret[0].(*IpxeImageUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetIpxeImageUsage indicates an expected call of GetIpxeImageUsage.
+func (mr *MockDBMockRecorder) GetIpxeImageUsage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetIpxeImageUsage", reflect.TypeOf((*MockDB)(nil).GetIpxeImageUsage), arg0, arg1)
+}
+
+// GetNodeImage mocks base method.
+func (m *MockDB) GetNodeImage(arg0 context.Context, arg1 string) (*IpxeNodeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNodeImage", arg0, arg1)
+	ret0, _ := ret[0].(*IpxeNodeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNodeImage indicates an expected call of GetNodeImage.
+func (mr *MockDBMockRecorder) GetNodeImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodeImage", reflect.TypeOf((*MockDB)(nil).GetNodeImage), arg0, arg1)
+}
+
+// GetSubnetDefaultIpxeDbConfig mocks base method.
+func (m *MockDB) GetSubnetDefaultIpxeDbConfig(arg0 context.Context, arg1 string) (*IpxeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubnetDefaultIpxeDbConfig", arg0, arg1)
+	ret0, _ := ret[0].(*IpxeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubnetDefaultIpxeDbConfig indicates an expected call of GetSubnetDefaultIpxeDbConfig.
+func (mr *MockDBMockRecorder) GetSubnetDefaultIpxeDbConfig(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubnetDefaultIpxeDbConfig", reflect.TypeOf((*MockDB)(nil).GetSubnetDefaultIpxeDbConfig), arg0, arg1)
+}
+
+// ListImageChannels mocks base method.
+func (m *MockDB) ListImageChannels(arg0 context.Context) ([]*ImageChannel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImageChannels", arg0)
+	ret0, _ := ret[0].([]*ImageChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImageChannels indicates an expected call of ListImageChannels.
+func (mr *MockDBMockRecorder) ListImageChannels(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImageChannels", reflect.TypeOf((*MockDB)(nil).ListImageChannels), arg0)
+}
+
+// ListImageRollouts mocks base method.
+func (m *MockDB) ListImageRollouts(arg0 context.Context, arg1 string) ([]*ImageRollout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListImageRollouts", arg0, arg1)
+	ret0, _ := ret[0].([]*ImageRollout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListImageRollouts indicates an expected call of ListImageRollouts.
+func (mr *MockDBMockRecorder) ListImageRollouts(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListImageRollouts", reflect.TypeOf((*MockDB)(nil).ListImageRollouts), arg0, arg1)
+}
+
+// ListIpxeImageUsage mocks base method.
+func (m *MockDB) ListIpxeImageUsage(arg0 context.Context, arg1 string) ([]*IpxeImageUsage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListIpxeImageUsage", arg0, arg1)
+	ret0, _ := ret[0].([]*IpxeImageUsage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListIpxeImageUsage indicates an expected call of ListIpxeImageUsage.
+func (mr *MockDBMockRecorder) ListIpxeImageUsage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIpxeImageUsage", reflect.TypeOf((*MockDB)(nil).ListIpxeImageUsage), arg0, arg1)
+}
+
+// ListIpxeImages mocks base method.
+func (m *MockDB) ListIpxeImages(arg0 context.Context) ([]*IpxeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListIpxeImages", arg0)
+	ret0, _ := ret[0].([]*IpxeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListIpxeImages indicates an expected call of ListIpxeImages.
+func (mr *MockDBMockRecorder) ListIpxeImages(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListIpxeImages", reflect.TypeOf((*MockDB)(nil).ListIpxeImages), arg0)
+}
+
+// ListNodeImageMacAddresses mocks base method.
+func (m *MockDB) ListNodeImageMacAddresses(arg0 context.Context, arg1 *IpxeImageTagType, arg2 []string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNodeImageMacAddresses", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNodeImageMacAddresses indicates an expected call of ListNodeImageMacAddresses.
+func (mr *MockDBMockRecorder) ListNodeImageMacAddresses(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodeImageMacAddresses", reflect.TypeOf((*MockDB)(nil).ListNodeImageMacAddresses), arg0, arg1, arg2)
+}
+
+// ListNodeImages mocks base method.
+func (m *MockDB) ListNodeImages(arg0 context.Context) ([]*IpxeNodeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNodeImages", arg0)
+	ret0, _ := ret[0].([]*IpxeNodeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNodeImages indicates an expected call of ListNodeImages.
+func (mr *MockDBMockRecorder) ListNodeImages(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodeImages", reflect.TypeOf((*MockDB)(nil).ListNodeImages), arg0)
+}
+
+// ListSubnetDefaultImages mocks base method.
+func (m *MockDB) ListSubnetDefaultImages(arg0 context.Context) ([]*SubnetDefaultImage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSubnetDefaultImages", arg0)
+	ret0, _ := ret[0].([]*SubnetDefaultImage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSubnetDefaultImages indicates an expected call of ListSubnetDefaultImages.
+func (mr *MockDBMockRecorder) ListSubnetDefaultImages(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSubnetDefaultImages", reflect.TypeOf((*MockDB)(nil).ListSubnetDefaultImages), arg0)
+}
+
+// PromoteImageChannel mocks base method.
+func (m *MockDB) PromoteImageChannel(arg0 context.Context, arg1 *ImageChannelPromoteConfig) (*ImageChannel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PromoteImageChannel", arg0, arg1)
+	ret0, _ := ret[0].(*ImageChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PromoteImageChannel indicates an expected call of PromoteImageChannel.
+func (mr *MockDBMockRecorder) PromoteImageChannel(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PromoteImageChannel", reflect.TypeOf((*MockDB)(nil).PromoteImageChannel), arg0, arg1)
+}
+
+// RollbackImageChannel mocks base method.
+func (m *MockDB) RollbackImageChannel(arg0 context.Context, arg1 string) (*ImageChannel, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RollbackImageChannel", arg0, arg1)
+	ret0, _ := ret[0].(*ImageChannel)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RollbackImageChannel indicates an expected call of RollbackImageChannel.
+func (mr *MockDBMockRecorder) RollbackImageChannel(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RollbackImageChannel", reflect.TypeOf((*MockDB)(nil).RollbackImageChannel), arg0, arg1)
+}
+
+// SetImageRolloutNodeStates mocks base method.
+func (m *MockDB) SetImageRolloutNodeStates(arg0 context.Context, arg1 int64, arg2 map[string]string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetImageRolloutNodeStates", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetImageRolloutNodeStates indicates an expected call of SetImageRolloutNodeStates.
+func (mr *MockDBMockRecorder) SetImageRolloutNodeStates(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetImageRolloutNodeStates", reflect.TypeOf((*MockDB)(nil).SetImageRolloutNodeStates), arg0, arg1, arg2)
+}
+
+// SetImageRolloutState mocks base method.
+func (m *MockDB) SetImageRolloutState(arg0 context.Context, arg1 int64, arg2 []string, arg3, arg4 string) (*ImageRollout, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetImageRolloutState", arg0, arg1, arg2, arg3, arg4)
+	ret0, _ := ret[0].(*ImageRollout)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetImageRolloutState indicates an expected call of SetImageRolloutState.
+func (mr *MockDBMockRecorder) SetImageRolloutState(arg0, arg1, arg2, arg3, arg4 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetImageRolloutState", reflect.TypeOf((*MockDB)(nil).SetImageRolloutState), arg0, arg1, arg2, arg3, arg4)
+}
+
+// SetIpxeImageState mocks base method.
+func (m *MockDB) SetIpxeImageState(arg0 context.Context, arg1 *IpxeImageStateConfig) (*IpxeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetIpxeImageState", arg0, arg1)
+	ret0, _ := ret[0].(*IpxeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetIpxeImageState indicates an expected call of SetIpxeImageState.
+func (mr *MockDBMockRecorder) SetIpxeImageState(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetIpxeImageState", reflect.TypeOf((*MockDB)(nil).SetIpxeImageState), arg0, arg1)
+}
+
+// SetSubnetDefaultImage mocks base method.
+func (m *MockDB) SetSubnetDefaultImage(arg0 context.Context, arg1 *SubnetDefaultImage) (*SubnetDefaultImage, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetSubnetDefaultImage", arg0, arg1)
+	ret0, _ := ret[0].(*SubnetDefaultImage)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// SetSubnetDefaultImage indicates an expected call of SetSubnetDefaultImage.
+func (mr *MockDBMockRecorder) SetSubnetDefaultImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetSubnetDefaultImage", reflect.TypeOf((*MockDB)(nil).SetSubnetDefaultImage), arg0, arg1)
+}
+
+// StartImageRolloutWave mocks base method.
+func (m *MockDB) StartImageRolloutWave(arg0 context.Context, arg1 int64, arg2 string) ([]*ImageRolloutNode, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "StartImageRolloutWave", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]*ImageRolloutNode)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// StartImageRolloutWave indicates an expected call of StartImageRolloutWave.
+func (mr *MockDBMockRecorder) StartImageRolloutWave(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "StartImageRolloutWave", reflect.TypeOf((*MockDB)(nil).StartImageRolloutWave), arg0, arg1, arg2)
+}
+
+// UpdateIpxeImage mocks base method.
+func (m *MockDB) UpdateIpxeImage(arg0 context.Context, arg1 *IpxeDbConfig) (*IpxeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateIpxeImage", arg0, arg1)
+	ret0, _ := ret[0].(*IpxeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateIpxeImage indicates an expected call of UpdateIpxeImage.
+func (mr *MockDBMockRecorder) UpdateIpxeImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateIpxeImage", reflect.TypeOf((*MockDB)(nil).UpdateIpxeImage), arg0, arg1)
+}
+
+// UpdateNodeImage mocks base method.
+func (m *MockDB) UpdateNodeImage(arg0 context.Context, arg1 *IpxeNodeDbConfig) (*IpxeNodeDbConfig, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateNodeImage", arg0, arg1)
+	ret0, _ := ret[0].(*IpxeNodeDbConfig)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateNodeImage indicates an expected call of UpdateNodeImage.
+func (mr *MockDBMockRecorder) UpdateNodeImage(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateNodeImage", reflect.TypeOf((*MockDB)(nil).UpdateNodeImage), arg0, arg1)
+}
+
+// WithAcquire mocks base method.
+func (m *MockDB) WithAcquire(arg0 context.Context, arg1 func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithAcquire", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithAcquire indicates an expected call of WithAcquire.
+func (mr *MockDBMockRecorder) WithAcquire(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithAcquire", reflect.TypeOf((*MockDB)(nil).WithAcquire), arg0, arg1)
+}
+
+// WithTx mocks base method.
+func (m *MockDB) WithTx(arg0 context.Context, arg1 database.TxOptions, arg2 func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockDBMockRecorder) WithTx(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockDB)(nil).WithTx), arg0, arg1, arg2)
+}
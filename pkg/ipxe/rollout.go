@@ -0,0 +1,449 @@
+package ipxe
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"go.uber.org/zap"
+)
+
+// Image rollout states stored in ipxe.image_rollouts.rollout_state.
+//
+//	pending -> in_progress -> wave_complete -> in_progress -> ... -> completed
+//
+// Advancing starts the next wave and assigns the target image to its nodes.
+// A wave is complete once every node of the wave sent a heartbeat after being updated.
+// Rollouts halt automatically when more than MaxFailures nodes miss the heartbeat deadline,
+// halted rollouts can only be rolled back, which restores the previous image of every updated node.
+const (
+	RolloutStatePending      = "pending"
+	RolloutStateInProgress   = "in_progress"
+	RolloutStateWaveComplete = "wave_complete"
+	RolloutStateCompleted    = "completed"
+	RolloutStateHalted       = "halted"
+	RolloutStateRolledBack   = "rolled_back"
+)
+
+// Rollout node states stored in ipxe.image_rollout_nodes.node_state.
+const (
+	RolloutNodePending    = "pending"
+	RolloutNodeUpdated    = "updated"
+	RolloutNodeHealthy    = "healthy"
+	RolloutNodeFailed     = "failed"
+	RolloutNodeRolledBack = "rolled_back"
+)
+
+// HeartbeatSource returns the last heartbeat of nodes, see nodes.Service.
+type HeartbeatSource interface {
+	GetNodesLastSeen(ctx context.Context, macAddresses []string) (map[string]time.Time, error)
+}
+
+// ImageRolloutConfig creates a rollout of (ImageTag, ImageType).
+// The rollout covers either the nodes currently assigned (SourceImageTag, SourceImageType) or MacAddresses.
+type ImageRolloutConfig struct {
+	SourceImageTag  string
+	SourceImageType string
+	MacAddresses    []string
+	ImageTag        string
+	ImageType       string
+	// Waves are cumulative percentages of the rollout nodes, the last wave must be 100.
+	Waves                 []int
+	HeartbeatDeadlineSecs int64
+	MaxFailures           int
+}
+
+// ImageRollout is an entry in ipxe.image_rollouts.
+type ImageRollout struct {
+	RolloutId             int64
+	SourceImageTag        string `json:",omitempty"`
+	SourceImageType       string `json:",omitempty"`
+	ImageTag              string
+	ImageType             string
+	Waves                 []int
+	CurrentWave           int
+	RolloutState          string
+	HeartbeatDeadlineSecs int64
+	MaxFailures           int
+	HaltReason            string              `json:",omitempty"`
+	WaveStartedAt         *time.Time          `json:",omitempty"`
+	Nodes                 []*ImageRolloutNode `json:",omitempty"`
+}
+
+// ImageRolloutNode is an entry in ipxe.image_rollout_nodes.
+type ImageRolloutNode struct {
+	MacAddress           string
+	Wave                 int
+	NodeState            string
+	UpdatedAt            *time.Time `json:",omitempty"`
+	PreviousImageTag     string     `json:",omitempty"`
+	PreviousImageType    string     `json:",omitempty"`
+	PreviousImageChannel string     `json:",omitempty"`
+	// PreviousEnrollment is the enrollment restored with the previous image, assigned when empty.
+	PreviousEnrollment string `json:",omitempty"`
+}
+
+// rolloutWaves assigns each of macAddresses the 1-based wave updating it.
+// Nodes are ordered by mac_address so the assignment doesn't depend on the request order.
+func rolloutWaves(macAddresses []string, waves []int) map[string]int {
+	sorted := append([]string(nil), macAddresses...)
+	sort.Strings(sorted)
+	assigned := make(map[string]int, len(sorted))
+	next := 0
+	for i, percentage := range waves {
+		end := (len(sorted)*percentage + 99) / 100
+		for ; next < end; next++ {
+			assigned[sorted[next]] = i + 1
+		}
+	}
+	return assigned
+}
+
+func validateRolloutConfig(config *ImageRolloutConfig) error {
+	var errors []string
+	if config.ImageTag == "" || config.ImageType == "" {
+		errors = append(errors, "missing ImageTag or ImageType")
+	}
+	source := config.SourceImageTag != "" || config.SourceImageType != ""
+	switch {
+	case source && len(config.MacAddresses) > 0:
+		errors = append(errors, "SourceImageTag/SourceImageType and MacAddresses are mutually exclusive")
+	case source && (config.SourceImageTag == "" || config.SourceImageType == ""):
+		errors = append(errors, "missing SourceImageTag or SourceImageType")
+	case !source && len(config.MacAddresses) == 0:
+		errors = append(errors, "missing SourceImageTag/SourceImageType or MacAddresses")
+	case source && config.SourceImageTag == config.ImageTag && config.SourceImageType == config.ImageType:
+		errors = append(errors, "source and target image are the same")
+	}
+	if len(config.Waves) == 0 {
+		errors = append(errors, "missing Waves")
+	}
+	for i, percentage := range config.Waves {
+		if percentage <= 0 || percentage > 100 || (i > 0 && percentage <= config.Waves[i-1]) {
+			errors = append(errors, "Waves must be increasing percentages between 1 and 100")
+			break
+		}
+	}
+	if len(config.Waves) > 0 && config.Waves[len(config.Waves)-1] != 100 {
+		errors = append(errors, "last wave must be 100")
+	}
+	if config.HeartbeatDeadlineSecs <= 0 {
+		errors = append(errors, "HeartbeatDeadlineSecs must be positive")
+	}
+	if config.MaxFailures < 0 {
+		errors = append(errors, "MaxFailures can't be negative")
+	}
+	if len(errors) > 0 {
+		return ValidationError{strings.Join(errors, ", ")}
+	}
+	return nil
+}
+
+// SetHeartbeatSource sets where rollouts read node heartbeats from.
+func (s *Service) SetHeartbeatSource(heartbeats HeartbeatSource) {
+	s.heartbeats = heartbeats
+}
+
+// CreateImageRollout snapshots the rollout nodes and assigns them to waves. No node is updated before AdvanceImageRollout.
+// The image check, the node snapshot and the insert run in one transaction.
+func (s *Service) CreateImageRollout(ctx context.Context, config *ImageRolloutConfig) (*ImageRollout, error) {
+	return inTx(ctx, s.db, func(ctx context.Context) (*ImageRollout, error) {
+		return s.createImageRollout(ctx, config)
+	})
+}
+
+func (s *Service) createImageRollout(ctx context.Context, config *ImageRolloutConfig) (*ImageRollout, error) {
+	if err := validateRolloutConfig(config); err != nil {
+		return nil, err
+	}
+	if err := s.checkImageTarget(ctx, config.ImageTag, config.ImageType, ""); err != nil {
+		return nil, err
+	}
+	var source *IpxeImageTagType
+	if config.SourceImageTag != "" {
+		source = &IpxeImageTagType{ImageTag: config.SourceImageTag, ImageType: config.SourceImageType}
+	}
+	macAddresses, err := s.db.ListNodeImageMacAddresses(ctx, source, config.MacAddresses)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		known := map[string]bool{}
+		for _, macAddress := range macAddresses {
+			known[macAddress] = true
+		}
+		var unknown []string
+		for _, macAddress := range config.MacAddresses {
+			if !known[macAddress] {
+				unknown = append(unknown, macAddress)
+			}
+		}
+		if len(unknown) > 0 {
+			return nil, ValidationError{fmt.Sprintf("mac_address not in node_images: %s", strings.Join(unknown, ", "))}
+		}
+	}
+	if len(macAddresses) == 0 {
+		return nil, ValidationError{"no nodes to roll out to"}
+	}
+	rollout := &ImageRollout{
+		SourceImageTag:        config.SourceImageTag,
+		SourceImageType:       config.SourceImageType,
+		ImageTag:              config.ImageTag,
+		ImageType:             config.ImageType,
+		Waves:                 config.Waves,
+		HeartbeatDeadlineSecs: config.HeartbeatDeadlineSecs,
+		MaxFailures:           config.MaxFailures,
+	}
+	logging.FromContext(ctx).Info("creating image rollout", zap.String("image_tag", config.ImageTag), zap.String("image_type", config.ImageType), zap.Int("nodes", len(macAddresses)), zap.Ints("waves", config.Waves))
+	return s.db.CreateImageRollout(ctx, rollout, rolloutWaves(macAddresses, config.Waves))
+}
+
+// GetImageRollout returns a rollout and its nodes.
+func (s *Service) GetImageRollout(ctx context.Context, rolloutId int64) (*ImageRollout, error) {
+	return s.db.GetImageRollout(ctx, rolloutId)
+}
+
+// ListImageRollouts returns every rollout in rolloutState, or all rollouts when rolloutState is empty.
+func (s *Service) ListImageRollouts(ctx context.Context, rolloutState string) ([]*ImageRollout, error) {
+	return s.db.ListImageRollouts(ctx, rolloutState)
+}
+
+// AdvanceImageRollout starts the next wave of a pending rollout or of a rollout whose current wave is complete,
+// and assigns the target image to the nodes of that wave.
+// The wave starts in one transaction, a database error leaves the wave unstarted and its nodes unchanged.
+// Nodes deleted from node_images since the rollout was created are marked failed.
+// The rollout is evaluated first in a transaction of its own, so a halt or a completed wave is kept when the
+// rollout then can't advance.
+func (s *Service) AdvanceImageRollout(ctx context.Context, rolloutId int64) (*ImageRollout, error) {
+	if _, err := s.EvaluateImageRollout(ctx, rolloutId); err != nil {
+		return nil, err
+	}
+	return inTx(ctx, s.db, func(ctx context.Context) (*ImageRollout, error) {
+		return s.advanceImageRollout(ctx, rolloutId)
+	})
+}
+
+func (s *Service) advanceImageRollout(ctx context.Context, rolloutId int64) (*ImageRollout, error) {
+	rollout, err := s.db.GetImageRollout(ctx, rolloutId)
+	if err != nil {
+		return nil, err
+	}
+	if rollout.RolloutState != RolloutStatePending && rollout.RolloutState != RolloutStateWaveComplete {
+		return nil, ValidationError{fmt.Sprintf("rollout %d is %s, only pending and wave_complete rollouts can advance", rolloutId, rollout.RolloutState)}
+	}
+	if err := s.checkImageTarget(ctx, rollout.ImageTag, rollout.ImageType, ""); err != nil {
+		return nil, err
+	}
+	wave, err := s.db.StartImageRolloutWave(ctx, rolloutId, rollout.RolloutState)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("advancing image rollout", zap.Int64("rollout_id", rolloutId), zap.Int("wave", rollout.CurrentWave+1), zap.Int("nodes", len(wave)))
+	states := map[string]string{}
+	for _, node := range wave {
+		_, err := s.db.UpdateNodeImage(ctx, &IpxeNodeDbConfig{
+			ImageTag:   rollout.ImageTag,
+			ImageType:  rollout.ImageType,
+			MacAddress: node.MacAddress,
+		})
+		switch {
+		case errdefs.IsNotFound(err):
+			// the node_images entry was deleted since the rollout was created, no statement failed
+			logging.FromContext(ctx).Warn("rollout node not in node_images", zap.Int64("rollout_id", rolloutId), zap.String("mac_address", node.MacAddress))
+			states[node.MacAddress] = RolloutNodeFailed
+		case err != nil:
+			// A failed statement aborts the transaction, the wave isn't started and no node is updated.
+			logging.FromContext(ctx).Error("cannot update rollout node", zap.Int64("rollout_id", rolloutId), zap.String("mac_address", node.MacAddress), zap.Error(err))
+			return nil, err
+		default:
+			states[node.MacAddress] = RolloutNodeUpdated
+		}
+	}
+	if err := s.db.SetImageRolloutNodeStates(ctx, rolloutId, states); err != nil {
+		return nil, err
+	}
+	return s.EvaluateImageRollout(ctx, rolloutId)
+}
+
+// EvaluateImageRollout checks the heartbeats of the updated nodes of an in progress rollout.
+// Nodes with a heartbeat after their update are healthy, nodes without one past the deadline failed.
+// The rollout halts once more than MaxFailures nodes failed and its wave completes once no node is left waiting.
+func (s *Service) EvaluateImageRollout(ctx context.Context, rolloutId int64) (*ImageRollout, error) {
+	return inTx(ctx, s.db, func(ctx context.Context) (*ImageRollout, error) {
+		return s.evaluateImageRollout(ctx, rolloutId)
+	})
+}
+
+func (s *Service) evaluateImageRollout(ctx context.Context, rolloutId int64) (*ImageRollout, error) {
+	rollout, err := s.db.GetImageRollout(ctx, rolloutId)
+	if err != nil {
+		return nil, err
+	}
+	if rollout.RolloutState != RolloutStateInProgress {
+		return rollout, nil
+	}
+	if s.heartbeats == nil {
+		return nil, fmt.Errorf("cannot evaluate rollout %d: no heartbeat source", rolloutId)
+	}
+	var waiting []string
+	for _, node := range rollout.Nodes {
+		if node.NodeState == RolloutNodeUpdated {
+			waiting = append(waiting, node.MacAddress)
+		}
+	}
+	lastSeen, err := s.heartbeats.GetNodesLastSeen(ctx, waiting)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	deadline := time.Duration(rollout.HeartbeatDeadlineSecs) * time.Second
+	states := map[string]string{}
+	failed, pending := 0, 0
+	for _, node := range rollout.Nodes {
+		switch {
+		case node.NodeState == RolloutNodeFailed:
+			failed++
+		case node.NodeState != RolloutNodeUpdated || node.UpdatedAt == nil:
+		case lastSeen[node.MacAddress].After(*node.UpdatedAt):
+			states[node.MacAddress] = RolloutNodeHealthy
+		case now.After(node.UpdatedAt.Add(deadline)):
+			states[node.MacAddress] = RolloutNodeFailed
+			failed++
+		default:
+			pending++
+		}
+	}
+	if len(states) > 0 {
+		if err := s.db.SetImageRolloutNodeStates(ctx, rolloutId, states); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case failed > rollout.MaxFailures:
+		reason := fmt.Sprintf("%d nodes failed to check in within %s, max %d", failed, deadline, rollout.MaxFailures)
+		logging.FromContext(ctx).Warn("halting image rollout", zap.Int64("rollout_id", rolloutId), zap.String("reason", reason))
+		_, err = s.db.SetImageRolloutState(ctx, rolloutId, []string{RolloutStateInProgress}, RolloutStateHalted, reason)
+	case pending == 0 && rollout.CurrentWave == len(rollout.Waves):
+		logging.FromContext(ctx).Info("image rollout completed", zap.Int64("rollout_id", rolloutId))
+		_, err = s.db.SetImageRolloutState(ctx, rolloutId, []string{RolloutStateInProgress}, RolloutStateCompleted, "")
+	case pending == 0:
+		logging.FromContext(ctx).Info("image rollout wave complete", zap.Int64("rollout_id", rolloutId), zap.Int("wave", rollout.CurrentWave))
+		_, err = s.db.SetImageRolloutState(ctx, rolloutId, []string{RolloutStateInProgress}, RolloutStateWaveComplete, "")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.db.GetImageRollout(ctx, rolloutId)
+}
+
+// HaltImageRollout stops a rollout from advancing.
+func (s *Service) HaltImageRollout(ctx context.Context, rolloutId int64, reason string) (*ImageRollout, error) {
+	if reason == "" {
+		reason = "halted manually"
+	}
+	logging.FromContext(ctx).Info("halting image rollout", zap.Int64("rollout_id", rolloutId), zap.String("reason", reason))
+	return s.db.SetImageRolloutState(ctx, rolloutId,
+		[]string{RolloutStatePending, RolloutStateInProgress, RolloutStateWaveComplete}, RolloutStateHalted, reason)
+}
+
+// RollbackImageRollout halts a rollout and restores the previous assignment and enrollment of every node it updated.
+// Like advancing, it is all or nothing: a database error rolls back every node.
+func (s *Service) RollbackImageRollout(ctx context.Context, rolloutId int64) (*ImageRollout, error) {
+	return inTx(ctx, s.db, func(ctx context.Context) (*ImageRollout, error) {
+		return s.rollbackImageRollout(ctx, rolloutId)
+	})
+}
+
+func (s *Service) rollbackImageRollout(ctx context.Context, rolloutId int64) (*ImageRollout, error) {
+	rollout, err := s.db.GetImageRollout(ctx, rolloutId)
+	if err != nil {
+		return nil, err
+	}
+	if rollout.RolloutState == RolloutStateRolledBack {
+		return rollout, nil
+	}
+	if rollout.RolloutState != RolloutStateHalted {
+		if _, err := s.HaltImageRollout(ctx, rolloutId, "rolling back"); err != nil {
+			return nil, err
+		}
+	}
+	states := map[string]string{}
+	for _, node := range rollout.Nodes {
+		if node.NodeState == RolloutNodePending || node.NodeState == RolloutNodeRolledBack {
+			continue
+		}
+		_, err := s.db.UpdateNodeImage(ctx, &IpxeNodeDbConfig{
+			ImageTag:     node.PreviousImageTag,
+			ImageType:    node.PreviousImageType,
+			ImageChannel: node.PreviousImageChannel,
+			MacAddress:   node.MacAddress,
+			Enrollment:   node.PreviousEnrollment,
+		})
+		switch {
+		case errdefs.IsNotFound(err):
+			logging.FromContext(ctx).Warn("rollout node not in node_images", zap.Int64("rollout_id", rolloutId), zap.String("mac_address", node.MacAddress))
+			continue
+		case err != nil:
+			// A failed statement aborts the transaction, the rollout and its nodes are left unchanged.
+			logging.FromContext(ctx).Error("cannot restore rollout node", zap.Int64("rollout_id", rolloutId), zap.String("mac_address", node.MacAddress), zap.Error(err))
+			return nil, err
+		}
+		states[node.MacAddress] = RolloutNodeRolledBack
+	}
+	if err := s.db.SetImageRolloutNodeStates(ctx, rolloutId, states); err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("rolled back image rollout", zap.Int64("rollout_id", rolloutId), zap.Int("nodes", len(states)))
+	if _, err := s.db.SetImageRolloutState(ctx, rolloutId, []string{RolloutStateHalted}, RolloutStateRolledBack, ""); err != nil {
+		return nil, err
+	}
+	return s.db.GetImageRollout(ctx, rolloutId)
+}
+
+// RolloutWatcher periodically evaluates in progress rollouts so they halt or complete without API calls.
+type RolloutWatcher struct {
+	svc      *Service
+	interval time.Duration
+}
+
+// NewRolloutWatcher creates a RolloutWatcher evaluating rollouts every interval.
+func NewRolloutWatcher(svc *Service, interval time.Duration) *RolloutWatcher {
+	return &RolloutWatcher{
+		svc:      svc,
+		interval: interval,
+	}
+}
+
+// Run evaluates in progress rollouts every interval until ctx is done.
+func (rw *RolloutWatcher) Run(ctx context.Context) {
+	logging.FromContext(ctx).Info("starting rollout watcher", zap.Duration("interval", rw.interval))
+	ticker := time.NewTicker(rw.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		// A single connection for the whole pass instead of one per statement.
+		err := rw.svc.db.WithAcquire(ctx, func(ctx context.Context) error {
+			rollouts, err := rw.svc.ListImageRollouts(ctx, RolloutStateInProgress)
+			if err != nil {
+				return fmt.Errorf("cannot list rollouts: %w", err)
+			}
+			for _, rollout := range rollouts {
+				if _, err := rw.svc.EvaluateImageRollout(ctx, rollout.RolloutId); err != nil {
+					logging.FromContext(ctx).Error("cannot evaluate image rollout", zap.Int64("rollout_id", rollout.RolloutId), zap.Error(err))
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			logging.FromContext(ctx).Error("rollout watcher pass failed", zap.Error(err))
+		}
+	}
+}
@@ -0,0 +1,179 @@
+package ipxe
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/s3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+type staticHeartbeats map[string]time.Time
+
+func (h staticHeartbeats) GetNodesLastSeen(ctx context.Context, macAddresses []string) (map[string]time.Time, error) {
+	return h, nil
+}
+
+func TestRolloutWaves(t *testing.T) {
+	macAddresses := []string{"000000000004", "000000000001", "000000000003", "000000000002"}
+
+	assert.Equal(t, map[string]int{
+		"000000000001": 1,
+		"000000000002": 2,
+		"000000000003": 3,
+		"000000000004": 3,
+	}, rolloutWaves(macAddresses, []int{10, 50, 100}))
+}
+
+func TestValidateRolloutConfig(t *testing.T) {
+	config := &ImageRolloutConfig{
+		SourceImageTag:        "master",
+		SourceImageType:       "ci-test",
+		ImageTag:              "develop",
+		ImageType:             "ci-test",
+		Waves:                 []int{10, 50, 100},
+		HeartbeatDeadlineSecs: 600,
+	}
+	assert.NoError(t, validateRolloutConfig(config))
+
+	config.Waves = []int{50, 10, 100}
+	assert.Error(t, validateRolloutConfig(config))
+
+	config.Waves = []int{10, 50}
+	assert.Error(t, validateRolloutConfig(config))
+}
+
+func TestService_EvaluateImageRollout_halt(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	updatedAt := time.Now().Add(-time.Hour)
+	svc.SetHeartbeatSource(staticHeartbeats{
+		"000000000001": updatedAt.Add(time.Minute),
+		"000000000002": updatedAt.Add(-time.Minute),
+	})
+	rollout := &ImageRollout{
+		RolloutId:             1,
+		ImageTag:              "develop",
+		ImageType:             "ci-test",
+		Waves:                 []int{50, 100},
+		CurrentWave:           1,
+		RolloutState:          RolloutStateInProgress,
+		HeartbeatDeadlineSecs: 600,
+		Nodes: []*ImageRolloutNode{
+			{MacAddress: "000000000001", Wave: 1, NodeState: RolloutNodeUpdated, UpdatedAt: &updatedAt},
+			{MacAddress: "000000000002", Wave: 1, NodeState: RolloutNodeUpdated, UpdatedAt: &updatedAt},
+			{MacAddress: "000000000003", Wave: 2, NodeState: RolloutNodePending},
+		},
+	}
+	gomock.InOrder(
+		db.EXPECT().GetImageRollout(gomock.Any(), int64(1)).Return(rollout, nil),
+		db.EXPECT().SetImageRolloutNodeStates(gomock.Any(), int64(1), map[string]string{
+			"000000000001": RolloutNodeHealthy,
+			"000000000002": RolloutNodeFailed,
+		}).Return(nil),
+		db.EXPECT().SetImageRolloutState(gomock.Any(), int64(1), []string{RolloutStateInProgress}, RolloutStateHalted, gomock.Any()).Return(&ImageRollout{}, nil),
+		db.EXPECT().GetImageRollout(gomock.Any(), int64(1)).Return(&ImageRollout{RolloutState: RolloutStateHalted}, nil),
+	)
+
+	got, err := svc.EvaluateImageRollout(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, RolloutStateHalted, got.RolloutState)
+}
+
+func TestService_AdvanceImageRollout_updateFailed(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	rollout := &ImageRollout{RolloutId: 1, ImageTag: "develop", ImageType: "ci-test", Waves: []int{100}, RolloutState: RolloutStatePending}
+	gomock.InOrder(
+		db.EXPECT().GetImageRollout(gomock.Any(), int64(1)).Return(rollout, nil),
+		db.EXPECT().GetImageRollout(gomock.Any(), int64(1)).Return(rollout, nil),
+		db.EXPECT().GetAvailableImages(gomock.Any()).Return([]IpxeImageTagType{{ImageTag: "develop", ImageType: "ci-test"}}),
+		db.EXPECT().StartImageRolloutWave(gomock.Any(), int64(1), RolloutStatePending).Return([]*ImageRolloutNode{
+			{MacAddress: "000000000001", Wave: 1, NodeState: RolloutNodePending},
+			{MacAddress: "000000000002", Wave: 1, NodeState: RolloutNodePending},
+		}, nil),
+		db.EXPECT().UpdateNodeImage(gomock.Any(), gomock.Any()).Return(nil, errdefs.Unavailable("database_unavailable", "statement failed")),
+	)
+
+	_, err := svc.AdvanceImageRollout(context.Background(), 1)
+
+	assert.True(t, errdefs.IsUnavailable(err), "the aborted transaction is not used to mark nodes failed")
+}
+
+func TestService_AdvanceImageRollout_keepsHalt(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	db := NewMockDB(ctrl)
+	var txs []error
+	db.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ database.TxOptions, fn func(ctx context.Context) error) error {
+			err := fn(ctx)
+			txs = append(txs, err)
+			return err
+		}).AnyTimes()
+	svc := NewService(db, s3.NewMemoryStore("https://objects.test"), "templates/template_ramdisk_https.ipxe", "default-image", "default-tag", "default-type", "default-bucket")
+	updatedAt := time.Now().Add(-time.Hour)
+	svc.SetHeartbeatSource(staticHeartbeats{})
+	rollout := &ImageRollout{
+		RolloutId:             1,
+		Waves:                 []int{50, 100},
+		CurrentWave:           1,
+		RolloutState:          RolloutStateInProgress,
+		HeartbeatDeadlineSecs: 600,
+		Nodes: []*ImageRolloutNode{
+			{MacAddress: "000000000001", Wave: 1, NodeState: RolloutNodeUpdated, UpdatedAt: &updatedAt},
+			{MacAddress: "000000000002", Wave: 2, NodeState: RolloutNodePending},
+		},
+	}
+	halted := &ImageRollout{RolloutId: 1, RolloutState: RolloutStateHalted}
+	gomock.InOrder(
+		db.EXPECT().GetImageRollout(gomock.Any(), int64(1)).Return(rollout, nil),
+		db.EXPECT().SetImageRolloutNodeStates(gomock.Any(), int64(1), map[string]string{"000000000001": RolloutNodeFailed}).Return(nil),
+		db.EXPECT().SetImageRolloutState(gomock.Any(), int64(1), []string{RolloutStateInProgress}, RolloutStateHalted, gomock.Any()).Return(halted, nil),
+		db.EXPECT().GetImageRollout(gomock.Any(), int64(1)).Return(halted, nil),
+		db.EXPECT().GetImageRollout(gomock.Any(), int64(1)).Return(halted, nil),
+	)
+
+	_, err := svc.AdvanceImageRollout(context.Background(), 1)
+
+	assert.ErrorIs(t, err, errdefs.ErrInvalid)
+	if assert.Len(t, txs, 2) {
+		assert.NoError(t, txs[0], "the halt commits before the advance is refused")
+		assert.Error(t, txs[1])
+	}
+}
+
+func TestService_RollbackImageRollout_enrollment(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	rollout := &ImageRollout{
+		RolloutId:    1,
+		ImageTag:     "develop",
+		ImageType:    "ci-test",
+		Waves:        []int{50, 100},
+		RolloutState: RolloutStateHalted,
+		Nodes: []*ImageRolloutNode{
+			{MacAddress: "000000000001", Wave: 1, NodeState: RolloutNodeHealthy, PreviousImageChannel: "stable", PreviousEnrollment: enrollment.Following},
+			{MacAddress: "000000000002", Wave: 1, NodeState: RolloutNodeFailed, PreviousImageTag: "release", PreviousImageType: "ci-test", PreviousEnrollment: enrollment.Pinned},
+			{MacAddress: "000000000003", Wave: 2, NodeState: RolloutNodePending},
+		},
+	}
+	gomock.InOrder(
+		db.EXPECT().GetImageRollout(gomock.Any(), int64(1)).Return(rollout, nil),
+		db.EXPECT().UpdateNodeImage(gomock.Any(), &IpxeNodeDbConfig{MacAddress: "000000000001", ImageChannel: "stable", Enrollment: enrollment.Following}).Return(nil, nil),
+		db.EXPECT().UpdateNodeImage(gomock.Any(), &IpxeNodeDbConfig{MacAddress: "000000000002", ImageTag: "release", ImageType: "ci-test", Enrollment: enrollment.Pinned}).Return(nil, nil),
+		db.EXPECT().SetImageRolloutNodeStates(gomock.Any(), int64(1), map[string]string{
+			"000000000001": RolloutNodeRolledBack,
+			"000000000002": RolloutNodeRolledBack,
+		}).Return(nil),
+		db.EXPECT().SetImageRolloutState(gomock.Any(), int64(1), []string{RolloutStateHalted}, RolloutStateRolledBack, "").Return(&ImageRollout{}, nil),
+		db.EXPECT().GetImageRollout(gomock.Any(), int64(1)).Return(&ImageRollout{RolloutState: RolloutStateRolledBack}, nil),
+	)
+
+	got, err := svc.RollbackImageRollout(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, RolloutStateRolledBack, got.RolloutState)
+}
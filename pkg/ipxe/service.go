@@ -2,27 +2,30 @@ package ipxe
 
 import (
 	"context"
-	"log"
 
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/logging"
 	"github.com/coreweave/ncore-api/pkg/s3"
+	"github.com/coreweave/ncore-api/pkg/snapshot"
+	"go.uber.org/zap"
 )
 
 // NewService creates an API service.
 func NewService(
 	db DB,
-	s3Svc s3.S3Svc,
-	s3Presigner s3.HttpPresigner,
+	objectStore s3.ObjectStore,
 	ipxeTemplateFile string,
 	ipxeDefaultImage string,
 	ipxeDefaultImageTag string,
 	ipxeDefaultImageType string,
 	ipxeDefaultBucket string,
 ) *Service {
-	log.Printf("Starting Ipxe service")
+	zap.L().Info("starting ipxe service")
 	return &Service{
 		db:                   db,
-		s3Svc:                s3Svc,
-		s3Presigner:          s3Presigner,
+		objectStore:          objectStore,
 		ipxeTemplateFile:     ipxeTemplateFile,
 		ipxeDefaultImage:     ipxeDefaultImage,
 		ipxeDefaultImageTag:  ipxeDefaultImageTag,
@@ -34,27 +37,107 @@ func NewService(
 // Service for the API.
 type Service struct {
 	db                   DB
-	s3Svc                s3.S3Svc
-	s3Presigner          s3.HttpPresigner
+	objectStore          s3.ObjectStore
 	ipxeTemplateFile     string
 	ipxeDefaultImage     string
 	ipxeDefaultImageTag  string
 	ipxeDefaultImageType string
 	ipxeDefaultBucket    string
+	heartbeats           HeartbeatSource
+	snapshots            *snapshot.Store[*Snapshot]
+	enrollmentPolicy     enrollment.Policy
+	registrar            enrollment.Registrar
+	discoveryImage       string
+	nodes                NodeResolver
+	hostnames            HostnameSource
 }
 
+// NodeResolver returns the mac_address keying the entries of the node macAddress is attached to, see nodes.Service.
+type NodeResolver interface {
+	ResolveMacAddress(ctx context.Context, macAddress string) (string, error)
+}
+
+// SetNodeResolver sets how the mac_address of a node interface is resolved to the one keying its node_images entry.
+// Every mac_address keys its own entry without one.
+func (s *Service) SetNodeResolver(nodes NodeResolver) {
+	s.nodes = nodes
+}
+
+// resolveMacAddress returns the mac_address keying the node_images entry of macAddress.
+func (s *Service) resolveMacAddress(ctx context.Context, macAddress string) (string, error) {
+	if s.nodes == nil {
+		return macAddress, nil
+	}
+	return s.nodes.ResolveMacAddress(ctx, macAddress)
+}
+
+// bootMacAddress is resolveMacAddress falling back to macAddress so nodes boot while the nodes database is unavailable.
+func (s *Service) bootMacAddress(ctx context.Context, macAddress string) string {
+	resolved, err := s.resolveMacAddress(ctx, macAddress)
+	if err != nil {
+		logging.FromContext(ctx).Warn("cannot resolve node mac_address", zap.String("mac_address", macAddress), zap.Error(err))
+		return macAddress
+	}
+	return resolved
+}
+
+// CodeNodeImageNotFound is the errdefs code of the error returned for a node without a node_images entry,
+// as opposed to a node whose image can't be found.
+const CodeNodeImageNotFound = "node_image_not_found"
+
 // DB layer.
 //
 //go:generate mockgen --build_flags=--mod=mod -package ipxe -destination mock_ipxe_db_test.go . DB
 type DB interface {
+	// WithTx runs fn in a transaction used by every DB method called with the ctx passed to fn.
+	WithTx(ctx context.Context, opts database.TxOptions, fn func(ctx context.Context) error) error
+	// WithAcquire runs fn with a single connection used by every DB method called with the ctx passed to fn.
+	WithAcquire(ctx context.Context, fn func(ctx context.Context) error) error
+	// GetAvailableImages returns the {image_tag image_type} of images that can be assigned to nodes.
 	GetAvailableImages(ctx context.Context) []IpxeImageTagType
 	UpdateNodeImage(ctx context.Context, config *IpxeNodeDbConfig) (*IpxeNodeDbConfig, error)
+	// GetNodeImage returns the node_images entry for macAddress, an errdefs.ErrNotFound error when the node has none.
+	GetNodeImage(ctx context.Context, macAddress string) (*IpxeNodeDbConfig, error)
+	// ListNodeImages returns every entry in ipxe.node_images.
+	ListNodeImages(ctx context.Context) ([]*IpxeNodeDbConfig, error)
+	// ListNodeImageMacAddresses returns the mac_address of the node_images entries assigned source,
+	// or of the entries in macAddresses when source is nil.
+	ListNodeImageMacAddresses(ctx context.Context, source *IpxeImageTagType, macAddresses []string) ([]string, error)
 	// GetIpxe returns an IpxeConfig for a macAddress.
 	GetIpxeDbConfig(ctx context.Context, macAddress string) (*IpxeDbConfig, error)
 	GetSubnetDefaultIpxeDbConfig(ctx context.Context, ipAddress string) (*IpxeDbConfig, error)
 	CreateNodeIpxeConfig(ctx context.Context, config *IpxeNodeDbConfig) error
 	CreateIpxeImage(ctx context.Context, config *IpxeDbConfig) (*IpxeConfig, error)
-	DeleteIpxeImage(ctx context.Context, config *IpxeImageTagType) (*IpxeDbConfig, error)
+	// UpdateIpxeImage updates the image_name, image_bucket and image_cmdline of an existing (image_tag, image_type).
+	UpdateIpxeImage(ctx context.Context, config *IpxeDbConfig) (*IpxeDbConfig, error)
+	// ListIpxeImages returns every entry in ipxe.images.
+	ListIpxeImages(ctx context.Context) ([]*IpxeDbConfig, error)
+	DeleteIpxeImage(ctx context.Context, config *IpxeImageDeleteConfig) (*IpxeDbConfig, error)
+	// SetIpxeImageState updates the image_state of (image_tag, image_type).
+	SetIpxeImageState(ctx context.Context, config *IpxeImageStateConfig) (*IpxeDbConfig, error)
+	// GetIpxeImageUsage returns the nodes and subnets referencing (image_tag, image_type).
+	GetIpxeImageUsage(ctx context.Context, config *IpxeImageTagType) (*IpxeImageUsage, error)
+	// ListIpxeImageUsage returns the usage of every image in imageState, or of all images when imageState is empty.
+	ListIpxeImageUsage(ctx context.Context, imageState string) ([]*IpxeImageUsage, error)
+	GetImageChannel(ctx context.Context, channel string) (*ImageChannel, error)
+	ListImageChannels(ctx context.Context) ([]*ImageChannel, error)
+	// PromoteImageChannel points a channel at a staged or active image and records the previous target.
+	PromoteImageChannel(ctx context.Context, config *ImageChannelPromoteConfig) (*ImageChannel, error)
+	// RollbackImageChannel swaps the current and previous target of a channel.
+	RollbackImageChannel(ctx context.Context, channel string) (*ImageChannel, error)
+	DeleteImageChannel(ctx context.Context, channel string) (*ImageChannel, error)
+	ListSubnetDefaultImages(ctx context.Context) ([]*SubnetDefaultImage, error)
+	SetSubnetDefaultImage(ctx context.Context, config *SubnetDefaultImage) (*SubnetDefaultImage, error)
+	DeleteSubnetDefaultImage(ctx context.Context, subnet string) (*SubnetDefaultImage, error)
+	// CreateImageRollout inserts a rollout and its nodes, waves maps each mac_address to its wave.
+	CreateImageRollout(ctx context.Context, rollout *ImageRollout, waves map[string]int) (*ImageRollout, error)
+	GetImageRollout(ctx context.Context, rolloutId int64) (*ImageRollout, error)
+	ListImageRollouts(ctx context.Context, rolloutState string) ([]*ImageRollout, error)
+	// StartImageRolloutWave moves a rollout still in fromState to in_progress with the next wave and returns its nodes.
+	StartImageRolloutWave(ctx context.Context, rolloutId int64, fromState string) ([]*ImageRolloutNode, error)
+	SetImageRolloutNodeStates(ctx context.Context, rolloutId int64, states map[string]string) error
+	// SetImageRolloutState moves a rollout in one of fromStates to rolloutState.
+	SetImageRolloutState(ctx context.Context, rolloutId int64, fromStates []string, rolloutState string, haltReason string) (*ImageRollout, error)
 }
 
 // ValidationError is returned when there is an invalid parameter received.
@@ -65,3 +148,8 @@ type ValidationError struct {
 func (e ValidationError) Error() string {
 	return e.s
 }
+
+// Is makes ValidationError an errdefs.ErrInvalid error.
+func (e ValidationError) Is(target error) bool {
+	return target == errdefs.ErrInvalid
+}
@@ -0,0 +1,65 @@
+package ipxe
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/s3"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestService(t *testing.T) (*Service, *MockDB, *s3.MemoryStore) {
+	ctrl := gomock.NewController(t)
+	db := NewMockDB(ctrl)
+	db.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ database.TxOptions, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		}).AnyTimes()
+	store := s3.NewMemoryStore("https://objects.test")
+	svc := NewService(db, store, "templates/template_ramdisk_https.ipxe", "default-image", "default-tag", "default-type", "default-bucket")
+	return svc, db, store
+}
+
+func TestService_GetNodeIpxeConfig(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	db.EXPECT().GetIpxeDbConfig(gomock.Any(), "0c42a1b2c3d4").Return(&IpxeDbConfig{
+		ImageName:    "ncore-develop-ci-test",
+		ImageBucket:  "ncore-images",
+		ImageTag:     "develop",
+		ImageType:    "ci-test",
+		ImageCmdline: "ro console=ttyS0",
+	}, nil)
+
+	ic, err := svc.GetNodeIpxeConfig(context.Background(), "0c42a1b2c3d4")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "ncore-develop-ci-test", ic.ImageName)
+	assert.Equal(t, "gb2c3d4", ic.Hostname)
+	assert.Equal(t, "https://objects.test/ncore-images/ncore-develop-ci-test/vmlinuz?expires=900", ic.ImageKernelUrlHttps)
+	assert.Equal(t, "http://objects.test/ncore-images/ncore-develop-ci-test/vmlinuz?expires=900", ic.ImageKernelUrlHttp)
+}
+
+func TestService_GetNodeIpxeConfig_notFound(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	db.EXPECT().GetIpxeDbConfig(gomock.Any(), "0c42a1b2c3d4").Return(nil, errdefs.NotFound("image_not_found", "no image found in database"))
+
+	ic, err := svc.GetNodeIpxeConfig(context.Background(), "0c42a1b2c3d4")
+
+	assert.True(t, errdefs.IsNotFound(err))
+	assert.Nil(t, ic)
+}
+
+func TestService_GetIpxeApiDefault(t *testing.T) {
+	svc, _, store := newTestService(t)
+	store.PutObject("default-bucket", "default-image/cmdline", []byte("root=live:default"))
+
+	ic := svc.GetIpxeApiDefault(context.Background())
+
+	assert.Equal(t, "root=live:default", ic.ImageCmdline)
+	assert.Equal(t, "default-tag", ic.ImageTag)
+	assert.Equal(t, "default-type", ic.ImageType)
+	assert.Equal(t, "https://objects.test/default-bucket/default-image/initrd.img?expires=900", ic.ImageInitrdUrlHttps)
+}
@@ -0,0 +1,129 @@
+package ipxe
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/snapshot"
+)
+
+// SnapshotName names the ipxe snapshot in logs and metrics.
+const SnapshotName = "ipxe"
+
+// Snapshot is the ipxe state needed to resolve boot images without the database.
+type Snapshot struct {
+	NodeImages     []*IpxeNodeDbConfig
+	Images         []*IpxeDbConfig
+	ImageChannels  []*ImageChannel
+	SubnetDefaults []*SubnetDefaultImage
+}
+
+// TakeSnapshot reads the node_images, images, image_channels and subnet_default_images entries
+// in one transaction.
+func (s *Service) TakeSnapshot(ctx context.Context) (*Snapshot, error) {
+	var snap Snapshot
+	err := s.db.WithTx(ctx, database.DefaultTxOptions, func(ctx context.Context) error {
+		var err error
+		if snap.NodeImages, err = s.db.ListNodeImages(ctx); err != nil {
+			return err
+		}
+		if snap.Images, err = s.db.ListIpxeImages(ctx); err != nil {
+			return err
+		}
+		if snap.ImageChannels, err = s.db.ListImageChannels(ctx); err != nil {
+			return err
+		}
+		snap.SubnetDefaults, err = s.db.ListSubnetDefaultImages(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// SetSnapshots sets the snapshots boot images are resolved from when the database is unavailable.
+func (s *Service) SetSnapshots(snapshots *snapshot.Store[*Snapshot]) {
+	s.snapshots = snapshots
+}
+
+// fromSnapshot calls lookup on the current snapshot when err is an errdefs.ErrUnavailable error,
+// and returns err unchanged otherwise or when the snapshot has no answer.
+func (s *Service) fromSnapshot(ctx context.Context, err error, lookup func(snap *Snapshot) (*IpxeDbConfig, error)) (*IpxeDbConfig, error) {
+	if !errdefs.IsUnavailable(err) {
+		return nil, err
+	}
+	current := s.snapshots.Get()
+	if current == nil || current.Data == nil {
+		return nil, err
+	}
+	idc, lookupErr := lookup(current.Data)
+	if lookupErr != nil {
+		return nil, err
+	}
+	snapshot.MarkStale(ctx, s.snapshots, current)
+	return idc, nil
+}
+
+// resolve returns the image of a node_images or subnet_default_images entry, following its channel.
+// Like the database lookups, retired images are never returned.
+func (snap *Snapshot) resolve(imageTag string, imageType string, imageChannel string) (*IpxeDbConfig, bool) {
+	if imageChannel != "" {
+		imageTag, imageType = "", ""
+		for _, ic := range snap.ImageChannels {
+			if ic.Channel == imageChannel {
+				imageTag, imageType = ic.ImageTag, ic.ImageType
+				break
+			}
+		}
+	}
+	for _, idc := range snap.Images {
+		if idc.ImageTag == imageTag && idc.ImageType == imageType && idc.ImageState != ImageStateRetired {
+			resolved := idc.dto()
+			resolved.ImageChannel = imageChannel
+			return resolved, true
+		}
+	}
+	return nil, false
+}
+
+// GetIpxeDbConfig returns the image of macAddress, see DB.GetIpxeDbConfig.
+func (snap *Snapshot) GetIpxeDbConfig(macAddress string) (*IpxeDbConfig, error) {
+	for _, node := range snap.NodeImages {
+		if macAddress != node.MacAddress {
+			continue
+		}
+		if idc, ok := snap.resolve(node.ImageTag, node.ImageType, node.ImageChannel); ok {
+			idc.Enrollment = node.Enrollment
+			return idc, nil
+		}
+		return nil, errdefs.NotFound("image_not_found", "no image found in snapshot for mac_address: %s", macAddress)
+	}
+	return nil, errdefs.NotFound(CodeNodeImageNotFound, "no node_images entry in snapshot for mac_address: %s", macAddress)
+}
+
+// GetSubnetDefaultIpxeDbConfig returns the default image of the most specific subnet containing ipAddress,
+// see DB.GetSubnetDefaultIpxeDbConfig.
+func (snap *Snapshot) GetSubnetDefaultIpxeDbConfig(ipAddress string) (*IpxeDbConfig, error) {
+	addr, err := netip.ParseAddr(ipAddress)
+	if err != nil {
+		return nil, ValidationError{"invalid ipAddress: " + ipAddress}
+	}
+	var best *SubnetDefaultImage
+	bestBits := -1
+	for _, sdi := range snap.SubnetDefaults {
+		prefix, err := netip.ParsePrefix(sdi.Subnet)
+		if err != nil || !prefix.Contains(addr.Unmap()) || prefix.Bits() <= bestBits {
+			continue
+		}
+		best, bestBits = sdi, prefix.Bits()
+	}
+	if best != nil {
+		if idc, ok := snap.resolve(best.ImageTag, best.ImageType, best.ImageChannel); ok {
+			return idc, nil
+		}
+	}
+	return nil, errdefs.NotFound("subnet_default_image_not_found", "no image found in snapshot for ip_address: %s", ipAddress)
+}
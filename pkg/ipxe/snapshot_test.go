@@ -0,0 +1,121 @@
+package ipxe
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/snapshot"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestSnapshot() *Snapshot {
+	return &Snapshot{
+		NodeImages: []*IpxeNodeDbConfig{
+			{MacAddress: "0c42a1b2c3d4", ImageTag: "develop", ImageType: "ci-test"},
+			{MacAddress: "0c42a1b2c3d5", ImageChannel: "stable"},
+			{MacAddress: "0c42a1b2c3d6", ImageTag: "old", ImageType: "ci-test"},
+		},
+		Images: []*IpxeDbConfig{
+			{ImageName: "ncore-develop-ci-test", ImageBucket: "ncore-images", ImageTag: "develop", ImageType: "ci-test", ImageState: ImageStateActive},
+			{ImageName: "ncore-release-ci-test", ImageBucket: "ncore-images", ImageTag: "release", ImageType: "ci-test", ImageState: ImageStateActive},
+			{ImageName: "ncore-old-ci-test", ImageBucket: "ncore-images", ImageTag: "old", ImageType: "ci-test", ImageState: ImageStateRetired},
+		},
+		ImageChannels: []*ImageChannel{
+			{Channel: "stable", ImageTag: "release", ImageType: "ci-test"},
+		},
+		SubnetDefaults: []*SubnetDefaultImage{
+			{Subnet: "10.0.0.0/8", ImageTag: "develop", ImageType: "ci-test"},
+			{Subnet: "10.1.0.0/16", ImageChannel: "stable"},
+			{Subnet: "2001:db8::/32", ImageTag: "develop", ImageType: "ci-test"},
+			{Subnet: "2001:db8:1::/48", ImageChannel: "stable"},
+		},
+	}
+}
+
+func TestSnapshot_GetIpxeDbConfig(t *testing.T) {
+	snap := newTestSnapshot()
+
+	idc, err := snap.GetIpxeDbConfig("0c42a1b2c3d4")
+	require.NoError(t, err)
+	assert.Equal(t, "ncore-develop-ci-test", idc.ImageName)
+
+	idc, err = snap.GetIpxeDbConfig("0c42a1b2c3d5")
+	require.NoError(t, err)
+	assert.Equal(t, "ncore-release-ci-test", idc.ImageName)
+	assert.Equal(t, "stable", idc.ImageChannel)
+
+	_, err = snap.GetIpxeDbConfig("0c42a1b2c3d6")
+	assert.True(t, errdefs.IsNotFound(err), "retired images are not served")
+
+	_, err = snap.GetIpxeDbConfig("0c42a1b2c3d7")
+	assert.True(t, errdefs.IsNotFound(err))
+}
+
+func TestSnapshot_GetSubnetDefaultIpxeDbConfig(t *testing.T) {
+	snap := newTestSnapshot()
+
+	idc, err := snap.GetSubnetDefaultIpxeDbConfig("10.1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, "ncore-release-ci-test", idc.ImageName, "the most specific subnet wins")
+
+	idc, err = snap.GetSubnetDefaultIpxeDbConfig("10.2.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, "ncore-develop-ci-test", idc.ImageName)
+
+	_, err = snap.GetSubnetDefaultIpxeDbConfig("192.168.0.1")
+	assert.True(t, errdefs.IsNotFound(err))
+
+	idc, err = snap.GetSubnetDefaultIpxeDbConfig("2001:db8:1::17")
+	require.NoError(t, err)
+	assert.Equal(t, "ncore-release-ci-test", idc.ImageName, "the most specific IPv6 subnet wins")
+
+	idc, err = snap.GetSubnetDefaultIpxeDbConfig("2001:db8:2::17")
+	require.NoError(t, err)
+	assert.Equal(t, "ncore-develop-ci-test", idc.ImageName)
+
+	idc, err = snap.GetSubnetDefaultIpxeDbConfig("::ffff:10.1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, "ncore-release-ci-test", idc.ImageName, "IPv4-mapped addresses match IPv4 subnets")
+
+	_, err = snap.GetSubnetDefaultIpxeDbConfig("2001:db9::17")
+	assert.True(t, errdefs.IsNotFound(err))
+}
+
+func TestService_GetSubnetDefaultIpxeConfig_ipAddress(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	notFound := errdefs.NotFound("subnet_default_image_not_found", "no subnet")
+	db.EXPECT().GetSubnetDefaultIpxeDbConfig(gomock.Any(), "10.1.2.3").Return(nil, notFound)
+	db.EXPECT().GetSubnetDefaultIpxeDbConfig(gomock.Any(), "2001:db8::17").Return(nil, notFound)
+
+	assert.Nil(t, svc.GetSubnetDefaultIpxeConfig(context.Background(), "::ffff:10.1.2.3"))
+	assert.Nil(t, svc.GetSubnetDefaultIpxeConfig(context.Background(), "2001:DB8::17%eth0"))
+	assert.Nil(t, svc.GetSubnetDefaultIpxeConfig(context.Background(), "node-1"), "invalid addresses are not looked up")
+}
+
+func TestService_GetNodeIpxeConfig_snapshot(t *testing.T) {
+	svc, db, _ := newTestService(t)
+	store := snapshot.NewStore(SnapshotName, "", func(ctx context.Context) (*Snapshot, error) {
+		return newTestSnapshot(), nil
+	})
+	require.NoError(t, store.Refresh(context.Background()))
+	svc.SetSnapshots(store)
+	unavailable := errdefs.Unavailable("database_unavailable", "database unavailable")
+	db.EXPECT().GetIpxeDbConfig(gomock.Any(), "0c42a1b2c3d4").Return(nil, unavailable)
+	db.EXPECT().GetIpxeDbConfig(gomock.Any(), "0c42a1b2c3d7").Return(nil, unavailable)
+	ctx := snapshot.WithStaleness(context.Background())
+
+	ic, err := svc.GetNodeIpxeConfig(ctx, "0c42a1b2c3d4")
+
+	require.NoError(t, err)
+	assert.Equal(t, "ncore-develop-ci-test", ic.ImageName)
+	assert.Equal(t, "https://objects.test/ncore-images/ncore-develop-ci-test/vmlinuz?expires=900", ic.ImageKernelUrlHttps)
+	takenAt, stale := snapshot.Stale(ctx)
+	assert.True(t, stale)
+	assert.Equal(t, store.Get().TakenAt, takenAt)
+
+	_, err = svc.GetNodeIpxeConfig(context.Background(), "0c42a1b2c3d7")
+	assert.True(t, errdefs.IsUnavailable(err), "nodes missing from the snapshot keep the database error")
+}
@@ -0,0 +1,302 @@
+package ipxe
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/s3"
+	"go.uber.org/zap"
+)
+
+// imageFiles are the objects CI uploads for every image, see GetIpxeImagePresignedUrls.
+var imageFiles = []string{"cmdline", "initrd.img", "rootfs.cpio.gz", "vmlinuz"}
+
+// DefaultImageSyncNamePattern maps CI image directories such as ncore-develop-ci-test.20230320-1916
+// to image_tag develop and image_type ci-test.
+const DefaultImageSyncNamePattern = `^ncore-(?P<tag>[^-]+)-(?P<type>[^.]+)(\..*)?$`
+
+// Image sync actions reported in ImageSyncResult.
+const (
+	ImageSyncCreate    = "create"
+	ImageSyncUpdate    = "update"
+	ImageSyncUnchanged = "unchanged"
+	ImageSyncSkipped   = "skipped"
+)
+
+// ImageSyncSource is a bucket and key prefix scanned for image directories.
+type ImageSyncSource struct {
+	Bucket string
+	Prefix string
+}
+
+// ParseImageSyncSources parses a comma separated list of bucket or bucket/prefix entries.
+func ParseImageSyncSources(sources string) ([]ImageSyncSource, error) {
+	var iss []ImageSyncSource
+	for _, source := range strings.Split(sources, ",") {
+		source = strings.TrimSpace(source)
+		if source == "" {
+			continue
+		}
+		bucket, prefix, _ := strings.Cut(strings.TrimPrefix(source, "s3://"), "/")
+		if bucket == "" {
+			return nil, fmt.Errorf("invalid image sync source: %q", source)
+		}
+		iss = append(iss, ImageSyncSource{Bucket: bucket, Prefix: prefix})
+	}
+	return iss, nil
+}
+
+// ImageSyncConfig configures an ImageSyncer.
+type ImageSyncConfig struct {
+	Sources []ImageSyncSource
+	// NamePattern is matched against the image directory name and must contain the named groups tag and type.
+	NamePattern *regexp.Regexp
+	Interval    time.Duration
+	// DryRun only reports what would be registered.
+	DryRun bool
+}
+
+// NewImageSyncConfig validates namePattern and returns an ImageSyncConfig.
+func NewImageSyncConfig(sources string, namePattern string, interval time.Duration, dryRun bool) (*ImageSyncConfig, error) {
+	iss, err := ParseImageSyncSources(sources)
+	if err != nil {
+		return nil, err
+	}
+	if len(iss) == 0 {
+		return nil, ValidationError{"missing image sync sources"}
+	}
+	re, err := regexp.Compile(namePattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid image sync name pattern: %w", err)
+	}
+	if re.SubexpIndex("tag") < 0 || re.SubexpIndex("type") < 0 {
+		return nil, ValidationError{"image sync name pattern must contain the named groups tag and type"}
+	}
+	if interval <= 0 {
+		return nil, ValidationError{"image sync interval must be positive"}
+	}
+	return &ImageSyncConfig{
+		Sources:     iss,
+		NamePattern: re,
+		Interval:    interval,
+		DryRun:      dryRun,
+	}, nil
+}
+
+// ImageSyncResult is the outcome of syncing one image directory.
+type ImageSyncResult struct {
+	ImageName   string
+	ImageBucket string
+	ImageTag    string
+	ImageType   string
+	Action      string
+	Reason      string `json:",omitempty"`
+}
+
+// ImageSyncer discovers complete image directories in object storage and registers them in ipxe.images.
+type ImageSyncer struct {
+	svc         *Service
+	objectStore s3.ObjectStore
+	config      ImageSyncConfig
+}
+
+// NewImageSyncer creates an ImageSyncer registering images through svc.
+func NewImageSyncer(svc *Service, config ImageSyncConfig) *ImageSyncer {
+	return &ImageSyncer{
+		svc:         svc,
+		objectStore: svc.objectStore,
+		config:      config,
+	}
+}
+
+// Run syncs images every Interval until ctx is done.
+func (is *ImageSyncer) Run(ctx context.Context) {
+	logging.FromContext(ctx).Info("starting image sync", zap.Any("sources", is.config.Sources), zap.Duration("interval", is.config.Interval), zap.Bool("dry_run", is.config.DryRun))
+	ticker := time.NewTicker(is.config.Interval)
+	defer ticker.Stop()
+	for {
+		if _, err := is.Sync(ctx); err != nil {
+			logging.FromContext(ctx).Error("image sync failed", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// imageCandidate is a complete image directory found in a source.
+type imageCandidate struct {
+	bucket       string
+	name         string
+	tag          string
+	imageType    string
+	lastModified time.Time
+}
+
+// Sync scans every source once and registers new or changed images.
+func (is *ImageSyncer) Sync(ctx context.Context) ([]ImageSyncResult, error) {
+	var results []ImageSyncResult
+	candidates := map[IpxeImageTagType]imageCandidate{}
+	for _, source := range is.config.Sources {
+		found, skipped, err := is.discover(ctx, source)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, skipped...)
+		for _, c := range found {
+			key := IpxeImageTagType{ImageTag: c.tag, ImageType: c.imageType}
+			// CI uploads a new directory per build, only the newest one is registered.
+			if prev, ok := candidates[key]; ok && !c.lastModified.After(prev.lastModified) {
+				continue
+			}
+			candidates[key] = c
+		}
+	}
+
+	registered, err := is.svc.db.ListIpxeImages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	existing := map[IpxeImageTagType]*IpxeDbConfig{}
+	for _, idc := range registered {
+		existing[IpxeImageTagType{ImageTag: idc.ImageTag, ImageType: idc.ImageType}] = idc
+	}
+
+	keys := make([]IpxeImageTagType, 0, len(candidates))
+	for key := range candidates {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].ImageTag+"/"+keys[i].ImageType < keys[j].ImageTag+"/"+keys[j].ImageType
+	})
+	for _, key := range keys {
+		results = append(results, is.register(ctx, candidates[key], existing[key]))
+	}
+	return results, nil
+}
+
+// discover lists source and returns the complete image directories matching NamePattern.
+func (is *ImageSyncer) discover(ctx context.Context, source ImageSyncSource) ([]imageCandidate, []ImageSyncResult, error) {
+	objects, err := is.objectStore.ListObjects(ctx, source.Bucket, source.Prefix)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot list %s/%s: %w", source.Bucket, source.Prefix, err)
+	}
+	files := map[string]map[string]time.Time{}
+	for _, o := range objects {
+		dir, file := path.Split(o.Key)
+		dir = strings.TrimSuffix(dir, "/")
+		if dir == "" {
+			continue
+		}
+		if files[dir] == nil {
+			files[dir] = map[string]time.Time{}
+		}
+		files[dir][file] = o.LastModified
+	}
+
+	var found []imageCandidate
+	var skipped []ImageSyncResult
+	for dir, dirFiles := range files {
+		var lastModified time.Time
+		complete := true
+		for _, f := range imageFiles {
+			modified, ok := dirFiles[f]
+			if !ok {
+				complete = false
+				break
+			}
+			if modified.After(lastModified) {
+				lastModified = modified
+			}
+		}
+		if !complete {
+			continue
+		}
+		match := is.config.NamePattern.FindStringSubmatch(path.Base(dir))
+		if match == nil {
+			skipped = append(skipped, ImageSyncResult{
+				ImageName:   dir,
+				ImageBucket: source.Bucket,
+				Action:      ImageSyncSkipped,
+				Reason:      "name doesn't match pattern",
+			})
+			continue
+		}
+		found = append(found, imageCandidate{
+			bucket:       source.Bucket,
+			name:         dir,
+			tag:          match[is.config.NamePattern.SubexpIndex("tag")],
+			imageType:    match[is.config.NamePattern.SubexpIndex("type")],
+			lastModified: lastModified,
+		})
+	}
+	return found, skipped, nil
+}
+
+// register creates or updates the images entry for c. existing is nil for new images.
+func (is *ImageSyncer) register(ctx context.Context, c imageCandidate, existing *IpxeDbConfig) ImageSyncResult {
+	result := ImageSyncResult{
+		ImageName:   c.name,
+		ImageBucket: c.bucket,
+		ImageTag:    c.tag,
+		ImageType:   c.imageType,
+	}
+	cmdline, err := is.objectStore.GetObject(ctx, c.bucket, c.name+"/cmdline")
+	if err != nil {
+		result.Action = ImageSyncSkipped
+		result.Reason = fmt.Sprintf("cannot read cmdline: %v", err)
+		return result
+	}
+	idc := &IpxeDbConfig{
+		ImageName:    c.name,
+		ImageBucket:  c.bucket,
+		ImageTag:     c.tag,
+		ImageType:    c.imageType,
+		ImageCmdline: strings.TrimSpace(string(cmdline)),
+	}
+	switch {
+	case idc.ImageCmdline == "":
+		result.Action = ImageSyncSkipped
+		result.Reason = "empty cmdline"
+		return result
+	case existing == nil:
+		result.Action = ImageSyncCreate
+	case existing.ImageName == idc.ImageName && existing.ImageBucket == idc.ImageBucket && existing.ImageCmdline == idc.ImageCmdline:
+		result.Action = ImageSyncUnchanged
+		return result
+	default:
+		result.Action = ImageSyncUpdate
+		result.Reason = fmt.Sprintf("replaces %s/%s", existing.ImageBucket, existing.ImageName)
+	}
+
+	logger := logging.FromContext(ctx).With(
+		zap.String("action", result.Action),
+		zap.String("bucket", c.bucket),
+		zap.String("image_name", c.name),
+		zap.String("image_tag", c.tag),
+		zap.String("image_type", c.imageType),
+		zap.Bool("dry_run", is.config.DryRun))
+	if is.config.DryRun {
+		logger.Info("image sync would change image")
+		return result
+	}
+	logger.Info("image sync changing image")
+	if result.Action == ImageSyncCreate {
+		_, err = is.svc.db.CreateIpxeImage(ctx, idc)
+	} else {
+		_, err = is.svc.db.UpdateIpxeImage(ctx, idc)
+	}
+	if err != nil {
+		result.Action = ImageSyncSkipped
+		result.Reason = err.Error()
+	}
+	return result
+}
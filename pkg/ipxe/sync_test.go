@@ -0,0 +1,71 @@
+package ipxe
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestImageSyncer_Sync(t *testing.T) {
+	svc, db, store := newTestService(t)
+	for _, f := range imageFiles {
+		store.PutObject("ncore-images", "ci/ncore-develop-ci-test.20230320-1916/"+f, []byte("root=live ro\n"))
+		store.PutObject("ncore-images", "ci/ncore-master-tenant/"+f, []byte("ro"))
+		store.PutObject("ncore-images", "ci/unrelated-image/"+f, []byte("ro"))
+	}
+	// incomplete image directories are ignored
+	store.PutObject("ncore-images", "ci/ncore-develop-partial/vmlinuz", []byte{})
+
+	db.EXPECT().ListIpxeImages(gomock.Any()).Return([]*IpxeDbConfig{{
+		ImageName:    "ci/ncore-master-tenant",
+		ImageBucket:  "ncore-images",
+		ImageTag:     "master",
+		ImageType:    "tenant",
+		ImageCmdline: "ro",
+	}}, nil)
+	db.EXPECT().CreateIpxeImage(gomock.Any(), &IpxeDbConfig{
+		ImageName:    "ci/ncore-develop-ci-test.20230320-1916",
+		ImageBucket:  "ncore-images",
+		ImageTag:     "develop",
+		ImageType:    "ci-test",
+		ImageCmdline: "root=live ro",
+	}).Return(&IpxeConfig{}, nil)
+
+	syncer := NewImageSyncer(svc, ImageSyncConfig{
+		Sources:     []ImageSyncSource{{Bucket: "ncore-images", Prefix: "ci/"}},
+		NamePattern: regexp.MustCompile(DefaultImageSyncNamePattern),
+		Interval:    time.Minute,
+	})
+	results, err := syncer.Sync(context.Background())
+
+	assert.NoError(t, err)
+	actions := map[string]string{}
+	for _, r := range results {
+		actions[r.ImageName] = r.Action
+	}
+	assert.Equal(t, map[string]string{
+		"ci/unrelated-image":                     ImageSyncSkipped,
+		"ci/ncore-develop-ci-test.20230320-1916": ImageSyncCreate,
+		"ci/ncore-master-tenant":                 ImageSyncUnchanged,
+	}, actions)
+}
+
+func TestImageSyncer_SyncDryRun(t *testing.T) {
+	svc, db, store := newTestService(t)
+	for _, f := range imageFiles {
+		store.PutObject("ncore-images", "ncore-develop-ci-test/"+f, []byte("ro"))
+	}
+	db.EXPECT().ListIpxeImages(gomock.Any()).Return(nil, nil)
+
+	config, err := NewImageSyncConfig("ncore-images", DefaultImageSyncNamePattern, time.Minute, true)
+	assert.NoError(t, err)
+	results, err := NewImageSyncer(svc, *config).Sync(context.Background())
+
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.Equal(t, ImageSyncCreate, results[0].Action)
+}
@@ -1,15 +1,16 @@
 package ipxe
 
 import (
-	"log"
 	"path/filepath"
 	"text/template"
+
+	"go.uber.org/zap"
 )
 
 func getIpxeConfigTemplate(ipxeTemplateFile string) template.Template {
 	t, err := template.New(filepath.Base(ipxeTemplateFile)).ParseFiles(ipxeTemplateFile)
 	if err != nil {
-		log.Printf("GetIpxeConfigTemplate error parsing template: %v", err)
+		zap.L().Error("cannot parse ipxe template", zap.String("file", ipxeTemplateFile), zap.Error(err))
 	}
 	return *t
 }
@@ -0,0 +1,40 @@
+package logging
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestWithRequestID(t *testing.T) {
+	core, logs := observer.New(zapcore.InfoLevel)
+	ctx := WithLogger(context.Background(), zap.New(core))
+
+	ctx = WithRequestID(ctx, "abc")
+	FromContext(ctx).Info("assigned image")
+
+	assert.Equal(t, "abc", RequestID(ctx))
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, map[string]any{"request_id": "abc"}, logs.All()[0].ContextMap())
+}
+
+func TestFromContext_global(t *testing.T) {
+	assert.Same(t, zap.L(), FromContext(context.Background()))
+	assert.Empty(t, RequestID(context.Background()))
+}
+
+func TestNew(t *testing.T) {
+	_, err := New("verbose", "json")
+	assert.Error(t, err)
+	_, err = New("info", "xml")
+	assert.Error(t, err)
+
+	l, err := New("warn", "json")
+	require.NoError(t, err)
+	assert.False(t, l.Core().Enabled(zapcore.InfoLevel))
+}
@@ -0,0 +1,62 @@
+// Package macaddr parses the mac addresses of nodes into their canonical form,
+// which keys nodes in the api and in the macaddr columns of the databases.
+package macaddr
+
+import (
+	"net"
+	"strings"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+)
+
+// MAC is a mac address in canonical form: 12 lowercase hex digits without separators, e.g. 0c42a1b2c3d4.
+type MAC string
+
+// Parse returns the MAC of s in the colon (0c:42:a1:b2:c3:d4), dash (0c-42-a1-b2-c3-d4),
+// dot (0c42.a1b2.c3d4) or bare (0c42a1b2c3d4) form, in any case.
+// Other input is an errdefs.ErrInvalid error with the invalid_mac_address code.
+func Parse(s string) (MAC, error) {
+	if len(s) == 12 && isHex(s) {
+		return MAC(strings.ToLower(s)), nil
+	}
+	hw, err := net.ParseMAC(s)
+	if err != nil || len(hw) != 6 {
+		return "", errdefs.Invalid("invalid_mac_address", "invalid mac_address: %q", s)
+	}
+	return MAC(strings.Replace(hw.String(), ":", "", -1)), nil
+}
+
+// ParseOUI returns the organizationally unique identifier prefix of a mac address in s, 6 lowercase hex digits,
+// from the colon (0c:42:a1), dash (0c-42-a1) or bare (0c42a1) form.
+func ParseOUI(s string) (string, error) {
+	oui := strings.ToLower(s)
+	if len(oui) == 8 && (oui[2] == ':' && oui[5] == ':' || oui[2] == '-' && oui[5] == '-') {
+		oui = oui[0:2] + oui[3:5] + oui[6:8]
+	}
+	if len(oui) != 6 || !isHex(oui) {
+		return "", errdefs.Invalid("invalid_mac_oui", "invalid mac_oui: %q", s)
+	}
+	return oui, nil
+}
+
+// String returns m in canonical form.
+func (m MAC) String() string {
+	return string(m)
+}
+
+// Suffix returns the last 6 hex digits of m, which name nodes by default.
+func (m MAC) Suffix() string {
+	if len(m) < 6 {
+		return string(m)
+	}
+	return string(m[len(m)-6:])
+}
+
+func isHex(s string) bool {
+	for _, c := range s {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,58 @@
+package macaddr
+
+import (
+	"testing"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	for _, s := range []string{"0c42a1b2c3d4", "0C42A1B2C3D4", "0c:42:a1:b2:c3:d4", "0C-42-A1-B2-C3-D4", "0c42.a1b2.c3d4"} {
+		mac, err := Parse(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, MAC("0c42a1b2c3d4"), mac, s)
+	}
+	for _, s := range []string{"", "b2c3d4", "0c42a1b2c3dz", "0c:42:a1:b2:c3", "0c:42-a1:b2:c3:d4", "0c42a1b2c3d4e5", "00:00:00:00:fe:80:00:00:00:00:00:00:02:00:5e:10:00:00:00:01"} {
+		_, err := Parse(s)
+		assert.ErrorIs(t, err, errdefs.ErrInvalid, s)
+		assert.Equal(t, "invalid_mac_address", errdefs.Code(err), s)
+	}
+}
+
+func TestParseOUI(t *testing.T) {
+	for _, s := range []string{"0c42a1", "0C:42:A1", "0c-42-a1"} {
+		oui, err := ParseOUI(s)
+		require.NoError(t, err, s)
+		assert.Equal(t, "0c42a1", oui, s)
+	}
+	_, err := ParseOUI("0c:42-a1")
+	assert.ErrorIs(t, err, errdefs.ErrInvalid)
+}
+
+func TestCodec(t *testing.T) {
+	m := pgtype.NewMap()
+	Register(m)
+
+	buf, err := m.Encode(pgtype.MacaddrOID, pgtype.BinaryFormatCode, "0c:42:a1:b2:c3:d4", nil)
+	require.NoError(t, err)
+	assert.Equal(t, []byte{0x0c, 0x42, 0xa1, 0xb2, 0xc3, 0xd4}, buf)
+	_, err = m.Encode(pgtype.MacaddrOID, pgtype.BinaryFormatCode, "g0c42a1", nil)
+	assert.Error(t, err)
+
+	var s string
+	require.NoError(t, m.Scan(pgtype.MacaddrOID, pgtype.BinaryFormatCode, buf, &s))
+	assert.Equal(t, "0c42a1b2c3d4", s)
+	var mac MAC
+	require.NoError(t, m.Scan(pgtype.MacaddrOID, pgtype.TextFormatCode, []byte("0c:42:a1:b2:c3:d5"), &mac))
+	assert.Equal(t, MAC("0c42a1b2c3d5"), mac)
+
+	// arrays of mac addresses, e.g. unnest($1::macaddr[])
+	buf, err = m.Encode(pgtype.MacaddrArrayOID, pgtype.BinaryFormatCode, []string{"0c42a1b2c3d4", "0c-42-a1-b2-c3-d5"}, nil)
+	require.NoError(t, err)
+	var macs []string
+	require.NoError(t, m.Scan(pgtype.MacaddrArrayOID, pgtype.BinaryFormatCode, buf, &macs))
+	assert.Equal(t, []string{"0c42a1b2c3d4", "0c42a1b2c3d5"}, macs)
+}
@@ -0,0 +1,113 @@
+package macaddr
+
+import (
+	"encoding/hex"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// Codec is the pgx codec of the postgres macaddr type which encodes strings in any form accepted by Parse
+// and scans into MAC values, and into strings in the binary format of the extended protocol, in canonical form.
+// The pgx macaddr codec handles the other values.
+type Codec struct {
+	pgtype.MacaddrCodec
+}
+
+// Register replaces the macaddr and macaddr[] types of m with Codec, e.g. in the AfterConnect of a pgxpool.Config.
+func Register(m *pgtype.Map) {
+	t := &pgtype.Type{Name: "macaddr", OID: pgtype.MacaddrOID, Codec: Codec{}}
+	m.RegisterType(t)
+	m.RegisterType(&pgtype.Type{Name: "_macaddr", OID: pgtype.MacaddrArrayOID, Codec: &pgtype.ArrayCodec{ElementType: t}})
+}
+
+func (c Codec) PlanEncode(m *pgtype.Map, oid uint32, format int16, value any) pgtype.EncodePlan {
+	switch value.(type) {
+	case string, MAC:
+		if format == pgtype.BinaryFormatCode {
+			return encodePlanBinary{}
+		}
+		return encodePlanText{}
+	}
+	return c.MacaddrCodec.PlanEncode(m, oid, format, value)
+}
+
+func (c Codec) PlanScan(m *pgtype.Map, oid uint32, format int16, target any) pgtype.ScanPlan {
+	switch target.(type) {
+	case *string, *MAC:
+		if format == pgtype.BinaryFormatCode {
+			return scanPlanBinary{}
+		}
+		return scanPlanText{}
+	}
+	return c.MacaddrCodec.PlanScan(m, oid, format, target)
+}
+
+func parseValue(value any) (MAC, error) {
+	switch v := value.(type) {
+	case MAC:
+		return Parse(string(v))
+	default:
+		return Parse(v.(string))
+	}
+}
+
+type encodePlanBinary struct{}
+
+func (encodePlanBinary) Encode(value any, buf []byte) ([]byte, error) {
+	mac, err := parseValue(value)
+	if err != nil {
+		return nil, err
+	}
+	b, err := hex.DecodeString(string(mac))
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, b...), nil
+}
+
+type encodePlanText struct{}
+
+func (encodePlanText) Encode(value any, buf []byte) ([]byte, error) {
+	mac, err := parseValue(value)
+	if err != nil {
+		return nil, err
+	}
+	return append(buf, mac...), nil
+}
+
+func scanMAC(mac MAC, target any) {
+	switch t := target.(type) {
+	case *MAC:
+		*t = mac
+	case *string:
+		*t = string(mac)
+	}
+}
+
+type scanPlanBinary struct{}
+
+func (scanPlanBinary) Scan(src []byte, target any) error {
+	if src == nil {
+		return fmt.Errorf("cannot scan NULL into %T", target)
+	}
+	if len(src) != 6 {
+		return fmt.Errorf("invalid length for macaddr: %v", len(src))
+	}
+	scanMAC(MAC(hex.EncodeToString(src)), target)
+	return nil
+}
+
+type scanPlanText struct{}
+
+func (scanPlanText) Scan(src []byte, target any) error {
+	if src == nil {
+		return fmt.Errorf("cannot scan NULL into %T", target)
+	}
+	mac, err := Parse(string(src))
+	if err != nil {
+		return err
+	}
+	scanMAC(mac, target)
+	return nil
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"go.uber.org/zap"
+)
+
+// pgxPoolCollector exports the pgxpool.Stat of pools, labeled by database name.
+type pgxPoolCollector struct {
+	pools map[string]*pgxpool.Pool
+
+	acquiredConns        *prometheus.Desc
+	idleConns            *prometheus.Desc
+	totalConns           *prometheus.Desc
+	maxConns             *prometheus.Desc
+	acquireCount         *prometheus.Desc
+	acquireDuration      *prometheus.Desc
+	canceledAcquireCount *prometheus.Desc
+	emptyAcquireCount    *prometheus.Desc
+}
+
+// NewPGXPoolCollector returns a collector of the stats of pools, keyed by the database label.
+func NewPGXPoolCollector(pools map[string]*pgxpool.Pool) prometheus.Collector {
+	desc := func(name, help string) *prometheus.Desc {
+		return prometheus.NewDesc(prometheus.BuildFQName(namespace, "pgxpool", name), help, []string{"database"}, nil)
+	}
+	return &pgxPoolCollector{
+		pools:                pools,
+		acquiredConns:        desc("acquired_conns", "Connections currently acquired from the pool."),
+		idleConns:            desc("idle_conns", "Idle connections in the pool."),
+		totalConns:           desc("total_conns", "Connections in the pool."),
+		maxConns:             desc("max_conns", "Maximum size of the pool."),
+		acquireCount:         desc("acquire_count_total", "Successful acquires from the pool."),
+		acquireDuration:      desc("acquire_duration_seconds_total", "Time spent acquiring connections from the pool."),
+		canceledAcquireCount: desc("canceled_acquire_count_total", "Acquires canceled by their context."),
+		emptyAcquireCount:    desc("empty_acquire_count_total", "Acquires that waited for a connection because the pool was empty."),
+	}
+}
+
+func (c *pgxPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquireCount
+	ch <- c.acquireDuration
+	ch <- c.canceledAcquireCount
+	ch <- c.emptyAcquireCount
+}
+
+func (c *pgxPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	for name, pool := range c.pools {
+		s := pool.Stat()
+		ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()), name)
+		ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()), name)
+		ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()), name)
+		ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()), name)
+		ch <- prometheus.MustNewConstMetric(c.acquireCount, prometheus.CounterValue, float64(s.AcquireCount()), name)
+		ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds(), name)
+		ch <- prometheus.MustNewConstMetric(c.canceledAcquireCount, prometheus.CounterValue, float64(s.CanceledAcquireCount()), name)
+		ch <- prometheus.MustNewConstMetric(c.emptyAcquireCount, prometheus.CounterValue, float64(s.EmptyAcquireCount()), name)
+	}
+}
+
+// HeartbeatWindows are the windows of NewHeartbeatCollector.
+var HeartbeatWindows = []time.Duration{5 * time.Minute, 15 * time.Minute, time.Hour, 24 * time.Hour}
+
+// CountNodesLastSeen returns the number of nodes whose last heartbeat is within each of within,
+// and the number of nodes with a heartbeat.
+type CountNodesLastSeen func(ctx context.Context, within []time.Duration) ([]int, int, error)
+
+// heartbeatCollector exports the number of nodes by time since their last heartbeat, queried on each scrape.
+type heartbeatCollector struct {
+	count   CountNodesLastSeen
+	timeout time.Duration
+	nodes   *prometheus.Desc
+}
+
+// NewHeartbeatCollector returns a collector of ncore_nodes_last_heartbeat, the number of nodes whose
+// last heartbeat is within each of HeartbeatWindows and +Inf for every node with a heartbeat.
+func NewHeartbeatCollector(count CountNodesLastSeen, timeout time.Duration) prometheus.Collector {
+	return &heartbeatCollector{
+		count:   count,
+		timeout: timeout,
+		nodes: prometheus.NewDesc(prometheus.BuildFQName(namespace, "nodes", "last_heartbeat"),
+			"Nodes whose last heartbeat is within the window, +Inf for every node with a heartbeat.", []string{"within"}, nil),
+	}
+}
+
+func (c *heartbeatCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.nodes
+}
+
+func (c *heartbeatCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
+	defer cancel()
+	counts, total, err := c.count(ctx, HeartbeatWindows)
+	if err != nil {
+		zap.L().Warn("cannot count nodes by last heartbeat", zap.Error(err))
+		ch <- prometheus.NewInvalidMetric(c.nodes, err)
+		return
+	}
+	for i, within := range HeartbeatWindows {
+		ch <- prometheus.MustNewConstMetric(c.nodes, prometheus.GaugeValue, float64(counts[i]), within.String())
+	}
+	ch <- prometheus.MustNewConstMetric(c.nodes, prometheus.GaugeValue, float64(total), "+Inf")
+}
@@ -0,0 +1,113 @@
+// Package metrics defines the Prometheus metrics of ncore-api, served by Handler on /metrics.
+//
+// Metrics are registered on Registry rather than the default registry so tests and other binaries
+// importing ncore-api packages don't export them.
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "ncore"
+
+// Sources of a boot resolution, the entry a node's image or payload came from.
+const (
+	SourceNode          = "node"
+	SourceSubnetDefault = "subnet_default"
+	SourceAPIDefault    = "api_default"
+	SourceDiscovery     = "discovery"
+)
+
+// Kinds of boot resolutions and defaulted nodes.
+const (
+	KindImage   = "image"
+	KindPayload = "payload"
+)
+
+// Registry holds every ncore-api metric along with the go and process collectors.
+var Registry = prometheus.NewRegistry()
+
+var (
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "http_requests_total",
+		Help:      "HTTP requests by method, route pattern and status.",
+	}, []string{"method", "route", "status"})
+
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "http_request_duration_seconds",
+		Help:      "Latency of HTTP requests by method, route pattern and status.",
+		Buckets:   prometheus.DefBuckets,
+	}, []string{"method", "route", "status"})
+
+	// BootResolutions counts the images and payloads served to nodes by the entry they came from.
+	BootResolutions = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "boot_resolutions_total",
+		Help:      "Images and payloads resolved for booting nodes by kind and source: node, subnet_default or api_default.",
+	}, []string{"kind", "source"})
+
+	// DefaultedNodes counts the node_images and node_payloads entries added for unknown nodes.
+	DefaultedNodes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "defaulted_nodes_total",
+		Help:      "Entries added for unknown nodes from a subnet or api default, by kind.",
+	}, []string{"kind"})
+
+	ObjectStoreDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Name:      "object_store_request_duration_seconds",
+		Help:      "Latency of object storage calls by backend and operation.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5},
+	}, []string{"backend", "operation"})
+
+	ObjectStoreErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "object_store_errors_total",
+		Help:      "Failed object storage calls by backend, operation and reason: not_found or error.",
+	}, []string{"backend", "operation", "reason"})
+
+	SnapshotTimestamp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "snapshot_timestamp_seconds",
+		Help:      "Unix time the boot snapshot served while the database is unavailable was taken, by snapshot: ipxe or payloads.",
+	}, []string{"snapshot"})
+
+	SnapshotRefreshErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "snapshot_refresh_errors_total",
+		Help:      "Failed boot snapshot refreshes by snapshot.",
+	}, []string{"snapshot"})
+
+	StaleResponses = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "stale_responses_total",
+		Help:      "Lookups answered from a boot snapshot because the database was unavailable, by snapshot.",
+	}, []string{"snapshot"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		BootResolutions,
+		DefaultedNodes,
+		ObjectStoreDuration,
+		ObjectStoreErrors,
+		SnapshotTimestamp,
+		SnapshotRefreshErrors,
+		StaleResponses,
+	)
+}
+
+// Handler serves the metrics of Registry.
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/s3"
+)
+
+// objectStore records the latency and errors of the calls to an s3.ObjectStore.
+type objectStore struct {
+	backend string
+	next    s3.ObjectStore
+}
+
+// InstrumentObjectStore returns store recording ObjectStoreDuration and ObjectStoreErrors
+// with backend, e.g. s3 or local, as label.
+func InstrumentObjectStore(backend string, store s3.ObjectStore) s3.ObjectStore {
+	return &objectStore{backend: backend, next: store}
+}
+
+func (o *objectStore) observe(operation string, start time.Time, err error) {
+	ObjectStoreDuration.WithLabelValues(o.backend, operation).Observe(time.Since(start).Seconds())
+	switch {
+	case err == nil:
+	case errors.Is(err, s3.ErrObjectNotFound):
+		ObjectStoreErrors.WithLabelValues(o.backend, operation, "not_found").Inc()
+	default:
+		ObjectStoreErrors.WithLabelValues(o.backend, operation, "error").Inc()
+	}
+}
+
+func (o *objectStore) GetObject(ctx context.Context, bucketName string, objectKey string) ([]byte, error) {
+	start := time.Now()
+	body, err := o.next.GetObject(ctx, bucketName, objectKey)
+	o.observe("get", start, err)
+	return body, err
+}
+
+func (o *objectStore) HeadBucket(ctx context.Context, bucketName string) error {
+	start := time.Now()
+	err := o.next.HeadBucket(ctx, bucketName)
+	o.observe("head_bucket", start, err)
+	return err
+}
+
+func (o *objectStore) HeadObject(ctx context.Context, bucketName string, objectKey string) (*s3.ObjectInfo, error) {
+	start := time.Now()
+	info, err := o.next.HeadObject(ctx, bucketName, objectKey)
+	o.observe("head", start, err)
+	return info, err
+}
+
+func (o *objectStore) ListObjects(ctx context.Context, bucketName string, prefix string) ([]s3.ObjectInfo, error) {
+	start := time.Now()
+	objects, err := o.next.ListObjects(ctx, bucketName, prefix)
+	o.observe("list", start, err)
+	return objects, err
+}
+
+func (o *objectStore) PresignGetObject(ctx context.Context, bucketName string, objectKey string, lifetimeSecs int64) (string, error) {
+	start := time.Now()
+	url, err := o.next.PresignGetObject(ctx, bucketName, objectKey, lifetimeSecs)
+	o.observe("presign", start, err)
+	return url, err
+}
@@ -0,0 +1,31 @@
+package metrics
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coreweave/ncore-api/pkg/s3"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentObjectStore(t *testing.T) {
+	ctx := context.Background()
+	ms := s3.NewMemoryStore("http://objects.test")
+	ms.PutObject("images", "ncore-develop-ci-test/cmdline", []byte("console=ttyS0"))
+	store := InstrumentObjectStore("memory", ms)
+
+	body, err := store.GetObject(ctx, "images", "ncore-develop-ci-test/cmdline")
+	require.NoError(t, err)
+	assert.Equal(t, "console=ttyS0", string(body))
+	_, err = store.GetObject(ctx, "images", "missing")
+	assert.ErrorIs(t, err, s3.ErrObjectNotFound)
+	_, err = store.PresignGetObject(ctx, "images", "ncore-develop-ci-test/cmdline", 60)
+	require.NoError(t, err)
+
+	// one series for get and one for presign
+	assert.Equal(t, 2, testutil.CollectAndCount(ObjectStoreDuration))
+	assert.Equal(t, 1.0, testutil.ToFloat64(ObjectStoreErrors.WithLabelValues("memory", "get", "not_found")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(ObjectStoreErrors.WithLabelValues("memory", "presign", "not_found")))
+}
@@ -0,0 +1,330 @@
+// Package migrate applies SQL migrations embedded in the binary.
+//
+// Migrations use the tern file format and version table, so databases migrated with tern keep their version:
+// NNN_name.sql files executed as text/template with an env function, the up statements above
+// the "---- create above / drop below ----" line and the down statements below it.
+// The files of the shared directory are templates available to every migration, e.g.
+// {{ template "shared/name.sql" . }}, as tern names the templates in subdirectories of a migration directory.
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+const separator = "---- create above / drop below ----"
+
+var migrationName = regexp.MustCompile(`^(\d+)_.+\.sql$`)
+
+// Migration is one NNN_name.sql file.
+type Migration struct {
+	Version int32
+	Name    string
+	Up      string
+	Down    string
+}
+
+// Load reads and renders the migrations in dir of fsys, ordered by version, with env looking up
+// the variables of {{ env "NAME" }}. Versions must start at 1 and have no gaps.
+func Load(fsys fs.FS, dir string, env func(name string) string) ([]*Migration, error) {
+	migrations, err := loadDir(fsys, dir, env)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i, m := range migrations {
+		if m.Version != int32(i+1) {
+			return nil, fmt.Errorf("migration %s out of sequence, expected version %d", m.Name, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// Replace returns migrations with each one replaced by the migration of the same file name in dir of fsys,
+// rendered like Load. The migrations of dir without a counterpart in migrations are ignored.
+func Replace(migrations []*Migration, fsys fs.FS, dir string, env func(name string) string) ([]*Migration, error) {
+	replacements, err := loadDir(fsys, dir, env)
+	if err != nil {
+		return nil, err
+	}
+	replaced := make([]*Migration, len(migrations))
+	copy(replaced, migrations)
+	for _, r := range replacements {
+		for i, m := range replaced {
+			if m.Name == r.Name {
+				replaced[i] = r
+			}
+		}
+	}
+	return replaced, nil
+}
+
+// loadDir reads and renders the migrations in dir of fsys, in directory order.
+func loadDir(fsys fs.FS, dir string, env func(name string) string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read migrations %s: %w", dir, err)
+	}
+	shared, err := loadShared(fsys, env)
+	if err != nil {
+		return nil, err
+	}
+	var migrations []*Migration
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || match == nil {
+			continue
+		}
+		version, err := strconv.ParseInt(match[1], 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %w", entry.Name(), err)
+		}
+		b, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("cannot read migration %s: %w", entry.Name(), err)
+		}
+		sql, err := render(shared, entry.Name(), string(b))
+		if err != nil {
+			return nil, err
+		}
+		up, down, _ := strings.Cut(sql, separator)
+		migrations = append(migrations, &Migration{
+			Version: int32(version),
+			Name:    entry.Name(),
+			Up:      up,
+			Down:    down,
+		})
+	}
+	return migrations, nil
+}
+
+// loadShared parses the templates of the shared directory of fsys, {{ env "NAME" }} is replaced with env("NAME").
+func loadShared(fsys fs.FS, env func(name string) string) (*template.Template, error) {
+	shared := template.New("").Funcs(template.FuncMap{"env": env})
+	names, err := fs.Glob(fsys, "shared/*.sql")
+	if err != nil {
+		return nil, fmt.Errorf("cannot read shared migrations: %w", err)
+	}
+	for _, name := range names {
+		b, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read migration %s: %w", name, err)
+		}
+		if _, err := shared.New(name).Parse(string(b)); err != nil {
+			return nil, fmt.Errorf("cannot parse migration %s: %w", name, err)
+		}
+	}
+	return shared, nil
+}
+
+// render executes sql as a template alongside the shared templates.
+func render(shared *template.Template, name string, sql string) (string, error) {
+	tmpl, err := shared.Clone()
+	if err != nil {
+		return "", fmt.Errorf("cannot parse migration %s: %w", name, err)
+	}
+	if _, err := tmpl.New(name).Parse(sql); err != nil {
+		return "", fmt.Errorf("cannot parse migration %s: %w", name, err)
+	}
+	var b strings.Builder
+	if err := tmpl.ExecuteTemplate(&b, name, nil); err != nil {
+		return "", fmt.Errorf("cannot render migration %s: %w", name, err)
+	}
+	return b.String(), nil
+}
+
+// Migrator applies migrations to one database, or to one schema of it.
+type Migrator struct {
+	pool       *pgxpool.Pool
+	schema     string
+	migrations []*Migration
+}
+
+// NewMigrator creates a Migrator for pool. With schema set, migrations run with search_path set to schema
+// and the version is tracked in schema.schema_version instead of schema_version.
+func NewMigrator(pool *pgxpool.Pool, schema string, migrations []*Migration) *Migrator {
+	return &Migrator{
+		pool:       pool,
+		schema:     schema,
+		migrations: migrations,
+	}
+}
+
+func (m *Migrator) versionTable() string {
+	if m.schema == "" {
+		return pgx.Identifier{"schema_version"}.Sanitize()
+	}
+	return pgx.Identifier{m.schema, "schema_version"}.Sanitize()
+}
+
+// LatestVersion returns the version of the last migration, expected by the binary.
+func (m *Migrator) LatestVersion() int32 {
+	return int32(len(m.migrations))
+}
+
+// CurrentVersion returns the version recorded in the version table, 0 when it doesn't exist.
+func (m *Migrator) CurrentVersion(ctx context.Context) (int32, error) {
+	var exists bool
+	if err := m.pool.QueryRow(ctx, `SELECT to_regclass($1) IS NOT NULL`, m.versionTable()).Scan(&exists); err != nil {
+		return 0, fmt.Errorf("cannot read schema version: %w", err)
+	}
+	if !exists {
+		return 0, nil
+	}
+	var version int32
+	if err := m.pool.QueryRow(ctx, `SELECT version FROM `+m.versionTable()).Scan(&version); err != nil {
+		return 0, fmt.Errorf("cannot read schema version: %w", err)
+	}
+	return version, nil
+}
+
+// Check returns an error unless the database is at LatestVersion.
+func (m *Migrator) Check(ctx context.Context) error {
+	version, err := m.CurrentVersion(ctx)
+	if err != nil {
+		return err
+	}
+	if version != m.LatestVersion() {
+		return fmt.Errorf("schema version %d in %s, expected %d", version, m.versionTable(), m.LatestVersion())
+	}
+	return nil
+}
+
+// Up applies every migration after the current version.
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.migrate(ctx, func(current int32) (int32, error) {
+		return m.LatestVersion(), nil
+	})
+}
+
+// Down reverts the last steps migrations.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.migrate(ctx, func(current int32) (int32, error) {
+		target := current - int32(steps)
+		if target < 0 {
+			return 0, fmt.Errorf("cannot revert %d migrations from version %d", steps, current)
+		}
+		return target, nil
+	})
+}
+
+// migrate moves the database to the version returned by target, one transaction per migration,
+// holding an advisory lock so concurrent migrators wait for each other.
+func (m *Migrator) migrate(ctx context.Context, target func(current int32) (int32, error)) error {
+	conn, err := m.pool.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot acquire connection: %w", err)
+	}
+	defer conn.Release()
+	if _, err := conn.Exec(ctx, `SELECT pg_advisory_lock(hashtext($1))`, m.versionTable()); err != nil {
+		return fmt.Errorf("cannot lock %s: %w", m.versionTable(), err)
+	}
+	defer conn.Exec(context.Background(), `SELECT pg_advisory_unlock(hashtext($1))`, m.versionTable())
+
+	if m.schema != "" {
+		if _, err := conn.Exec(ctx, `CREATE SCHEMA IF NOT EXISTS `+pgx.Identifier{m.schema}.Sanitize()); err != nil {
+			return fmt.Errorf("cannot create schema %s: %w", m.schema, err)
+		}
+	}
+	if _, err := conn.Exec(ctx, fmt.Sprintf(`CREATE TABLE IF NOT EXISTS %[1]s(version int4 NOT NULL);
+INSERT INTO %[1]s(version) SELECT 0 WHERE 0 = (SELECT count(*) FROM %[1]s);`, m.versionTable())); err != nil {
+		return fmt.Errorf("cannot create %s: %w", m.versionTable(), err)
+	}
+	var current int32
+	if err := conn.QueryRow(ctx, `SELECT version FROM `+m.versionTable()).Scan(&current); err != nil {
+		return fmt.Errorf("cannot read schema version: %w", err)
+	}
+	if current > m.LatestVersion() {
+		return fmt.Errorf("schema version %d in %s is newer than the latest migration %d", current, m.versionTable(), m.LatestVersion())
+	}
+	to, err := target(current)
+	if err != nil {
+		return err
+	}
+
+	for current != to {
+		var sql string
+		var next int32
+		var migration *Migration
+		if current < to {
+			migration = m.migrations[current]
+			sql, next = migration.Up, current+1
+			logging.FromContext(ctx).Info("applying migration", zap.String("version_table", m.versionTable()), zap.String("migration", migration.Name))
+		} else {
+			migration = m.migrations[current-1]
+			sql, next = migration.Down, current-1
+			if strings.TrimSpace(sql) == "" {
+				return fmt.Errorf("migration %s is irreversible", migration.Name)
+			}
+			logging.FromContext(ctx).Info("reverting migration", zap.String("version_table", m.versionTable()), zap.String("migration", migration.Name))
+		}
+		if err := m.apply(ctx, conn, sql, next); err != nil {
+			return fmt.Errorf("migration %s failed: %w", migration.Name, err)
+		}
+		current = next
+	}
+	return nil
+}
+
+// apply runs sql and records version in one transaction.
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, sql string, version int32) error {
+	tx, err := conn.Begin(ctx)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback(ctx)
+	if m.schema != "" {
+		if _, err := tx.Exec(ctx, `SET LOCAL search_path TO `+pgx.Identifier{m.schema}.Sanitize()); err != nil {
+			return err
+		}
+	}
+	// Without arguments Exec uses the simple protocol, which allows several statements.
+	if _, err := tx.Exec(ctx, sql); err != nil {
+		return err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE `+m.versionTable()+` SET version = $1`, version); err != nil {
+		return err
+	}
+	return tx.Commit(ctx)
+}
+
+// Status of a Migrator, see Migrator.Status.
+type Status struct {
+	VersionTable   string
+	CurrentVersion int32
+	LatestVersion  int32
+	Pending        []string
+}
+
+// Status returns the current and latest versions and the names of the migrations not applied yet.
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	version, err := m.CurrentVersion(ctx)
+	if err != nil {
+		return nil, err
+	}
+	status := &Status{
+		VersionTable:   m.versionTable(),
+		CurrentVersion: version,
+		LatestVersion:  m.LatestVersion(),
+	}
+	if version > status.LatestVersion {
+		return status, errors.New("schema version is newer than the latest migration")
+	}
+	for _, migration := range m.migrations[version:] {
+		status.Pending = append(status.Pending, migration.Name)
+	}
+	return status, nil
+}
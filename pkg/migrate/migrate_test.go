@@ -0,0 +1,68 @@
+package migrate
+
+import (
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/coreweave/ncore-api/migrations"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoad(t *testing.T) {
+	env := map[string]string{"READ_ONLY_PASSWORD": "secret"}
+	fsys := fstest.MapFS{
+		"db/002_grant.sql": {Data: []byte("CREATE USER read_only WITH PASSWORD '{{ env \"READ_ONLY_PASSWORD\" }}';\n{{ template \"shared/grant.sql\" \"t\" }}\n")},
+		"shared/grant.sql": {Data: []byte("GRANT SELECT ON {{ . }} TO read_only;")},
+		"db/001_init.sql":  {Data: []byte("CREATE TABLE t (id int);\n---- create above / drop below ----\nDROP TABLE t;\n")},
+		"db/README.md":     {Data: []byte("not a migration")},
+	}
+
+	ms, err := Load(fsys, "db", func(name string) string { return env[name] })
+
+	assert.NoError(t, err)
+	assert.Len(t, ms, 2)
+	assert.Equal(t, int32(1), ms[0].Version)
+	assert.Equal(t, "CREATE TABLE t (id int);\n", ms[0].Up)
+	assert.Equal(t, "\nDROP TABLE t;\n", ms[0].Down)
+	assert.Equal(t, "CREATE USER read_only WITH PASSWORD 'secret';\nGRANT SELECT ON t TO read_only;\n", ms[1].Up)
+	assert.Empty(t, ms[1].Down)
+}
+
+func TestLoad_gap(t *testing.T) {
+	fsys := fstest.MapFS{
+		"db/001_init.sql":  {Data: []byte("SELECT 1;")},
+		"db/003_later.sql": {Data: []byte("SELECT 3;")},
+	}
+
+	_, err := Load(fsys, "db", os.Getenv)
+
+	assert.Error(t, err)
+}
+
+func TestReplace(t *testing.T) {
+	fsys := fstest.MapFS{
+		"db/001_init.sql":         {Data: []byte("CREATE TABLE t (id int);")},
+		"db/002_grant.sql":        {Data: []byte("GRANT SELECT ON t TO read_only;")},
+		"db_schema/002_grant.sql": {Data: []byte("GRANT USAGE ON SCHEMA s TO read_only;")},
+		"db_schema/003_other.sql": {Data: []byte("SELECT 3;")},
+	}
+	ms, err := Load(fsys, "db", os.Getenv)
+	assert.NoError(t, err)
+
+	replaced, err := Replace(ms, fsys, "db_schema", os.Getenv)
+
+	assert.NoError(t, err)
+	assert.Len(t, replaced, 2)
+	assert.Equal(t, "CREATE TABLE t (id int);", replaced[0].Up)
+	assert.Equal(t, "GRANT USAGE ON SCHEMA s TO read_only;", replaced[1].Up)
+	assert.Equal(t, "GRANT SELECT ON t TO read_only;", ms[1].Up)
+}
+
+func TestLoad_embedded(t *testing.T) {
+	for _, dir := range []string{"ipxe", "payloads", "nodes", "ipxe_test", "payloads_test"} {
+		ms, err := Load(migrations.FS, dir, os.Getenv)
+		assert.NoError(t, err, dir)
+		assert.NotEmpty(t, ms, dir)
+	}
+}
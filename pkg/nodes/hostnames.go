@@ -0,0 +1,244 @@
+package nodes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"regexp"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/macaddr"
+	"go.uber.org/zap"
+)
+
+// Sources of a NodeHostname.
+const (
+	HostnameExplicit  = "explicit"
+	HostnameGenerated = "generated"
+)
+
+// DefaultHostnameTemplate is the hostname of nodes matching no HostnamePolicy unless set otherwise, see SetDefaultHostnameTemplate.
+const DefaultHostnameTemplate = "g{{.MacSuffix}}"
+
+// NodeHostname is the hostname of a node, unique among nodes.
+type NodeHostname struct {
+	MacAddress string `json:"mac_address"`
+	Hostname   string `json:"hostname"`
+	// Source is explicit for assigned hostnames, generated for the ones rendered from a template on first boot.
+	Source string `json:"source"`
+	// PolicyId is the HostnamePolicy a generated hostname was rendered from, nil for the default template.
+	PolicyId   *int64    `json:"policy_id,omitempty"`
+	AssignedAt time.Time `json:"assigned_at"`
+}
+
+// HostnamePolicy names the nodes first booting from Subnet, or from any subnet when empty.
+// The policy with the longest Subnet containing the ip_address of the node wins.
+type HostnamePolicy struct {
+	PolicyId int64  `json:"policy_id"`
+	Subnet   string `json:"subnet,omitempty"`
+	// Template is a text/template executed with HostnameFields, e.g. gpu-{{.Rack}}-{{.Slot}} or node{{printf "%05d" .Counter}}.
+	Template string `json:"template"`
+	// Counter is the last value of the policy's serial counter.
+	Counter   int64     `json:"counter"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// HostnameFields are the fields of hostname templates.
+type HostnameFields struct {
+	MacAddress string
+	// MacSuffix is the last 6 hex digits of MacAddress.
+	MacSuffix string
+	// Counter increments for every node named by the policy, only when the template uses it.
+	Counter int64
+	// rack and slot are the boot hints returned by Rack and Slot.
+	rack, slot string
+}
+
+// Rack returns the rack boot hint of the node, templates using it fail for nodes booting without it.
+func (f *HostnameFields) Rack() (string, error) {
+	if f.rack == "" {
+		return "", errors.New("missing rack hint")
+	}
+	return f.rack, nil
+}
+
+// Slot returns the slot boot hint of the node, templates using it fail for nodes booting without it.
+func (f *HostnameFields) Slot() (string, error) {
+	if f.slot == "" {
+		return "", errors.New("missing slot hint")
+	}
+	return f.slot, nil
+}
+
+var hostnamePattern = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]{0,61}[a-z0-9])?$`)
+
+// parseHostnameTemplate parses text and checks it renders a valid hostname.
+func parseHostnameTemplate(text string) (*template.Template, error) {
+	tmpl, err := template.New("hostname").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, ValidationError{fmt.Sprintf("invalid hostname template: %v", err)}
+	}
+	if _, err := renderHostname(tmpl, &HostnameFields{MacAddress: "0c42a1b2c3d4", MacSuffix: "b2c3d4", Counter: 1, rack: "r01", slot: "01"}); err != nil {
+		return nil, err
+	}
+	return tmpl, nil
+}
+
+func renderHostname(tmpl *template.Template, fields *HostnameFields) (string, error) {
+	var b strings.Builder
+	if err := tmpl.Execute(&b, fields); err != nil {
+		return "", ValidationError{fmt.Sprintf("cannot render hostname template: %v", err)}
+	}
+	hostname := strings.ToLower(b.String())
+	if !hostnamePattern.MatchString(hostname) {
+		return "", ValidationError{fmt.Sprintf("invalid hostname: %q", hostname)}
+	}
+	return hostname, nil
+}
+
+// usesCounter reports whether text uses the Counter of HostnameFields.
+func usesCounter(text string) bool {
+	return strings.Contains(text, ".Counter")
+}
+
+// SetDefaultHostnameTemplate sets the template of nodes matching no HostnamePolicy, it can't use the counter.
+func (s *Service) SetDefaultHostnameTemplate(text string) error {
+	if usesCounter(text) {
+		return ValidationError{"the default hostname template can't use .Counter, add a hostname policy instead"}
+	}
+	tmpl, err := parseHostnameTemplate(text)
+	if err != nil {
+		return err
+	}
+	s.hostnameTemplate = tmpl
+	return nil
+}
+
+// NodeHostname returns the hostname of macAddress, which is rendered from the hostname policy of ipAddress and
+// recorded when the node has none. Without ipAddress an errdefs.ErrNotFound error is returned for such nodes.
+func (s *Service) NodeHostname(ctx context.Context, macAddress string, ipAddress string, hints enrollment.Hints) (string, error) {
+	if macAddress == "" {
+		return "", ValidationError{"Missing macAddress"}
+	}
+	nh, err := s.db.GetNodeHostname(ctx, macAddress)
+	switch {
+	case err == nil:
+		return nh.Hostname, nil
+	case !errdefs.IsNotFound(err) || ipAddress == "":
+		return "", err
+	}
+	err = s.db.WithTx(ctx, database.SerializableTxOptions, func(ctx context.Context) error {
+		// another boot of the node may have named it since
+		if nh, err = s.db.GetNodeHostname(ctx, macAddress); !errdefs.IsNotFound(err) {
+			return err
+		}
+		nh = &NodeHostname{MacAddress: macAddress, Source: HostnameGenerated}
+		tmpl := s.hostnameTemplate
+		fields := &HostnameFields{MacAddress: macAddress, MacSuffix: macaddr.MAC(macAddress).Suffix(), rack: hints["rack"], slot: hints["slot"]}
+		policy, err := s.db.MatchHostnamePolicy(ctx, ipAddress)
+		switch {
+		case err == nil:
+			if tmpl, err = parseHostnameTemplate(policy.Template); err != nil {
+				return err
+			}
+			nh.PolicyId = &policy.PolicyId
+			if usesCounter(policy.Template) {
+				if fields.Counter, err = s.db.IncrementHostnamePolicyCounter(ctx, policy.PolicyId); err != nil {
+					return err
+				}
+			}
+		case !errdefs.IsNotFound(err):
+			return err
+		}
+		if nh.Hostname, err = renderHostname(tmpl, fields); err != nil {
+			return err
+		}
+		nh, err = s.db.SetNodeHostname(ctx, nh)
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	logging.FromContext(ctx).Info("named node", zap.String("mac_address", macAddress), zap.String("hostname", nh.Hostname))
+	return nh.Hostname, nil
+}
+
+// GetNodeHostname returns the hostname of the node of macAddress, an errdefs.ErrNotFound error when it has none.
+func (s *Service) GetNodeHostname(ctx context.Context, macAddress string) (*NodeHostname, error) {
+	if macAddress == "" {
+		return nil, ValidationError{"Missing macAddress"}
+	}
+	macAddress, err := s.db.ResolveMacAddress(ctx, macAddress)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.GetNodeHostname(ctx, macAddress)
+}
+
+// SetNodeHostname assigns hostname to the node of macAddress, an errdefs.ErrConflict error when another node has it.
+func (s *Service) SetNodeHostname(ctx context.Context, macAddress string, hostname string) (*NodeHostname, error) {
+	if macAddress == "" {
+		return nil, ValidationError{"Missing macAddress"}
+	}
+	macAddress, err := s.db.ResolveMacAddress(ctx, macAddress)
+	if err != nil {
+		return nil, err
+	}
+	hostname = strings.ToLower(hostname)
+	if !hostnamePattern.MatchString(hostname) {
+		return nil, ValidationError{fmt.Sprintf("invalid hostname: %q", hostname)}
+	}
+	return s.db.SetNodeHostname(ctx, &NodeHostname{MacAddress: macAddress, Hostname: hostname, Source: HostnameExplicit})
+}
+
+// DeleteNodeHostname removes the hostname of the node of macAddress, a new one is generated on its next boot.
+func (s *Service) DeleteNodeHostname(ctx context.Context, macAddress string) (*NodeHostname, error) {
+	if macAddress == "" {
+		return nil, ValidationError{"Missing macAddress"}
+	}
+	macAddress, err := s.db.ResolveMacAddress(ctx, macAddress)
+	if err != nil {
+		return nil, err
+	}
+	return s.db.DeleteNodeHostname(ctx, macAddress)
+}
+
+// LookupHostname returns the node named hostname, an errdefs.ErrNotFound error when there is none.
+func (s *Service) LookupHostname(ctx context.Context, hostname string) (*NodeHostname, error) {
+	if hostname == "" {
+		return nil, ValidationError{"Missing hostname"}
+	}
+	return s.db.LookupHostname(ctx, strings.ToLower(hostname))
+}
+
+// ListHostnamePolicies returns every hostname policy.
+func (s *Service) ListHostnamePolicies(ctx context.Context) ([]*HostnamePolicy, error) {
+	return s.db.ListHostnamePolicies(ctx)
+}
+
+// CreateHostnamePolicy adds policy, an errdefs.ErrConflict error when its Subnet has one.
+func (s *Service) CreateHostnamePolicy(ctx context.Context, policy *HostnamePolicy) (*HostnamePolicy, error) {
+	if policy.Subnet != "" {
+		prefix, err := netip.ParsePrefix(policy.Subnet)
+		if err != nil {
+			return nil, ValidationError{fmt.Sprintf("invalid subnet: %s", policy.Subnet)}
+		}
+		policy.Subnet = prefix.Masked().String()
+	}
+	if _, err := parseHostnameTemplate(policy.Template); err != nil {
+		return nil, err
+	}
+	return s.db.CreateHostnamePolicy(ctx, policy)
+}
+
+// DeleteHostnamePolicy deletes the hostname policy policyId, the hostnames it generated are kept.
+func (s *Service) DeleteHostnamePolicy(ctx context.Context, policyId int64) (*HostnamePolicy, error) {
+	return s.db.DeleteHostnamePolicy(ctx, policyId)
+}
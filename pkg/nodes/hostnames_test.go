@@ -0,0 +1,40 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRenderHostname(t *testing.T) {
+	tmpl, err := parseHostnameTemplate(`GPU-{{.Rack}}-{{printf "%03d" .Counter}}`)
+	require.NoError(t, err)
+
+	hostname, err := renderHostname(tmpl, &HostnameFields{Counter: 12, rack: "r07"})
+	require.NoError(t, err)
+	assert.Equal(t, "gpu-r07-012", hostname)
+
+	// templates fail for nodes booting without the hints they use
+	_, err = renderHostname(tmpl, &HostnameFields{Counter: 12})
+	assert.ErrorIs(t, err, errdefs.ErrInvalid)
+}
+
+func TestParseHostnameTemplate_invalid(t *testing.T) {
+	for _, text := range []string{"g{{.MacSuffix", "{{.Rack}}.example.com", "g{{.Unknown}}"} {
+		_, err := parseHostnameTemplate(text)
+		assert.ErrorIs(t, err, errdefs.ErrInvalid, text)
+	}
+}
+
+func TestSetDefaultHostnameTemplate(t *testing.T) {
+	s := NewService(nil)
+
+	assert.ErrorIs(t, s.SetDefaultHostnameTemplate("node{{.Counter}}"), errdefs.ErrInvalid)
+	require.NoError(t, s.SetDefaultHostnameTemplate("node-{{.MacAddress}}"))
+	hostname, err := renderHostname(s.hostnameTemplate, &HostnameFields{MacAddress: "0c42a1b2c3d4"})
+	require.NoError(t, err)
+	assert.Equal(t, "node-0c42a1b2c3d4", hostname)
+}
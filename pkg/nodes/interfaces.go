@@ -0,0 +1,113 @@
+package nodes
+
+import (
+	"context"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/macaddr"
+	"go.uber.org/zap"
+)
+
+// NodeIdentity is a node booting from any of its Interfaces.
+type NodeIdentity struct {
+	NodeId int64 `json:"node_id"`
+	// MacAddress keys the node_images, node_payloads and node_heartbeat entries of the node.
+	// It is the mac_address the node was first known by and stays so after its interface is detached.
+	MacAddress string       `json:"mac_address"`
+	Interfaces []*Interface `json:"interfaces"`
+	CreatedAt  time.Time    `json:"created_at"`
+}
+
+// Interface is a NIC attached to a node.
+type Interface struct {
+	MacAddress string    `json:"mac_address"`
+	Name       string    `json:"name,omitempty"`
+	AttachedAt time.Time `json:"attached_at"`
+}
+
+// ResolveMacAddress returns the mac_address keying the entries of the node macAddress is attached to,
+// macAddress itself when it is attached to no node.
+func (s *Service) ResolveMacAddress(ctx context.Context, macAddress string) (string, error) {
+	if macAddress == "" {
+		return "", ValidationError{"Missing macAddress"}
+	}
+	return s.db.ResolveMacAddress(ctx, macAddress)
+}
+
+// GetNodeIdentity returns the node macAddress is attached to or keys, an errdefs.ErrNotFound error when there is none.
+func (s *Service) GetNodeIdentity(ctx context.Context, macAddress string) (*NodeIdentity, error) {
+	if macAddress == "" {
+		return nil, ValidationError{"Missing macAddress"}
+	}
+	return s.db.GetNodeIdentity(ctx, macAddress)
+}
+
+// AttachInterface attaches iface to the node of macAddress, which is created with its own interface when unknown.
+// Attaching an interface of another node is an errdefs.ErrConflict error.
+func (s *Service) AttachInterface(ctx context.Context, macAddress string, iface *Interface) (*NodeIdentity, error) {
+	if macAddress == "" {
+		return nil, ValidationError{"Missing macAddress"}
+	}
+	mac, err := macaddr.Parse(iface.MacAddress)
+	if err != nil {
+		return nil, err
+	}
+	iface.MacAddress = mac.String()
+	var identity *NodeIdentity
+	err = s.db.WithTx(ctx, database.SerializableTxOptions, func(ctx context.Context) error {
+		var err error
+		identity, err = s.db.GetNodeIdentity(ctx, macAddress)
+		if errdefs.IsNotFound(err) {
+			identity, err = s.db.CreateNodeIdentity(ctx, macAddress)
+		}
+		if err != nil {
+			return err
+		}
+		owner, err := s.db.GetNodeIdentity(ctx, iface.MacAddress)
+		switch {
+		case err == nil && owner.NodeId != identity.NodeId:
+			return errdefs.Conflict("interface_attached", "mac_address %s belongs to node %d", iface.MacAddress, owner.NodeId)
+		case err != nil && !errdefs.IsNotFound(err):
+			return err
+		}
+		if err := s.db.AttachInterface(ctx, identity.NodeId, iface); err != nil {
+			return err
+		}
+		logging.FromContext(ctx).Info("attached node interface", zap.Int64("node_id", identity.NodeId), zap.String("mac_address", iface.MacAddress))
+		identity, err = s.db.GetNodeIdentity(ctx, macAddress)
+		return err
+	})
+	return identity, err
+}
+
+// DetachInterface detaches interfaceMacAddress from the node of macAddress.
+// The node keeps its entries, which stay keyed by its MacAddress.
+func (s *Service) DetachInterface(ctx context.Context, macAddress string, interfaceMacAddress string) (*NodeIdentity, error) {
+	if macAddress == "" || interfaceMacAddress == "" {
+		return nil, ValidationError{"Missing macAddress"}
+	}
+	var identity *NodeIdentity
+	err := s.db.WithTx(ctx, database.SerializableTxOptions, func(ctx context.Context) error {
+		var err error
+		if identity, err = s.db.GetNodeIdentity(ctx, macAddress); err != nil {
+			return err
+		}
+		attached := false
+		for _, iface := range identity.Interfaces {
+			attached = attached || iface.MacAddress == interfaceMacAddress
+		}
+		if !attached {
+			return errdefs.NotFound("interface_not_found", "mac_address %s not attached to node %d", interfaceMacAddress, identity.NodeId)
+		}
+		if err := s.db.DetachInterface(ctx, interfaceMacAddress); err != nil {
+			return err
+		}
+		logging.FromContext(ctx).Info("detached node interface", zap.Int64("node_id", identity.NodeId), zap.String("mac_address", interfaceMacAddress))
+		identity, err = s.db.GetNodeIdentity(ctx, identity.MacAddress)
+		return err
+	})
+	return identity, err
+}
@@ -0,0 +1,242 @@
+package nodes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/macaddr"
+	"go.uber.org/zap"
+)
+
+// Inventory is the hardware report of a node.
+type Inventory struct {
+	Serial       string         `json:"serial,omitempty"`
+	Vendor       string         `json:"vendor,omitempty"`
+	Model        string         `json:"model,omitempty"`
+	BiosVersion  string         `json:"bios_version,omitempty"`
+	Cpu          *Cpu           `json:"cpu,omitempty"`
+	MemoryBytes  int64          `json:"memory_bytes,omitempty"`
+	Nics         []*Nic         `json:"nics,omitempty"`
+	Disks        []*Disk        `json:"disks,omitempty"`
+	Accelerators []*Accelerator `json:"accelerators,omitempty"`
+}
+
+type Cpu struct {
+	Model   string `json:"model,omitempty"`
+	Sockets int    `json:"sockets,omitempty"`
+	Cores   int    `json:"cores,omitempty"`
+	Threads int    `json:"threads,omitempty"`
+}
+
+type Nic struct {
+	Name       string `json:"name"`
+	MacAddress string `json:"mac_address"`
+	SpeedMbps  int64  `json:"speed_mbps,omitempty"`
+}
+
+type Disk struct {
+	Name      string `json:"name"`
+	Model     string `json:"model,omitempty"`
+	Serial    string `json:"serial,omitempty"`
+	SizeBytes int64  `json:"size_bytes,omitempty"`
+	// Type is nvme, ssd or hdd.
+	Type string `json:"type,omitempty"`
+}
+
+// Accelerator is a GPU or another PCI accelerator.
+type Accelerator struct {
+	Vendor      string `json:"vendor,omitempty"`
+	Model       string `json:"model"`
+	Serial      string `json:"serial,omitempty"`
+	PciAddress  string `json:"pci_address,omitempty"`
+	MemoryBytes int64  `json:"memory_bytes,omitempty"`
+}
+
+// NodeInventory is the last Inventory reported by a node.
+type NodeInventory struct {
+	MacAddress    string     `json:"mac_address"`
+	HardwareClass string     `json:"hardware_class"`
+	Inventory     *Inventory `json:"inventory"`
+	ReportedAt    time.Time  `json:"reported_at"`
+	// ChangedAt is when the last report differing from the previous one was received.
+	ChangedAt time.Time `json:"changed_at"`
+}
+
+// InventoryChange records a report differing from the previous one in ChangedFields, the json names of Inventory.
+type InventoryChange struct {
+	ChangeId      int64      `json:"change_id"`
+	MacAddress    string     `json:"mac_address"`
+	ChangedAt     time.Time  `json:"changed_at"`
+	ChangedFields []string   `json:"changed_fields"`
+	Previous      *Inventory `json:"previous,omitempty"`
+	Inventory     *Inventory `json:"inventory"`
+}
+
+var classReplacer = regexp.MustCompile(`[^a-z0-9.]+`)
+
+// normalize validates inv and sorts its lists so reordered reports don't count as changes.
+func (inv *Inventory) normalize() error {
+	if inv.MemoryBytes < 0 {
+		return ValidationError{"invalid memory_bytes"}
+	}
+	for _, nic := range inv.Nics {
+		mac, err := macaddr.Parse(nic.MacAddress)
+		if err != nil {
+			return ValidationError{fmt.Sprintf("invalid mac_address of nic %s", nic.Name)}
+		}
+		nic.MacAddress = mac.String()
+	}
+	for _, disk := range inv.Disks {
+		switch disk.Type {
+		case "", "nvme", "ssd", "hdd":
+		default:
+			return ValidationError{fmt.Sprintf("invalid type of disk %s: %s", disk.Name, disk.Type)}
+		}
+	}
+	sort.Slice(inv.Nics, func(i, j int) bool { return inv.Nics[i].Name < inv.Nics[j].Name })
+	sort.Slice(inv.Disks, func(i, j int) bool { return inv.Disks[i].Name < inv.Disks[j].Name })
+	sort.Slice(inv.Accelerators, func(i, j int) bool {
+		if inv.Accelerators[i].PciAddress != inv.Accelerators[j].PciAddress {
+			return inv.Accelerators[i].PciAddress < inv.Accelerators[j].PciAddress
+		}
+		return inv.Accelerators[i].Serial < inv.Accelerators[j].Serial
+	})
+	return nil
+}
+
+// HardwareClass identifies nodes with the same vendor, model and accelerators,
+// e.g. supermicro/sys-821ge-tnhr+8xnvidia-h100-80gb-hbm3.
+func (inv *Inventory) HardwareClass() string {
+	slug := func(s string) string {
+		return strings.Trim(classReplacer.ReplaceAllString(strings.ToLower(s), "-"), "-")
+	}
+	class := slug(inv.Vendor) + "/" + slug(inv.Model)
+	if class == "/" {
+		class = "unknown"
+	}
+	counts := map[string]int{}
+	for _, a := range inv.Accelerators {
+		counts[slug(a.Model)]++
+	}
+	models := make([]string, 0, len(counts))
+	for model := range counts {
+		models = append(models, model)
+	}
+	sort.Strings(models)
+	for _, model := range models {
+		class += fmt.Sprintf("+%dx%s", counts[model], model)
+	}
+	return class
+}
+
+// changedFields returns the json names of the fields of inv differing in previous, every field set in inv without previous.
+func (inv *Inventory) changedFields(previous *Inventory) ([]string, error) {
+	fields := func(v *Inventory) (map[string]json.RawMessage, error) {
+		m := map[string]json.RawMessage{}
+		if v == nil {
+			return m, nil
+		}
+		b, err := json.Marshal(v)
+		if err != nil {
+			return nil, err
+		}
+		return m, json.Unmarshal(b, &m)
+	}
+	current, err := fields(inv)
+	if err != nil {
+		return nil, err
+	}
+	before, err := fields(previous)
+	if err != nil {
+		return nil, err
+	}
+	changed := []string{}
+	for name, v := range current {
+		if string(before[name]) != string(v) {
+			changed = append(changed, name)
+		}
+	}
+	for name := range before {
+		if _, ok := current[name]; !ok {
+			changed = append(changed, name)
+		}
+	}
+	sort.Strings(changed)
+	return changed, nil
+}
+
+// ReportInventory records the Inventory of macAddress, and the fields it changed since the previous report.
+func (s *Service) ReportInventory(ctx context.Context, macAddress string, inv *Inventory) (*NodeInventory, error) {
+	if macAddress == "" {
+		return nil, ValidationError{"Missing macAddress"}
+	}
+	if inv == nil {
+		return nil, ValidationError{"Missing inventory"}
+	}
+	if err := inv.normalize(); err != nil {
+		return nil, err
+	}
+	var ni *NodeInventory
+	err := s.db.WithTx(ctx, database.SerializableTxOptions, func(ctx context.Context) error {
+		var previous *Inventory
+		current, err := s.db.GetNodeInventory(ctx, macAddress)
+		switch {
+		case err == nil:
+			previous = current.Inventory
+		case !errdefs.IsNotFound(err):
+			return err
+		}
+		changed, err := inv.changedFields(previous)
+		if err != nil {
+			return err
+		}
+		if len(changed) > 0 {
+			logging.FromContext(ctx).Info("node inventory changed", zap.String("mac_address", macAddress), zap.Strings("changed_fields", changed))
+		}
+		ni, err = s.db.SetNodeInventory(ctx, &NodeInventory{MacAddress: macAddress, HardwareClass: inv.HardwareClass(), Inventory: inv}, previous, changed)
+		return err
+	})
+	return ni, err
+}
+
+// GetNodeInventory returns the last Inventory reported by macAddress, an errdefs.ErrNotFound error when there is none.
+func (s *Service) GetNodeInventory(ctx context.Context, macAddress string) (*NodeInventory, error) {
+	if macAddress == "" {
+		return nil, ValidationError{"Missing macAddress"}
+	}
+	return s.db.GetNodeInventory(ctx, macAddress)
+}
+
+// ListNodeInventories returns the inventories of the nodes in hardwareClass, every inventory when empty.
+func (s *Service) ListNodeInventories(ctx context.Context, hardwareClass string) ([]*NodeInventory, error) {
+	return s.db.ListNodeInventories(ctx, hardwareClass)
+}
+
+// ListInventoryChanges returns the inventory changes of macAddress, the latest first.
+func (s *Service) ListInventoryChanges(ctx context.Context, macAddress string) ([]*InventoryChange, error) {
+	if macAddress == "" {
+		return nil, ValidationError{"Missing macAddress"}
+	}
+	return s.db.ListInventoryChanges(ctx, macAddress)
+}
+
+// ListNodesWithHardwareClass returns the mac_address of the nodes whose last inventory is in hardwareClass.
+func (s *Service) ListNodesWithHardwareClass(ctx context.Context, hardwareClass string) ([]string, error) {
+	inventories, err := s.db.ListNodeInventories(ctx, hardwareClass)
+	if err != nil {
+		return nil, err
+	}
+	macAddresses := make([]string, 0, len(inventories))
+	for _, ni := range inventories {
+		macAddresses = append(macAddresses, ni.MacAddress)
+	}
+	return macAddresses, nil
+}
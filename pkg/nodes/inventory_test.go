@@ -0,0 +1,58 @@
+package nodes
+
+import (
+	"testing"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInventoryHardwareClass(t *testing.T) {
+	inv := &Inventory{
+		Vendor: "Supermicro",
+		Model:  "SYS-821GE-TNHR",
+		Accelerators: []*Accelerator{
+			{Model: "NVIDIA H100 80GB HBM3"},
+			{Model: "NVIDIA H100 80GB HBM3"},
+			{Model: "BlueField-3"},
+		},
+	}
+	assert.Equal(t, "supermicro/sys-821ge-tnhr+1xbluefield-3+2xnvidia-h100-80gb-hbm3", inv.HardwareClass())
+	assert.Equal(t, "unknown", (&Inventory{}).HardwareClass())
+}
+
+func TestInventoryChangedFields(t *testing.T) {
+	previous := &Inventory{
+		Serial:      "S1",
+		BiosVersion: "2.1",
+		Nics:        []*Nic{{Name: "eth0", MacAddress: "0c:42:a1:00:00:01"}, {Name: "eth1", MacAddress: "0c42a1000002"}},
+	}
+	require.NoError(t, previous.normalize())
+
+	changed, err := previous.changedFields(nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bios_version", "nics", "serial"}, changed)
+
+	// reordered nics are not a change
+	inv := &Inventory{
+		Serial:      "S1",
+		BiosVersion: "2.2",
+		Nics:        []*Nic{{Name: "eth1", MacAddress: "0C42A1000002"}, {Name: "eth0", MacAddress: "0c42a1000001"}},
+		MemoryBytes: 1 << 40,
+	}
+	require.NoError(t, inv.normalize())
+	changed, err = inv.changedFields(previous)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bios_version", "memory_bytes"}, changed)
+
+	changed, err = (&Inventory{Serial: "S1"}).changedFields(previous)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"bios_version", "nics"}, changed)
+}
+
+func TestInventoryNormalizeInvalid(t *testing.T) {
+	assert.ErrorIs(t, (&Inventory{Nics: []*Nic{{Name: "eth0", MacAddress: "zz"}}}).normalize(), errdefs.ErrInvalid)
+	assert.ErrorIs(t, (&Inventory{Disks: []*Disk{{Name: "sda", Type: "tape"}}}).normalize(), errdefs.ErrInvalid)
+}
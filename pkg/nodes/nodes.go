@@ -2,18 +2,223 @@ package nodes
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"net/netip"
+	"sort"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/ipxe"
+	"github.com/coreweave/ncore-api/pkg/payloads"
 )
 
 type Node struct {
 	MacAddress string `json:"mac_address"`
 	Hostname   string `json:"hostname"`
-	IpAddress  string `json:"ip_address"`
+	// IpAddress is the primary address of the node, the first of IpAddresses when unset.
+	IpAddress string `json:"ip_address"`
+	// IpAddresses are every address of the node, e.g. its IPv4 and IPv6 addresses when dual-stack.
+	IpAddresses []string `json:"ip_addresses,omitempty"`
+}
+
+// ErrSingleDatabaseRequired is returned by queries joining the ipxe, payloads and nodes schemas
+// when they are separate databases, see SetViewSources.
+var ErrSingleDatabaseRequired = errdefs.Unavailable("single_database_required", "requires the single database mode")
+
+// NodeView is a node with its image, payloads and last heartbeat.
+type NodeView struct {
+	MacAddress   string     `json:"mac_address"`
+	Hostname     string     `json:"hostname,omitempty"`
+	IpAddress    string     `json:"ip_address,omitempty"`
+	IpAddresses  []string   `json:"ip_addresses,omitempty"`
+	ImageTag     string     `json:"image_tag,omitempty"`
+	ImageType    string     `json:"image_type,omitempty"`
+	ImageChannel string     `json:"image_channel,omitempty"`
+	PayloadIds   []string   `json:"payload_ids"`
+	LastSeen     *time.Time `json:"last_seen,omitempty"`
 }
 
 // Update nodes stats by provided data payload.
+// The heartbeat of a node is recorded under its MacAddress whichever of its interfaces sends it, see NodeIdentity.
 func (s *Service) UpdateNodeStats(ctx context.Context, n *Node) (*Node, error) {
 	if n.MacAddress == "" {
 		return nil, ValidationError{"Missing macAddress"}
 	}
+	if err := normalizeIpAddresses(n); err != nil {
+		return nil, err
+	}
+	macAddress, err := s.db.ResolveMacAddress(ctx, n.MacAddress)
+	if err != nil {
+		return nil, err
+	}
+	n.MacAddress = macAddress
 	return s.db.UpdateNodeStats(ctx, n)
 }
+
+// normalizeIpAddresses parses the addresses of n, with or without prefix length, e.g. 10.1.2.3/24 or
+// 2001:db8::17, and sets IpAddresses to IpAddress followed by the other addresses without duplicates.
+// IPv4-mapped IPv6 addresses become IPv4 addresses and zones are dropped.
+func normalizeIpAddresses(n *Node) error {
+	reported := n.IpAddresses
+	if n.IpAddress != "" {
+		reported = append([]string{n.IpAddress}, reported...)
+	}
+	if len(reported) == 0 {
+		return ValidationError{"Missing ipAddress"}
+	}
+	seen := map[netip.Addr]bool{}
+	n.IpAddresses = make([]string, 0, len(reported))
+	for _, s := range reported {
+		addr, err := netip.ParseAddr(s)
+		if prefix, perr := netip.ParsePrefix(s); err != nil && perr == nil {
+			addr, err = prefix.Addr(), nil
+		}
+		if err != nil {
+			return ValidationError{fmt.Sprintf("invalid ipAddress: %s", s)}
+		}
+		addr = addr.Unmap().WithZone("")
+		if !seen[addr] {
+			seen[addr] = true
+			n.IpAddresses = append(n.IpAddresses, addr.String())
+		}
+	}
+	n.IpAddress = n.IpAddresses[0]
+	return nil
+}
+
+// GetNodesLastSeen returns the last heartbeat of each of macAddresses, nodes without heartbeat are omitted.
+func (s *Service) GetNodesLastSeen(ctx context.Context, macAddresses []string) (map[string]time.Time, error) {
+	if len(macAddresses) == 0 {
+		return map[string]time.Time{}, nil
+	}
+	return s.db.GetNodesLastSeen(ctx, macAddresses)
+}
+
+// CountNodesLastSeen returns the number of nodes whose last heartbeat is within each of within,
+// and the number of nodes with a heartbeat.
+func (s *Service) CountNodesLastSeen(ctx context.Context, within []time.Duration) ([]int, int, error) {
+	return s.db.CountNodesLastSeen(ctx, within)
+}
+
+// ListNodesInSubnet returns the mac_address of the nodes whose last heartbeat reported an ip_address in subnet.
+func (s *Service) ListNodesInSubnet(ctx context.Context, subnet string) ([]string, error) {
+	prefix, err := netip.ParsePrefix(subnet)
+	if err != nil {
+		return nil, ValidationError{fmt.Sprintf("invalid subnet: %s", subnet)}
+	}
+	return s.db.ListNodesInSubnet(ctx, prefix.Masked().String())
+}
+
+// ImageSource reads the node_images entries of the ipxe database, see ipxe.Service.
+type ImageSource interface {
+	GetNodeImage(ctx context.Context, macAddress string) (*ipxe.IpxeNodeDbConfig, error)
+	ListNodeImages(ctx context.Context) ([]*ipxe.IpxeNodeDbConfig, error)
+}
+
+// PayloadSource reads the node_payloads entries of the payloads database, see payloads.Service.
+type PayloadSource interface {
+	GetNodePayloads(ctx context.Context, macAddress string) ([]*payloads.NodePayload, error)
+	ListNodePayloads(ctx context.Context) ([]*payloads.NodePayload, error)
+}
+
+// SetViewSources sets where node views read images and payloads from when ipxe, payloads and nodes
+// are separate databases. Node views return ErrSingleDatabaseRequired in that case without them.
+func (s *Service) SetViewSources(images ImageSource, payloads PayloadSource) {
+	s.images = images
+	s.payloads = payloads
+}
+
+// ListNodeViews returns every node known to node_images, node_payloads or node_heartbeat.
+func (s *Service) ListNodeViews(ctx context.Context) ([]*NodeView, error) {
+	return s.listNodeViews(ctx, "")
+}
+
+// listNodeViews joins node_images, node_payloads and node_heartbeat in the database when they share one,
+// and otherwise merges the entries read from each database, for macAddress only unless empty.
+func (s *Service) listNodeViews(ctx context.Context, macAddress string) ([]*NodeView, error) {
+	views, err := s.db.ListNodeViews(ctx, macAddress)
+	if !errors.Is(err, ErrSingleDatabaseRequired) || s.images == nil || s.payloads == nil {
+		return views, err
+	}
+	heartbeats, err := s.db.ListNodeHeartbeatViews(ctx, macAddress)
+	if err != nil {
+		return nil, err
+	}
+	var images []*ipxe.IpxeNodeDbConfig
+	var nps []*payloads.NodePayload
+	if macAddress == "" {
+		if images, err = s.images.ListNodeImages(ctx); err != nil {
+			return nil, err
+		}
+		if nps, err = s.payloads.ListNodePayloads(ctx); err != nil {
+			return nil, err
+		}
+	} else {
+		image, err := s.images.GetNodeImage(ctx, macAddress)
+		if err == nil {
+			images = append(images, image)
+		} else if !errdefs.IsNotFound(err) {
+			return nil, err
+		}
+		if nps, err = s.payloads.GetNodePayloads(ctx, macAddress); err != nil {
+			return nil, err
+		}
+	}
+	return mergeNodeViews(heartbeats, images, nps), nil
+}
+
+// mergeNodeViews adds images and nps to the views of heartbeats, and the views of the nodes without
+// heartbeat, ordered by mac_address like the joined query.
+func mergeNodeViews(heartbeats []*NodeView, images []*ipxe.IpxeNodeDbConfig, nps []*payloads.NodePayload) []*NodeView {
+	byMacAddress := map[string]*NodeView{}
+	view := func(macAddress string) *NodeView {
+		v := byMacAddress[macAddress]
+		if v == nil {
+			v = &NodeView{MacAddress: macAddress}
+			byMacAddress[macAddress] = v
+		}
+		return v
+	}
+	for _, h := range heartbeats {
+		byMacAddress[h.MacAddress] = h
+	}
+	for _, image := range images {
+		v := view(image.MacAddress)
+		v.ImageTag, v.ImageType, v.ImageChannel = image.ImageTag, image.ImageType, image.ImageChannel
+	}
+	for _, np := range nps {
+		v := view(np.MacAddress)
+		v.PayloadIds = append(v.PayloadIds, np.PayloadId)
+	}
+	views := make([]*NodeView, 0, len(byMacAddress))
+	for _, v := range byMacAddress {
+		if v.PayloadIds == nil {
+			v.PayloadIds = []string{}
+		}
+		sort.Strings(v.PayloadIds)
+		views = append(views, v)
+	}
+	sort.Slice(views, func(i, j int) bool { return views[i].MacAddress < views[j].MacAddress })
+	return views
+}
+
+// GetNodeView returns the image, payloads and last heartbeat of the node of macAddress,
+// an errdefs.ErrNotFound error when the node is unknown.
+func (s *Service) GetNodeView(ctx context.Context, macAddress string) (*NodeView, error) {
+	if macAddress == "" {
+		return nil, ValidationError{"Missing macAddress"}
+	}
+	macAddress, err := s.db.ResolveMacAddress(ctx, macAddress)
+	if err != nil {
+		return nil, err
+	}
+	views, err := s.listNodeViews(ctx, macAddress)
+	if err != nil {
+		return nil, err
+	}
+	if len(views) == 0 {
+		return nil, errdefs.NotFound("node_not_found", "mac_address not in database: %s", macAddress)
+	}
+	return views[0], nil
+}
@@ -0,0 +1,62 @@
+package nodes
+
+import (
+	"testing"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/ipxe"
+	"github.com/coreweave/ncore-api/pkg/payloads"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeIpAddresses(t *testing.T) {
+	tests := []struct {
+		name        string
+		node        Node
+		ipAddress   string
+		ipAddresses []string
+	}{
+		{"ipv4", Node{IpAddress: "10.1.2.3"}, "10.1.2.3", []string{"10.1.2.3"}},
+		{"ipv6", Node{IpAddress: "2001:DB8::17"}, "2001:db8::17", []string{"2001:db8::17"}},
+		{"dual-stack", Node{IpAddress: "10.1.2.3", IpAddresses: []string{"2001:db8::17", "10.1.2.3"}}, "10.1.2.3", []string{"10.1.2.3", "2001:db8::17"}},
+		{"primary from addresses", Node{IpAddresses: []string{"2001:db8::17/64", "10.1.2.3/24"}}, "2001:db8::17", []string{"2001:db8::17", "10.1.2.3"}},
+		{"mapped and zoned", Node{IpAddress: "::ffff:10.1.2.3", IpAddresses: []string{"fe80::1%eth0"}}, "10.1.2.3", []string{"10.1.2.3", "fe80::1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			n := tt.node
+			require.NoError(t, normalizeIpAddresses(&n))
+			assert.Equal(t, tt.ipAddress, n.IpAddress)
+			assert.Equal(t, tt.ipAddresses, n.IpAddresses)
+		})
+	}
+
+	for _, n := range []Node{{}, {IpAddress: "node-1"}, {IpAddress: "10.1.2.3", IpAddresses: []string{"10.1.2.3:8080"}}} {
+		assert.ErrorIs(t, normalizeIpAddresses(&n), errdefs.ErrInvalid, n)
+	}
+}
+
+func TestMergeNodeViews(t *testing.T) {
+	lastSeen := time.Date(2023, 3, 20, 22, 15, 17, 0, time.UTC)
+	heartbeats := []*NodeView{{MacAddress: "0c42a1b2c3d5", Hostname: "node-2", IpAddress: "10.1.2.3", IpAddresses: []string{"10.1.2.3"}, LastSeen: &lastSeen}}
+	images := []*ipxe.IpxeNodeDbConfig{
+		{MacAddress: "0c42a1b2c3d5", ImageTag: "v2", ImageType: "ramdisk"},
+		{MacAddress: "0c42a1b2c3d4", ImageChannel: "stable"},
+	}
+	nps := []*payloads.NodePayload{
+		{MacAddress: "0c42a1b2c3d5", PayloadId: "gpu"},
+		{MacAddress: "0c42a1b2c3d5", PayloadId: "default"},
+		{MacAddress: "0c42a1b2c3d6", PayloadId: "default"},
+	}
+
+	views := mergeNodeViews(heartbeats, images, nps)
+
+	assert.Equal(t, []*NodeView{
+		{MacAddress: "0c42a1b2c3d4", ImageChannel: "stable", PayloadIds: []string{}},
+		{MacAddress: "0c42a1b2c3d5", Hostname: "node-2", IpAddress: "10.1.2.3", IpAddresses: []string{"10.1.2.3"}, ImageTag: "v2", ImageType: "ramdisk", PayloadIds: []string{"default", "gpu"}, LastSeen: &lastSeen},
+		{MacAddress: "0c42a1b2c3d6", PayloadIds: []string{"default"}},
+	}, views)
+}
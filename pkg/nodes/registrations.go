@@ -0,0 +1,116 @@
+package nodes
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/macaddr"
+)
+
+// Registration of a node that booted without a node_images entry under enrollment.PolicyApprove.
+type Registration struct {
+	MacAddress string           `json:"mac_address"`
+	IpAddress  string           `json:"ip_address"`
+	Hints      enrollment.Hints `json:"hints"`
+	State      string           `json:"state"`
+	// RuleId is the RegistrationRule that approved the node, unset when approved through the api.
+	RuleId    *int64     `json:"rule_id,omitempty"`
+	FirstSeen time.Time  `json:"first_seen"`
+	LastSeen  time.Time  `json:"last_seen"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+}
+
+// RegistrationRule approves pending nodes booting from Subnet, with a mac_address in MacOui, or both.
+// Approved nodes are assigned the image and payload of the rule, their defaults when unset.
+type RegistrationRule struct {
+	RuleId       int64     `json:"rule_id"`
+	Subnet       string    `json:"subnet,omitempty"`
+	MacOui       string    `json:"mac_oui,omitempty"`
+	ImageTag     string    `json:"image_tag,omitempty"`
+	ImageType    string    `json:"image_type,omitempty"`
+	ImageChannel string    `json:"image_channel,omitempty"`
+	PayloadId    string    `json:"payload_id,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// RegisterNode records a boot of r.MacAddress, adding a pending registration for new nodes.
+// The hints are merged into the ones recorded by previous boots.
+func (s *Service) RegisterNode(ctx context.Context, r *Registration) (*Registration, error) {
+	if r.MacAddress == "" {
+		return nil, ValidationError{"Missing macAddress"}
+	}
+	if r.Hints == nil {
+		r.Hints = enrollment.Hints{}
+	}
+	return s.db.RegisterNode(ctx, r)
+}
+
+// GetRegistration returns the registration of macAddress, an errdefs.ErrNotFound error when there is none.
+func (s *Service) GetRegistration(ctx context.Context, macAddress string) (*Registration, error) {
+	if macAddress == "" {
+		return nil, ValidationError{"Missing macAddress"}
+	}
+	return s.db.GetRegistration(ctx, macAddress)
+}
+
+// ListRegistrations returns the registrations in state, every registration when empty.
+func (s *Service) ListRegistrations(ctx context.Context, state string) ([]*Registration, error) {
+	switch state {
+	case "", enrollment.StatePending, enrollment.StateApproved, enrollment.StateRejected:
+	default:
+		return nil, ValidationError{fmt.Sprintf("invalid state: %s", state)}
+	}
+	return s.db.ListRegistrations(ctx, state)
+}
+
+// SetRegistrationState approves or rejects the registration of macAddress, ruleId is the approving rule if any.
+func (s *Service) SetRegistrationState(ctx context.Context, macAddress string, state string, ruleId *int64) (*Registration, error) {
+	if state != enrollment.StateApproved && state != enrollment.StateRejected {
+		return nil, ValidationError{fmt.Sprintf("invalid state: %s", state)}
+	}
+	return s.db.SetRegistrationState(ctx, macAddress, state, ruleId)
+}
+
+// MatchRegistrationRule returns the first rule, by RuleId, matching macAddress booting from ipAddress,
+// an errdefs.ErrNotFound error when none does.
+func (s *Service) MatchRegistrationRule(ctx context.Context, macAddress string, ipAddress string) (*RegistrationRule, error) {
+	if _, err := netip.ParseAddr(ipAddress); err != nil {
+		return nil, ValidationError{fmt.Sprintf("invalid ip_address: %s", ipAddress)}
+	}
+	return s.db.MatchRegistrationRule(ctx, macAddress, ipAddress)
+}
+
+// ListRegistrationRules returns every registration rule ordered by RuleId.
+func (s *Service) ListRegistrationRules(ctx context.Context) ([]*RegistrationRule, error) {
+	return s.db.ListRegistrationRules(ctx)
+}
+
+// CreateRegistrationRule adds rule after validating its Subnet and MacOui, its targets are checked by the caller.
+func (s *Service) CreateRegistrationRule(ctx context.Context, rule *RegistrationRule) (*RegistrationRule, error) {
+	if rule.Subnet == "" && rule.MacOui == "" {
+		return nil, ValidationError{"missing subnet or mac_oui"}
+	}
+	if rule.Subnet != "" {
+		prefix, err := netip.ParsePrefix(rule.Subnet)
+		if err != nil {
+			return nil, ValidationError{fmt.Sprintf("invalid subnet: %s", rule.Subnet)}
+		}
+		rule.Subnet = prefix.Masked().String()
+	}
+	if rule.MacOui != "" {
+		oui, err := macaddr.ParseOUI(rule.MacOui)
+		if err != nil {
+			return nil, err
+		}
+		rule.MacOui = oui
+	}
+	return s.db.CreateRegistrationRule(ctx, rule)
+}
+
+// DeleteRegistrationRule deletes the rule ruleId, registrations it approved keep their state.
+func (s *Service) DeleteRegistrationRule(ctx context.Context, ruleId int64) (*RegistrationRule, error) {
+	return s.db.DeleteRegistrationRule(ctx, ruleId)
+}
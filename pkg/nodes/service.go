@@ -2,22 +2,78 @@ package nodes
 
 import (
 	"context"
-	"log"
+	"text/template"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"go.uber.org/zap"
 )
 
 func NewService(db DB) *Service {
-	log.Printf("Starting Nodes service")
+	zap.L().Info("starting nodes service")
 	return &Service{
-		db: db,
+		db:               db,
+		hostnameTemplate: template.Must(parseHostnameTemplate(DefaultHostnameTemplate)),
 	}
 }
 
 type Service struct {
-	db DB
+	db               DB
+	hostnameTemplate *template.Template
+	images           ImageSource
+	payloads         PayloadSource
 }
 
 type DB interface {
 	UpdateNodeStats(ctx context.Context, n *Node) (*Node, error)
+	GetNodesLastSeen(ctx context.Context, macAddresses []string) (map[string]time.Time, error)
+	CountNodesLastSeen(ctx context.Context, within []time.Duration) ([]int, int, error)
+	ListNodesInSubnet(ctx context.Context, subnet string) ([]string, error)
+	// ListNodeViews joins node_images, node_payloads and node_heartbeat, for macAddress only unless empty.
+	// Returns ErrSingleDatabaseRequired unless the three schemas share a database.
+	ListNodeViews(ctx context.Context, macAddress string) ([]*NodeView, error)
+	// ListNodeHeartbeatViews returns the views of the node_heartbeat entries without image and payloads,
+	// for macAddress only unless empty.
+	ListNodeHeartbeatViews(ctx context.Context, macAddress string) ([]*NodeView, error)
+
+	// WithTx runs fn in a transaction used by every DB method called with the ctx passed to fn.
+	WithTx(ctx context.Context, opts database.TxOptions, fn func(ctx context.Context) error) error
+	// RegisterNode adds a pending node_registrations entry, or updates the ip_address, hints and last_seen of the existing one.
+	RegisterNode(ctx context.Context, r *Registration) (*Registration, error)
+	GetRegistration(ctx context.Context, macAddress string) (*Registration, error)
+	ListRegistrations(ctx context.Context, state string) ([]*Registration, error)
+	SetRegistrationState(ctx context.Context, macAddress string, state string, ruleId *int64) (*Registration, error)
+	MatchRegistrationRule(ctx context.Context, macAddress string, ipAddress string) (*RegistrationRule, error)
+	ListRegistrationRules(ctx context.Context) ([]*RegistrationRule, error)
+	CreateRegistrationRule(ctx context.Context, rule *RegistrationRule) (*RegistrationRule, error)
+	DeleteRegistrationRule(ctx context.Context, ruleId int64) (*RegistrationRule, error)
+	GetNodeInventory(ctx context.Context, macAddress string) (*NodeInventory, error)
+	// SetNodeInventory upserts ni, recording an InventoryChange from previous unless changedFields is empty.
+	SetNodeInventory(ctx context.Context, ni *NodeInventory, previous *Inventory, changedFields []string) (*NodeInventory, error)
+	ListNodeInventories(ctx context.Context, hardwareClass string) ([]*NodeInventory, error)
+	ListInventoryChanges(ctx context.Context, macAddress string) ([]*InventoryChange, error)
+	// ResolveMacAddress returns the MacAddress of the node macAddress is attached to, macAddress itself when there is none.
+	ResolveMacAddress(ctx context.Context, macAddress string) (string, error)
+	// GetNodeIdentity returns the node macAddress is attached to, or else the node keyed by macAddress.
+	GetNodeIdentity(ctx context.Context, macAddress string) (*NodeIdentity, error)
+	// CreateNodeIdentity adds a node keyed by macAddress with macAddress attached.
+	CreateNodeIdentity(ctx context.Context, macAddress string) (*NodeIdentity, error)
+	// AttachInterface attaches iface to nodeId, or renames it when it is attached already.
+	AttachInterface(ctx context.Context, nodeId int64, iface *Interface) error
+	DetachInterface(ctx context.Context, macAddress string) error
+	GetNodeHostname(ctx context.Context, macAddress string) (*NodeHostname, error)
+	// SetNodeHostname upserts the node_hostnames entry of nh.MacAddress, an errdefs.ErrConflict error when another node has nh.Hostname.
+	SetNodeHostname(ctx context.Context, nh *NodeHostname) (*NodeHostname, error)
+	DeleteNodeHostname(ctx context.Context, macAddress string) (*NodeHostname, error)
+	LookupHostname(ctx context.Context, hostname string) (*NodeHostname, error)
+	// MatchHostnamePolicy returns the hostname policy with the longest subnet containing ipAddress, or else the one without subnet.
+	MatchHostnamePolicy(ctx context.Context, ipAddress string) (*HostnamePolicy, error)
+	// IncrementHostnamePolicyCounter increments the counter of policyId and returns it.
+	IncrementHostnamePolicyCounter(ctx context.Context, policyId int64) (int64, error)
+	ListHostnamePolicies(ctx context.Context) ([]*HostnamePolicy, error)
+	CreateHostnamePolicy(ctx context.Context, policy *HostnamePolicy) (*HostnamePolicy, error)
+	DeleteHostnamePolicy(ctx context.Context, policyId int64) (*HostnamePolicy, error)
 }
 
 type ValidationError struct {
@@ -27,3 +83,13 @@ type ValidationError struct {
 func (e ValidationError) Error() string {
 	return e.s
 }
+
+// Is makes ValidationError an errdefs.ErrInvalid error.
+func (e ValidationError) Is(target error) bool {
+	return target == errdefs.ErrInvalid
+}
+
+// WithTx runs fn in a transaction on the nodes database, see postgres.DB.WithTx.
+func (s *Service) WithTx(ctx context.Context, opts database.TxOptions, fn func(ctx context.Context) error) error {
+	return s.db.WithTx(ctx, opts, fn)
+}
@@ -0,0 +1,139 @@
+package payloads
+
+import (
+	"context"
+
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/metrics"
+	"github.com/coreweave/ncore-api/pkg/snapshot"
+	"go.uber.org/zap"
+)
+
+// SetEnrollmentPolicy sets how nodes booting without a node_payloads entry are enrolled, enrollment.PolicyPin by default.
+func (s *Service) SetEnrollmentPolicy(policy enrollment.Policy) {
+	s.enrollmentPolicy = policy
+}
+
+// SetRegistrar sets where unknown nodes are registered under enrollment.PolicyApprove.
+func (s *Service) SetRegistrar(registrar enrollment.Registrar) {
+	s.registrar = registrar
+}
+
+// SetDiscoveryPayload sets the payload pending nodes boot with under enrollment.PolicyApprove, the api default by default.
+func (s *Service) SetDiscoveryPayload(payloadId string, payloadDirectory string) {
+	s.discoveryPayload = &Payload{PayloadId: payloadId, PayloadDirectory: payloadDirectory}
+}
+
+// BootNodePayload returns the payloads the node of macAddress boots with from ipAddress: the payloads of its node_payloads entries,
+// or the default of the subnet containing ipAddress or the api default for unknown and following nodes.
+// Unknown nodes are enrolled according to the enrollment policy, an enrollment.NotRegistered error is
+// returned for them under enrollment.PolicyRegister. Under enrollment.PolicyApprove they are registered with hints
+// and boot with the discovery payload until they are approved.
+func (s *Service) BootNodePayload(ctx context.Context, macAddress string, ipAddress string, hints enrollment.Hints) ([]*NodePayload, error) {
+	if macAddress == "" {
+		return nil, ValidationError{"missing payload macAddress"}
+	}
+	macAddress = s.bootMacAddress(ctx, macAddress)
+	assigned, err := s.getNodePayloads(ctx, macAddress)
+	if err != nil {
+		return nil, err
+	}
+	following := len(assigned) > 0 && assigned[0].Enrollment == enrollment.Following
+	if len(assigned) > 0 && !following {
+		metrics.BootResolutions.WithLabelValues(metrics.KindPayload, metrics.SourceNode).Inc()
+		return assigned, nil
+	}
+	logger := logging.FromContext(ctx).With(zap.String("mac_address", macAddress))
+	if !following && s.enrollmentPolicy == enrollment.PolicyRegister {
+		logger.Info("refusing to boot unregistered node", zap.String("request_ip", ipAddress))
+		return nil, enrollment.NotRegistered(macAddress)
+	}
+	if !following && s.enrollmentPolicy == enrollment.PolicyApprove {
+		switch state, err := s.registrar.RegisterNode(ctx, macAddress, ipAddress, hints); {
+		case err != nil:
+			logger.Error("cannot register node, booting discovery payload", zap.Error(err))
+			return s.bootDiscoveryPayload(ctx, macAddress), nil
+		case state == enrollment.StateRejected:
+			logger.Info("refusing to boot rejected node", zap.String("request_ip", ipAddress))
+			return nil, enrollment.NotRegistered(macAddress)
+		case state == enrollment.StatePending:
+			return s.bootDiscoveryPayload(ctx, macAddress), nil
+		}
+		// approved nodes boot the payload assigned by the approval, or are pinned to their default
+		if assigned, err = s.getNodePayloads(ctx, macAddress); err != nil {
+			return nil, err
+		}
+		if len(assigned) > 0 {
+			metrics.BootResolutions.WithLabelValues(metrics.KindPayload, metrics.SourceNode).Inc()
+			return assigned, nil
+		}
+	}
+
+	logger.Debug("checking subnet_default_payloads", zap.String("request_ip", ipAddress))
+	p, err := s.GetSubnetDefaultPayload(ctx, ipAddress)
+	switch {
+	case err == nil:
+		logger.Info("using subnet default payload", zap.String("payload_id", p.PayloadId))
+		metrics.BootResolutions.WithLabelValues(metrics.KindPayload, metrics.SourceSubnetDefault).Inc()
+	case !errdefs.IsNotFound(err):
+		return nil, err
+	default:
+		logger.Info("using api default payload")
+		metrics.BootResolutions.WithLabelValues(metrics.KindPayload, metrics.SourceAPIDefault).Inc()
+		p = s.GetDefaultPayload(ctx)
+	}
+	np := &NodePayload{
+		PayloadId:        p.PayloadId,
+		PayloadDirectory: p.PayloadDirectory,
+		MacAddress:       macAddress,
+		Enrollment:       s.enrollmentPolicy.Enrollment(),
+	}
+	config := &NodePayloadDb{
+		PayloadId:  p.PayloadId,
+		MacAddress: macAddress,
+		Enrollment: np.Enrollment,
+	}
+	logger = logger.With(zap.String("payload_id", config.PayloadId))
+	switch _, stale := snapshot.Stale(ctx); {
+	case stale:
+		// the database is unavailable, the node is enrolled or updated once it is back
+		logger.Warn("not recording node_payloads entry from snapshot")
+		if following {
+			np.Enrollment = enrollment.Following
+		}
+		return []*NodePayload{np}, nil
+	case following:
+		np.Enrollment = enrollment.Following
+		if assigned[0].PayloadId == config.PayloadId {
+			return []*NodePayload{np}, nil
+		}
+		config.Enrollment = enrollment.Following
+		logger.Info("updating following node_payloads entry", zap.String("previous_payload_id", assigned[0].PayloadId))
+		return s.UpdateNodePayload(ctx, config)
+	default:
+		logger.Info("adding defaulted node_payloads entry", zap.String("enrollment", config.Enrollment))
+		nps, err := s.AddNodePayload(ctx, config)
+		if err != nil {
+			return nil, err
+		}
+		metrics.DefaultedNodes.WithLabelValues(metrics.KindPayload).Inc()
+		return nps, nil
+	}
+}
+
+// bootDiscoveryPayload returns the discovery payload booted by pending nodes.
+func (s *Service) bootDiscoveryPayload(ctx context.Context, macAddress string) []*NodePayload {
+	p := s.discoveryPayload
+	if p == nil {
+		p = s.GetDefaultPayload(ctx)
+	}
+	logging.FromContext(ctx).Info("using discovery payload", zap.String("mac_address", macAddress), zap.String("payload_id", p.PayloadId))
+	metrics.BootResolutions.WithLabelValues(metrics.KindPayload, metrics.SourceDiscovery).Inc()
+	return []*NodePayload{{
+		PayloadId:        p.PayloadId,
+		PayloadDirectory: p.PayloadDirectory,
+		MacAddress:       macAddress,
+	}}
+}
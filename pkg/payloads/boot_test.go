@@ -0,0 +1,49 @@
+package payloads
+
+import (
+	"context"
+	"testing"
+
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestService_BootNodePayload_follow(t *testing.T) {
+	svc, db := newTestService(t)
+	svc.SetEnrollmentPolicy(enrollment.PolicyFollow)
+	added := []*NodePayload{{PayloadId: "default", PayloadDirectory: "/payloads/default", MacAddress: "0c42a1b2c3d4", Enrollment: enrollment.Following}}
+	db.EXPECT().GetNodePayloads(gomock.Any(), "0c42a1b2c3d4").Return(nil, nil)
+	db.EXPECT().GetSubnetDefaultPayload(gomock.Any(), "10.1.2.3").Return(nil, errdefs.NotFound("subnet_default_payload_not_found", "no subnet"))
+	db.EXPECT().AddNodePayload(gomock.Any(), &NodePayloadDb{PayloadId: "default", MacAddress: "0c42a1b2c3d4", Enrollment: enrollment.Following}).Return(added, nil)
+
+	nps, err := svc.BootNodePayload(context.Background(), "0c42a1b2c3d4", "10.1.2.3", nil)
+
+	require.NoError(t, err)
+	assert.Equal(t, added, nps)
+}
+
+func TestService_BootNodePayload_following(t *testing.T) {
+	svc, db := newTestService(t)
+	db.EXPECT().GetNodePayloads(gomock.Any(), "0c42a1b2c3d4").Return([]*NodePayload{{PayloadId: "gpu", MacAddress: "0c42a1b2c3d4", Enrollment: enrollment.Following}}, nil)
+	db.EXPECT().GetSubnetDefaultPayload(gomock.Any(), "10.1.2.3").Return(&Payload{PayloadId: "gpu", PayloadDirectory: "/payloads/gpu"}, nil)
+
+	nps, err := svc.BootNodePayload(context.Background(), "0c42a1b2c3d4", "10.1.2.3", nil)
+
+	require.NoError(t, err)
+	require.Len(t, nps, 1)
+	assert.Equal(t, "/payloads/gpu", nps[0].PayloadDirectory, "unchanged defaults are not written")
+	assert.Equal(t, enrollment.Following, nps[0].Enrollment)
+}
+
+func TestService_BootNodePayload_register(t *testing.T) {
+	svc, db := newTestService(t)
+	svc.SetEnrollmentPolicy(enrollment.PolicyRegister)
+	db.EXPECT().GetNodePayloads(gomock.Any(), "0c42a1b2c3d4").Return(nil, nil)
+
+	_, err := svc.BootNodePayload(context.Background(), "0c42a1b2c3d4", "10.1.2.3", nil)
+
+	assert.Equal(t, "node_not_registered", errdefs.Code(err))
+}
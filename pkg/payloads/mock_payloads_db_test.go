@@ -8,6 +8,7 @@ import (
 	context "context"
 	reflect "reflect"
 
+	database "github.com/coreweave/ncore-api/pkg/database"
 	gomock "github.com/golang/mock/gomock"
 )
 
@@ -34,19 +35,63 @@ func (m *MockDB) EXPECT() *MockDBMockRecorder {
 	return m.recorder
 }
 
-// GetNodePayload mocks base method.
-func (m *MockDB) GetNodePayload(arg0 context.Context, arg1 string) (*NodePayload, error) {
+// AddNodePayload mocks base method.
+func (m *MockDB) AddNodePayload(arg0 context.Context, arg1 *NodePayloadDb) ([]*NodePayload, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "GetNodePayload", arg0, arg1)
-	ret0, _ := ret[0].(*NodePayload)
+	ret := m.ctrl.Call(m, "AddNodePayload", arg0, arg1)
+	ret0, _ := ret[0].([]*NodePayload)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
-// GetNodePayload indicates an expected call of GetNodePayload.
-func (mr *MockDBMockRecorder) GetNodePayload(arg0, arg1 interface{}) *gomock.Call {
+// AddNodePayload indicates an expected call of AddNodePayload.
+func (mr *MockDBMockRecorder) AddNodePayload(arg0, arg1 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodePayload", reflect.TypeOf((*MockDB)(nil).GetNodePayload), arg0, arg1)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddNodePayload", reflect.TypeOf((*MockDB)(nil).AddNodePayload), arg0, arg1)
+}
+
+// DeleteNodePayload mocks base method.
+func (m *MockDB) DeleteNodePayload(arg0 context.Context, arg1 *NodePayloadDb) ([]*NodePayload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteNodePayload", arg0, arg1)
+	ret0, _ := ret[0].([]*NodePayload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteNodePayload indicates an expected call of DeleteNodePayload.
+func (mr *MockDBMockRecorder) DeleteNodePayload(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteNodePayload", reflect.TypeOf((*MockDB)(nil).DeleteNodePayload), arg0, arg1)
+}
+
+// GetAvailablePayloads mocks base method.
+func (m *MockDB) GetAvailablePayloads(arg0 context.Context) []string {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAvailablePayloads", arg0)
+	ret0, _ := ret[0].([]string)
+	return ret0
+}
+
+// GetAvailablePayloads indicates an expected call of GetAvailablePayloads.
+func (mr *MockDBMockRecorder) GetAvailablePayloads(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAvailablePayloads", reflect.TypeOf((*MockDB)(nil).GetAvailablePayloads), arg0)
+}
+
+// GetNodePayloads mocks base method.
+func (m *MockDB) GetNodePayloads(arg0 context.Context, arg1 string) ([]*NodePayload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetNodePayloads", arg0, arg1)
+	ret0, _ := ret[0].([]*NodePayload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetNodePayloads indicates an expected call of GetNodePayloads.
+func (mr *MockDBMockRecorder) GetNodePayloads(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetNodePayloads", reflect.TypeOf((*MockDB)(nil).GetNodePayloads), arg0, arg1)
 }
 
 // GetPayloadParameters mocks base method.
@@ -63,3 +108,92 @@ func (mr *MockDBMockRecorder) GetPayloadParameters(arg0, arg1 interface{}) *gomo
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetPayloadParameters", reflect.TypeOf((*MockDB)(nil).GetPayloadParameters), arg0, arg1)
 }
+
+// GetSubnetDefaultPayload mocks base method.
+func (m *MockDB) GetSubnetDefaultPayload(arg0 context.Context, arg1 string) (*Payload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetSubnetDefaultPayload", arg0, arg1)
+	ret0, _ := ret[0].(*Payload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetSubnetDefaultPayload indicates an expected call of GetSubnetDefaultPayload.
+func (mr *MockDBMockRecorder) GetSubnetDefaultPayload(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetSubnetDefaultPayload", reflect.TypeOf((*MockDB)(nil).GetSubnetDefaultPayload), arg0, arg1)
+}
+
+// ListNodePayloadMacAddresses mocks base method.
+func (m *MockDB) ListNodePayloadMacAddresses(arg0 context.Context, arg1 string) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNodePayloadMacAddresses", arg0, arg1)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNodePayloadMacAddresses indicates an expected call of ListNodePayloadMacAddresses.
+func (mr *MockDBMockRecorder) ListNodePayloadMacAddresses(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodePayloadMacAddresses", reflect.TypeOf((*MockDB)(nil).ListNodePayloadMacAddresses), arg0, arg1)
+}
+
+// ListNodePayloads mocks base method.
+func (m *MockDB) ListNodePayloads(arg0 context.Context) ([]*NodePayload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListNodePayloads", arg0)
+	ret0, _ := ret[0].([]*NodePayload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListNodePayloads indicates an expected call of ListNodePayloads.
+func (mr *MockDBMockRecorder) ListNodePayloads(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListNodePayloads", reflect.TypeOf((*MockDB)(nil).ListNodePayloads), arg0)
+}
+
+// ListSubnetDefaultPayloads mocks base method.
+func (m *MockDB) ListSubnetDefaultPayloads(arg0 context.Context) ([]*SubnetDefaultPayload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListSubnetDefaultPayloads", arg0)
+	ret0, _ := ret[0].([]*SubnetDefaultPayload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListSubnetDefaultPayloads indicates an expected call of ListSubnetDefaultPayloads.
+func (mr *MockDBMockRecorder) ListSubnetDefaultPayloads(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListSubnetDefaultPayloads", reflect.TypeOf((*MockDB)(nil).ListSubnetDefaultPayloads), arg0)
+}
+
+// UpdateNodePayload mocks base method.
+func (m *MockDB) UpdateNodePayload(arg0 context.Context, arg1 *NodePayloadDb) ([]*NodePayload, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateNodePayload", arg0, arg1)
+	ret0, _ := ret[0].([]*NodePayload)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateNodePayload indicates an expected call of UpdateNodePayload.
+func (mr *MockDBMockRecorder) UpdateNodePayload(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateNodePayload", reflect.TypeOf((*MockDB)(nil).UpdateNodePayload), arg0, arg1)
+}
+
+// WithTx mocks base method.
+func (m *MockDB) WithTx(arg0 context.Context, arg1 database.TxOptions, arg2 func(context.Context) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "WithTx", arg0, arg1, arg2)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// WithTx indicates an expected call of WithTx.
+func (mr *MockDBMockRecorder) WithTx(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "WithTx", reflect.TypeOf((*MockDB)(nil).WithTx), arg0, arg1, arg2)
+}
@@ -2,7 +2,15 @@ package payloads
 
 import (
 	"context"
+	"fmt"
+	"net/netip"
 	"time"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/snapshot"
+	"go.uber.org/zap"
 )
 
 // NodePayload with directory for mac_address.
@@ -10,12 +18,16 @@ type NodePayload struct {
 	PayloadId        string
 	PayloadDirectory string
 	MacAddress       string
+	// Enrollment of the node_payloads entry, see package enrollment.
+	Enrollment string `json:",omitempty"`
 }
 
 // NodePayload payloads.node_payloads entry for mac_address.
 type NodePayloadDb struct {
 	PayloadId  string
 	MacAddress string
+	// Enrollment of the entry, assigned unless set.
+	Enrollment string `json:",omitempty"`
 }
 
 // Payload for payload_id.
@@ -46,13 +58,29 @@ type PayloadParameters struct {
 	ModifiedAt time.Time
 }
 
-// GetNodePayloads reads all payloads for mac_address and returns them as a list.
+// GetNodePayloads reads all payloads for the node of mac_address and returns them as a list.
+// When the database is unavailable the payloads are read from the snapshot, see SetSnapshots.
 // Returns a list of Payloads
 func (s *Service) GetNodePayloads(ctx context.Context, macAddress string) ([]*NodePayload, error) {
 	if macAddress == "" {
 		return nil, ValidationError{"missing payload macAddress"}
 	}
-	return s.db.GetNodePayloads(ctx, macAddress)
+	return s.getNodePayloads(ctx, s.bootMacAddress(ctx, macAddress))
+}
+
+// getNodePayloads is GetNodePayloads for a resolved macAddress.
+func (s *Service) getNodePayloads(ctx context.Context, macAddress string) ([]*NodePayload, error) {
+	nps, err := s.db.GetNodePayloads(ctx, macAddress)
+	if current := s.current(err); current != nil {
+		snapshot.MarkStale(ctx, s.snapshots, current)
+		return current.Data.GetNodePayloads(macAddress), nil
+	}
+	return nps, err
+}
+
+// ListNodePayloads returns every node_payloads entry.
+func (s *Service) ListNodePayloads(ctx context.Context) ([]*NodePayload, error) {
+	return s.db.ListNodePayloads(ctx)
 }
 
 // GetSubnetDefaultPayload accepts an ip address string and checks if payloads.subnet_default_payloads table
@@ -62,7 +90,18 @@ func (s *Service) GetSubnetDefaultPayload(ctx context.Context, ipAddress string)
 	if ipAddress == "" {
 		return nil, ValidationError{"missing payload ipAddress"}
 	}
-	return s.db.GetSubnetDefaultPayload(ctx, ipAddress)
+	addr, err := netip.ParseAddr(ipAddress)
+	if err != nil {
+		return nil, ValidationError{"invalid payload ipAddress: " + ipAddress}
+	}
+	// IPv4 clients of IPv6 listeners are reported as IPv4-mapped addresses, which IPv4 subnets don't contain
+	ipAddress = addr.Unmap().WithZone("").String()
+	p, err := s.db.GetSubnetDefaultPayload(ctx, ipAddress)
+	if current := s.current(err); current != nil {
+		snapshot.MarkStale(ctx, s.snapshots, current)
+		return current.Data.GetSubnetDefaultPayload(ipAddress)
+	}
+	return p, err
 }
 
 // AddNodePayload adds a NodePayload entry.
@@ -75,7 +114,68 @@ func (s *Service) AddNodePayload(ctx context.Context, nodePayloadDb *NodePayload
 		return nil, ValidationError{"missing nodePayloadDb payload MacAddress"}
 	}
 
-	return s.db.AddNodePayload(ctx, nodePayloadDb)
+	var nps []*NodePayload
+	err := s.db.WithTx(ctx, database.DefaultTxOptions, func(ctx context.Context) error {
+		var err error
+		nps, err = s.db.AddNodePayload(ctx, nodePayloadDb)
+		return err
+	})
+	return nps, err
+}
+
+// AssignNodePayload assigns payloadId to the node of config.MacAddress, adding a node_payloads entry or replacing the assigned payload.
+// The entry's Enrollment becomes assigned.
+// The payload check and the write run in one serializable transaction.
+// Returns a list of Payloads
+func (s *Service) AssignNodePayload(ctx context.Context, config *NodePayloadDb) ([]*NodePayload, error) {
+	if config.PayloadId == "" {
+		return nil, ValidationError{"missing PayloadId"}
+	}
+	if config.MacAddress == "" {
+		return nil, ValidationError{"missing MacAddress"}
+	}
+	macAddress, err := s.resolveMacAddress(ctx, config.MacAddress)
+	if err != nil {
+		return nil, err
+	}
+	config.MacAddress = macAddress
+	var nps []*NodePayload
+	err = s.db.WithTx(ctx, database.SerializableTxOptions, func(ctx context.Context) error {
+		if !contains(s.db.GetAvailablePayloads(ctx), config.PayloadId) {
+			return ValidationError{fmt.Sprintf("PayloadId doesn't exist: %s", config.PayloadId)}
+		}
+		assigned, err := s.db.GetNodePayloads(ctx, config.MacAddress)
+		if err != nil {
+			return err
+		}
+		if assigned == nil {
+			logging.FromContext(ctx).Info("adding node_payloads entry", zap.String("mac_address", config.MacAddress), zap.String("payload_id", config.PayloadId))
+			nps, err = s.db.AddNodePayload(ctx, config)
+			return err
+		}
+		for _, np := range assigned {
+			// following nodes are updated so they keep the payload
+			if np.PayloadId == config.PayloadId && np.Enrollment != enrollment.Following {
+				logging.FromContext(ctx).Debug("node_payloads entry unchanged", zap.String("mac_address", config.MacAddress), zap.String("payload_id", config.PayloadId))
+				nps = assigned
+				return nil
+			}
+		}
+		logging.FromContext(ctx).Info("updating node_payloads entry", zap.String("mac_address", config.MacAddress), zap.String("payload_id", config.PayloadId))
+		// TODO: add instead of update when we decide to allow multiple payloads per node
+		nps, err = s.db.UpdateNodePayload(ctx, config)
+		return err
+	})
+	return nps, err
+}
+
+func contains(s []string, str string) bool {
+	for _, v := range s {
+		if v == str {
+			return true
+		}
+	}
+	return false
 }
 
 // GetDefaultPayload returns the default Payload from flags.
@@ -95,13 +195,30 @@ func (s *Service) GetAvailablePayloads(ctx context.Context) []string {
 // UpdateNodePayload updates the PayloadId for mac_address.
 // Returns a list of Payloads
 func (s *Service) UpdateNodePayload(ctx context.Context, config *NodePayloadDb) ([]*NodePayload, error) {
-	return s.db.UpdateNodePayload(ctx, config)
+	var nps []*NodePayload
+	err := s.db.WithTx(ctx, database.DefaultTxOptions, func(ctx context.Context) error {
+		var err error
+		nps, err = s.db.UpdateNodePayload(ctx, config)
+		return err
+	})
+	return nps, err
 }
 
-// DeleteNodePayload deletes a NodePayload for mac_address/payload tuple.
+// DeleteNodePayload deletes a NodePayload for the mac_address of the node/payload tuple.
 // Returns a list of Payloads
 func (s *Service) DeleteNodePayload(ctx context.Context, config *NodePayloadDb) ([]*NodePayload, error) {
-	return s.db.DeleteNodePayload(ctx, config)
+	macAddress, err := s.resolveMacAddress(ctx, config.MacAddress)
+	if err != nil {
+		return nil, err
+	}
+	config.MacAddress = macAddress
+	var nps []*NodePayload
+	err = s.db.WithTx(ctx, database.DefaultTxOptions, func(ctx context.Context) error {
+		var err error
+		nps, err = s.db.DeleteNodePayload(ctx, config)
+		return err
+	})
+	return nps, err
 }
 
 // GetPayloadParameters returns a PayloadSchema for PayloadId.
@@ -111,3 +228,16 @@ func (s *Service) GetPayloadParameters(ctx context.Context, payloadId string) (i
 	}
 	return s.db.GetPayloadParameters(ctx, payloadId)
 }
+
+// WithTx runs fn in a transaction on the payloads database, see postgres.DB.WithTx.
+func (s *Service) WithTx(ctx context.Context, opts database.TxOptions, fn func(ctx context.Context) error) error {
+	return s.db.WithTx(ctx, opts, fn)
+}
+
+// ListNodePayloadMacAddresses returns the mac_address of the nodes assigned payloadId.
+func (s *Service) ListNodePayloadMacAddresses(ctx context.Context, payloadId string) ([]string, error) {
+	if payloadId == "" {
+		return nil, ValidationError{"missing payloadId"}
+	}
+	return s.db.ListNodePayloadMacAddresses(ctx, payloadId)
+}
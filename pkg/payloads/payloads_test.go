@@ -0,0 +1,113 @@
+package payloads
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/snapshot"
+	"github.com/golang/mock/gomock"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestService(t *testing.T) (*Service, *MockDB) {
+	ctrl := gomock.NewController(t)
+	db := NewMockDB(ctrl)
+	db.EXPECT().WithTx(gomock.Any(), gomock.Any(), gomock.Any()).DoAndReturn(
+		func(ctx context.Context, _ database.TxOptions, fn func(ctx context.Context) error) error {
+			return fn(ctx)
+		}).AnyTimes()
+	return NewService(db, "default", "/payloads/default"), db
+}
+
+func TestService_AssignNodePayload_unknownPayload(t *testing.T) {
+	svc, db := newTestService(t)
+	db.EXPECT().GetAvailablePayloads(gomock.Any()).Return([]string{"default"})
+
+	_, err := svc.AssignNodePayload(context.Background(), &NodePayloadDb{PayloadId: "gone", MacAddress: "0c42a1b2c3d4"})
+
+	var validationErr ValidationError
+	assert.True(t, errors.As(err, &validationErr))
+}
+
+func TestService_AssignNodePayload_update(t *testing.T) {
+	svc, db := newTestService(t)
+	config := &NodePayloadDb{PayloadId: "gpu", MacAddress: "0c42a1b2c3d4"}
+	updated := []*NodePayload{{PayloadId: "gpu", MacAddress: "0c42a1b2c3d4"}}
+	db.EXPECT().GetAvailablePayloads(gomock.Any()).Return([]string{"default", "gpu"})
+	db.EXPECT().GetNodePayloads(gomock.Any(), "0c42a1b2c3d4").Return([]*NodePayload{{PayloadId: "default", MacAddress: "0c42a1b2c3d4"}}, nil)
+	db.EXPECT().UpdateNodePayload(gomock.Any(), config).Return(updated, nil)
+
+	nps, err := svc.AssignNodePayload(context.Background(), config)
+
+	assert.NoError(t, err)
+	assert.Equal(t, updated, nps)
+}
+
+func TestService_GetNodePayloads_snapshot(t *testing.T) {
+	svc, db := newTestService(t)
+	store := snapshot.NewStore(SnapshotName, "", func(ctx context.Context) (*Snapshot, error) {
+		return &Snapshot{
+			NodePayloads: []*NodePayload{{PayloadId: "gpu", PayloadDirectory: "/payloads/gpu", MacAddress: "0c42a1b2c3d4"}},
+			SubnetDefaults: []*SubnetDefaultPayload{
+				{Subnet: "10.0.0.0/8", PayloadId: "default", PayloadDirectory: "/payloads/default"},
+				{Subnet: "10.1.0.0/16", PayloadId: "gpu", PayloadDirectory: "/payloads/gpu"},
+			},
+		}, nil
+	})
+	require.NoError(t, store.Refresh(context.Background()))
+	svc.SetSnapshots(store)
+	unavailable := errdefs.Unavailable("database_unavailable", "database unavailable")
+	db.EXPECT().GetNodePayloads(gomock.Any(), "0c42a1b2c3d4").Return(nil, unavailable)
+	db.EXPECT().GetSubnetDefaultPayload(gomock.Any(), "10.1.2.3").Return(nil, unavailable)
+	ctx := snapshot.WithStaleness(context.Background())
+
+	nps, err := svc.GetNodePayloads(ctx, "0c42a1b2c3d4")
+	require.NoError(t, err)
+	assert.Equal(t, []*NodePayload{{PayloadId: "gpu", PayloadDirectory: "/payloads/gpu", MacAddress: "0c42a1b2c3d4"}}, nps)
+
+	p, err := svc.GetSubnetDefaultPayload(ctx, "10.1.2.3")
+	require.NoError(t, err)
+	assert.Equal(t, "gpu", p.PayloadId)
+
+	_, stale := snapshot.Stale(ctx)
+	assert.True(t, stale)
+}
+
+func TestService_GetSubnetDefaultPayload_ipv6(t *testing.T) {
+	svc, db := newTestService(t)
+	store := snapshot.NewStore(SnapshotName, "", func(ctx context.Context) (*Snapshot, error) {
+		return &Snapshot{
+			SubnetDefaults: []*SubnetDefaultPayload{
+				{Subnet: "10.0.0.0/8", PayloadId: "ipv4", PayloadDirectory: "/payloads/ipv4"},
+				{Subnet: "2001:db8::/32", PayloadId: "default", PayloadDirectory: "/payloads/default"},
+				{Subnet: "2001:db8:1::/48", PayloadId: "gpu", PayloadDirectory: "/payloads/gpu"},
+			},
+		}, nil
+	})
+	require.NoError(t, store.Refresh(context.Background()))
+	svc.SetSnapshots(store)
+	unavailable := errdefs.Unavailable("database_unavailable", "database unavailable")
+	ctx := snapshot.WithStaleness(context.Background())
+
+	for ipAddress, want := range map[string]struct{ lookup, payloadId string }{
+		"2001:db8:1::17":  {"2001:db8:1::17", "gpu"},
+		"2001:DB8:2::17":  {"2001:db8:2::17", "default"},
+		"::ffff:10.1.2.3": {"10.1.2.3", "ipv4"},
+	} {
+		db.EXPECT().GetSubnetDefaultPayload(gomock.Any(), want.lookup).Return(nil, unavailable)
+		p, err := svc.GetSubnetDefaultPayload(ctx, ipAddress)
+		require.NoError(t, err, ipAddress)
+		assert.Equal(t, want.payloadId, p.PayloadId, ipAddress)
+	}
+
+	db.EXPECT().GetSubnetDefaultPayload(gomock.Any(), "2001:db9::17").Return(nil, unavailable)
+	_, err := svc.GetSubnetDefaultPayload(ctx, "2001:db9::17")
+	assert.True(t, errdefs.IsNotFound(err))
+
+	_, err = svc.GetSubnetDefaultPayload(ctx, "node-1")
+	assert.ErrorIs(t, err, errdefs.ErrInvalid)
+}
@@ -2,7 +2,13 @@ package payloads
 
 import (
 	"context"
-	"log"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/snapshot"
+	"go.uber.org/zap"
 )
 
 // NewService creates an API service.
@@ -11,7 +17,7 @@ func NewService(
 	payloadsDefaultPayloadId string,
 	payloadsDefaultPayloadDirectory string,
 ) *Service {
-	log.Printf("Starting Payloads service")
+	zap.L().Info("starting payloads service")
 	return &Service{
 		db:                              db,
 		payloadsDefaultPayloadId:        payloadsDefaultPayloadId,
@@ -24,20 +30,66 @@ type Service struct {
 	db                              DB
 	payloadsDefaultPayloadId        string
 	payloadsDefaultPayloadDirectory string
+	snapshots                       *snapshot.Store[*Snapshot]
+	enrollmentPolicy                enrollment.Policy
+	registrar                       enrollment.Registrar
+	discoveryPayload                *Payload
+	nodes                           NodeResolver
+}
+
+// NodeResolver returns the mac_address keying the entries of the node macAddress is attached to, see nodes.Service.
+type NodeResolver interface {
+	ResolveMacAddress(ctx context.Context, macAddress string) (string, error)
+}
+
+// SetNodeResolver sets how the mac_address of a node interface is resolved to the one keying its node_payloads entries.
+// Every mac_address keys its own entries without one.
+func (s *Service) SetNodeResolver(nodes NodeResolver) {
+	s.nodes = nodes
+}
+
+// resolveMacAddress returns the mac_address keying the node_payloads entries of macAddress.
+func (s *Service) resolveMacAddress(ctx context.Context, macAddress string) (string, error) {
+	if s.nodes == nil {
+		return macAddress, nil
+	}
+	return s.nodes.ResolveMacAddress(ctx, macAddress)
+}
+
+// bootMacAddress is resolveMacAddress falling back to macAddress so nodes boot while the nodes database is unavailable.
+func (s *Service) bootMacAddress(ctx context.Context, macAddress string) string {
+	resolved, err := s.resolveMacAddress(ctx, macAddress)
+	if err != nil {
+		logging.FromContext(ctx).Warn("cannot resolve node mac_address", zap.String("mac_address", macAddress), zap.Error(err))
+		return macAddress
+	}
+	return resolved
 }
 
 // DB layer.
 //
 //go:generate mockgen --build_flags=--mod=mod -package payloads -destination mock_payloads_db_test.go . DB
 type DB interface {
+	// WithTx runs fn in a transaction used by every DB method called with the ctx passed to fn.
+	WithTx(ctx context.Context, opts database.TxOptions, fn func(ctx context.Context) error) error
+
+	// ListNodePayloadMacAddresses returns the mac_address of the nodes assigned payloadId.
+	ListNodePayloadMacAddresses(ctx context.Context, payloadId string) ([]string, error)
+
 	// GetNodePayloads reads all payloads for mac_address and returns them as a list.
 	GetNodePayloads(ctx context.Context, macAddress string) ([]*NodePayload, error)
 
+	// ListNodePayloads returns every node_payloads entry with its payload directory.
+	ListNodePayloads(ctx context.Context) ([]*NodePayload, error)
+
 	// GetSubnetDefaultPayload accepts an ip address string and checks if payloads.subnet_default_payloads table
 	// contains a payload_id for the corresponding cidr
 	// Returns a Payload
 	GetSubnetDefaultPayload(ctx context.Context, ipAddress string) (*Payload, error)
 
+	// ListSubnetDefaultPayloads returns every subnet_default_payloads entry with its payload directory.
+	ListSubnetDefaultPayloads(ctx context.Context) ([]*SubnetDefaultPayload, error)
+
 	// GetAvailablePayloads returns a list of available payloads
 	GetAvailablePayloads(ctx context.Context) []string
 
@@ -62,3 +114,8 @@ type ValidationError struct {
 func (e ValidationError) Error() string {
 	return e.s
 }
+
+// Is makes ValidationError an errdefs.ErrInvalid error.
+func (e ValidationError) Is(target error) bool {
+	return target == errdefs.ErrInvalid
+}
@@ -0,0 +1,98 @@
+package payloads
+
+import (
+	"context"
+	"net/netip"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/snapshot"
+)
+
+// SnapshotName names the payloads snapshot in logs and metrics.
+const SnapshotName = "payloads"
+
+// SubnetDefaultPayload is a payloads.subnet_default_payloads entry with its payload directory.
+type SubnetDefaultPayload struct {
+	Subnet           string
+	PayloadId        string
+	PayloadDirectory string
+}
+
+// Snapshot is the payloads state needed to resolve node payloads without the database.
+type Snapshot struct {
+	NodePayloads   []*NodePayload
+	SubnetDefaults []*SubnetDefaultPayload
+}
+
+// TakeSnapshot reads the node_payloads and subnet_default_payloads entries in one transaction.
+func (s *Service) TakeSnapshot(ctx context.Context) (*Snapshot, error) {
+	var snap Snapshot
+	err := s.db.WithTx(ctx, database.DefaultTxOptions, func(ctx context.Context) error {
+		var err error
+		if snap.NodePayloads, err = s.db.ListNodePayloads(ctx); err != nil {
+			return err
+		}
+		snap.SubnetDefaults, err = s.db.ListSubnetDefaultPayloads(ctx)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &snap, nil
+}
+
+// SetSnapshots sets the snapshots node payloads are resolved from when the database is unavailable.
+func (s *Service) SetSnapshots(snapshots *snapshot.Store[*Snapshot]) {
+	s.snapshots = snapshots
+}
+
+// current returns the snapshot to answer from when err is an errdefs.ErrUnavailable error.
+func (s *Service) current(err error) *snapshot.Snapshot[*Snapshot] {
+	if !errdefs.IsUnavailable(err) {
+		return nil
+	}
+	current := s.snapshots.Get()
+	if current == nil || current.Data == nil {
+		return nil
+	}
+	return current
+}
+
+// GetNodePayloads returns the payloads of macAddress, see DB.GetNodePayloads.
+func (snap *Snapshot) GetNodePayloads(macAddress string) []*NodePayload {
+	var nps []*NodePayload
+	for _, np := range snap.NodePayloads {
+		if macAddress == np.MacAddress {
+			nps = append(nps, &NodePayload{
+				PayloadId:        np.PayloadId,
+				PayloadDirectory: np.PayloadDirectory,
+				MacAddress:       np.MacAddress,
+				Enrollment:       np.Enrollment,
+			})
+		}
+	}
+	return nps
+}
+
+// GetSubnetDefaultPayload returns the payload of the most specific subnet containing ipAddress,
+// see DB.GetSubnetDefaultPayload.
+func (snap *Snapshot) GetSubnetDefaultPayload(ipAddress string) (*Payload, error) {
+	addr, err := netip.ParseAddr(ipAddress)
+	if err != nil {
+		return nil, ValidationError{"invalid ipAddress: " + ipAddress}
+	}
+	var best *SubnetDefaultPayload
+	bestBits := -1
+	for _, sdp := range snap.SubnetDefaults {
+		prefix, err := netip.ParsePrefix(sdp.Subnet)
+		if err != nil || !prefix.Contains(addr.Unmap()) || prefix.Bits() <= bestBits {
+			continue
+		}
+		best, bestBits = sdp, prefix.Bits()
+	}
+	if best == nil {
+		return nil, errdefs.NotFound("subnet_default_payload_not_found", "no subnet_default_payloads entry in snapshot for ip_address: %s", ipAddress)
+	}
+	return &Payload{PayloadId: best.PayloadId, PayloadDirectory: best.PayloadDirectory}, nil
+}
@@ -0,0 +1,303 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/ipxe"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+)
+
+const imageChannelColumns = `
+        channel,
+        image_tag,
+        image_type,
+        COALESCE(previous_image_tag, ''),
+        COALESCE(previous_image_type, '')
+`
+
+type imageChannel struct {
+	Channel           string
+	ImageTag          string
+	ImageType         string
+	PreviousImageTag  string
+	PreviousImageType string
+}
+
+func (ic *imageChannel) dto() *ipxe.ImageChannel {
+	return &ipxe.ImageChannel{
+		Channel:           ic.Channel,
+		ImageTag:          ic.ImageTag,
+		ImageType:         ic.ImageType,
+		PreviousImageTag:  ic.PreviousImageTag,
+		PreviousImageType: ic.PreviousImageType,
+	}
+}
+
+type subnetDefaultImage struct {
+	Subnet       string
+	ImageTag     string
+	ImageType    string
+	ImageChannel string
+}
+
+func (sdi *subnetDefaultImage) dto() *ipxe.SubnetDefaultImage {
+	return &ipxe.SubnetDefaultImage{
+		Subnet:       sdi.Subnet,
+		ImageTag:     sdi.ImageTag,
+		ImageType:    sdi.ImageType,
+		ImageChannel: sdi.ImageChannel,
+	}
+}
+
+const subnetDefaultImageColumns = `
+        subnet::text,
+        COALESCE(image_tag, ''),
+        COALESCE(image_type, ''),
+        COALESCE(image_channel, '')
+`
+
+// queryImageChannel runs sql returning imageChannelColumns and collects exactly one row.
+func (db *DB) queryImageChannel(ctx context.Context, action string, sql string, args ...any) (*ipxe.ImageChannel, error) {
+	rows, err := db.conn(ctx).Query(ctx, sql, args...)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var ic imageChannel
+	if err == nil {
+		ic, err = pgx.CollectOneRow(rows, pgx.RowToStructByPos[imageChannel])
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, pgx.ErrNoRows
+	}
+	if err != nil {
+		if pgErrorCode(err) == pgerrcode.ForeignKeyViolation {
+			return nil, errdefs.Conflict("image_channel_in_use", "image channel is still followed by nodes or subnets")
+		}
+		return nil, queryError(ctx, err, fmt.Sprintf("cannot %s image channel", action))
+	}
+	return ic.dto(), nil
+}
+
+// GetImageChannel returns the entry in ipxe.image_channels for channel.
+func (db *DB) GetImageChannel(ctx context.Context, channel string) (*ipxe.ImageChannel, error) {
+	ctx, span := tracer.Start(ctx, "postgres.GetImageChannel")
+	defer span.End()
+	sql := `
+    SELECT` + imageChannelColumns + `
+    FROM image_channels
+    WHERE channel = $1
+  `
+	ic, err := db.queryImageChannel(ctx, "get", sql, channel)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.NotFound("image_channel_not_found", "image channel not in database: %s", channel)
+	}
+	return ic, err
+}
+
+// ListImageChannels returns every entry in ipxe.image_channels.
+func (db *DB) ListImageChannels(ctx context.Context) ([]*ipxe.ImageChannel, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListImageChannels")
+	defer span.End()
+	sql := `
+    SELECT` + imageChannelColumns + `
+    FROM image_channels
+    ORDER BY channel
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var ics []imageChannel
+	if err == nil {
+		ics, err = pgx.CollectRows(rows, pgx.RowToStructByPos[imageChannel])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list image channels from database")
+	}
+	channels := make([]*ipxe.ImageChannel, 0, len(ics))
+	for i := range ics {
+		channels = append(channels, ics[i].dto())
+	}
+	return channels, nil
+}
+
+// PromoteImageChannel points a channel at a staged or active image in a single statement,
+// moving the current target to previous_image_tag and previous_image_type.
+// Promoting a channel to its current target keeps the previous target.
+func (db *DB) PromoteImageChannel(ctx context.Context, config *ipxe.ImageChannelPromoteConfig) (*ipxe.ImageChannel, error) {
+	ctx, span := tracer.Start(ctx, "postgres.PromoteImageChannel")
+	defer span.End()
+	sql := `
+    INSERT INTO image_channels (
+        channel,
+        image_tag,
+        image_type
+    )
+    SELECT $1, image_tag, image_type
+    FROM images
+    WHERE
+        image_tag = $2
+        AND
+        image_type = $3
+        AND
+        image_state IN ('staged', 'active')
+    ON CONFLICT (channel) DO UPDATE
+    SET
+        previous_image_tag = CASE
+          WHEN (image_channels.image_tag, image_channels.image_type) = (EXCLUDED.image_tag, EXCLUDED.image_type)
+          THEN image_channels.previous_image_tag ELSE image_channels.image_tag END,
+        previous_image_type = CASE
+          WHEN (image_channels.image_tag, image_channels.image_type) = (EXCLUDED.image_tag, EXCLUDED.image_type)
+          THEN image_channels.previous_image_type ELSE image_channels.image_type END,
+        image_tag = EXCLUDED.image_tag,
+        image_type = EXCLUDED.image_type,
+        modified_at = current_timestamp
+    RETURNING` + imageChannelColumns
+	ic, err := db.queryImageChannel(ctx, "promote", sql, config.Channel, config.ImageTag, config.ImageType)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.Invalid("image_not_assignable", "image (%s, %s) doesn't exist or can't be assigned", config.ImageTag, config.ImageType)
+	}
+	return ic, err
+}
+
+// RollbackImageChannel swaps the current and previous target of channel.
+// The previous image must still exist and not be retired.
+func (db *DB) RollbackImageChannel(ctx context.Context, channel string) (*ipxe.ImageChannel, error) {
+	ctx, span := tracer.Start(ctx, "postgres.RollbackImageChannel")
+	defer span.End()
+	sql := `
+    UPDATE image_channels
+    SET
+        image_tag = previous_image_tag,
+        image_type = previous_image_type,
+        previous_image_tag = image_tag,
+        previous_image_type = image_type,
+        modified_at = current_timestamp
+    WHERE
+        channel = $1
+        AND
+        EXISTS (
+          SELECT 1 FROM images
+          WHERE images.image_tag = image_channels.previous_image_tag
+            AND images.image_type = image_channels.previous_image_type
+            AND images.image_state != 'retired'
+        )
+    RETURNING` + imageChannelColumns
+	ic, err := db.queryImageChannel(ctx, "rollback", sql, channel)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.NotFound("previous_image_not_found", "image channel not in database or no previous image to roll back to: %s", channel)
+	}
+	return ic, err
+}
+
+// DeleteImageChannel deletes channel, failing while node_images or subnet_default_images follow it.
+func (db *DB) DeleteImageChannel(ctx context.Context, channel string) (*ipxe.ImageChannel, error) {
+	ctx, span := tracer.Start(ctx, "postgres.DeleteImageChannel")
+	defer span.End()
+	sql := `
+    DELETE FROM image_channels
+    WHERE channel = $1
+    RETURNING` + imageChannelColumns
+	ic, err := db.queryImageChannel(ctx, "delete", sql, channel)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.NotFound("image_channel_not_found", "image channel not in database: %s", channel)
+	}
+	return ic, err
+}
+
+// ListSubnetDefaultImages returns every entry in ipxe.subnet_default_images.
+func (db *DB) ListSubnetDefaultImages(ctx context.Context) ([]*ipxe.SubnetDefaultImage, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListSubnetDefaultImages")
+	defer span.End()
+	sql := `
+    SELECT` + subnetDefaultImageColumns + `
+    FROM subnet_default_images
+    ORDER BY subnet
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var sdis []subnetDefaultImage
+	if err == nil {
+		sdis, err = pgx.CollectRows(rows, pgx.RowToStructByPos[subnetDefaultImage])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list subnet default images from database")
+	}
+	subnets := make([]*ipxe.SubnetDefaultImage, 0, len(sdis))
+	for i := range sdis {
+		subnets = append(subnets, sdis[i].dto())
+	}
+	return subnets, nil
+}
+
+// SetSubnetDefaultImage inserts or replaces the entry in ipxe.subnet_default_images for config.Subnet.
+func (db *DB) SetSubnetDefaultImage(ctx context.Context, config *ipxe.SubnetDefaultImage) (*ipxe.SubnetDefaultImage, error) {
+	ctx, span := tracer.Start(ctx, "postgres.SetSubnetDefaultImage")
+	defer span.End()
+	sql := `
+    INSERT INTO subnet_default_images (
+        subnet,
+        image_tag,
+        image_type,
+        image_channel
+    )
+    VALUES (
+        $1,
+        NULLIF($2, ''),
+        NULLIF($3, ''),
+        NULLIF($4, '')
+    )
+    ON CONFLICT (subnet) DO UPDATE
+    SET
+        image_tag = EXCLUDED.image_tag,
+        image_type = EXCLUDED.image_type,
+        image_channel = EXCLUDED.image_channel,
+        modified_at = current_timestamp
+    RETURNING` + subnetDefaultImageColumns
+	rows, err := db.conn(ctx).Query(ctx, sql, config.Subnet, config.ImageTag, config.ImageType, config.ImageChannel)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var sdi subnetDefaultImage
+	if err == nil {
+		sdi, err = pgx.CollectOneRow(rows, pgx.RowToStructByPos[subnetDefaultImage])
+	}
+	if err != nil {
+		if pgErrorCode(err) == pgerrcode.ForeignKeyViolation {
+			return nil, errdefs.Invalid("unknown_image_channel", "image channel not in database: %s", config.ImageChannel)
+		}
+		return nil, queryError(ctx, err, "cannot set subnet default image")
+	}
+	return sdi.dto(), nil
+}
+
+// DeleteSubnetDefaultImage deletes the entry in ipxe.subnet_default_images for subnet.
+func (db *DB) DeleteSubnetDefaultImage(ctx context.Context, subnet string) (*ipxe.SubnetDefaultImage, error) {
+	ctx, span := tracer.Start(ctx, "postgres.DeleteSubnetDefaultImage")
+	defer span.End()
+	sql := `
+    DELETE FROM subnet_default_images
+    WHERE subnet = $1
+    RETURNING` + subnetDefaultImageColumns
+	rows, err := db.conn(ctx).Query(ctx, sql, subnet)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var sdi subnetDefaultImage
+	if err == nil {
+		sdi, err = pgx.CollectOneRow(rows, pgx.RowToStructByPos[subnetDefaultImage])
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.NotFound("subnet_not_found", "subnet not in database: %s", subnet)
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot delete subnet default image")
+	}
+	return sdi.dto(), nil
+}
@@ -0,0 +1,76 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"net"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5/pgconn"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+// queryError logs err, records it on the span of ctx and returns msg as an error,
+// an errdefs.ErrUnavailable one when the database couldn't be reached.
+func queryError(ctx context.Context, err error, msg string) error {
+	logging.FromContext(ctx).Error(msg, zap.Error(err))
+	span := trace.SpanFromContext(ctx)
+	span.RecordError(err)
+	span.SetStatus(codes.Error, msg)
+	if unavailable(err) {
+		return errdefs.Unavailable("database_unavailable", "%s: database unavailable", msg)
+	}
+	return errors.New(msg)
+}
+
+// unavailable reports whether err comes from a failed connection rather than from the query.
+func unavailable(err error) bool {
+	var netErr net.Error
+	if errors.As(err, &netErr) || pgconn.Timeout(err) {
+		return true
+	}
+	code := pgErrorCode(err)
+	return pgerrcode.IsConnectionException(code) ||
+		pgerrcode.IsInsufficientResources(code) ||
+		code == pgerrcode.AdminShutdown ||
+		code == pgerrcode.CannotConnectNow
+}
+
+// pgErrorCode returns the PostgreSQL error code of err, empty if err doesn't come from PostgreSQL.
+func pgErrorCode(err error) string {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return ""
+	}
+	return pgErr.Code
+}
+
+// createIpxeImagePgError maps constraint violations on ipxe.images, nil for other errors.
+func createIpxeImagePgError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return nil
+	}
+	if pgErr.Code == pgerrcode.UniqueViolation {
+		return errdefs.Conflict("image_exists", "(image_tag, image_type) exists")
+	}
+	if pgErr.Code == pgerrcode.CheckViolation {
+		switch pgErr.ConstraintName {
+		case "image_bucket", "image_name", "image_tag", "image_type", "image_cmdline", "image_state":
+			return errdefs.Invalid("invalid_"+pgErr.ConstraintName, "invalid %s", pgErr.ConstraintName)
+		}
+	}
+	return nil
+}
+
+// deleteIpxeImagePgError maps a foreign key violation on ipxe.images, nil for other errors.
+func deleteIpxeImagePgError(err error) error {
+	if pgErrorCode(err) == pgerrcode.ForeignKeyViolation {
+		return errdefs.Conflict("image_in_use", "(image_tag, image_type) is still referenced")
+	}
+	return nil
+}
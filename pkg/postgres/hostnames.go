@@ -0,0 +1,256 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/nodes"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+)
+
+const nodeHostnameColumns = `
+        mac_address,
+        hostname,
+        source,
+        policy_id,
+        assigned_at
+`
+
+const hostnamePolicyColumns = `
+        policy_id,
+        COALESCE(subnet::text, ''),
+        template,
+        counter,
+        created_at
+`
+
+func (db *DB) queryNodeHostname(ctx context.Context, msg string, notFound error, sql string, args ...any) (*nodes.NodeHostname, error) {
+	rows, err := db.conn(ctx).Query(ctx, sql, args...)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var nh *nodes.NodeHostname
+	if err == nil {
+		nh, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByPos[nodes.NodeHostname])
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, notFound
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, msg)
+	}
+	return nh, nil
+}
+
+// GetNodeHostname returns the node_hostnames entry of macAddress.
+func (db *DB) GetNodeHostname(ctx context.Context, macAddress string) (*nodes.NodeHostname, error) {
+	ctx, span := tracer.Start(ctx, "postgres.GetNodeHostname")
+	defer span.End()
+	sql := `
+    SELECT` + nodeHostnameColumns + `
+    FROM node_hostnames
+    WHERE mac_address = $1
+  `
+	return db.queryNodeHostname(ctx, "cannot get node hostname from database",
+		errdefs.NotFound("hostname_not_found", "no node_hostnames entry for mac_address: %s", macAddress), sql, macAddress)
+}
+
+// LookupHostname returns the node_hostnames entry of hostname.
+func (db *DB) LookupHostname(ctx context.Context, hostname string) (*nodes.NodeHostname, error) {
+	ctx, span := tracer.Start(ctx, "postgres.LookupHostname")
+	defer span.End()
+	sql := `
+    SELECT` + nodeHostnameColumns + `
+    FROM node_hostnames
+    WHERE hostname = $1
+  `
+	return db.queryNodeHostname(ctx, "cannot look up hostname in database",
+		errdefs.NotFound("hostname_not_found", "no node named %s", hostname), sql, hostname)
+}
+
+// SetNodeHostname upserts the node_hostnames entry of nh.MacAddress.
+func (db *DB) SetNodeHostname(ctx context.Context, nh *nodes.NodeHostname) (*nodes.NodeHostname, error) {
+	ctx, span := tracer.Start(ctx, "postgres.SetNodeHostname")
+	defer span.End()
+	sql := `
+    INSERT INTO node_hostnames (
+        mac_address,
+        hostname,
+        source,
+        policy_id
+    )
+    VALUES (
+        $1,
+        $2,
+        $3,
+        $4
+    )
+    ON CONFLICT (mac_address)
+    DO UPDATE SET
+        hostname = EXCLUDED.hostname,
+        source = EXCLUDED.source,
+        policy_id = EXCLUDED.policy_id,
+        assigned_at = now()
+    RETURNING` + nodeHostnameColumns
+	rows, err := db.conn(ctx).Query(ctx, sql, nh.MacAddress, nh.Hostname, nh.Source, nh.PolicyId)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var set *nodes.NodeHostname
+	if err == nil {
+		set, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByPos[nodes.NodeHostname])
+	}
+	if pgErrorCode(err) == pgerrcode.UniqueViolation {
+		return nil, errdefs.Conflict("hostname_exists", "hostname %s belongs to another node", nh.Hostname)
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot set node hostname")
+	}
+	return set, nil
+}
+
+// DeleteNodeHostname deletes the node_hostnames entry of macAddress.
+func (db *DB) DeleteNodeHostname(ctx context.Context, macAddress string) (*nodes.NodeHostname, error) {
+	ctx, span := tracer.Start(ctx, "postgres.DeleteNodeHostname")
+	defer span.End()
+	sql := `
+    DELETE FROM node_hostnames
+    WHERE mac_address = $1
+    RETURNING` + nodeHostnameColumns
+	return db.queryNodeHostname(ctx, "cannot delete node hostname",
+		errdefs.NotFound("hostname_not_found", "no node_hostnames entry for mac_address: %s", macAddress), sql, macAddress)
+}
+
+// MatchHostnamePolicy returns the hostname_policies entry with the longest subnet containing ipAddress,
+// or else the one without subnet.
+func (db *DB) MatchHostnamePolicy(ctx context.Context, ipAddress string) (*nodes.HostnamePolicy, error) {
+	ctx, span := tracer.Start(ctx, "postgres.MatchHostnamePolicy")
+	defer span.End()
+	sql := `
+    SELECT` + hostnamePolicyColumns + `
+    FROM hostname_policies
+    WHERE subnet IS NULL OR subnet >>= $1::inet
+    ORDER BY masklen(subnet) DESC NULLS LAST
+    LIMIT 1
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, ipAddress)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var policy *nodes.HostnamePolicy
+	if err == nil {
+		policy, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByPos[nodes.HostnamePolicy])
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.NotFound("hostname_policy_not_found", "no hostname_policies entry for ip_address: %s", ipAddress)
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot match hostname policies")
+	}
+	return policy, nil
+}
+
+// IncrementHostnamePolicyCounter increments the counter of policyId and returns it.
+func (db *DB) IncrementHostnamePolicyCounter(ctx context.Context, policyId int64) (int64, error) {
+	ctx, span := tracer.Start(ctx, "postgres.IncrementHostnamePolicyCounter")
+	defer span.End()
+	sql := `
+    UPDATE hostname_policies
+    SET counter = counter + 1
+    WHERE policy_id = $1
+    RETURNING counter
+  `
+	var counter int64
+	err := db.conn(ctx).QueryRow(ctx, sql, policyId).Scan(&counter)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return 0, err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return 0, errdefs.NotFound("hostname_policy_not_found", "hostname policy not in database: %d", policyId)
+	}
+	if err != nil {
+		return 0, queryError(ctx, err, "cannot increment hostname policy counter")
+	}
+	return counter, nil
+}
+
+// ListHostnamePolicies returns every hostname_policies entry, the site policy first.
+func (db *DB) ListHostnamePolicies(ctx context.Context) ([]*nodes.HostnamePolicy, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListHostnamePolicies")
+	defer span.End()
+	sql := `
+    SELECT` + hostnamePolicyColumns + `
+    FROM hostname_policies
+    ORDER BY subnet NULLS FIRST
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var policies []*nodes.HostnamePolicy
+	if err == nil {
+		policies, err = pgx.CollectRows(rows, pgx.RowToAddrOfStructByPos[nodes.HostnamePolicy])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list hostname policies from database")
+	}
+	return policies, nil
+}
+
+// CreateHostnamePolicy inserts policy, an empty subnet is stored as NULL.
+func (db *DB) CreateHostnamePolicy(ctx context.Context, policy *nodes.HostnamePolicy) (*nodes.HostnamePolicy, error) {
+	ctx, span := tracer.Start(ctx, "postgres.CreateHostnamePolicy")
+	defer span.End()
+	sql := `
+    INSERT INTO hostname_policies (
+        subnet,
+        template
+    )
+    VALUES (
+        NULLIF($1, '')::cidr,
+        $2
+    )
+    RETURNING` + hostnamePolicyColumns
+	rows, err := db.conn(ctx).Query(ctx, sql, policy.Subnet, policy.Template)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var created *nodes.HostnamePolicy
+	if err == nil {
+		created, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByPos[nodes.HostnamePolicy])
+	}
+	if pgErrorCode(err) == pgerrcode.UniqueViolation {
+		return nil, errdefs.Conflict("hostname_policy_exists", "a hostname policy exists for subnet: %q", policy.Subnet)
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot add hostname policy")
+	}
+	return created, nil
+}
+
+// DeleteHostnamePolicy deletes the hostname_policies entry policyId.
+func (db *DB) DeleteHostnamePolicy(ctx context.Context, policyId int64) (*nodes.HostnamePolicy, error) {
+	ctx, span := tracer.Start(ctx, "postgres.DeleteHostnamePolicy")
+	defer span.End()
+	sql := `
+    DELETE FROM hostname_policies
+    WHERE policy_id = $1
+    RETURNING` + hostnamePolicyColumns
+	rows, err := db.conn(ctx).Query(ctx, sql, policyId)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var deleted *nodes.HostnamePolicy
+	if err == nil {
+		deleted, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByPos[nodes.HostnamePolicy])
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.NotFound("hostname_policy_not_found", "hostname policy not in database: %d", policyId)
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot delete hostname policy")
+	}
+	return deleted, nil
+}
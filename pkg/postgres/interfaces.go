@@ -0,0 +1,153 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/nodes"
+	"github.com/jackc/pgx/v5"
+)
+
+// ResolveMacAddress returns the nodes mac_address of the node_interfaces entry of macAddress, macAddress itself when there is none.
+func (db *DB) ResolveMacAddress(ctx context.Context, macAddress string) (string, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ResolveMacAddress")
+	defer span.End()
+	sql := `
+    SELECT n.mac_address
+    FROM node_interfaces i
+    JOIN nodes n USING (node_id)
+    WHERE i.mac_address = $1
+  `
+	var resolved string
+	err := db.conn(ctx).QueryRow(ctx, sql, macAddress).Scan(&resolved)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return "", err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return macAddress, nil
+	}
+	if err != nil {
+		return "", queryError(ctx, err, "cannot resolve node mac_address")
+	}
+	return resolved, nil
+}
+
+// GetNodeIdentity returns the nodes entry macAddress is attached to, or else the one keyed by macAddress, with its interfaces.
+func (db *DB) GetNodeIdentity(ctx context.Context, macAddress string) (*nodes.NodeIdentity, error) {
+	ctx, span := tracer.Start(ctx, "postgres.GetNodeIdentity")
+	defer span.End()
+	sql := `
+    SELECT
+        node_id,
+        mac_address,
+        created_at
+    FROM nodes
+    WHERE node_id = (SELECT node_id FROM node_interfaces WHERE mac_address = $1)
+    OR mac_address = $1
+    ORDER BY mac_address = $1
+    LIMIT 1
+  `
+	identity := &nodes.NodeIdentity{}
+	err := db.conn(ctx).QueryRow(ctx, sql, macAddress).Scan(&identity.NodeId, &identity.MacAddress, &identity.CreatedAt)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.NotFound("node_identity_not_found", "no nodes entry for mac_address: %s", macAddress)
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot get node from database")
+	}
+	sql = `
+    SELECT
+        mac_address,
+        COALESCE(name, ''),
+        attached_at
+    FROM node_interfaces
+    WHERE node_id = $1
+    ORDER BY attached_at, mac_address
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, identity.NodeId)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if err == nil {
+		identity.Interfaces, err = pgx.CollectRows(rows, pgx.RowToAddrOfStructByPos[nodes.Interface])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list node interfaces from database")
+	}
+	return identity, nil
+}
+
+// CreateNodeIdentity inserts a nodes entry keyed by macAddress and the node_interfaces entry of macAddress.
+func (db *DB) CreateNodeIdentity(ctx context.Context, macAddress string) (*nodes.NodeIdentity, error) {
+	ctx, span := tracer.Start(ctx, "postgres.CreateNodeIdentity")
+	defer span.End()
+	sql := `
+    WITH node AS (
+        INSERT INTO nodes (mac_address)
+        VALUES ($1)
+        RETURNING node_id
+    )
+    INSERT INTO node_interfaces (mac_address, node_id)
+    SELECT $1, node_id FROM node
+  `
+	_, err := db.conn(ctx).Exec(ctx, sql, macAddress)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot add node")
+	}
+	return db.GetNodeIdentity(ctx, macAddress)
+}
+
+// AttachInterface upserts the node_interfaces entry of iface for nodeId.
+func (db *DB) AttachInterface(ctx context.Context, nodeId int64, iface *nodes.Interface) error {
+	ctx, span := tracer.Start(ctx, "postgres.AttachInterface")
+	defer span.End()
+	sql := `
+    INSERT INTO node_interfaces (
+        mac_address,
+        node_id,
+        name
+    )
+    VALUES (
+        $1,
+        $2,
+        NULLIF($3, '')
+    )
+    ON CONFLICT (mac_address)
+    DO UPDATE SET
+        name = EXCLUDED.name
+    WHERE node_interfaces.node_id = EXCLUDED.node_id
+  `
+	_, err := db.conn(ctx).Exec(ctx, sql, iface.MacAddress, nodeId, iface.Name)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return queryError(ctx, err, "cannot attach node interface")
+	}
+	return nil
+}
+
+// DetachInterface deletes the node_interfaces entry of macAddress.
+func (db *DB) DetachInterface(ctx context.Context, macAddress string) error {
+	ctx, span := tracer.Start(ctx, "postgres.DetachInterface")
+	defer span.End()
+	sql := `
+    DELETE FROM node_interfaces
+    WHERE mac_address = $1
+  `
+	_, err := db.conn(ctx).Exec(ctx, sql, macAddress)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	if err != nil {
+		return queryError(ctx, err, "cannot detach node interface")
+	}
+	return nil
+}
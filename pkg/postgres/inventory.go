@@ -0,0 +1,160 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/nodes"
+	"github.com/jackc/pgx/v5"
+)
+
+const inventoryColumns = `
+        mac_address,
+        hardware_class,
+        inventory,
+        reported_at,
+        changed_at
+`
+
+const inventoryChangeColumns = `
+        change_id,
+        mac_address,
+        changed_at,
+        changed_fields,
+        previous,
+        inventory
+`
+
+// GetNodeInventory returns the node_inventory entry of macAddress.
+func (db *DB) GetNodeInventory(ctx context.Context, macAddress string) (*nodes.NodeInventory, error) {
+	ctx, span := tracer.Start(ctx, "postgres.GetNodeInventory")
+	defer span.End()
+	sql := `
+    SELECT` + inventoryColumns + `
+    FROM node_inventory
+    WHERE mac_address = $1
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, macAddress)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var ni *nodes.NodeInventory
+	if err == nil {
+		ni, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByPos[nodes.NodeInventory])
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.NotFound("inventory_not_found", "no node_inventory entry for mac_address: %s", macAddress)
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot get node inventory from database")
+	}
+	return ni, nil
+}
+
+// SetNodeInventory upserts the node_inventory entry of ni.MacAddress, moving changed_at and inserting a
+// node_inventory_changes entry from previous unless changedFields is empty.
+func (db *DB) SetNodeInventory(ctx context.Context, ni *nodes.NodeInventory, previous *nodes.Inventory, changedFields []string) (*nodes.NodeInventory, error) {
+	ctx, span := tracer.Start(ctx, "postgres.SetNodeInventory")
+	defer span.End()
+	var result *nodes.NodeInventory
+	err := db.WithTx(ctx, database.DefaultTxOptions, func(ctx context.Context) error {
+		sql := `
+    INSERT INTO node_inventory (
+        mac_address,
+        hardware_class,
+        inventory
+    )
+    VALUES (
+        $1,
+        $2,
+        $3
+    )
+    ON CONFLICT (mac_address)
+    DO UPDATE SET
+        hardware_class = EXCLUDED.hardware_class,
+        inventory = EXCLUDED.inventory,
+        reported_at = now(),
+        changed_at = CASE WHEN $4 THEN now() ELSE node_inventory.changed_at END
+    RETURNING` + inventoryColumns
+		rows, err := db.conn(ctx).Query(ctx, sql, ni.MacAddress, ni.HardwareClass, ni.Inventory, len(changedFields) > 0)
+		if err == nil {
+			result, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByPos[nodes.NodeInventory])
+		}
+		if err != nil || len(changedFields) == 0 {
+			return err
+		}
+		sql = `
+    INSERT INTO node_inventory_changes (
+        mac_address,
+        changed_fields,
+        previous,
+        inventory
+    )
+    VALUES (
+        $1,
+        $2,
+        $3,
+        $4
+    )
+  `
+		_, err = db.conn(ctx).Exec(ctx, sql, ni.MacAddress, changedFields, previous, ni.Inventory)
+		return err
+	})
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot set node inventory")
+	}
+	return result, nil
+}
+
+// ListNodeInventories returns the node_inventory entries in hardwareClass, or every entry when hardwareClass is empty.
+func (db *DB) ListNodeInventories(ctx context.Context, hardwareClass string) ([]*nodes.NodeInventory, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListNodeInventories")
+	defer span.End()
+	sql := `
+    SELECT` + inventoryColumns + `
+    FROM node_inventory
+    WHERE $1 = '' OR hardware_class = $1
+    ORDER BY mac_address
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, hardwareClass)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var nis []*nodes.NodeInventory
+	if err == nil {
+		nis, err = pgx.CollectRows(rows, pgx.RowToAddrOfStructByPos[nodes.NodeInventory])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list node inventories from database")
+	}
+	return nis, nil
+}
+
+// ListInventoryChanges returns the node_inventory_changes entries of macAddress, the latest first.
+func (db *DB) ListInventoryChanges(ctx context.Context, macAddress string) ([]*nodes.InventoryChange, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListInventoryChanges")
+	defer span.End()
+	sql := `
+    SELECT` + inventoryChangeColumns + `
+    FROM node_inventory_changes
+    WHERE mac_address = $1
+    ORDER BY changed_at DESC, change_id DESC
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, macAddress)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var changes []*nodes.InventoryChange
+	if err == nil {
+		changes, err = pgx.CollectRows(rows, pgx.RowToAddrOfStructByPos[nodes.InventoryChange])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list node inventory changes from database")
+	}
+	return changes, nil
+}
@@ -0,0 +1,265 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/ipxe"
+	"github.com/jackc/pgx/v5"
+)
+
+// imageUsageColumns selects an ipxe.IpxeImageUsage for every row of images.
+const imageUsageColumns = `
+        images.image_tag,
+        images.image_type,
+        images.image_name,
+        images.image_state,
+        ARRAY(
+          SELECT node_images.mac_address
+          FROM node_images
+          WHERE node_images.image_tag = images.image_tag
+            AND node_images.image_type = images.image_type
+          ORDER BY node_images.mac_address
+        ),
+        ARRAY(
+          SELECT subnet_default_images.subnet::text
+          FROM subnet_default_images
+          WHERE subnet_default_images.image_tag = images.image_tag
+            AND subnet_default_images.image_type = images.image_type
+          ORDER BY subnet_default_images.subnet
+        ),
+        ARRAY(
+          SELECT image_channels.channel
+          FROM image_channels
+          WHERE image_channels.image_tag = images.image_tag
+            AND image_channels.image_type = images.image_type
+          ORDER BY image_channels.channel
+        )
+`
+
+// imageUnreferenced is true while no node, subnet or image channel references ($1, $2).
+const imageUnreferenced = `
+          NOT EXISTS (SELECT 1 FROM node_images WHERE image_tag = $1 AND image_type = $2)
+          AND
+          NOT EXISTS (SELECT 1 FROM subnet_default_images WHERE image_tag = $1 AND image_type = $2)
+          AND
+          NOT EXISTS (SELECT 1 FROM image_channels WHERE image_tag = $1 AND image_type = $2)
+`
+
+type ipxeImageUsage struct {
+	ImageTag     string
+	ImageType    string
+	ImageName    string
+	ImageState   string
+	MacAddresses []string
+	Subnets      []string
+	Channels     []string
+}
+
+func (iu *ipxeImageUsage) dto() *ipxe.IpxeImageUsage {
+	return &ipxe.IpxeImageUsage{
+		ImageTag:     iu.ImageTag,
+		ImageType:    iu.ImageType,
+		ImageName:    iu.ImageName,
+		ImageState:   iu.ImageState,
+		MacAddresses: iu.MacAddresses,
+		Subnets:      iu.Subnets,
+		Channels:     iu.Channels,
+	}
+}
+
+// GetIpxeImageUsage returns the nodes and subnets referencing (image_tag, image_type).
+func (db *DB) GetIpxeImageUsage(ctx context.Context, config *ipxe.IpxeImageTagType) (*ipxe.IpxeImageUsage, error) {
+	ctx, span := tracer.Start(ctx, "postgres.GetIpxeImageUsage")
+	defer span.End()
+	sql := `
+    SELECT` + imageUsageColumns + `
+    FROM images
+    WHERE
+        image_tag = $1
+        AND
+        image_type = $2
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, config.ImageTag, config.ImageType)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var iu ipxeImageUsage
+	if err == nil {
+		iu, err = pgx.CollectOneRow(rows, pgx.RowToStructByPos[ipxeImageUsage])
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.NotFound("image_not_found", `image not in database: %v`, *config)
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot get image usage from database")
+	}
+	return iu.dto(), nil
+}
+
+// ListIpxeImageUsage returns the usage of every image in imageState, or of all images when imageState is empty.
+func (db *DB) ListIpxeImageUsage(ctx context.Context, imageState string) ([]*ipxe.IpxeImageUsage, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListIpxeImageUsage")
+	defer span.End()
+	sql := `
+    SELECT` + imageUsageColumns + `
+    FROM images
+    WHERE
+        $1 = '' OR image_state = $1
+    ORDER BY image_tag, image_type
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, imageState)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var ius []ipxeImageUsage
+	if err == nil {
+		ius, err = pgx.CollectRows(rows, pgx.RowToStructByPos[ipxeImageUsage])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list image usage from database")
+	}
+	usage := make([]*ipxe.IpxeImageUsage, 0, len(ius))
+	for i := range ius {
+		usage = append(usage, ius[i].dto())
+	}
+	return usage, nil
+}
+
+// SetIpxeImageState updates the image_state of (image_tag, image_type).
+// Retiring only succeeds while no node, subnet or image channel references the image.
+func (db *DB) SetIpxeImageState(ctx context.Context, config *ipxe.IpxeImageStateConfig) (*ipxe.IpxeDbConfig, error) {
+	ctx, span := tracer.Start(ctx, "postgres.SetIpxeImageState")
+	defer span.End()
+	sql := `
+    UPDATE images
+    SET
+        image_state=$3,
+        modified_at=current_timestamp
+    WHERE
+        image_tag = $1
+        AND
+        image_type = $2
+        AND (
+          $3 != 'retired'
+          OR (` + imageUnreferenced + `)
+        )
+    RETURNING
+        image_name,
+        image_bucket,
+        image_tag,
+        image_type,
+        image_cmdline,
+        image_state
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, config.ImageTag, config.ImageType, config.ImageState)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var ic ipxeDbConfig
+	if err == nil {
+		ic, err = pgx.CollectOneRow(rows, pgx.RowToStructByPos[ipxeDbConfig])
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.Conflict("image_in_use", `image not in database or still in use: %v`, *config)
+	}
+	if err != nil {
+		if sqlErr := createIpxeImagePgError(err); sqlErr != nil {
+			return nil, sqlErr
+		}
+		return nil, queryError(ctx, err, "cannot update image state")
+	}
+	return ic.dto(), nil
+}
+
+// DeleteIpxeImage deletes an entry in ipxe.images matching image_tag and image_type.
+// References in node_images and subnet_default_images are deleted or reassigned in the same statement
+// depending on config, otherwise the image is only deleted while it is unreferenced.
+// Image channels are reassigned too but never deleted, and rollback targets pointing at the image are cleared.
+func (db *DB) DeleteIpxeImage(ctx context.Context, config *ipxe.IpxeImageDeleteConfig) (*ipxe.IpxeDbConfig, error) {
+	ctx, span := tracer.Start(ctx, "postgres.DeleteIpxeImage")
+	defer span.End()
+	// deleted is followed by cleared_rollbacks so rollback targets are only cleared once the image is gone.
+	const deleted = `
+    deleted_images AS (
+        DELETE FROM images
+        WHERE
+            image_tag = $1
+            AND
+            image_type = $2
+            AND %s
+        RETURNING
+            image_name,
+            image_bucket,
+            image_tag,
+            image_type,
+            image_cmdline,
+            image_state
+    ), cleared_rollbacks AS (
+        UPDATE image_channels
+        SET previous_image_tag = NULL, previous_image_type = NULL
+        FROM deleted_images
+        WHERE image_channels.previous_image_tag = deleted_images.image_tag
+          AND image_channels.previous_image_type = deleted_images.image_type
+          AND NOT (image_channels.image_tag = $1 AND image_channels.image_type = $2)
+    )
+    SELECT * FROM deleted_images
+  `
+	const unchanneled = `NOT EXISTS (SELECT 1 FROM image_channels WHERE image_tag = $1 AND image_type = $2)`
+	var sql string
+	args := []any{config.ImageTag, config.ImageType}
+	switch {
+	case config.Cascade:
+		sql = `
+    WITH deleted_node_images AS (
+        DELETE FROM node_images WHERE image_tag = $1 AND image_type = $2 AND ` + unchanneled + `
+    ), deleted_subnet_default_images AS (
+        DELETE FROM subnet_default_images WHERE image_tag = $1 AND image_type = $2 AND ` + unchanneled + `
+    ), ` + fmt.Sprintf(deleted, unchanneled)
+	case config.Reassign():
+		sql = `
+    WITH reassigned_node_images AS (
+        UPDATE node_images
+        SET image_tag = $3, image_type = $4, modified_at = current_timestamp
+        WHERE image_tag = $1 AND image_type = $2
+    ), reassigned_subnet_default_images AS (
+        UPDATE subnet_default_images
+        SET image_tag = $3, image_type = $4, modified_at = current_timestamp
+        WHERE image_tag = $1 AND image_type = $2
+    ), reassigned_image_channels AS (
+        UPDATE image_channels
+        SET
+            image_tag = $3,
+            image_type = $4,
+            previous_image_tag = NULL,
+            previous_image_type = NULL,
+            modified_at = current_timestamp
+        WHERE image_tag = $1 AND image_type = $2
+    ), ` + fmt.Sprintf(deleted, "true")
+		args = append(args, config.ReassignImageTag, config.ReassignImageType)
+	default:
+		sql = `
+    WITH ` + fmt.Sprintf(deleted, imageUnreferenced)
+	}
+	rows, err := db.conn(ctx).Query(ctx, sql, args...)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var ic ipxeDbConfig
+	if err == nil {
+		ic, err = pgx.CollectOneRow(rows, pgx.RowToStructByPos[ipxeDbConfig])
+	}
+	switch {
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, errdefs.Conflict("image_in_use", `image not in database or still in use: %v`, *config)
+	case err != nil:
+		if sqlErr := deleteIpxeImagePgError(err); sqlErr != nil {
+			return nil, sqlErr
+		}
+		return nil, queryError(ctx, err, "cannot delete image")
+	default:
+		return ic.dto(), nil
+	}
+}
@@ -4,38 +4,51 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"log"
-	"strings"
+	"time"
 
 	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
 	"github.com/coreweave/ncore-api/pkg/ipxe"
+	"github.com/coreweave/ncore-api/pkg/logging"
 	"github.com/coreweave/ncore-api/pkg/nodes"
 	"github.com/coreweave/ncore-api/pkg/payloads"
 	"github.com/jackc/pgerrcode"
 	"github.com/jackc/pgx/v5"
-	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
+	"go.opentelemetry.io/otel"
+	"go.uber.org/zap"
 )
 
+var tracer = otel.Tracer("github.com/coreweave/ncore-api/pkg/postgres")
+
 // DB handles database communication with PostgreSQL.
 type DB struct {
 	Postgres *pgxpool.Pool
+
+	// Single is set when the ipxe, payloads and nodes schemas are in the database of Postgres,
+	// enabling queries across them.
+	Single bool
 }
 
-// txCtx key.
-type txCtx struct{}
+// txCtx key. Transactions are keyed by pool so a context can carry one transaction per database.
+type txCtx struct {
+	pool *pgxpool.Pool
+}
 
 // connCtx key.
-type connCtx struct{}
+type connCtx struct {
+	pool *pgxpool.Pool
+}
 
 // conn returns a PostgreSQL transaction if one exists.
 // If not, returns a connection if a connection has been acquired by calling WithAcquire.
 // Otherwise, it returns *pgxpool.Pool which acquires the connection and closes it immediately after a SQL command is executed.
 func (db *DB) conn(ctx context.Context) database.PGXQuerier {
-	if tx, ok := ctx.Value(txCtx{}).(pgx.Tx); ok && tx != nil {
+	if tx, ok := ctx.Value(txCtx{db.Postgres}).(pgx.Tx); ok && tx != nil {
 		return tx
 	}
-	if res, ok := ctx.Value(connCtx{}).(*pgxpool.Conn); ok && res != nil {
+	if res, ok := ctx.Value(connCtx{db.Postgres}).(*pgxpool.Conn); ok && res != nil {
 		return res
 	}
 	return db.Postgres
@@ -49,6 +62,7 @@ type nodePayload struct {
 	PayloadId        string
 	PayloadDirectory string
 	MacAddress       string
+	Enrollment       string
 }
 
 func (np *nodePayload) dto() *payloads.NodePayload {
@@ -56,6 +70,7 @@ func (np *nodePayload) dto() *payloads.NodePayload {
 		PayloadId:        np.PayloadId,
 		PayloadDirectory: np.PayloadDirectory,
 		MacAddress:       np.MacAddress,
+		Enrollment:       np.Enrollment,
 	}
 }
 
@@ -77,6 +92,33 @@ type ipxeDbConfig struct {
 	ImageTag     string
 	ImageType    string
 	ImageCmdline string
+	ImageState   string
+}
+
+// resolvedIpxeDbConfig is an ipxeDbConfig looked up through node_images or subnet_default_images,
+// ImageChannel is empty unless the image was resolved through image_channels, Enrollment unless through node_images.
+type resolvedIpxeDbConfig struct {
+	ImageName    string
+	ImageBucket  string
+	ImageTag     string
+	ImageType    string
+	ImageCmdline string
+	ImageState   string
+	ImageChannel string
+	Enrollment   string
+}
+
+func (ic *resolvedIpxeDbConfig) dto() *ipxe.IpxeDbConfig {
+	return &ipxe.IpxeDbConfig{
+		ImageName:    ic.ImageName,
+		ImageBucket:  ic.ImageBucket,
+		ImageTag:     ic.ImageTag,
+		ImageType:    ic.ImageType,
+		ImageCmdline: ic.ImageCmdline,
+		ImageState:   ic.ImageState,
+		ImageChannel: ic.ImageChannel,
+		Enrollment:   ic.Enrollment,
+	}
 }
 
 type ipxeDbNodeConfig struct {
@@ -92,105 +134,107 @@ func (ic *ipxeDbConfig) dto() *ipxe.IpxeDbConfig {
 		ImageTag:     ic.ImageTag,
 		ImageType:    ic.ImageType,
 		ImageCmdline: ic.ImageCmdline,
+		ImageState:   ic.ImageState,
 	}
 }
 
 // GetNodePayloads reads all payloads for mac_address and returns them as a list.
 func (db *DB) GetNodePayloads(ctx context.Context, macAddress string) ([]*payloads.NodePayload, error) {
+	ctx, span := tracer.Start(ctx, "postgres.GetNodePayloads")
+	defer span.End()
 	var np []*payloads.NodePayload
 
-	np_sql := fmt.Sprintf(`
+	np_sql := `
     SELECT
       node_payloads.mac_address,
       node_payloads.payload_id,
-      payloads.payload_directory
+      payloads.payload_directory,
+      node_payloads.enrollment
     FROM "node_payloads"
     JOIN payloads on (node_payloads.payload_id = payloads.payload_id)
-    WHERE mac_address like '%s'
-  `, macAddress) // #nosec G201
+    WHERE mac_address = $1
+  `
 
-	np_rows, err := db.conn(ctx).Query(ctx, np_sql)
+	np_rows, err := db.conn(ctx).Query(ctx, np_sql, macAddress)
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return nil, err
 	}
-	if errors.Is(err, pgx.ErrNoRows) {
-		log.Printf("cannot get node_payload from database: %v\n", err)
-		return nil, errors.New("cannot get node_payload from database")
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot get node_payload from database")
 	}
-	if err == nil {
-		defer np_rows.Close()
-		for np_rows.Next() {
-			var p nodePayload
-			err = np_rows.Scan(&p.MacAddress, &p.PayloadId, &p.PayloadDirectory)
-			if err != nil {
-				log.Printf("Error - GetNodePayloads - %v", err)
-			}
-			np = append(np, p.dto())
+	defer np_rows.Close()
+	for np_rows.Next() {
+		var p nodePayload
+		err = np_rows.Scan(&p.MacAddress, &p.PayloadId, &p.PayloadDirectory, &p.Enrollment)
+		if err != nil {
+			logging.FromContext(ctx).Error("cannot scan node_payloads row", zap.Error(err))
 		}
-		return np, nil
+		np = append(np, p.dto())
 	}
-
-	return nil, nil
+	return np, nil
 }
 
 // GetSubnetDefaultPayload accepts an ip address string and checks if payloads.subnet_default_payloads table
 // contains a payload_id for the corresponding cidr
-// Returns a Payload
+// Returns a Payload, an errdefs.ErrNotFound error when no subnet contains ipAddress
 func (db *DB) GetSubnetDefaultPayload(ctx context.Context, ipAddress string) (*payloads.Payload, error) {
+	ctx, span := tracer.Start(ctx, "postgres.GetSubnetDefaultPayload")
+	defer span.End()
 	var sdp []payload
-	sdp_sql := fmt.Sprintf(`
+	const sdp_sql = `
 			SELECT
         subnet_default_payloads.payload_id,
 				payloads.payload_directory
 			FROM subnet_default_payloads
 			JOIN payloads on (subnet_default_payloads.payload_id = payloads.payload_id)
-			WHERE subnet >> '%s'
-	`, ipAddress) // #nosec G201
-	sdp_rows, err := db.conn(ctx).Query(ctx, sdp_sql)
+			WHERE subnet >>= $1::inet
+			ORDER BY masklen(subnet) DESC
+			LIMIT 1
+	`
+	sdp_rows, err := db.conn(ctx).Query(ctx, sdp_sql, ipAddress)
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return nil, err
 	}
 	if err == nil {
 		sdp, err = pgx.CollectRows(sdp_rows, pgx.RowToStructByPos[payload])
 	}
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, nil
-	}
 	if err != nil {
-		log.Printf("cannot get default payload for subnet from database: %v\n", err)
-		return nil, errors.New("cannot get default payload for subnet from database")
+		return nil, queryError(ctx, err, "cannot get default payload for subnet from database")
 	}
 	if len(sdp) == 0 {
-		return nil, nil
+		return nil, errdefs.NotFound("subnet_default_payload_not_found", "no subnet_default_payloads entry for ip_address: %s", ipAddress)
 	}
 
 	return sdp[0].dto(), nil
 }
 
 func (db *DB) AddNodePayload(ctx context.Context, nodePayloadDb *payloads.NodePayloadDb) ([]*payloads.NodePayload, error) {
+	ctx, span := tracer.Start(ctx, "postgres.AddNodePayload")
+	defer span.End()
 	const npd_sql = `
     INSERT INTO node_payloads (
       payload_id,
-      mac_address
+      mac_address,
+      enrollment
     )
     VALUES (
         $1,
-        $2
+        $2,
+        COALESCE(NULLIF($3, ''), 'assigned')
     );
 	`
 	switch _, err := db.conn(ctx).Exec(ctx, npd_sql,
 		nodePayloadDb.PayloadId,
 		nodePayloadDb.MacAddress,
+		nodePayloadDb.Enrollment,
 	); {
 	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
 		return nil, err
 	case err != nil:
-		if sqlErr := db.pgErrorCode(err); sqlErr != nil {
-			if sqlErr.Error() == "23505" {
-				return nil, fmt.Errorf("node_payloads entry already exists for macAddress: %s - payloadId: %s", nodePayloadDb.MacAddress, nodePayloadDb.PayloadId)
-			}
+		if pgErrorCode(err) == pgerrcode.UniqueViolation {
+			return nil, errdefs.Conflict("node_payload_exists", "node_payloads entry already exists for macAddress: %s - payloadId: %s", nodePayloadDb.MacAddress, nodePayloadDb.PayloadId)
 		}
-		return nil, err
+		return nil, queryError(ctx, err, "cannot add node payload")
 	default:
 		// Query remaining payloads for node to return
 		np, err := db.GetNodePayloads(ctx, nodePayloadDb.MacAddress)
@@ -203,6 +247,8 @@ func (db *DB) AddNodePayload(ctx context.Context, nodePayloadDb *payloads.NodePa
 
 // GetAvailablePayloads returns a list of available payloads
 func (db *DB) GetAvailablePayloads(ctx context.Context) []string {
+	ctx, span := tracer.Start(ctx, "postgres.GetAvailablePayloads")
+	defer span.End()
 	const p_sql = `
     SELECT ARRAY(
       SELECT
@@ -221,9 +267,9 @@ func (db *DB) GetAvailablePayloads(ctx context.Context) []string {
 		for p_rows.Next() {
 			err = p_rows.Scan(&availablePayloads)
 			if err != nil {
-				log.Printf("Error - GetAvailablePayloads - %v", err)
+				logging.FromContext(ctx).Error("cannot scan available payloads", zap.Error(err))
 			}
-			log.Printf("AvailablePayloads - %v", availablePayloads)
+			logging.FromContext(ctx).Debug("available payloads", zap.Strings("payload_ids", availablePayloads))
 		}
 		return availablePayloads
 	}
@@ -232,32 +278,32 @@ func (db *DB) GetAvailablePayloads(ctx context.Context) []string {
 
 // UpdateNodePayload updates the PayloadId for mac_address.
 func (db *DB) UpdateNodePayload(ctx context.Context, config *payloads.NodePayloadDb) ([]*payloads.NodePayload, error) {
-	log.Printf("UpdateNodePayload: %v\n", *config)
+	ctx, span := tracer.Start(ctx, "postgres.UpdateNodePayload")
+	defer span.End()
+	logging.FromContext(ctx).Debug("updating node_payloads entry", zap.String("mac_address", config.MacAddress), zap.String("payload_id", config.PayloadId))
 	const npd_sql = `
     UPDATE node_payloads
     SET
         payload_id=$1,
+        enrollment=COALESCE(NULLIF($3, ''), 'assigned'),
         modified_at=current_timestamp
     WHERE
-        mac_address like $2
+        mac_address = $2
   `
 	switch commandTag, err := db.conn(ctx).Exec(ctx, npd_sql,
 		config.PayloadId,
 		config.MacAddress,
+		config.Enrollment,
 	); {
 	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
 		return nil, err
 	case err != nil:
-		if sqlErr := db.pgErrorCode(err); sqlErr != nil {
-			if sqlErr.Error() == "23505" {
-				return nil, fmt.Errorf("node_payloads entry already exists for macAddress: %s - payloadId: %s", config.MacAddress, config.PayloadId)
-			}
+		if pgErrorCode(err) == pgerrcode.UniqueViolation {
+			return nil, errdefs.Conflict("node_payload_exists", "node_payloads entry already exists for macAddress: %s - payloadId: %s", config.MacAddress, config.PayloadId)
 		}
-		log.Printf("Error - UpdateNodePayload: %v - %v\n", err, config)
-		return nil, fmt.Errorf(`cannot update payload for config: %v`, *config)
-	case strings.HasPrefix(commandTag.String(), "UPDATE 0"):
-		log.Printf("UpdateNodePayload: mac_address doesn't exist: %v\n", config)
-		return nil, fmt.Errorf(`mac_address not in database: %v`, *config)
+		return nil, queryError(ctx, err, fmt.Sprintf(`cannot update payload for config: %v`, *config))
+	case commandTag.RowsAffected() == 0:
+		return nil, errdefs.NotFound("node_not_found", `mac_address not in database: %v`, *config)
 	default:
 		// Query assigned payloads for node to return
 		np, err := db.GetNodePayloads(ctx, config.MacAddress)
@@ -270,29 +316,26 @@ func (db *DB) UpdateNodePayload(ctx context.Context, config *payloads.NodePayloa
 
 // DeleteNodePayload deletes the PayloadId for mac_address.
 func (db *DB) DeleteNodePayload(ctx context.Context, config *payloads.NodePayloadDb) ([]*payloads.NodePayload, error) {
-	log.Printf("DeleteNodePayload: %v\n", config)
-	var npd *payloads.NodePayloadDb
-	dp_sql := fmt.Sprintf(`
+	ctx, span := tracer.Start(ctx, "postgres.DeleteNodePayload")
+	defer span.End()
+	logging.FromContext(ctx).Debug("deleting node_payloads entry", zap.String("mac_address", config.MacAddress), zap.String("payload_id", config.PayloadId))
+	var payloadId string
+	dp_sql := `
 		DELETE from node_payloads
 		WHERE
-		    mac_address like '%s'
+		    mac_address = $1
         AND
-        payload_id = '%s'
-    RETURNING (
-        payload_id,
-        mac_address
-    )
-	`, config.MacAddress, config.PayloadId) // #nosec G201
-	dp_row := db.conn(ctx).QueryRow(ctx, dp_sql)
-	switch err := dp_row.Scan(&npd); {
+        payload_id = $2
+    RETURNING payload_id
+	`
+	dp_row := db.conn(ctx).QueryRow(ctx, dp_sql, config.MacAddress, config.PayloadId)
+	switch err := dp_row.Scan(&payloadId); {
 	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
 		return nil, err
+	case errors.Is(err, pgx.ErrNoRows):
+		return nil, errdefs.NotFound("node_payload_not_found", `node payload entry not in database: %v`, *config)
 	case err != nil:
-		if sqlErr := db.pgErrorCode(err); sqlErr != nil {
-			return nil, sqlErr
-		}
-		log.Printf("cannot delete node payload: %v\n", err)
-		return nil, fmt.Errorf(`node payload entry not in database: %v`, config)
+		return nil, queryError(ctx, err, "cannot delete node payload")
 	default:
 		// Query remaining payloads for node to return
 		np, err := db.GetNodePayloads(ctx, config.MacAddress)
@@ -303,8 +346,89 @@ func (db *DB) DeleteNodePayload(ctx context.Context, config *payloads.NodePayloa
 	}
 }
 
-// GetPayloadParameters returns an interface{} for a payloadId.
+// ListNodePayloadMacAddresses returns the mac_address of the node_payloads entries for payloadId.
+func (db *DB) ListNodePayloadMacAddresses(ctx context.Context, payloadId string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListNodePayloadMacAddresses")
+	defer span.End()
+	const sql = `
+    SELECT mac_address
+    FROM node_payloads
+    WHERE payload_id = $1
+    ORDER BY mac_address
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, payloadId)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var macAddresses []string
+	if err == nil {
+		macAddresses, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list node_payloads from database")
+	}
+	return macAddresses, nil
+}
+
+// ListNodePayloads returns every node_payloads entry with its payload directory.
+func (db *DB) ListNodePayloads(ctx context.Context) ([]*payloads.NodePayload, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListNodePayloads")
+	defer span.End()
+	const sql = `
+    SELECT
+      node_payloads.payload_id,
+      payloads.payload_directory,
+      node_payloads.mac_address,
+      node_payloads.enrollment
+    FROM node_payloads
+    JOIN payloads on (node_payloads.payload_id = payloads.payload_id)
+    ORDER BY node_payloads.mac_address, node_payloads.payload_id
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var nps []*payloads.NodePayload
+	if err == nil {
+		nps, err = pgx.CollectRows(rows, pgx.RowToAddrOfStructByPos[payloads.NodePayload])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list node_payloads from database")
+	}
+	return nps, nil
+}
+
+// ListSubnetDefaultPayloads returns every subnet_default_payloads entry with its payload directory.
+func (db *DB) ListSubnetDefaultPayloads(ctx context.Context) ([]*payloads.SubnetDefaultPayload, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListSubnetDefaultPayloads")
+	defer span.End()
+	const sql = `
+    SELECT
+      subnet_default_payloads.subnet::text,
+      subnet_default_payloads.payload_id,
+      payloads.payload_directory
+    FROM subnet_default_payloads
+    JOIN payloads on (subnet_default_payloads.payload_id = payloads.payload_id)
+    ORDER BY subnet_default_payloads.subnet
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var sdps []*payloads.SubnetDefaultPayload
+	if err == nil {
+		sdps, err = pgx.CollectRows(rows, pgx.RowToAddrOfStructByPos[payloads.SubnetDefaultPayload])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list subnet_default_payloads from database")
+	}
+	return sdps, nil
+}
+
+// GetPayloadParameters returns an interface{} for a payloadId, an errdefs.ErrNotFound error when it has no parameters.
 func (db *DB) GetPayloadParameters(ctx context.Context, payloadId string) (interface{}, error) {
+	ctx, span := tracer.Start(ctx, "postgres.GetPayloadParameters")
+	defer span.End()
 	// https://faraday.ai/blog/how-to-aggregate-jsonb-in-postgres/
 	sql := fmt.Sprintf(`
 		with params as (select distinct
@@ -323,37 +447,36 @@ func (db *DB) GetPayloadParameters(ctx context.Context, payloadId string) (inter
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return nil, err
 	}
-	if err == nil {
-		var result interface{}
-		for pp_rows.Next() {
-			values, _ := pp_rows.Values()
-			for _, v := range values {
-				result = v
-			}
-		}
-		if result == nil {
-			fmt.Printf("result is nil")
-			return nil, nil
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot get payload_parameters from database")
+	}
+	var result interface{}
+	for pp_rows.Next() {
+		values, _ := pp_rows.Values()
+		for _, v := range values {
+			result = v
 		}
-		return result, err
 	}
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, nil
+	if err := pp_rows.Err(); err != nil {
+		return nil, queryError(ctx, err, "cannot get payload_parameters from database")
 	}
-	if err != nil {
-		log.Printf("cannot get payload_parameters from database: %v\n", err)
-		return nil, errors.New("cannot get payload_parameters from database")
+	if result == nil {
+		return nil, errdefs.NotFound("payload_not_found", "no payload_parameters for payloadId: %s", payloadId)
 	}
-	return nil, nil
+	return result, nil
 }
 
 // GetAvailableImages returns a list of available {image_tag image_type}
 func (db *DB) GetAvailableImages(ctx context.Context) []ipxe.IpxeImageTagType {
+	ctx, span := tracer.Start(ctx, "postgres.GetAvailableImages")
+	defer span.End()
 	const i_sql = `
       SELECT
         image_tag, image_type
       FROM
           images
+      WHERE
+          image_state IN ('staged', 'active')
   `
 	i_rows, err := db.conn(ctx).Query(ctx, i_sql)
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
@@ -368,7 +491,7 @@ func (db *DB) GetAvailableImages(ctx context.Context) []ipxe.IpxeImageTagType {
 		for i_rows.Next() {
 			err = i_rows.Scan(&availableImageTag, &availableImageType)
 			if err != nil {
-				log.Printf("Error - GetAvailableImages - %v", err)
+				logging.FromContext(ctx).Error("cannot scan available images", zap.Error(err))
 			}
 			availableImageTagType = &ipxe.IpxeImageTagType{
 				ImageTag:  availableImageTag,
@@ -382,46 +505,159 @@ func (db *DB) GetAvailableImages(ctx context.Context) []ipxe.IpxeImageTagType {
 }
 
 func (db *DB) UpdateNodeImage(ctx context.Context, config *ipxe.IpxeNodeDbConfig) (*ipxe.IpxeNodeDbConfig, error) {
-	log.Printf("UpdateNodeImage: %v\n", *config)
+	ctx, span := tracer.Start(ctx, "postgres.UpdateNodeImage")
+	defer span.End()
+	logging.FromContext(ctx).Debug("updating node_images entry",
+		zap.String("mac_address", config.MacAddress),
+		zap.String("image_tag", config.ImageTag),
+		zap.String("image_type", config.ImageType),
+		zap.String("image_channel", config.ImageChannel))
 	const indc_sql = `
     UPDATE node_images
     SET
-        image_tag=$1,
-        image_type=$2,
+        image_tag=NULLIF($1, ''),
+        image_type=NULLIF($2, ''),
+        image_channel=NULLIF($4, ''),
+        enrollment=COALESCE(NULLIF($5, ''), 'assigned'),
         modified_at=current_timestamp
     WHERE
-        mac_address like $3
+        mac_address = $3
   `
 	switch commandTag, err := db.conn(ctx).Exec(ctx, indc_sql,
 		config.ImageTag,
 		config.ImageType,
 		config.MacAddress,
+		config.ImageChannel,
+		config.Enrollment,
 	); {
 	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
 		return nil, err
 	case err != nil:
-		log.Printf("Error - UpdateNodeImage: %v - %v\n", err, config)
-		return nil, fmt.Errorf(`cannot update image for config: %v`, *config)
-	case strings.HasPrefix(commandTag.String(), "UPDATE 0"):
-		log.Printf("UpdateNodeImage: mac_address doesn't exist: %v\n", config)
-		return nil, fmt.Errorf(`mac_address not in database: %v`, *config)
+		return nil, queryError(ctx, err, fmt.Sprintf(`cannot update image for config: %v`, *config))
+	case commandTag.RowsAffected() == 0:
+		return nil, errdefs.NotFound("node_not_found", `mac_address not in database: %v`, *config)
 	}
-	return config, nil
+	updated := *config
+	if updated.Enrollment == "" {
+		updated.Enrollment = enrollment.Assigned
+	}
+	return &updated, nil
+}
+
+// ListNodeImageMacAddresses returns the mac_address of the node_images entries assigned source,
+// or of the entries in macAddresses when source is nil.
+func (db *DB) ListNodeImageMacAddresses(ctx context.Context, source *ipxe.IpxeImageTagType, macAddresses []string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListNodeImageMacAddresses")
+	defer span.End()
+	var rows pgx.Rows
+	var err error
+	if source != nil {
+		const sql = `
+    SELECT mac_address
+    FROM node_images
+    WHERE
+        image_tag = $1
+        AND
+        image_type = $2
+    ORDER BY mac_address
+  `
+		rows, err = db.conn(ctx).Query(ctx, sql, source.ImageTag, source.ImageType)
+	} else {
+		const sql = `
+    SELECT mac_address
+    FROM node_images
+    WHERE mac_address = ANY($1)
+    ORDER BY mac_address
+  `
+		rows, err = db.conn(ctx).Query(ctx, sql, macAddresses)
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var candidates []string
+	if err == nil {
+		candidates, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list rollout candidates from database")
+	}
+	return candidates, nil
+}
+
+// GetNodeImage returns the node_images entry for macAddress, an errdefs.ErrNotFound error when the node has none.
+func (db *DB) GetNodeImage(ctx context.Context, macAddress string) (*ipxe.IpxeNodeDbConfig, error) {
+	ctx, span := tracer.Start(ctx, "postgres.GetNodeImage")
+	defer span.End()
+	const sql = `
+    SELECT
+        COALESCE(image_tag, ''),
+        COALESCE(image_type, ''),
+        COALESCE(image_channel, ''),
+        mac_address,
+        enrollment
+    FROM node_images
+    WHERE
+        mac_address = $1
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, macAddress)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var indc ipxe.IpxeNodeDbConfig
+	if err == nil {
+		indc, err = pgx.CollectOneRow(rows, pgx.RowToStructByPos[ipxe.IpxeNodeDbConfig])
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.NotFound(ipxe.CodeNodeImageNotFound, "no node_images entry for mac_address: %s", macAddress)
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot get node_images entry from database")
+	}
+	return &indc, nil
 }
 
-// GetIpxe returns an IpxeConfig for a macAddress.
-// TODO: https://github.com/uber-go/zap
+// ListNodeImages returns every entry in ipxe.node_images.
+func (db *DB) ListNodeImages(ctx context.Context) ([]*ipxe.IpxeNodeDbConfig, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListNodeImages")
+	defer span.End()
+	const sql = `
+    SELECT
+        COALESCE(image_tag, ''),
+        COALESCE(image_type, ''),
+        COALESCE(image_channel, ''),
+        mac_address,
+        enrollment
+    FROM node_images
+    ORDER BY mac_address
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var indcs []*ipxe.IpxeNodeDbConfig
+	if err == nil {
+		indcs, err = pgx.CollectRows(rows, pgx.RowToAddrOfStructByPos[ipxe.IpxeNodeDbConfig])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list node_images from database")
+	}
+	return indcs, nil
+}
+
+// GetIpxeDbConfig returns the image of a macAddress.
 func (db *DB) GetIpxeDbConfig(ctx context.Context, macAddress string) (*ipxe.IpxeDbConfig, error) {
+	ctx, span := tracer.Start(ctx, "postgres.GetIpxeDbConfig")
+	defer span.End()
 	var idnc []ipxeDbNodeConfig
-	var ic []ipxeDbConfig
+	var ic []resolvedIpxeDbConfig
 	idnc_sql := fmt.Sprintf(`
     SELECT
-        image_tag,
-        image_type,
+        COALESCE(image_tag, ''),
+        COALESCE(image_type, ''),
         mac_address
     FROM node_images
     WHERE
-        mac_address like $1
+        mac_address = $1
   `)
 	idnc_rows, err := db.conn(ctx).Query(ctx, idnc_sql,
 		macAddress,
@@ -432,15 +668,11 @@ func (db *DB) GetIpxeDbConfig(ctx context.Context, macAddress string) (*ipxe.Ipx
 	if err == nil {
 		idnc, err = pgx.CollectRows(idnc_rows, pgx.RowToStructByPos[ipxeDbNodeConfig])
 	}
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, pgx.ErrNoRows
-	}
 	if err != nil {
-		log.Printf("cannot get image_tag and image_type from database: %v\n", err)
-		return nil, errors.New("cannot get image_tag and image_type from database")
+		return nil, queryError(ctx, err, "cannot get image_tag and image_type from database")
 	}
 	if len(idnc) == 0 {
-		return nil, errors.New("no image_tag and image_type found in database")
+		return nil, errdefs.NotFound(ipxe.CodeNodeImageNotFound, "no node_images entry for mac_address: %s", macAddress)
 	}
 	ic_sql := fmt.Sprintf(`
     SELECT
@@ -448,14 +680,21 @@ func (db *DB) GetIpxeDbConfig(ctx context.Context, macAddress string) (*ipxe.Ipx
         images.image_bucket,
         images.image_tag,
         images.image_type,
-        images.image_cmdline
-    FROM images
-    JOIN node_images on (
-      node_images.image_tag = images.image_tag
+        images.image_cmdline,
+        images.image_state,
+        COALESCE(node_images.image_channel, ''),
+        node_images.enrollment
+    FROM node_images
+    LEFT JOIN image_channels on (
+      image_channels.channel = node_images.image_channel
+    )
+    JOIN images on (
+      images.image_tag = COALESCE(image_channels.image_tag, node_images.image_tag)
     ) AND (
-      node_images.image_type = images.image_type
+      images.image_type = COALESCE(image_channels.image_type, node_images.image_type)
     )
-      WHERE node_images.mac_address like $1;
+      WHERE node_images.mac_address = $1
+      AND images.image_state != 'retired';
 	`)
 	ic_rows, err := db.conn(ctx).Query(ctx, ic_sql,
 		macAddress,
@@ -464,25 +703,22 @@ func (db *DB) GetIpxeDbConfig(ctx context.Context, macAddress string) (*ipxe.Ipx
 		return nil, err
 	}
 	if err == nil {
-		ic, err = pgx.CollectRows(ic_rows, pgx.RowToStructByPos[ipxeDbConfig])
-	}
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, pgx.ErrNoRows
+		ic, err = pgx.CollectRows(ic_rows, pgx.RowToStructByPos[resolvedIpxeDbConfig])
 	}
 	if err != nil {
-		log.Printf("cannot get ipxe from database: %v\n", err)
-		return nil, errors.New("cannot get ipxe from database")
+		return nil, queryError(ctx, err, "cannot get ipxe from database")
 	}
 	if len(ic) == 0 {
-		return nil, errors.New("no image found in database")
+		return nil, errdefs.NotFound("image_not_found", "no image found in database for mac_address: %s", macAddress)
 	}
 	return ic[0].dto(), nil
 }
 
-// GetIpxe returns an IpxeConfig for a macAddress.
-// TODO: https://github.com/uber-go/zap
+// GetSubnetDefaultIpxeDbConfig returns the default image of the subnet containing ipAddress.
 func (db *DB) GetSubnetDefaultIpxeDbConfig(ctx context.Context, ipAddress string) (*ipxe.IpxeDbConfig, error) {
-	var ic []ipxeDbConfig
+	ctx, span := tracer.Start(ctx, "postgres.GetSubnetDefaultIpxeDbConfig")
+	defer span.End()
+	var ic []resolvedIpxeDbConfig
 
 	ic_sql := `
     SELECT
@@ -490,64 +726,75 @@ func (db *DB) GetSubnetDefaultIpxeDbConfig(ctx context.Context, ipAddress string
         images.image_bucket,
         images.image_tag,
         images.image_type,
-        images.image_cmdline
-    FROM images
-    JOIN subnet_default_images on (
-      subnet_default_images.image_tag = images.image_tag
+        images.image_cmdline,
+        images.image_state,
+        COALESCE(subnet_default_images.image_channel, ''),
+        ''
+    FROM subnet_default_images
+    LEFT JOIN image_channels on (
+      image_channels.channel = subnet_default_images.image_channel
+    )
+    JOIN images on (
+      images.image_tag = COALESCE(image_channels.image_tag, subnet_default_images.image_tag)
     ) AND (
-      subnet_default_images.image_type = images.image_type
+      images.image_type = COALESCE(image_channels.image_type, subnet_default_images.image_type)
     )
     WHERE
-        subnet_default_images.subnet >> $1
+        subnet_default_images.subnet >>= $1::inet
+        AND images.image_state != 'retired'
+    ORDER BY masklen(subnet_default_images.subnet) DESC
+    LIMIT 1
 	`
 	ic_rows, err := db.conn(ctx).Query(ctx, ic_sql, ipAddress)
 	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
 		return nil, err
 	}
 	if err == nil {
-		ic, err = pgx.CollectRows(ic_rows, pgx.RowToStructByPos[ipxeDbConfig])
-	}
-	if errors.Is(err, pgx.ErrNoRows) {
-		return nil, pgx.ErrNoRows
+		ic, err = pgx.CollectRows(ic_rows, pgx.RowToStructByPos[resolvedIpxeDbConfig])
 	}
 	if err != nil {
-		log.Printf("cannot get ipxe from database: %v\n", err)
-		return nil, errors.New("cannot get ipxe from database")
+		return nil, queryError(ctx, err, "cannot get ipxe from database")
 	}
 	if len(ic) == 0 {
-		return nil, errors.New("no image found in database")
+		return nil, errdefs.NotFound("subnet_default_image_not_found", "no image found in database for ip_address: %s", ipAddress)
 	}
 	return ic[0].dto(), nil
 }
 
 // CreateNodeIpxeConfig inserts an IpxeNodeDbConfig into ipxe.node_images.
 func (db *DB) CreateNodeIpxeConfig(ctx context.Context, config *ipxe.IpxeNodeDbConfig) error {
+	ctx, span := tracer.Start(ctx, "postgres.CreateNodeIpxeConfig")
+	defer span.End()
 	const sql = `
     INSERT INTO node_images (
         image_tag,
         image_type,
-        mac_address
+        mac_address,
+        image_channel,
+        enrollment
     )
     VALUES (
-        $1,
-        $2,
-        $3
+        NULLIF($1, ''),
+        NULLIF($2, ''),
+        $3,
+        NULLIF($4, ''),
+        COALESCE(NULLIF($5, ''), 'assigned')
     );
 	`
 	switch _, err := db.conn(ctx).Exec(ctx, sql,
 		config.ImageTag,
 		config.ImageType,
 		config.MacAddress,
+		config.ImageChannel,
+		config.Enrollment,
 	); {
 	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
 		return err
 	case err != nil:
-		if sqlErr := db.pgErrorCode(err); sqlErr != nil {
-			if sqlErr.Error() == "23505" {
-				return fmt.Errorf("node_images entry already exists for macAddress: %s", config.MacAddress)
-			}
+		if pgErrorCode(err) == pgerrcode.UniqueViolation {
+			return errdefs.Conflict("node_image_exists", "node_images entry already exists for macAddress: %s", config.MacAddress)
 		}
-		return err
+		return queryError(ctx, err, "cannot create node_images entry")
 	default:
 		return nil
 	}
@@ -555,6 +802,8 @@ func (db *DB) CreateNodeIpxeConfig(ctx context.Context, config *ipxe.IpxeNodeDbC
 
 // CreateIpxeImage inserts an IpxeDbConfig into ipxe.images.
 func (db *DB) CreateIpxeImage(ctx context.Context, config *ipxe.IpxeDbConfig) (*ipxe.IpxeConfig, error) {
+	ctx, span := tracer.Start(ctx, "postgres.CreateIpxeImage")
+	defer span.End()
 	var ic *ipxe.IpxeConfig
 	const sql = `
     INSERT INTO images (
@@ -562,31 +811,35 @@ func (db *DB) CreateIpxeImage(ctx context.Context, config *ipxe.IpxeDbConfig) (*
         image_bucket,
         image_tag,
         image_type,
-        image_cmdline
+        image_cmdline,
+        image_state
     )
     VALUES (
         $1,
         $2,
         $3,
         $4,
-        $5
-    );
+        $5,
+        COALESCE(NULLIF($6, ''), 'active')
+    )
+    RETURNING image_state;
 	`
-	switch _, err := db.conn(ctx).Exec(ctx, sql,
+	var imageState string
+	switch err := db.conn(ctx).QueryRow(ctx, sql,
 		config.ImageName,
 		config.ImageBucket,
 		config.ImageTag,
 		config.ImageType,
 		config.ImageCmdline,
-	); {
+		config.ImageState,
+	).Scan(&imageState); {
 	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
 		return nil, err
 	case err != nil:
-		if sqlErr := db.createIpxeImagePgError(err); sqlErr != nil {
+		if sqlErr := createIpxeImagePgError(err); sqlErr != nil {
 			return nil, sqlErr
 		}
-		log.Printf("cannot create image: %v\n", err)
-		return nil, errors.New("cannot create image")
+		return nil, queryError(ctx, err, "cannot create image")
 	}
 	ic = &ipxe.IpxeConfig{
 		ImageName:    config.ImageName,
@@ -594,110 +847,197 @@ func (db *DB) CreateIpxeImage(ctx context.Context, config *ipxe.IpxeDbConfig) (*
 		ImageTag:     config.ImageTag,
 		ImageType:    config.ImageType,
 		ImageCmdline: config.ImageCmdline,
+		ImageState:   imageState,
 	}
 	return ic, nil
 }
 
-// DeleteIpxeImage deletes an entry in ipxe.images matching image_tag and image_type.
-func (db *DB) DeleteIpxeImage(ctx context.Context, config *ipxe.IpxeImageTagType) (*ipxe.IpxeDbConfig, error) {
-	var idc *ipxeDbConfig
-	sql := fmt.Sprintf(`
-    DELETE FROM images
+// UpdateIpxeImage updates the image_name, image_bucket and image_cmdline of an existing (image_tag, image_type).
+func (db *DB) UpdateIpxeImage(ctx context.Context, config *ipxe.IpxeDbConfig) (*ipxe.IpxeDbConfig, error) {
+	ctx, span := tracer.Start(ctx, "postgres.UpdateIpxeImage")
+	defer span.End()
+	const sql = `
+    UPDATE images
+    SET
+        image_name=$1,
+        image_bucket=$2,
+        image_cmdline=$3,
+        modified_at=current_timestamp
     WHERE
-        image_tag = '%s'
+        image_tag = $4
         AND
-        image_type = '%s'
-    RETURNING (
-        image_name,
-        image_bucket,
-        image_tag,
-        image_type
-    )
-	`,
+        image_type = $5
+  `
+	switch commandTag, err := db.conn(ctx).Exec(ctx, sql,
+		config.ImageName,
+		config.ImageBucket,
+		config.ImageCmdline,
 		config.ImageTag,
 		config.ImageType,
-	)
-	row := db.conn(ctx).QueryRow(ctx, sql)
-	switch err := row.Scan(&idc); {
+	); {
 	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
 		return nil, err
 	case err != nil:
-		if sqlErr := db.deleteIpxeImagePgError(err); sqlErr != nil {
+		if sqlErr := createIpxeImagePgError(err); sqlErr != nil {
 			return nil, sqlErr
 		}
-		log.Printf("cannot delete image: %v\n", err)
-		return nil, errors.New("cannot delete image")
-	default:
-		return idc.dto(), nil
+		return nil, queryError(ctx, err, "cannot update image")
+	case commandTag.RowsAffected() == 0:
+		return nil, errdefs.NotFound("image_not_found", `image not in database: %v`, *config)
 	}
+	return config, nil
 }
 
-func (db *DB) createIpxeImagePgError(err error) error {
-	var pgErr *pgconn.PgError
-	if !errors.As(err, &pgErr) {
-		return nil
+// ListIpxeImages returns every entry in ipxe.images.
+func (db *DB) ListIpxeImages(ctx context.Context) ([]*ipxe.IpxeDbConfig, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListIpxeImages")
+	defer span.End()
+	const sql = `
+    SELECT
+        image_name,
+        image_bucket,
+        image_tag,
+        image_type,
+        image_cmdline,
+        image_state
+    FROM images
+    ORDER BY image_tag, image_type
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
 	}
-	if pgErr.Code == pgerrcode.UniqueViolation {
-		return errors.New("UniqueViolation - (image_tag, image_type) exists")
-	}
-	if pgErr.Code == pgerrcode.CheckViolation {
-		switch pgErr.ConstraintName {
-		case "image_bucket":
-			return errors.New("invalid image_bucket")
-		case "image_name":
-			return errors.New("invalid image_name")
-		case "image_tag":
-			return errors.New("invalid image_tag")
-		case "image_type":
-			return errors.New("invalid image_type")
-		case "image_cmdline":
-			return errors.New("invalid image_cmdline")
-		}
+	var ic []ipxeDbConfig
+	if err == nil {
+		ic, err = pgx.CollectRows(rows, pgx.RowToStructByPos[ipxeDbConfig])
 	}
-	return nil
-}
-
-func (db *DB) deleteIpxeImagePgError(err error) error {
-	var pgErr *pgconn.PgError
-	if !errors.As(err, &pgErr) {
-		return nil
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list images from database")
 	}
-	return errors.New("Error deleting (image_tag, image_type): " + pgErr.Code)
-}
-
-func (db *DB) pgErrorCode(err error) error {
-	var pgErr *pgconn.PgError
-	if !errors.As(err, &pgErr) {
-		return nil
+	idc := make([]*ipxe.IpxeDbConfig, 0, len(ic))
+	for i := range ic {
+		idc = append(idc, ic[i].dto())
 	}
-	return errors.New(pgErr.Code)
+	return idc, nil
 }
 
 func (db *DB) UpdateNodeStats(ctx context.Context, n *nodes.Node) (*nodes.Node, error) {
+	ctx, span := tracer.Start(ctx, "postgres.UpdateNodeStats")
+	defer span.End()
 	const npd_sql = `
     INSERT INTO node_heartbeat (
 		mac_address,
 		hostname,
-		ip_address
+		ip_address,
+		ip_addresses
 	)
 	VALUES (
 		$1,
 		$2,
-		$3
+		$3::inet,
+		$4::text[]::inet[]
 	)
 	ON CONFLICT (mac_address)
-	DO UPDATE set mac_address = $1, hostname = $2, ip_address = $3, last_seen=now();
+	DO UPDATE set mac_address = $1, hostname = $2, ip_address = $3::inet, ip_addresses = $4::text[]::inet[], last_seen=now();
 	`
 	switch _, err := db.conn(ctx).Exec(ctx, npd_sql,
 		n.MacAddress,
 		n.Hostname,
 		n.IpAddress,
+		n.IpAddresses,
 	); {
 	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
 		return nil, err
 	case err != nil:
-		return nil, err
+		return nil, queryError(ctx, err, "cannot update node_heartbeat")
 	}
 
 	return n, nil
 }
+
+// GetNodesLastSeen returns node_heartbeat.last_seen for each of macAddresses with a heartbeat.
+func (db *DB) GetNodesLastSeen(ctx context.Context, macAddresses []string) (map[string]time.Time, error) {
+	ctx, span := tracer.Start(ctx, "postgres.GetNodesLastSeen")
+	defer span.End()
+	const sql = `
+    SELECT
+        mac_address,
+        last_seen
+    FROM node_heartbeat
+    WHERE mac_address = ANY($1)
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, macAddresses)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	lastSeen := map[string]time.Time{}
+	if err == nil {
+		var macAddress string
+		var seen time.Time
+		_, err = pgx.ForEachRow(rows, []any{&macAddress, &seen}, func() error {
+			lastSeen[macAddress] = seen
+			return nil
+		})
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot get node_heartbeat from database")
+	}
+	return lastSeen, nil
+}
+
+// CountNodesLastSeen returns the number of node_heartbeat entries whose last_seen is within each of within,
+// and the number of node_heartbeat entries.
+func (db *DB) CountNodesLastSeen(ctx context.Context, within []time.Duration) ([]int, int, error) {
+	ctx, span := tracer.Start(ctx, "postgres.CountNodesLastSeen")
+	defer span.End()
+	const sql = `
+    SELECT
+        (SELECT count(*) FROM node_heartbeat),
+        ARRAY(
+            SELECT (
+                SELECT count(*)
+                FROM node_heartbeat
+                WHERE last_seen >= now() - make_interval(secs => w.secs)
+            )
+            FROM unnest($1::float8[]) WITH ORDINALITY AS w(secs, i)
+            ORDER BY w.i
+        )
+  `
+	secs := make([]float64, len(within))
+	for i, d := range within {
+		secs[i] = d.Seconds()
+	}
+	var total int
+	var counts []int
+	switch err := db.conn(ctx).QueryRow(ctx, sql, secs).Scan(&total, &counts); {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return nil, 0, err
+	case err != nil:
+		return nil, 0, queryError(ctx, err, "cannot count node_heartbeat from database")
+	}
+	return counts, total, nil
+}
+
+// ListNodesInSubnet returns the mac_address of the node_heartbeat entries with one of their ip_addresses in subnet.
+func (db *DB) ListNodesInSubnet(ctx context.Context, subnet string) ([]string, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListNodesInSubnet")
+	defer span.End()
+	const sql = `
+    SELECT mac_address
+    FROM node_heartbeat
+    WHERE EXISTS (SELECT FROM unnest(ip_addresses) AS a WHERE a <<= $1::cidr)
+    ORDER BY mac_address
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, subnet)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var macAddresses []string
+	if err == nil {
+		macAddresses, err = pgx.CollectRows(rows, pgx.RowTo[string])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list node_heartbeat from database")
+	}
+	return macAddresses, nil
+}
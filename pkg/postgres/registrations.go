@@ -0,0 +1,247 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/nodes"
+	"github.com/jackc/pgx/v5"
+)
+
+const registrationColumns = `
+        mac_address,
+        ip_address,
+        hints,
+        registration_state,
+        rule_id,
+        first_seen,
+        last_seen,
+        decided_at
+`
+
+const registrationRuleColumns = `
+        rule_id,
+        COALESCE(subnet::text, ''),
+        COALESCE(mac_oui, ''),
+        COALESCE(image_tag, ''),
+        COALESCE(image_type, ''),
+        COALESCE(image_channel, ''),
+        COALESCE(payload_id, ''),
+        created_at
+`
+
+// RegisterNode adds a pending node_registrations entry, or updates the ip_address, hints and last_seen of the existing one.
+// The entry stays locked until the transaction carried by ctx ends.
+func (db *DB) RegisterNode(ctx context.Context, r *nodes.Registration) (*nodes.Registration, error) {
+	ctx, span := tracer.Start(ctx, "postgres.RegisterNode")
+	defer span.End()
+	sql := `
+    INSERT INTO node_registrations (
+        mac_address,
+        ip_address,
+        hints
+    )
+    VALUES (
+        $1,
+        $2,
+        $3
+    )
+    ON CONFLICT (mac_address)
+    DO UPDATE SET
+        ip_address = EXCLUDED.ip_address,
+        hints = node_registrations.hints || EXCLUDED.hints,
+        last_seen = now()
+    RETURNING` + registrationColumns
+	return db.queryRegistration(ctx, "cannot register node", r.MacAddress, sql, r.MacAddress, r.IpAddress, r.Hints)
+}
+
+// GetRegistration returns the node_registrations entry of macAddress.
+func (db *DB) GetRegistration(ctx context.Context, macAddress string) (*nodes.Registration, error) {
+	ctx, span := tracer.Start(ctx, "postgres.GetRegistration")
+	defer span.End()
+	sql := `
+    SELECT` + registrationColumns + `
+    FROM node_registrations
+    WHERE mac_address = $1
+  `
+	return db.queryRegistration(ctx, "cannot get node registration from database", macAddress, sql, macAddress)
+}
+
+// SetRegistrationState sets the registration_state of macAddress and the rule that decided it.
+func (db *DB) SetRegistrationState(ctx context.Context, macAddress string, state string, ruleId *int64) (*nodes.Registration, error) {
+	ctx, span := tracer.Start(ctx, "postgres.SetRegistrationState")
+	defer span.End()
+	sql := `
+    UPDATE node_registrations
+    SET
+        registration_state = $2,
+        rule_id = $3,
+        decided_at = now()
+    WHERE mac_address = $1
+    RETURNING` + registrationColumns
+	return db.queryRegistration(ctx, "cannot update node registration", macAddress, sql, macAddress, state, ruleId)
+}
+
+func (db *DB) queryRegistration(ctx context.Context, msg string, macAddress string, sql string, args ...any) (*nodes.Registration, error) {
+	rows, err := db.conn(ctx).Query(ctx, sql, args...)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var r *nodes.Registration
+	if err == nil {
+		r, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByPos[nodes.Registration])
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.NotFound("registration_not_found", "no node_registrations entry for mac_address: %s", macAddress)
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, msg)
+	}
+	return r, nil
+}
+
+// ListRegistrations returns the node_registrations entries in state, or every entry when state is empty.
+func (db *DB) ListRegistrations(ctx context.Context, state string) ([]*nodes.Registration, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListRegistrations")
+	defer span.End()
+	sql := `
+    SELECT` + registrationColumns + `
+    FROM node_registrations
+    WHERE $1 = '' OR registration_state = $1
+    ORDER BY first_seen, mac_address
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, state)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var rs []*nodes.Registration
+	if err == nil {
+		rs, err = pgx.CollectRows(rows, pgx.RowToAddrOfStructByPos[nodes.Registration])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list node registrations from database")
+	}
+	return rs, nil
+}
+
+// MatchRegistrationRule returns the registration_rules entry with the lowest rule_id whose subnet contains ipAddress
+// and whose mac_oui prefixes macAddress, ignoring the NULL ones.
+func (db *DB) MatchRegistrationRule(ctx context.Context, macAddress string, ipAddress string) (*nodes.RegistrationRule, error) {
+	ctx, span := tracer.Start(ctx, "postgres.MatchRegistrationRule")
+	defer span.End()
+	sql := `
+    SELECT` + registrationRuleColumns + `
+    FROM registration_rules
+    WHERE (subnet IS NULL OR subnet >>= $2::inet)
+    AND (mac_oui IS NULL OR left($1, 6) = mac_oui)
+    ORDER BY rule_id
+    LIMIT 1
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, macAddress, ipAddress)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var rule *nodes.RegistrationRule
+	if err == nil {
+		rule, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByPos[nodes.RegistrationRule])
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.NotFound("registration_rule_not_found", "no registration_rules entry for mac_address: %s - ip_address: %s", macAddress, ipAddress)
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot match registration rules")
+	}
+	return rule, nil
+}
+
+// ListRegistrationRules returns every registration_rules entry ordered by rule_id.
+func (db *DB) ListRegistrationRules(ctx context.Context) ([]*nodes.RegistrationRule, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListRegistrationRules")
+	defer span.End()
+	sql := `
+    SELECT` + registrationRuleColumns + `
+    FROM registration_rules
+    ORDER BY rule_id
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var rules []*nodes.RegistrationRule
+	if err == nil {
+		rules, err = pgx.CollectRows(rows, pgx.RowToAddrOfStructByPos[nodes.RegistrationRule])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list registration rules from database")
+	}
+	return rules, nil
+}
+
+// CreateRegistrationRule inserts rule, empty fields are stored as NULL.
+func (db *DB) CreateRegistrationRule(ctx context.Context, rule *nodes.RegistrationRule) (*nodes.RegistrationRule, error) {
+	ctx, span := tracer.Start(ctx, "postgres.CreateRegistrationRule")
+	defer span.End()
+	sql := `
+    INSERT INTO registration_rules (
+        subnet,
+        mac_oui,
+        image_tag,
+        image_type,
+        image_channel,
+        payload_id
+    )
+    VALUES (
+        NULLIF($1, '')::cidr,
+        NULLIF($2, ''),
+        NULLIF($3, ''),
+        NULLIF($4, ''),
+        NULLIF($5, ''),
+        NULLIF($6, '')
+    )
+    RETURNING` + registrationRuleColumns
+	rows, err := db.conn(ctx).Query(ctx, sql,
+		rule.Subnet,
+		rule.MacOui,
+		rule.ImageTag,
+		rule.ImageType,
+		rule.ImageChannel,
+		rule.PayloadId,
+	)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var created *nodes.RegistrationRule
+	if err == nil {
+		created, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByPos[nodes.RegistrationRule])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot add registration rule")
+	}
+	return created, nil
+}
+
+// DeleteRegistrationRule deletes the registration_rules entry ruleId.
+func (db *DB) DeleteRegistrationRule(ctx context.Context, ruleId int64) (*nodes.RegistrationRule, error) {
+	ctx, span := tracer.Start(ctx, "postgres.DeleteRegistrationRule")
+	defer span.End()
+	sql := `
+    DELETE FROM registration_rules
+    WHERE rule_id = $1
+    RETURNING` + registrationRuleColumns
+	rows, err := db.conn(ctx).Query(ctx, sql, ruleId)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var deleted *nodes.RegistrationRule
+	if err == nil {
+		deleted, err = pgx.CollectOneRow(rows, pgx.RowToAddrOfStructByPos[nodes.RegistrationRule])
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.NotFound("registration_rule_not_found", "registration rule not in database: %d", ruleId)
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot delete registration rule")
+	}
+	return deleted, nil
+}
@@ -0,0 +1,398 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/ipxe"
+	"github.com/jackc/pgx/v5"
+)
+
+const imageRolloutColumns = `
+        rollout_id,
+        COALESCE(source_image_tag, ''),
+        COALESCE(source_image_type, ''),
+        image_tag,
+        image_type,
+        waves,
+        current_wave,
+        rollout_state,
+        heartbeat_deadline_secs,
+        max_failures,
+        halt_reason,
+        wave_started_at
+`
+
+type imageRollout struct {
+	RolloutId             int64
+	SourceImageTag        string
+	SourceImageType       string
+	ImageTag              string
+	ImageType             string
+	Waves                 []int32
+	CurrentWave           int32
+	RolloutState          string
+	HeartbeatDeadlineSecs int64
+	MaxFailures           int32
+	HaltReason            string
+	WaveStartedAt         *time.Time
+}
+
+func (ir *imageRollout) dto() *ipxe.ImageRollout {
+	waves := make([]int, 0, len(ir.Waves))
+	for _, w := range ir.Waves {
+		waves = append(waves, int(w))
+	}
+	return &ipxe.ImageRollout{
+		RolloutId:             ir.RolloutId,
+		SourceImageTag:        ir.SourceImageTag,
+		SourceImageType:       ir.SourceImageType,
+		ImageTag:              ir.ImageTag,
+		ImageType:             ir.ImageType,
+		Waves:                 waves,
+		CurrentWave:           int(ir.CurrentWave),
+		RolloutState:          ir.RolloutState,
+		HeartbeatDeadlineSecs: ir.HeartbeatDeadlineSecs,
+		MaxFailures:           int(ir.MaxFailures),
+		HaltReason:            ir.HaltReason,
+		WaveStartedAt:         ir.WaveStartedAt,
+	}
+}
+
+const imageRolloutNodeColumns = `
+        image_rollout_nodes.mac_address,
+        image_rollout_nodes.wave,
+        image_rollout_nodes.node_state,
+        image_rollout_nodes.updated_at,
+        COALESCE(image_rollout_nodes.previous_image_tag, ''),
+        COALESCE(image_rollout_nodes.previous_image_type, ''),
+        COALESCE(image_rollout_nodes.previous_image_channel, ''),
+        COALESCE(image_rollout_nodes.previous_enrollment, '')
+`
+
+type imageRolloutNode struct {
+	MacAddress           string
+	Wave                 int32
+	NodeState            string
+	UpdatedAt            *time.Time
+	PreviousImageTag     string
+	PreviousImageType    string
+	PreviousImageChannel string
+	PreviousEnrollment   string
+}
+
+func (irn *imageRolloutNode) dto() *ipxe.ImageRolloutNode {
+	return &ipxe.ImageRolloutNode{
+		MacAddress:           irn.MacAddress,
+		Wave:                 int(irn.Wave),
+		NodeState:            irn.NodeState,
+		UpdatedAt:            irn.UpdatedAt,
+		PreviousImageTag:     irn.PreviousImageTag,
+		PreviousImageType:    irn.PreviousImageType,
+		PreviousImageChannel: irn.PreviousImageChannel,
+		PreviousEnrollment:   irn.PreviousEnrollment,
+	}
+}
+
+// CreateImageRollout inserts a rollout and snapshots the current node_images assignment of its nodes in one statement.
+func (db *DB) CreateImageRollout(ctx context.Context, rollout *ipxe.ImageRollout, waves map[string]int) (*ipxe.ImageRollout, error) {
+	ctx, span := tracer.Start(ctx, "postgres.CreateImageRollout")
+	defer span.End()
+	const sql = `
+    WITH rollout AS (
+        INSERT INTO image_rollouts (
+            source_image_tag,
+            source_image_type,
+            image_tag,
+            image_type,
+            waves,
+            heartbeat_deadline_secs,
+            max_failures
+        )
+        VALUES (
+            NULLIF($1, ''),
+            NULLIF($2, ''),
+            $3,
+            $4,
+            $5,
+            $6,
+            $7
+        )
+        RETURNING rollout_id
+    ), rollout_nodes AS (
+        INSERT INTO image_rollout_nodes (
+            rollout_id,
+            mac_address,
+            wave,
+            previous_image_tag,
+            previous_image_type,
+            previous_image_channel,
+            previous_enrollment
+        )
+        SELECT
+            rollout.rollout_id,
+            nodes.mac_address,
+            nodes.wave,
+            node_images.image_tag,
+            node_images.image_type,
+            node_images.image_channel,
+            node_images.enrollment
+        FROM rollout, unnest($8::macaddr[], $9::integer[]) AS nodes (mac_address, wave)
+        JOIN node_images ON node_images.mac_address = nodes.mac_address
+    )
+    SELECT rollout_id FROM rollout
+  `
+	macAddresses := make([]string, 0, len(waves))
+	nodeWaves := make([]int32, 0, len(waves))
+	for macAddress, wave := range waves {
+		macAddresses = append(macAddresses, macAddress)
+		nodeWaves = append(nodeWaves, int32(wave))
+	}
+	rolloutWaves := make([]int32, 0, len(rollout.Waves))
+	for _, w := range rollout.Waves {
+		rolloutWaves = append(rolloutWaves, int32(w))
+	}
+	rows, err := db.conn(ctx).Query(ctx, sql,
+		rollout.SourceImageTag,
+		rollout.SourceImageType,
+		rollout.ImageTag,
+		rollout.ImageType,
+		rolloutWaves,
+		rollout.HeartbeatDeadlineSecs,
+		rollout.MaxFailures,
+		macAddresses,
+		nodeWaves,
+	)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var rolloutId int64
+	if err == nil {
+		rolloutId, err = pgx.CollectOneRow(rows, pgx.RowTo[int64])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot create image rollout")
+	}
+	return db.GetImageRollout(ctx, rolloutId)
+}
+
+// GetImageRollout returns the entry in ipxe.image_rollouts for rolloutId and its nodes.
+func (db *DB) GetImageRollout(ctx context.Context, rolloutId int64) (*ipxe.ImageRollout, error) {
+	ctx, span := tracer.Start(ctx, "postgres.GetImageRollout")
+	defer span.End()
+	sql := `
+    SELECT` + imageRolloutColumns + `
+    FROM image_rollouts
+    WHERE rollout_id = $1
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, rolloutId)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var ir imageRollout
+	if err == nil {
+		ir, err = pgx.CollectOneRow(rows, pgx.RowToStructByPos[imageRollout])
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.NotFound("image_rollout_not_found", "image rollout not in database: %d", rolloutId)
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot get image rollout from database")
+	}
+
+	nodesSql := `
+    SELECT` + imageRolloutNodeColumns + `
+    FROM image_rollout_nodes
+    WHERE rollout_id = $1
+    ORDER BY wave, mac_address
+  `
+	rows, err = db.conn(ctx).Query(ctx, nodesSql, rolloutId)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var irns []imageRolloutNode
+	if err == nil {
+		irns, err = pgx.CollectRows(rows, pgx.RowToStructByPos[imageRolloutNode])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot get image rollout nodes from database")
+	}
+	rollout := ir.dto()
+	for i := range irns {
+		rollout.Nodes = append(rollout.Nodes, irns[i].dto())
+	}
+	return rollout, nil
+}
+
+// ListImageRollouts returns every rollout in rolloutState, or all rollouts when rolloutState is empty, without nodes.
+func (db *DB) ListImageRollouts(ctx context.Context, rolloutState string) ([]*ipxe.ImageRollout, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListImageRollouts")
+	defer span.End()
+	sql := `
+    SELECT` + imageRolloutColumns + `
+    FROM image_rollouts
+    WHERE
+        $1 = '' OR rollout_state = $1
+    ORDER BY rollout_id
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, rolloutState)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var irs []imageRollout
+	if err == nil {
+		irs, err = pgx.CollectRows(rows, pgx.RowToStructByPos[imageRollout])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list image rollouts from database")
+	}
+	rollouts := make([]*ipxe.ImageRollout, 0, len(irs))
+	for i := range irs {
+		rollouts = append(rollouts, irs[i].dto())
+	}
+	return rollouts, nil
+}
+
+// StartImageRolloutWave moves a rollout still in fromState to in_progress with the next wave and returns its nodes.
+// Waves without nodes, possible when there are fewer nodes than waves, return an empty list.
+func (db *DB) StartImageRolloutWave(ctx context.Context, rolloutId int64, fromState string) ([]*ipxe.ImageRolloutNode, error) {
+	ctx, span := tracer.Start(ctx, "postgres.StartImageRolloutWave")
+	defer span.End()
+	sql := `
+    WITH rollout AS (
+        UPDATE image_rollouts
+        SET
+            current_wave = current_wave + 1,
+            rollout_state = 'in_progress',
+            wave_started_at = current_timestamp,
+            modified_at = current_timestamp
+        WHERE
+            rollout_id = $1
+            AND
+            rollout_state = $2
+            AND
+            current_wave < cardinality(waves)
+        RETURNING rollout_id, current_wave
+    )
+    SELECT
+        rollout.rollout_id,` + imageRolloutNodeColumns + `
+    FROM rollout
+    LEFT JOIN image_rollout_nodes ON (
+      image_rollout_nodes.rollout_id = rollout.rollout_id
+    ) AND (
+      image_rollout_nodes.wave = rollout.current_wave
+    )
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, rolloutId, fromState)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var nodes []*ipxe.ImageRolloutNode
+	var found bool
+	if err == nil {
+		defer rows.Close()
+		for rows.Next() {
+			found = true
+			var (
+				macAddress, nodeState, previousImageTag, previousImageType, previousImageChannel, previousEnrollment *string
+				wave                                                                                                 *int32
+				updatedAt                                                                                            *time.Time
+				id                                                                                                   int64
+			)
+			if err = rows.Scan(&id, &macAddress, &wave, &nodeState, &updatedAt, &previousImageTag, &previousImageType, &previousImageChannel, &previousEnrollment); err != nil {
+				break
+			}
+			if macAddress == nil {
+				continue
+			}
+			nodes = append(nodes, &ipxe.ImageRolloutNode{
+				MacAddress:           *macAddress,
+				Wave:                 int(*wave),
+				NodeState:            *nodeState,
+				UpdatedAt:            updatedAt,
+				PreviousImageTag:     *previousImageTag,
+				PreviousImageType:    *previousImageType,
+				PreviousImageChannel: *previousImageChannel,
+				PreviousEnrollment:   *previousEnrollment,
+			})
+		}
+		if err == nil {
+			err = rows.Err()
+		}
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot start image rollout wave")
+	}
+	if !found {
+		return nil, errdefs.Conflict("image_rollout_state", "image rollout %d is not %s or has no wave left", rolloutId, fromState)
+	}
+	return nodes, nil
+}
+
+// SetImageRolloutNodeStates updates the node_state of the nodes of rolloutId in a single statement.
+// updated_at is set when a node moves to updated.
+func (db *DB) SetImageRolloutNodeStates(ctx context.Context, rolloutId int64, states map[string]string) error {
+	ctx, span := tracer.Start(ctx, "postgres.SetImageRolloutNodeStates")
+	defer span.End()
+	if len(states) == 0 {
+		return nil
+	}
+	const sql = `
+    UPDATE image_rollout_nodes
+    SET
+        node_state = states.node_state,
+        updated_at = CASE WHEN states.node_state = 'updated' THEN current_timestamp ELSE updated_at END
+    FROM unnest($2::macaddr[], $3::text[]) AS states (mac_address, node_state)
+    WHERE
+        image_rollout_nodes.rollout_id = $1
+        AND
+        image_rollout_nodes.mac_address = states.mac_address
+  `
+	macAddresses := make([]string, 0, len(states))
+	nodeStates := make([]string, 0, len(states))
+	for macAddress, state := range states {
+		macAddresses = append(macAddresses, macAddress)
+		nodeStates = append(nodeStates, state)
+	}
+	switch _, err := db.conn(ctx).Exec(ctx, sql, rolloutId, macAddresses, nodeStates); {
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return err
+	case err != nil:
+		return queryError(ctx, err, "cannot update image rollout nodes")
+	}
+	return nil
+}
+
+// SetImageRolloutState moves a rollout in one of fromStates to rolloutState.
+func (db *DB) SetImageRolloutState(ctx context.Context, rolloutId int64, fromStates []string, rolloutState string, haltReason string) (*ipxe.ImageRollout, error) {
+	ctx, span := tracer.Start(ctx, "postgres.SetImageRolloutState")
+	defer span.End()
+	sql := `
+    UPDATE image_rollouts
+    SET
+        rollout_state = $3,
+        halt_reason = $4,
+        modified_at = current_timestamp
+    WHERE
+        rollout_id = $1
+        AND
+        rollout_state = ANY($2)
+    RETURNING` + imageRolloutColumns
+	rows, err := db.conn(ctx).Query(ctx, sql, rolloutId, fromStates, rolloutState, haltReason)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var ir imageRollout
+	if err == nil {
+		ir, err = pgx.CollectOneRow(rows, pgx.RowToStructByPos[imageRollout])
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, errdefs.Conflict("image_rollout_state", "image rollout %d not in database or not in %v", rolloutId, fromStates)
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot update image rollout state")
+	}
+	return ir.dto(), nil
+}
@@ -0,0 +1,141 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/nodes"
+	"github.com/jackc/pgx/v5"
+)
+
+// Schemas of the single database mode, in search_path order.
+// Tables are named after their domain where names would overlap, like ipxe_mac_address_conflicts,
+// so unqualified queries work unchanged on the search_path.
+var Schemas = []string{"ipxe", "payloads", "nodes"}
+
+type nodeView struct {
+	MacAddress   string
+	Hostname     string
+	IpAddress    string
+	IpAddresses  []string
+	ImageTag     string
+	ImageType    string
+	ImageChannel string
+	PayloadIds   []string
+	LastSeen     *time.Time
+}
+
+func (nv *nodeView) dto() *nodes.NodeView {
+	return &nodes.NodeView{
+		MacAddress:   nv.MacAddress,
+		Hostname:     nv.Hostname,
+		IpAddress:    nv.IpAddress,
+		IpAddresses:  nv.IpAddresses,
+		ImageTag:     nv.ImageTag,
+		ImageType:    nv.ImageType,
+		ImageChannel: nv.ImageChannel,
+		PayloadIds:   nv.PayloadIds,
+		LastSeen:     nv.LastSeen,
+	}
+}
+
+// ListNodeViews joins ipxe.node_images, payloads.node_payloads and nodes.node_heartbeat in a single query.
+func (db *DB) ListNodeViews(ctx context.Context, macAddress string) ([]*nodes.NodeView, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListNodeViews")
+	defer span.End()
+	if !db.Single {
+		return nil, nodes.ErrSingleDatabaseRequired
+	}
+	const sql = `
+    WITH macs AS (
+        SELECT mac_address FROM ipxe.node_images
+        UNION
+        SELECT mac_address FROM payloads.node_payloads
+        UNION
+        SELECT mac_address FROM nodes.node_heartbeat
+    )
+    SELECT
+        macs.mac_address,
+        COALESCE(h.hostname, ''),
+        COALESCE(host(h.ip_address), ''),
+        ARRAY(
+          SELECT host(u.a)
+          FROM unnest(h.ip_addresses) WITH ORDINALITY AS u(a, i)
+          ORDER BY u.i
+        ),
+        COALESCE(i.image_tag, ''),
+        COALESCE(i.image_type, ''),
+        COALESCE(i.image_channel, ''),
+        ARRAY(
+          SELECT payload_id
+          FROM payloads.node_payloads p
+          WHERE p.mac_address = macs.mac_address
+          ORDER BY payload_id
+        ),
+        h.last_seen
+    FROM macs
+    LEFT JOIN ipxe.node_images i ON i.mac_address = macs.mac_address
+    LEFT JOIN nodes.node_heartbeat h ON h.mac_address = macs.mac_address
+    WHERE $1::text = '' OR macs.mac_address = NULLIF($1, '')::macaddr
+    ORDER BY macs.mac_address
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, macAddress)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var nvs []nodeView
+	if err == nil {
+		nvs, err = pgx.CollectRows(rows, pgx.RowToStructByPos[nodeView])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list node views from database")
+	}
+	views := make([]*nodes.NodeView, 0, len(nvs))
+	for i := range nvs {
+		views = append(views, nvs[i].dto())
+	}
+	return views, nil
+}
+
+// ListNodeHeartbeatViews returns the views of the nodes in node_heartbeat, for ListNodeViews outside the
+// single database mode.
+func (db *DB) ListNodeHeartbeatViews(ctx context.Context, macAddress string) ([]*nodes.NodeView, error) {
+	ctx, span := tracer.Start(ctx, "postgres.ListNodeHeartbeatViews")
+	defer span.End()
+	const sql = `
+    SELECT
+        mac_address,
+        hostname,
+        COALESCE(host(ip_address), ''),
+        ARRAY(
+          SELECT host(u.a)
+          FROM unnest(ip_addresses) WITH ORDINALITY AS u(a, i)
+          ORDER BY u.i
+        ),
+        '',
+        '',
+        '',
+        ARRAY[]::text[],
+        last_seen
+    FROM node_heartbeat
+    WHERE $1::text = '' OR mac_address = NULLIF($1, '')::macaddr
+    ORDER BY mac_address
+  `
+	rows, err := db.conn(ctx).Query(ctx, sql, macAddress)
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil, err
+	}
+	var nvs []nodeView
+	if err == nil {
+		nvs, err = pgx.CollectRows(rows, pgx.RowToStructByPos[nodeView])
+	}
+	if err != nil {
+		return nil, queryError(ctx, err, "cannot list node_heartbeat from database")
+	}
+	views := make([]*nodes.NodeView, 0, len(nvs))
+	for i := range nvs {
+		views = append(views, nvs[i].dto())
+	}
+	return views, nil
+}
@@ -0,0 +1,145 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/jackc/pgerrcode"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"go.uber.org/zap"
+)
+
+// retryBackoff is the delay before the first retry of a transaction, doubled on every further retry.
+const retryBackoff = 10 * time.Millisecond
+
+// retryable returns true for errors resolved by running the transaction again.
+func retryable(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) &&
+		(pgErr.Code == pgerrcode.SerializationFailure || pgErr.Code == pgerrcode.DeadlockDetected)
+}
+
+// WithTx runs fn in a transaction, committing when fn returns nil and rolling back otherwise.
+// Every DB method called with the ctx passed to fn uses the transaction.
+// If ctx already carries a transaction on the same pool, fn joins it, opts are ignored and the outermost WithTx
+// commits or retries. A transaction failing with a serialization failure or a deadlock is run again up to
+// opts.MaxRetries times.
+func (db *DB) WithTx(ctx context.Context, opts database.TxOptions, fn func(ctx context.Context) error) error {
+	ctx, span := tracer.Start(ctx, "postgres.WithTx")
+	defer span.End()
+	if tx, ok := ctx.Value(txCtx{db.Postgres}).(pgx.Tx); ok && tx != nil {
+		return fn(ctx)
+	}
+	backoff := retryBackoff
+	for attempt := 0; ; attempt++ {
+		retry, err := db.runTx(ctx, opts, fn)
+		if err == nil || !retry || attempt >= opts.MaxRetries {
+			return err
+		}
+		logging.FromContext(ctx).Warn("retrying transaction", zap.Duration("backoff", backoff), zap.Int("attempt", attempt+1), zap.Error(err))
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+	}
+}
+
+// runTx runs fn in a single transaction and returns whether it failed with a retryable error.
+func (db *DB) runTx(ctx context.Context, opts database.TxOptions, fn func(ctx context.Context) error) (bool, error) {
+	txOptions := pgx.TxOptions{IsoLevel: pgx.TxIsoLevel(opts.IsoLevel)}
+	var tx pgx.Tx
+	var err error
+	if c, ok := ctx.Value(connCtx{db.Postgres}).(*pgxpool.Conn); ok && c != nil {
+		tx, err = c.BeginTx(ctx, txOptions)
+	} else {
+		tx, err = db.Postgres.BeginTx(ctx, txOptions)
+	}
+	if err != nil {
+		return false, fmt.Errorf("cannot begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+	ttx := &trackedTx{Tx: tx}
+	if err := fn(context.WithValue(ctx, txCtx{db.Postgres}, pgx.Tx(ttx))); err != nil {
+		return ttx.retry || retryable(err), err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return retryable(err), fmt.Errorf("cannot commit transaction: %w", err)
+	}
+	return false, nil
+}
+
+// WithAcquire runs fn with a single connection acquired from the pool, released when fn returns.
+// Every DB method called with the ctx passed to fn uses the connection, WithTx begins its transactions on it.
+func (db *DB) WithAcquire(ctx context.Context, fn func(ctx context.Context) error) error {
+	ctx, span := tracer.Start(ctx, "postgres.WithAcquire")
+	defer span.End()
+	if tx, ok := ctx.Value(txCtx{db.Postgres}).(pgx.Tx); ok && tx != nil {
+		return fn(ctx)
+	}
+	if c, ok := ctx.Value(connCtx{db.Postgres}).(*pgxpool.Conn); ok && c != nil {
+		return fn(ctx)
+	}
+	c, err := db.Postgres.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("cannot acquire connection: %w", err)
+	}
+	defer c.Release()
+	return fn(context.WithValue(ctx, connCtx{db.Postgres}, c))
+}
+
+// trackedTx records statements failing with a retryable error.
+// Most DB methods replace database errors with their own message, so WithTx can't rely on the error returned by fn.
+type trackedTx struct {
+	pgx.Tx
+	retry bool
+}
+
+func (t *trackedTx) track(err error) error {
+	if retryable(err) {
+		t.retry = true
+	}
+	return err
+}
+
+func (t *trackedTx) Exec(ctx context.Context, sql string, arguments ...any) (pgconn.CommandTag, error) {
+	commandTag, err := t.Tx.Exec(ctx, sql, arguments...)
+	return commandTag, t.track(err)
+}
+
+func (t *trackedTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	rows, err := t.Tx.Query(ctx, sql, args...)
+	if rows == nil {
+		return nil, t.track(err)
+	}
+	return &trackedRows{Rows: rows, tx: t}, t.track(err)
+}
+
+func (t *trackedTx) QueryRow(ctx context.Context, sql string, args ...any) pgx.Row {
+	return &trackedRow{Row: t.Tx.QueryRow(ctx, sql, args...), tx: t}
+}
+
+type trackedRows struct {
+	pgx.Rows
+	tx *trackedTx
+}
+
+func (r *trackedRows) Err() error {
+	return r.tx.track(r.Rows.Err())
+}
+
+type trackedRow struct {
+	pgx.Row
+	tx *trackedTx
+}
+
+func (r *trackedRow) Scan(dest ...any) error {
+	return r.tx.track(r.Row.Scan(dest...))
+}
@@ -0,0 +1,174 @@
+// Package proxyproto reads the PROXY protocol header load balancers prepend to the connections they forward,
+// so the address of the client they accepted the connection from is seen instead of their own.
+//
+// Both the text v1 and binary v2 versions of https://www.haproxy.org/download/2.8/doc/proxy-protocol.txt are read.
+package proxyproto
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/netip"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// v2Signature starts v2 headers.
+var v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+
+// maxV1HeaderLength is the longest v1 header, "PROXY TCP6 " followed by two full IPv6 addresses and ports.
+const maxV1HeaderLength = 107
+
+// Listener accepts the connections of Listener, reading a PROXY protocol header from the ones of Trusted peers.
+// Connections of other peers are returned as is, their headers are not read so they can't pretend to be another client.
+type Listener struct {
+	net.Listener
+	// Trusted reports whether the peer at addr is a load balancer sending a header, which it must.
+	Trusted func(addr netip.Addr) bool
+	// HeaderTimeout bounds reading the header, no timeout when 0.
+	HeaderTimeout time.Duration
+}
+
+// Accept returns the next connection, with the client address of its header as RemoteAddr.
+// The header is read on the first Read or RemoteAddr call, not to block Accept on slow peers.
+func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+	if err != nil {
+		return nil, err
+	}
+	peer, err := netip.ParseAddrPort(c.RemoteAddr().String())
+	if err != nil || !l.Trusted(peer.Addr().Unmap()) {
+		return c, nil
+	}
+	return &conn{Conn: c, r: bufio.NewReader(c), timeout: l.HeaderTimeout}, nil
+}
+
+type conn struct {
+	net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+	once    sync.Once
+	remote  net.Addr
+	err     error
+}
+
+func (c *conn) readHeader() {
+	c.once.Do(func() {
+		if c.timeout > 0 {
+			c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+			defer c.Conn.SetReadDeadline(time.Time{})
+		}
+		c.remote, c.err = ReadHeader(c.r)
+		if c.err != nil {
+			c.err = fmt.Errorf("proxy protocol from %s: %w", c.Conn.RemoteAddr(), c.err)
+		}
+		if c.remote == nil {
+			c.remote = c.Conn.RemoteAddr()
+		}
+	})
+}
+
+func (c *conn) Read(b []byte) (int, error) {
+	c.readHeader()
+	if c.err != nil {
+		return 0, c.err
+	}
+	return c.r.Read(b)
+}
+
+func (c *conn) RemoteAddr() net.Addr {
+	c.readHeader()
+	return c.remote
+}
+
+// ReadHeader reads a v1 or v2 header from r and returns the source address it carries,
+// nil for the UNKNOWN and LOCAL headers of the load balancer's own connections, e.g. health checks.
+func ReadHeader(r *bufio.Reader) (net.Addr, error) {
+	b, err := r.Peek(len(v2Signature))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read header: %w", err)
+	}
+	switch {
+	case bytes.Equal(b, v2Signature):
+		return readV2(r)
+	case bytes.HasPrefix(b, []byte("PROXY ")):
+		return readV1(r)
+	}
+	return nil, errors.New("missing header")
+}
+
+func readV1(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+	for len(line) < maxV1HeaderLength {
+		c, err := r.ReadByte()
+		if err != nil {
+			return nil, fmt.Errorf("cannot read v1 header: %w", err)
+		}
+		line = append(line, c)
+		if c == '\n' {
+			break
+		}
+	}
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, errors.New("v1 header too long")
+	}
+	fields := strings.Split(strings.TrimSuffix(string(line), "\r\n"), " ")
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, fmt.Errorf("invalid v1 header: %q", line)
+	}
+	addr, err := netip.ParseAddr(fields[2])
+	if err != nil || addr.Is4() != (fields[1] == "TCP4") {
+		return nil, fmt.Errorf("invalid v1 source address: %q", fields[2])
+	}
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid v1 source port: %q", fields[4])
+	}
+	return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, uint16(port))), nil
+}
+
+func readV2(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, fmt.Errorf("cannot read v2 header: %w", err)
+	}
+	if header[12]>>4 != 2 {
+		return nil, fmt.Errorf("invalid v2 version: %d", header[12]>>4)
+	}
+	body := make([]byte, binary.BigEndian.Uint16(header[14:16]))
+	if _, err := io.ReadFull(r, body); err != nil {
+		return nil, fmt.Errorf("cannot read v2 addresses: %w", err)
+	}
+	switch command := header[12] & 0xf; command {
+	case 0x0: // LOCAL
+		return nil, nil
+	case 0x1: // PROXY
+	default:
+		return nil, fmt.Errorf("invalid v2 command: %d", command)
+	}
+	switch family := header[13]; family {
+	case 0x11, 0x12: // TCP or UDP over IPv4: source, destination, source port, destination port
+		if len(body) < 12 {
+			return nil, errors.New("v2 IPv4 addresses too short")
+		}
+		addr := netip.AddrFrom4([4]byte(body[0:4]))
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, binary.BigEndian.Uint16(body[8:10]))), nil
+	case 0x21, 0x22: // TCP or UDP over IPv6
+		if len(body) < 36 {
+			return nil, errors.New("v2 IPv6 addresses too short")
+		}
+		addr := netip.AddrFrom16([16]byte(body[0:16]))
+		return net.TCPAddrFromAddrPort(netip.AddrPortFrom(addr, binary.BigEndian.Uint16(body[32:34]))), nil
+	}
+	// UNSPEC or unix sockets carry no client address
+	return nil, nil
+}
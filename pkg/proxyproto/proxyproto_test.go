@@ -0,0 +1,79 @@
+package proxyproto
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadHeader(t *testing.T) {
+	v2 := func(command, family byte, body ...byte) string {
+		return string(v2Signature) + string([]byte{0x20 | command, family, 0, byte(len(body))}) + string(body)
+	}
+	tests := []struct {
+		name, header, addr string
+	}{
+		{"v1 tcp4", "PROXY TCP4 10.1.2.3 10.0.0.1 51234 8080\r\n", "10.1.2.3:51234"},
+		{"v1 tcp6", "PROXY TCP6 2001:db8::17 2001:db8::1 51234 8080\r\n", "[2001:db8::17]:51234"},
+		{"v1 unknown", "PROXY UNKNOWN\r\n", ""},
+		{"v2 tcp4", v2(1, 0x11, 10, 1, 2, 3, 10, 0, 0, 1, 0xc8, 0x22, 0x1f, 0x90), "10.1.2.3:51234"},
+		{"v2 tcp6", v2(1, 0x21, append(append(netip.MustParseAddr("2001:db8::17").AsSlice(), make([]byte, 16)...), 0xc8, 0x22, 0x1f, 0x90)...), "[2001:db8::17]:51234"},
+		{"v2 local", v2(0, 0x00), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := bufio.NewReader(strings.NewReader(tt.header + "GET / HTTP/1.1\r\n"))
+			addr, err := ReadHeader(r)
+			require.NoError(t, err)
+			if tt.addr == "" {
+				assert.Nil(t, addr)
+			} else {
+				assert.Equal(t, tt.addr, addr.String())
+			}
+			rest, _ := io.ReadAll(r)
+			assert.Equal(t, "GET / HTTP/1.1\r\n", string(rest), "the header is consumed")
+		})
+	}
+
+	for _, header := range []string{"GET / HTTP/1.1\r\n", "PROXY TCP4 10.1.2.3\r\n", "PROXY TCP4 2001:db8::17 10.0.0.1 51234 8080\r\n", "PROXY TCP4 10.1.2.3 10.0.0.1 51234 8080" + strings.Repeat(" ", 100)} {
+		_, err := ReadHeader(bufio.NewReader(strings.NewReader(header)))
+		assert.Error(t, err, header)
+	}
+}
+
+func TestListener(t *testing.T) {
+	inner, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer inner.Close()
+	trusted := true
+	l := &Listener{Listener: inner, Trusted: func(netip.Addr) bool { return trusted }}
+
+	for _, tt := range []struct {
+		trusted      bool
+		remote, body string
+	}{
+		{true, "10.1.2.3:51234", "hello"},
+		{false, "127.0.0.1", "PROXY TCP4 10.1.2.3 10.0.0.1 51234 8080\r\nhello"},
+	} {
+		trusted = tt.trusted
+		client, err := net.Dial("tcp", inner.Addr().String())
+		require.NoError(t, err)
+		_, err = client.Write([]byte("PROXY TCP4 10.1.2.3 10.0.0.1 51234 8080\r\nhello"))
+		require.NoError(t, err)
+		client.Close()
+
+		c, err := l.Accept()
+		require.NoError(t, err)
+		assert.Contains(t, c.RemoteAddr().String(), tt.remote)
+		body, err := io.ReadAll(c)
+		assert.NoError(t, err)
+		assert.Equal(t, tt.body, string(body), "untrusted peers' headers are not read")
+		c.Close()
+	}
+}
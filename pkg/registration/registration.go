@@ -0,0 +1,218 @@
+// Package registration records the nodes booting without an entry under enrollment.PolicyApprove,
+// and approves them into the fleet through the api or registration rules.
+package registration
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/coreweave/ncore-api/pkg/bulk"
+	"github.com/coreweave/ncore-api/pkg/database"
+	"github.com/coreweave/ncore-api/pkg/enrollment"
+	"github.com/coreweave/ncore-api/pkg/errdefs"
+	"github.com/coreweave/ncore-api/pkg/ipxe"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/nodes"
+	"github.com/coreweave/ncore-api/pkg/payloads"
+	"go.uber.org/zap"
+)
+
+// Approval assigns an approved node an image, a payload or both.
+// Unset ones are resolved from the subnet or api default on the node's next boot and pinned.
+type Approval struct {
+	ImageTag     string `json:"image_tag,omitempty"`
+	ImageType    string `json:"image_type,omitempty"`
+	ImageChannel string `json:"image_channel,omitempty"`
+	PayloadId    string `json:"payload_id,omitempty"`
+}
+
+func (a *Approval) assignment(macAddress string) *bulk.Assignment {
+	return &bulk.Assignment{
+		MacAddresses: []string{macAddress},
+		ImageTag:     a.ImageTag,
+		ImageType:    a.ImageType,
+		ImageChannel: a.ImageChannel,
+		PayloadId:    a.PayloadId,
+	}
+}
+
+func (a *Approval) empty() bool {
+	return a.ImageTag == "" && a.ImageType == "" && a.ImageChannel == "" && a.PayloadId == ""
+}
+
+// NewService creates a registration service on top of the ipxe, payloads and nodes services,
+// approving nodes with bulkSvc.
+func NewService(ipxeSvc *ipxe.Service, payloadsSvc *payloads.Service, nodesSvc *nodes.Service, bulkSvc *bulk.Service) *Service {
+	return &Service{
+		ipxe:     ipxeSvc,
+		payloads: payloadsSvc,
+		nodes:    nodesSvc,
+		bulk:     bulkSvc,
+	}
+}
+
+// Service for node registrations, an enrollment.Registrar.
+type Service struct {
+	ipxe     *ipxe.Service
+	payloads *payloads.Service
+	nodes    *nodes.Service
+	bulk     *bulk.Service
+}
+
+var _ enrollment.Registrar = (*Service)(nil)
+
+// ValidationError is returned when there is an invalid parameter received.
+type ValidationError struct {
+	s string
+}
+
+func (e ValidationError) Error() string {
+	return e.s
+}
+
+// Is makes ValidationError an errdefs.ErrInvalid error.
+func (e ValidationError) Is(target error) bool {
+	return target == errdefs.ErrInvalid
+}
+
+// RegisterNode records a boot of macAddress from ipAddress and returns the state of its registration.
+// New and pending nodes are approved by the first registration rule matching them, if any.
+func (s *Service) RegisterNode(ctx context.Context, macAddress string, ipAddress string, hints enrollment.Hints) (string, error) {
+	var state string
+	// The registration stays locked until the approval is done, so concurrent boot requests approve the node once.
+	err := s.nodes.WithTx(ctx, database.DefaultTxOptions, func(ctx context.Context) error {
+		r, err := s.nodes.RegisterNode(ctx, &nodes.Registration{MacAddress: macAddress, IpAddress: ipAddress, Hints: hints})
+		if err != nil {
+			return err
+		}
+		state = r.State
+		if r.State != enrollment.StatePending {
+			return nil
+		}
+		logger := logging.FromContext(ctx).With(zap.String("mac_address", macAddress), zap.String("ip_address", ipAddress))
+		rule, err := s.nodes.MatchRegistrationRule(ctx, macAddress, ipAddress)
+		if errdefs.IsNotFound(err) {
+			if r.FirstSeen.Equal(r.LastSeen) {
+				logger.Info("registered pending node", zap.Any("hints", hints))
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		logger.Info("approving node by registration rule", zap.Int64("rule_id", rule.RuleId))
+		approval := &Approval{
+			ImageTag:     rule.ImageTag,
+			ImageType:    rule.ImageType,
+			ImageChannel: rule.ImageChannel,
+			PayloadId:    rule.PayloadId,
+		}
+		if _, err := s.approve(ctx, macAddress, approval, &rule.RuleId); err != nil {
+			return err
+		}
+		state = enrollment.StateApproved
+		return nil
+	})
+	return state, err
+}
+
+// Approve approves the pending or rejected registration of macAddress with a.
+func (s *Service) Approve(ctx context.Context, macAddress string, a *Approval) (*nodes.Registration, error) {
+	var r *nodes.Registration
+	err := s.nodes.WithTx(ctx, database.DefaultTxOptions, func(ctx context.Context) error {
+		current, err := s.nodes.GetRegistration(ctx, macAddress)
+		if err != nil {
+			return err
+		}
+		if current.State == enrollment.StateApproved {
+			return errdefs.Conflict("registration_approved", "registration already approved for mac_address: %s", macAddress)
+		}
+		logging.FromContext(ctx).Info("approving node", zap.String("mac_address", macAddress), zap.Any("approval", a))
+		r, err = s.approve(ctx, macAddress, a, nil)
+		return err
+	})
+	return r, err
+}
+
+// approve assigns a to macAddress and marks its registration approved by ruleId.
+// The assignment commits before the registration, a failing registration commit leaves the node assigned:
+// it then boots its assignment and stays listed as pending. The same goes for a failed payload step of a
+// bulk.Assign without the single database mode, the node then boots its new image with its previous payload.
+func (s *Service) approve(ctx context.Context, macAddress string, a *Approval, ruleId *int64) (*nodes.Registration, error) {
+	if !a.empty() {
+		result, err := s.bulk.Assign(ctx, a.assignment(macAddress), false)
+		if err != nil {
+			return nil, err
+		}
+		if !result.Committed {
+			return nil, errdefs.Conflict("registration_assignment_failed", "cannot assign approved node %s: %s", macAddress, result.Steps[len(result.Steps)-1].Error)
+		}
+	}
+	return s.nodes.SetRegistrationState(ctx, macAddress, enrollment.StateApproved, ruleId)
+}
+
+// Reject rejects the pending registration of macAddress, which is then refused to boot until it is approved.
+func (s *Service) Reject(ctx context.Context, macAddress string) (*nodes.Registration, error) {
+	var r *nodes.Registration
+	err := s.nodes.WithTx(ctx, database.DefaultTxOptions, func(ctx context.Context) error {
+		current, err := s.nodes.GetRegistration(ctx, macAddress)
+		if err != nil {
+			return err
+		}
+		if current.State == enrollment.StateApproved {
+			return errdefs.Conflict("registration_approved", "registration already approved for mac_address: %s", macAddress)
+		}
+		logging.FromContext(ctx).Info("rejecting node", zap.String("mac_address", macAddress))
+		r, err = s.nodes.SetRegistrationState(ctx, macAddress, enrollment.StateRejected, nil)
+		return err
+	})
+	return r, err
+}
+
+// ListRegistrations returns the registrations in state, every registration when empty.
+func (s *Service) ListRegistrations(ctx context.Context, state string) ([]*nodes.Registration, error) {
+	return s.nodes.ListRegistrations(ctx, state)
+}
+
+// GetRegistration returns the registration of macAddress.
+func (s *Service) GetRegistration(ctx context.Context, macAddress string) (*nodes.Registration, error) {
+	return s.nodes.GetRegistration(ctx, macAddress)
+}
+
+// ListRules returns every registration rule ordered by RuleId, the order they are matched in.
+func (s *Service) ListRules(ctx context.Context) ([]*nodes.RegistrationRule, error) {
+	return s.nodes.ListRegistrationRules(ctx)
+}
+
+// CreateRule adds a registration rule after checking its image and payload exist.
+// Pending nodes are approved by it on their next boot.
+func (s *Service) CreateRule(ctx context.Context, rule *nodes.RegistrationRule) (*nodes.RegistrationRule, error) {
+	if rule.ImageTag != "" || rule.ImageType != "" || rule.ImageChannel != "" {
+		if err := s.ipxe.CheckImageTarget(ctx, rule.ImageTag, rule.ImageType, rule.ImageChannel); err != nil {
+			return nil, err
+		}
+	}
+	if rule.PayloadId != "" {
+		available := false
+		for _, payloadId := range s.payloads.GetAvailablePayloads(ctx) {
+			if payloadId == rule.PayloadId {
+				available = true
+				break
+			}
+		}
+		if !available {
+			return nil, ValidationError{fmt.Sprintf("PayloadId doesn't exist: %s", rule.PayloadId)}
+		}
+	}
+	created, err := s.nodes.CreateRegistrationRule(ctx, rule)
+	if err != nil {
+		return nil, err
+	}
+	logging.FromContext(ctx).Info("added registration rule", zap.Int64("rule_id", created.RuleId), zap.String("subnet", created.Subnet), zap.String("mac_oui", created.MacOui))
+	return created, nil
+}
+
+// DeleteRule deletes the registration rule ruleId.
+func (s *Service) DeleteRule(ctx context.Context, ruleId int64) (*nodes.RegistrationRule, error) {
+	return s.nodes.DeleteRegistrationRule(ctx, ruleId)
+}
@@ -2,92 +2,144 @@ package s3
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"log"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
-	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"go.uber.org/zap"
 )
 
 func NewClient(host string) *S3Svc {
-	sdkConfig, err := config.LoadDefaultConfig(context.TODO(), config.WithDefaultRegion("default"),
+	ctx := context.TODO()
+	sdkConfig, err := config.LoadDefaultConfig(ctx, config.WithDefaultRegion("default"),
 		config.WithEndpointResolverWithOptions(
 			aws.EndpointResolverWithOptionsFunc(
 				func(service, region string, options ...interface{}) (aws.Endpoint, error) {
 					return aws.Endpoint{URL: host}, nil
 				})))
 	if err != nil {
-		fmt.Println("Couldn't load default configuration. Ensure Env Var's are set for access and secret key.")
-		fmt.Println(err)
+		logging.FromContext(ctx).Error("cannot load default configuration, ensure the access and secret key variables are set", zap.Error(err))
 		return nil
 	}
 
 	s3Client := s3.NewFromConfig(sdkConfig)
-	return &S3Svc{Client: s3Client}
+	return &S3Svc{
+		Client:        s3Client,
+		PresignClient: s3.NewPresignClient(s3Client),
+	}
 }
 
+// S3Svc is the ObjectStore backed by an S3 compatible endpoint.
 type S3Svc struct {
-	Client *s3.Client
+	Client        *s3.Client
+	PresignClient *s3.PresignClient
 }
 
+var _ ObjectStore = (*S3Svc)(nil)
+
 func (svc *S3Svc) GetObject(
-	bucketName string, objectKey string) ([]byte, error) {
-	request, err := svc.Client.GetObject(context.TODO(), &s3.GetObjectInput{
+	ctx context.Context, bucketName string, objectKey string) ([]byte, error) {
+	request, err := svc.Client.GetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(bucketName),
 		Key:    aws.String(objectKey),
 	}, func(opts *s3.Options) {
 	})
 	if err != nil {
-		log.Printf("Couldn't get GetObjectOutput for %v:%v. Here's why: %v\n",
-			bucketName, objectKey, err)
-		return nil, err
+		logging.FromContext(ctx).Warn("cannot get object", zap.String("bucket", bucketName), zap.String("key", objectKey), zap.Error(err))
+		return nil, notFoundError(err)
 	}
 	defer request.Body.Close()
 	body, err := io.ReadAll(request.Body)
 	if err != nil {
-		log.Printf("Couldn't read object body from %v. Here's why: %v\n", objectKey, err)
+		logging.FromContext(ctx).Error("cannot read object body", zap.String("bucket", bucketName), zap.String("key", objectKey), zap.Error(err))
 		return nil, err
 	}
 	return body, err
 }
 
-func NewPresigner(s S3Svc) HttpPresigner {
-
-	preSignClient := s3.NewPresignClient(s.Client)
-
-	return &Presigner{preSignClient}
+// HeadBucket checks bucketName exists and the credentials can access it.
+func (svc *S3Svc) HeadBucket(ctx context.Context, bucketName string) error {
+	_, err := svc.Client.HeadBucket(ctx, &s3.HeadBucketInput{
+		Bucket: aws.String(bucketName),
+	})
+	return notFoundError(err)
 }
 
-//go:generate mockgen --build_flags=--mod=mod -package s3 -destination mock_s3_test.go . HttpPresigner
-type HttpPresigner interface {
-	GetObject(bucketName string, objectKey string, lifetimeSecs int64) (*v4.PresignedHTTPRequest, error)
+// HeadObject returns the ObjectInfo for objectKey without reading its body.
+func (svc *S3Svc) HeadObject(
+	ctx context.Context, bucketName string, objectKey string) (*ObjectInfo, error) {
+	head, err := svc.Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(objectKey),
+	})
+	if err != nil {
+		return nil, notFoundError(err)
+	}
+	return &ObjectInfo{
+		Key:          objectKey,
+		Size:         head.ContentLength,
+		LastModified: aws.ToTime(head.LastModified),
+	}, nil
 }
 
-// Presigner To request a presigned object from simply request GetObject
-//
-//	request, err := myPresigner.GetObject(bucket,key,3600)
-//	httpRequest := http.NewRequest(request.URL, "GET", nil)
-type Presigner struct {
-	PresignClient *s3.PresignClient
+// ListObjects returns every object in bucketName whose key starts with prefix.
+func (svc *S3Svc) ListObjects(
+	ctx context.Context, bucketName string, prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	paginator := s3.NewListObjectsV2Paginator(svc.Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+		Prefix: aws.String(prefix),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			logging.FromContext(ctx).Warn("cannot list objects", zap.String("bucket", bucketName), zap.String("prefix", prefix), zap.Error(err))
+			return nil, notFoundError(err)
+		}
+		for _, o := range page.Contents {
+			objects = append(objects, ObjectInfo{
+				Key:          aws.ToString(o.Key),
+				Size:         o.Size,
+				LastModified: aws.ToTime(o.LastModified),
+			})
+		}
+	}
+	return objects, nil
 }
 
-// GetObject makes a presigned request that can be used to get an object from a bucket.
+// PresignGetObject makes a presigned request that can be used to get an object from a bucket.
 // The presigned request is valid for the specified number of seconds.
-func (presigner Presigner) GetObject(
-	bucketName string, objectKey string, lifetimeSecs int64) (*v4.PresignedHTTPRequest, error) {
-	request, err := presigner.PresignClient.PresignGetObject(context.TODO(), &s3.GetObjectInput{
+func (svc *S3Svc) PresignGetObject(
+	ctx context.Context, bucketName string, objectKey string, lifetimeSecs int64) (string, error) {
+	request, err := svc.PresignClient.PresignGetObject(ctx, &s3.GetObjectInput{
 		Bucket: aws.String(bucketName),
 		Key:    aws.String(objectKey),
 	}, func(opts *s3.PresignOptions) {
 		opts.Expires = time.Duration(lifetimeSecs * int64(time.Second))
 	})
 	if err != nil {
-		log.Printf("Couldn't get a presigned request to get %v:%v. Here's why: %v\n",
-			bucketName, objectKey, err)
+		logging.FromContext(ctx).Error("cannot presign object", zap.String("bucket", bucketName), zap.String("key", objectKey), zap.Error(err))
+		return "", err
+	}
+	return request.URL, nil
+}
+
+// notFoundError wraps S3 missing key and missing bucket errors with ErrObjectNotFound.
+func notFoundError(err error) error {
+	if err == nil {
+		return nil
+	}
+	var noSuchKey *types.NoSuchKey
+	var noSuchBucket *types.NoSuchBucket
+	var notFound *types.NotFound
+	if errors.As(err, &noSuchKey) || errors.As(err, &noSuchBucket) || errors.As(err, &notFound) {
+		return fmt.Errorf("%w: %v", ErrObjectNotFound, err)
 	}
-	return request, err
+	return err
 }
@@ -1,19 +1,21 @@
 package s3
 
 import (
-	"github.com/stretchr/testify/assert"
+	"context"
 	"os"
 	"testing"
+
+	"github.com/stretchr/testify/assert"
 )
 
-func TestPresigner_GetObject(t *testing.T) {
+func TestS3Svc_PresignGetObject(t *testing.T) {
 
 	os.Setenv("AWS_ACCESS_KEY_ID", "my-test-key")
 	os.Setenv("AWS_SECRET_ACCESS_KEY", "my-secret-value")
 	os.Setenv("AWS_REGION", "default")
-	presigner := NewPresigner("https://object.ord1.coreweave.com")
-	request, err := presigner.GetObject("ncore-images", "img-2202.iso", 900)
+	svc := NewClient("https://object.ord1.coreweave.com")
+	url, err := svc.PresignGetObject(context.Background(), "ncore-images", "img-2202.iso", 900)
 
 	assert.NoError(t, err)
-	t.Logf("request: %s", request.URL)
+	t.Logf("request: %s", url)
 }
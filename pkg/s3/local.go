@@ -0,0 +1,166 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// LocalStore is an ObjectStore backed by a local directory.
+// Buckets are the top level directories of root and object keys are paths below them:
+//
+//	<root>/<bucket>/<image>/vmlinuz
+//
+// Presigned urls point to baseURL/<bucket>/<key>, so root has to be served over http at baseURL
+// for nodes to be able to download images. This is meant for air-gapped hosts without S3.
+type LocalStore struct {
+	root    string
+	baseURL string
+}
+
+var _ ObjectStore = (*LocalStore)(nil)
+
+// NewLocalStore returns a LocalStore for root served at the http or https baseURL,
+// iPXE can't download images from file:// urls.
+func NewLocalStore(root string, baseURL string) (*LocalStore, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, fmt.Errorf("local object store url must be an http or https url: %q", baseURL)
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(abs)
+	if err != nil {
+		return nil, err
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("local object store root is not a directory: %s", abs)
+	}
+	return &LocalStore{
+		root:    abs,
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+	}, nil
+}
+
+// bucketPath returns the directory of bucketName.
+func (ls *LocalStore) bucketPath(bucketName string) (string, error) {
+	if bucketName == "" || strings.ContainsAny(bucketName, `/\`) || bucketName == "." || bucketName == ".." {
+		return "", fmt.Errorf("invalid bucket name: %q", bucketName)
+	}
+	return filepath.Join(ls.root, bucketName), nil
+}
+
+// objectPath returns the path on disk of objectKey, refusing keys that escape the bucket.
+func (ls *LocalStore) objectPath(bucketName string, objectKey string) (string, error) {
+	bucketPath, err := ls.bucketPath(bucketName)
+	if err != nil {
+		return "", err
+	}
+	clean := path.Clean("/" + objectKey)
+	if clean == "/" {
+		return "", fmt.Errorf("invalid object key: %q", objectKey)
+	}
+	return filepath.Join(bucketPath, filepath.FromSlash(clean)), nil
+}
+
+func (ls *LocalStore) GetObject(ctx context.Context, bucketName string, objectKey string) ([]byte, error) {
+	p, err := ls.objectPath(bucketName, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	body, err := os.ReadFile(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s/%s", ErrObjectNotFound, bucketName, objectKey)
+	}
+	return body, err
+}
+
+func (ls *LocalStore) HeadBucket(ctx context.Context, bucketName string) error {
+	p, err := ls.bucketPath(bucketName)
+	if err != nil {
+		return err
+	}
+	info, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && !info.IsDir()) {
+		return fmt.Errorf("%w: %s", ErrObjectNotFound, bucketName)
+	}
+	return err
+}
+
+func (ls *LocalStore) HeadObject(ctx context.Context, bucketName string, objectKey string) (*ObjectInfo, error) {
+	p, err := ls.objectPath(bucketName, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	info, err := os.Stat(p)
+	if errors.Is(err, fs.ErrNotExist) || (err == nil && info.IsDir()) {
+		return nil, fmt.Errorf("%w: %s/%s", ErrObjectNotFound, bucketName, objectKey)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectInfo{
+		Key:          objectKey,
+		Size:         info.Size(),
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+func (ls *LocalStore) ListObjects(ctx context.Context, bucketName string, prefix string) ([]ObjectInfo, error) {
+	bucketPath, err := ls.bucketPath(bucketName)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(bucketPath); errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, bucketName)
+	}
+	var objects []ObjectInfo
+	err = filepath.WalkDir(bucketPath, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(bucketPath, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, ObjectInfo{
+			Key:          key,
+			Size:         info.Size(),
+			LastModified: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// PresignGetObject returns the url objectKey is served at. Local objects don't expire, lifetimeSecs is ignored.
+func (ls *LocalStore) PresignGetObject(ctx context.Context, bucketName string, objectKey string, lifetimeSecs int64) (string, error) {
+	if _, err := ls.objectPath(bucketName, objectKey); err != nil {
+		return "", err
+	}
+	return ls.baseURL + (&url.URL{Path: "/" + bucketName + path.Clean("/"+objectKey)}).EscapedPath(), nil
+}
@@ -0,0 +1,45 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLocalStore(t *testing.T) {
+	root := t.TempDir()
+	assert.NoError(t, os.MkdirAll(filepath.Join(root, "ncore-images", "ncore-develop-ci-test"), 0o755))
+	assert.NoError(t, os.WriteFile(filepath.Join(root, "ncore-images", "ncore-develop-ci-test", "cmdline"), []byte("ro"), 0o644))
+
+	for _, baseURL := range []string{"", "/srv/ncore-images", "file:///srv/ncore-images", "http://"} {
+		_, err := NewLocalStore(root, baseURL)
+		assert.Error(t, err, baseURL)
+	}
+
+	store, err := NewLocalStore(root, "http://10.0.0.1:8081/")
+	assert.NoError(t, err)
+	ctx := context.Background()
+
+	body, err := store.GetObject(ctx, "ncore-images", "ncore-develop-ci-test/cmdline")
+	assert.NoError(t, err)
+	assert.Equal(t, "ro", string(body))
+
+	_, err = store.HeadObject(ctx, "ncore-images", "ncore-develop-ci-test/vmlinuz")
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+
+	_, err = store.GetObject(ctx, "ncore-images", "../../etc/passwd")
+	assert.True(t, errors.Is(err, ErrObjectNotFound))
+
+	objects, err := store.ListObjects(ctx, "ncore-images", "ncore-develop")
+	assert.NoError(t, err)
+	assert.Len(t, objects, 1)
+	assert.Equal(t, "ncore-develop-ci-test/cmdline", objects[0].Key)
+
+	url, err := store.PresignGetObject(ctx, "ncore-images", "ncore-develop-ci-test/vmlinuz", 900)
+	assert.NoError(t, err)
+	assert.Equal(t, "http://10.0.0.1:8081/ncore-images/ncore-develop-ci-test/vmlinuz", url)
+}
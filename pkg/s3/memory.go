@@ -0,0 +1,114 @@
+package s3
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore is an in-memory ObjectStore, mainly used by tests.
+// Presigned urls are memory://<bucket>/<key> unless a baseURL is given.
+type MemoryStore struct {
+	mu      sync.RWMutex
+	baseURL string
+	buckets map[string]map[string]memoryObject
+}
+
+type memoryObject struct {
+	body         []byte
+	lastModified time.Time
+}
+
+var _ ObjectStore = (*MemoryStore)(nil)
+
+// NewMemoryStore returns an empty MemoryStore.
+func NewMemoryStore(baseURL string) *MemoryStore {
+	if baseURL == "" {
+		baseURL = "memory://"
+	}
+	return &MemoryStore{
+		baseURL: strings.TrimSuffix(baseURL, "/"),
+		buckets: map[string]map[string]memoryObject{},
+	}
+}
+
+// PutObject stores body as objectKey in bucketName, creating the bucket if needed.
+func (ms *MemoryStore) PutObject(bucketName string, objectKey string, body []byte) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+	if ms.buckets[bucketName] == nil {
+		ms.buckets[bucketName] = map[string]memoryObject{}
+	}
+	ms.buckets[bucketName][objectKey] = memoryObject{
+		body:         append([]byte(nil), body...),
+		lastModified: time.Now(),
+	}
+}
+
+func (ms *MemoryStore) object(bucketName string, objectKey string) (memoryObject, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	o, ok := ms.buckets[bucketName][objectKey]
+	if !ok {
+		return memoryObject{}, fmt.Errorf("%w: %s/%s", ErrObjectNotFound, bucketName, objectKey)
+	}
+	return o, nil
+}
+
+func (ms *MemoryStore) GetObject(ctx context.Context, bucketName string, objectKey string) ([]byte, error) {
+	o, err := ms.object(bucketName, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(nil), o.body...), nil
+}
+
+func (ms *MemoryStore) HeadBucket(ctx context.Context, bucketName string) error {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	if _, ok := ms.buckets[bucketName]; !ok {
+		return fmt.Errorf("%w: %s", ErrObjectNotFound, bucketName)
+	}
+	return nil
+}
+
+func (ms *MemoryStore) HeadObject(ctx context.Context, bucketName string, objectKey string) (*ObjectInfo, error) {
+	o, err := ms.object(bucketName, objectKey)
+	if err != nil {
+		return nil, err
+	}
+	return &ObjectInfo{
+		Key:          objectKey,
+		Size:         int64(len(o.body)),
+		LastModified: o.lastModified,
+	}, nil
+}
+
+func (ms *MemoryStore) ListObjects(ctx context.Context, bucketName string, prefix string) ([]ObjectInfo, error) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+	bucket, ok := ms.buckets[bucketName]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrObjectNotFound, bucketName)
+	}
+	var objects []ObjectInfo
+	for key, o := range bucket {
+		if strings.HasPrefix(key, prefix) {
+			objects = append(objects, ObjectInfo{
+				Key:          key,
+				Size:         int64(len(o.body)),
+				LastModified: o.lastModified,
+			})
+		}
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+// PresignGetObject returns baseURL/<bucket>/<key>. The object doesn't have to exist.
+func (ms *MemoryStore) PresignGetObject(ctx context.Context, bucketName string, objectKey string, lifetimeSecs int64) (string, error) {
+	return fmt.Sprintf("%s/%s/%s?expires=%d", ms.baseURL, bucketName, objectKey, lifetimeSecs), nil
+}
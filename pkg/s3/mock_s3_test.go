@@ -1,50 +1,109 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/coreweave/ncore-api/pkg/s3 (interfaces: HttpPresigner)
+// Source: github.com/coreweave/ncore-api/pkg/s3 (interfaces: ObjectStore)
 
 // Package s3 is a generated GoMock package.
 package s3
 
 import (
+	context "context"
 	reflect "reflect"
 
-	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
 	gomock "github.com/golang/mock/gomock"
 )
 
-// MockHttpPresigner is a mock of HttpPresigner interface.
-type MockHttpPresigner struct {
+// MockObjectStore is a mock of ObjectStore interface.
+type MockObjectStore struct {
 	ctrl     *gomock.Controller
-	recorder *MockHttpPresignerMockRecorder
+	recorder *MockObjectStoreMockRecorder
 }
 
-// MockHttpPresignerMockRecorder is the mock recorder for MockHttpPresigner.
-type MockHttpPresignerMockRecorder struct {
-	mock *MockHttpPresigner
+// MockObjectStoreMockRecorder is the mock recorder for MockObjectStore.
+type MockObjectStoreMockRecorder struct {
+	mock *MockObjectStore
 }
 
-// NewMockHttpPresigner creates a new mock instance.
-func NewMockHttpPresigner(ctrl *gomock.Controller) *MockHttpPresigner {
-	mock := &MockHttpPresigner{ctrl: ctrl}
-	mock.recorder = &MockHttpPresignerMockRecorder{mock}
+// NewMockObjectStore creates a new mock instance.
+func NewMockObjectStore(ctrl *gomock.Controller) *MockObjectStore {
+	mock := &MockObjectStore{ctrl: ctrl}
+	mock.recorder = &MockObjectStoreMockRecorder{mock}
 	return mock
 }
 
 // EXPECT returns an object that allows the caller to indicate expected use.
-func (m *MockHttpPresigner) EXPECT() *MockHttpPresignerMockRecorder {
+func (m *MockObjectStore) EXPECT() *MockObjectStoreMockRecorder {
 	return m.recorder
 }
 
 // GetObject mocks base method.
-func (m *MockHttpPresigner) GetObject(arg0, arg1 string, arg2 int64) (*v4.PresignedHTTPRequest, error) {
+func (m *MockObjectStore) GetObject(arg0 context.Context, arg1, arg2 string) ([]byte, error) {
 	m.ctrl.T.Helper()
 	ret := m.ctrl.Call(m, "GetObject", arg0, arg1, arg2)
-	ret0, _ := ret[0].(*v4.PresignedHTTPRequest)
+	ret0, _ := ret[0].([]byte)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // GetObject indicates an expected call of GetObject.
-func (mr *MockHttpPresignerMockRecorder) GetObject(arg0, arg1, arg2 interface{}) *gomock.Call {
+func (mr *MockObjectStoreMockRecorder) GetObject(arg0, arg1, arg2 interface{}) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetObject", reflect.TypeOf((*MockHttpPresigner)(nil).GetObject), arg0, arg1, arg2)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetObject", reflect.TypeOf((*MockObjectStore)(nil).GetObject), arg0, arg1, arg2)
+}
+
+// HeadBucket mocks base method.
+func (m *MockObjectStore) HeadBucket(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HeadBucket", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// HeadBucket indicates an expected call of HeadBucket.
+func (mr *MockObjectStoreMockRecorder) HeadBucket(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HeadBucket", reflect.TypeOf((*MockObjectStore)(nil).HeadBucket), arg0, arg1)
+}
+
+// HeadObject mocks base method.
+func (m *MockObjectStore) HeadObject(arg0 context.Context, arg1, arg2 string) (*ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "HeadObject", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*ObjectInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// HeadObject indicates an expected call of HeadObject.
+func (mr *MockObjectStoreMockRecorder) HeadObject(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "HeadObject", reflect.TypeOf((*MockObjectStore)(nil).HeadObject), arg0, arg1, arg2)
+}
+
+// ListObjects mocks base method.
+func (m *MockObjectStore) ListObjects(arg0 context.Context, arg1, arg2 string) ([]ObjectInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListObjects", arg0, arg1, arg2)
+	ret0, _ := ret[0].([]ObjectInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListObjects indicates an expected call of ListObjects.
+func (mr *MockObjectStoreMockRecorder) ListObjects(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListObjects", reflect.TypeOf((*MockObjectStore)(nil).ListObjects), arg0, arg1, arg2)
+}
+
+// PresignGetObject mocks base method.
+func (m *MockObjectStore) PresignGetObject(arg0 context.Context, arg1, arg2 string, arg3 int64) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "PresignGetObject", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// PresignGetObject indicates an expected call of PresignGetObject.
+func (mr *MockObjectStoreMockRecorder) PresignGetObject(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "PresignGetObject", reflect.TypeOf((*MockObjectStore)(nil).PresignGetObject), arg0, arg1, arg2, arg3)
 }
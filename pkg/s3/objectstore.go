@@ -0,0 +1,39 @@
+package s3
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrObjectNotFound is returned when a bucket or object key doesn't exist in an ObjectStore.
+var ErrObjectNotFound = errors.New("object not found")
+
+// ObjectInfo describes an object stored in an ObjectStore.
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	LastModified time.Time
+}
+
+// ObjectStore is the object storage used to read image files and hand out download urls for them.
+// S3Svc talks to an S3 compatible endpoint, LocalStore serves a directory on disk
+// and MemoryStore keeps everything in memory for tests.
+//
+//go:generate mockgen --build_flags=--mod=mod -package s3 -destination mock_s3_test.go . ObjectStore
+type ObjectStore interface {
+	// GetObject returns the contents of objectKey in bucketName.
+	GetObject(ctx context.Context, bucketName string, objectKey string) ([]byte, error)
+
+	// HeadBucket returns an ErrObjectNotFound error unless bucketName exists and is accessible.
+	HeadBucket(ctx context.Context, bucketName string) error
+
+	// HeadObject returns the ObjectInfo for objectKey in bucketName.
+	HeadObject(ctx context.Context, bucketName string, objectKey string) (*ObjectInfo, error)
+
+	// ListObjects returns every object in bucketName whose key starts with prefix.
+	ListObjects(ctx context.Context, bucketName string, prefix string) ([]ObjectInfo, error)
+
+	// PresignGetObject returns a url that can be used to download objectKey for lifetimeSecs.
+	PresignGetObject(ctx context.Context, bucketName string, objectKey string, lifetimeSecs int64) (string, error)
+}
@@ -0,0 +1,181 @@
+// Package snapshot keeps a periodically refreshed copy of database state in memory, and optionally on disk,
+// so boot requests can still be answered when the database is unavailable.
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/coreweave/ncore-api/pkg/logging"
+	"github.com/coreweave/ncore-api/pkg/metrics"
+	"go.uber.org/zap"
+)
+
+// Snapshot is the data loaded by a Store and the time it was taken.
+type Snapshot[T any] struct {
+	TakenAt time.Time
+	Data    T
+}
+
+// Store holds the last snapshot loaded successfully. A nil Store holds no snapshot.
+type Store[T any] struct {
+	name    string
+	path    string
+	load    func(ctx context.Context) (T, error)
+	current atomic.Pointer[Snapshot[T]]
+}
+
+// NewStore creates a Store named name refreshed by load.
+// Snapshots are also written to path, and read back by ReadFile, unless path is empty.
+func NewStore[T any](name string, path string, load func(ctx context.Context) (T, error)) *Store[T] {
+	return &Store[T]{
+		name: name,
+		path: path,
+		load: load,
+	}
+}
+
+// Name returns the name of the snapshot, used in logs and metrics.
+func (s *Store[T]) Name() string {
+	return s.name
+}
+
+// Get returns the current snapshot, nil until one is loaded.
+func (s *Store[T]) Get() *Snapshot[T] {
+	if s == nil {
+		return nil
+	}
+	return s.current.Load()
+}
+
+func (s *Store[T]) set(snap *Snapshot[T]) {
+	s.current.Store(snap)
+	metrics.SnapshotTimestamp.WithLabelValues(s.name).Set(float64(snap.TakenAt.Unix()))
+}
+
+// ReadFile loads the snapshot written to path by a previous process, so it can answer requests
+// before the database is reachable. A missing file is not an error.
+func (s *Store[T]) ReadFile() error {
+	if s.path == "" {
+		return nil
+	}
+	b, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var snap Snapshot[T]
+	if err := json.Unmarshal(b, &snap); err != nil {
+		return fmt.Errorf("cannot decode snapshot %s: %w", s.path, err)
+	}
+	// Keep a snapshot loaded from the database in the meantime.
+	if s.current.CompareAndSwap(nil, &snap) {
+		metrics.SnapshotTimestamp.WithLabelValues(s.name).Set(float64(snap.TakenAt.Unix()))
+	}
+	return nil
+}
+
+// Refresh loads a new snapshot and writes it to path. The previous snapshot is kept when load fails.
+func (s *Store[T]) Refresh(ctx context.Context) error {
+	takenAt := time.Now()
+	data, err := s.load(ctx)
+	if err != nil {
+		metrics.SnapshotRefreshErrors.WithLabelValues(s.name).Inc()
+		return err
+	}
+	snap := &Snapshot[T]{TakenAt: takenAt, Data: data}
+	s.set(snap)
+	if s.path == "" {
+		return nil
+	}
+	return writeFile(s.path, snap)
+}
+
+// writeFile replaces path atomically so a crash never leaves a truncated snapshot.
+func writeFile(path string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*")
+	if err != nil {
+		return fmt.Errorf("cannot write snapshot %s: %w", path, err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(b); err != nil {
+		f.Close()
+		return fmt.Errorf("cannot write snapshot %s: %w", path, err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("cannot write snapshot %s: %w", path, err)
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("cannot write snapshot %s: %w", path, err)
+	}
+	return nil
+}
+
+// Run refreshes the snapshot every interval until ctx is done.
+func (s *Store[T]) Run(ctx context.Context, interval time.Duration) {
+	logger := logging.FromContext(ctx).With(zap.String("snapshot", s.name))
+	logger.Info("starting snapshot refresh", zap.Duration("interval", interval), zap.String("path", s.path))
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if err := s.Refresh(ctx); err != nil {
+			logger.Error("cannot refresh snapshot", zap.Error(err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+type staleKey struct{}
+
+// staleness records the oldest snapshot a request was answered from.
+type staleness struct {
+	mu      sync.Mutex
+	takenAt time.Time
+}
+
+// WithStaleness returns a context in which MarkStale records that a snapshot was used, see Stale.
+func WithStaleness(ctx context.Context) context.Context {
+	return context.WithValue(ctx, staleKey{}, &staleness{})
+}
+
+// MarkStale records that ctx's request was answered from snap instead of the database.
+func MarkStale[T any](ctx context.Context, s *Store[T], snap *Snapshot[T]) {
+	metrics.StaleResponses.WithLabelValues(s.name).Inc()
+	logging.FromContext(ctx).Warn("database unavailable, using snapshot", zap.String("snapshot", s.name), zap.Time("taken_at", snap.TakenAt))
+	st, ok := ctx.Value(staleKey{}).(*staleness)
+	if !ok {
+		return
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	if st.takenAt.IsZero() || snap.TakenAt.Before(st.takenAt) {
+		st.takenAt = snap.TakenAt
+	}
+}
+
+// Stale returns the time the oldest snapshot used for ctx's request was taken, false when none was used.
+func Stale(ctx context.Context) (time.Time, bool) {
+	st, ok := ctx.Value(staleKey{}).(*staleness)
+	if !ok {
+		return time.Time{}, false
+	}
+	st.mu.Lock()
+	defer st.mu.Unlock()
+	return st.takenAt, !st.takenAt.IsZero()
+}
@@ -0,0 +1,67 @@
+package snapshot
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestStore_Refresh_keepsPreviousOnError(t *testing.T) {
+	var err error
+	store := NewStore("test", "", func(ctx context.Context) ([]string, error) {
+		return []string{"a"}, err
+	})
+	require.NoError(t, store.Refresh(context.Background()))
+
+	err = errors.New("database unavailable")
+	assert.Error(t, store.Refresh(context.Background()))
+
+	assert.Equal(t, []string{"a"}, store.Get().Data)
+}
+
+func TestStore_ReadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.json")
+	written := NewStore("test", path, func(ctx context.Context) ([]string, error) {
+		return []string{"a", "b"}, nil
+	})
+	require.NoError(t, written.Refresh(context.Background()))
+
+	read := NewStore("test", path, func(ctx context.Context) ([]string, error) {
+		return nil, errors.New("database unavailable")
+	})
+	require.NoError(t, read.ReadFile())
+
+	assert.Equal(t, []string{"a", "b"}, read.Get().Data)
+	assert.True(t, written.Get().TakenAt.Equal(read.Get().TakenAt))
+}
+
+func TestStore_ReadFile_missing(t *testing.T) {
+	store := NewStore("test", filepath.Join(t.TempDir(), "test.json"), func(ctx context.Context) ([]string, error) {
+		return nil, nil
+	})
+
+	assert.NoError(t, store.ReadFile())
+	assert.Nil(t, store.Get())
+}
+
+func TestMarkStale(t *testing.T) {
+	store := NewStore("test", "", func(ctx context.Context) (string, error) { return "", nil })
+	older := &Snapshot[string]{TakenAt: time.Unix(100, 0)}
+	newer := &Snapshot[string]{TakenAt: time.Unix(200, 0)}
+	ctx := WithStaleness(context.Background())
+
+	_, stale := Stale(ctx)
+	assert.False(t, stale)
+
+	MarkStale(ctx, store, newer)
+	MarkStale(ctx, store, older)
+
+	takenAt, stale := Stale(ctx)
+	assert.True(t, stale)
+	assert.Equal(t, older.TakenAt, takenAt)
+}
@@ -0,0 +1,81 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+
+	"github.com/coreweave/ncore-api/pkg/s3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("github.com/coreweave/ncore-api/pkg/tracing")
+
+// objectStore starts a span for each call to an s3.ObjectStore.
+type objectStore struct {
+	backend string
+	next    s3.ObjectStore
+}
+
+// InstrumentObjectStore returns store starting an s3.<Operation> span per call,
+// with backend, e.g. s3 or local, bucket and key as attributes.
+func InstrumentObjectStore(backend string, store s3.ObjectStore) s3.ObjectStore {
+	return &objectStore{backend: backend, next: store}
+}
+
+func (o *objectStore) start(ctx context.Context, operation string, bucketName string, key string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "s3."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("ncore.object_store.backend", o.backend),
+			attribute.String("aws.s3.bucket", bucketName),
+			attribute.String("aws.s3.key", key),
+		))
+}
+
+// end records err on span unless the object wasn't found, an expected outcome of HeadObject.
+func end(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, s3.ErrObjectNotFound) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (o *objectStore) GetObject(ctx context.Context, bucketName string, objectKey string) ([]byte, error) {
+	ctx, span := o.start(ctx, "GetObject", bucketName, objectKey)
+	body, err := o.next.GetObject(ctx, bucketName, objectKey)
+	end(span, err)
+	return body, err
+}
+
+func (o *objectStore) HeadBucket(ctx context.Context, bucketName string) error {
+	ctx, span := o.start(ctx, "HeadBucket", bucketName, "")
+	err := o.next.HeadBucket(ctx, bucketName)
+	end(span, err)
+	return err
+}
+
+func (o *objectStore) HeadObject(ctx context.Context, bucketName string, objectKey string) (*s3.ObjectInfo, error) {
+	ctx, span := o.start(ctx, "HeadObject", bucketName, objectKey)
+	info, err := o.next.HeadObject(ctx, bucketName, objectKey)
+	end(span, err)
+	return info, err
+}
+
+func (o *objectStore) ListObjects(ctx context.Context, bucketName string, prefix string) ([]s3.ObjectInfo, error) {
+	ctx, span := o.start(ctx, "ListObjects", bucketName, prefix)
+	objects, err := o.next.ListObjects(ctx, bucketName, prefix)
+	span.SetAttributes(attribute.Int("ncore.object_store.objects", len(objects)))
+	end(span, err)
+	return objects, err
+}
+
+func (o *objectStore) PresignGetObject(ctx context.Context, bucketName string, objectKey string, lifetimeSecs int64) (string, error) {
+	ctx, span := o.start(ctx, "PresignGetObject", bucketName, objectKey)
+	url, err := o.next.PresignGetObject(ctx, bucketName, objectKey, lifetimeSecs)
+	end(span, err)
+	return url, err
+}
@@ -0,0 +1,73 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/coreweave/ncore-api/pkg/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// stdoutSpan is the part of the spans written by the stdout exporter checked by the tests.
+type stdoutSpan struct {
+	Name   string
+	Status struct {
+		Code string
+	}
+	Attributes []struct {
+		Key   string
+		Value struct {
+			Value any
+		}
+	}
+}
+
+func TestSetup_stdout(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	shutdown, err := Setup(ctx, Config{Exporter: ExporterStdout, SampleRatio: 1, Writer: &buf})
+	require.NoError(t, err)
+
+	ms := s3.NewMemoryStore("http://objects.test")
+	ms.PutObject("images", "ncore-develop-ci-test/vmlinuz", []byte("kernel"))
+	store := InstrumentObjectStore("memory", ms)
+	_, err = store.PresignGetObject(ctx, "images", "ncore-develop-ci-test/vmlinuz", 60)
+	require.NoError(t, err)
+	_, err = store.HeadObject(ctx, "images", "missing")
+	assert.ErrorIs(t, err, s3.ErrObjectNotFound)
+	require.NoError(t, shutdown(ctx))
+
+	var spans []stdoutSpan
+	dec := json.NewDecoder(&buf)
+	for {
+		var span stdoutSpan
+		if err := dec.Decode(&span); errors.Is(err, io.EOF) {
+			break
+		} else {
+			require.NoError(t, err)
+		}
+		spans = append(spans, span)
+	}
+	require.Len(t, spans, 2)
+	assert.Equal(t, "s3.PresignGetObject", spans[0].Name)
+	assert.Equal(t, "s3.HeadObject", spans[1].Name)
+	// a missing object isn't an error of the call
+	assert.Equal(t, "Unset", spans[1].Status.Code)
+	attrs := map[string]any{}
+	for _, a := range spans[0].Attributes {
+		attrs[a.Key] = a.Value.Value
+	}
+	assert.Equal(t, "images", attrs["aws.s3.bucket"])
+	assert.Equal(t, "ncore-develop-ci-test/vmlinuz", attrs["aws.s3.key"])
+	assert.Equal(t, "memory", attrs["ncore.object_store.backend"])
+}
+
+func TestSetup_unknownExporter(t *testing.T) {
+	_, err := Setup(context.Background(), Config{Exporter: "jaeger"})
+	assert.ErrorContains(t, err, `unknown tracing exporter: "jaeger"`)
+}
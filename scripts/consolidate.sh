@@ -0,0 +1,41 @@
+#!/usr/bin/env bash
+# Moves the payloads, ipxe and nodes databases into the ipxe, payloads and nodes schemas of $PGDATABASE
+# for the single database mode (-database.single).
+# The public schema of each source database, including its tern schema_version table, is restored into the
+# public schema of the target and renamed. Stop ncore-api before running it, the source databases are left as is.
+# Table privileges of read_only are restored with the tables, the schemas are granted the way the
+# per-database migrations grant public: usage of every schema and select on future nodes tables.
+# New installs don't need it, ncore-api -database.single migrate up starts empty schemas.
+#
+# Usage: PGHOST=... PGUSER=... PGPASSWORD=... PGDATABASE=ncore ./scripts/consolidate.sh
+set -eo pipefail
+
+target=${PGDATABASE:?PGDATABASE must name the target database}
+dump_dir=$(mktemp -d)
+trap 'rm -rf "$dump_dir"' EXIT
+
+psql -h $PGHOST -U $PGUSER -d postgres -tc "SELECT 1 FROM pg_database WHERE datname = '${target}'" | grep -q 1 || psql -h $PGHOST -U $PGUSER -d postgres -c "CREATE DATABASE ${target};"
+
+if psql -h $PGHOST -U $PGUSER -d $target -tc "SELECT 1 FROM pg_tables WHERE schemaname = 'public'" | grep -q 1; then
+  echo "public schema of ${target} isn't empty, refusing to replace it" >&2
+  exit 1
+fi
+
+for schema in ipxe payloads nodes; do
+  source=${schema}
+  if psql -h $PGHOST -U $PGUSER -d $target -tc "SELECT 1 FROM pg_namespace WHERE nspname = '${schema}'" | grep -q 1; then
+    echo "schema ${schema} already exists in ${target}, skipping"
+    continue
+  fi
+  echo "moving database ${source} to schema ${target}.${schema}"
+  pg_dump -h $PGHOST -U $PGUSER -d $source -n public --no-owner -Fc -f "$dump_dir/${source}.dump"
+  psql -h $PGHOST -U $PGUSER -d $target -v ON_ERROR_STOP=1 -c "DROP SCHEMA IF EXISTS public CASCADE;"
+  pg_restore -h $PGHOST -U $PGUSER -d $target --no-owner --exit-on-error "$dump_dir/${source}.dump"
+  psql -h $PGHOST -U $PGUSER -d $target -v ON_ERROR_STOP=1 \
+    -c "ALTER SCHEMA public RENAME TO ${schema};" \
+    -c "CREATE SCHEMA public;" \
+    -c "GRANT USAGE ON SCHEMA ${schema} TO read_only;"
+done
+
+psql -h $PGHOST -U $PGUSER -d $target -v ON_ERROR_STOP=1 \
+  -c "ALTER DEFAULT PRIVILEGES IN SCHEMA nodes GRANT SELECT ON TABLES TO read_only;"
@@ -1,3 +1,21 @@
 #!/usr/bin/env bash
+# Creates $PGDATABASE if needed and applies /migrations/$PGDATABASE.
+# With PGSCHEMA set (single database mode), applies /migrations/$PGSCHEMA to that schema of $PGDATABASE instead,
+# tracking its version in $PGSCHEMA.schema_version. The migrations of /migrations/${PGSCHEMA}_single replace the
+# ones with the same name, they grant read_only on the schema instead of the ipxe, payloads and nodes databases.
+set -eo pipefail
 
-(psql -h $PGHOST -U $PGUSER -d postgres -tc "SELECT 1 FROM pg_database WHERE datname = '${PGDATABASE}'" | grep -q 1 || psql -h $PGHOST -U $PGUSER -d postgres -c "CREATE DATABASE ${PGDATABASE};") && tern migrate -m /migrations/$PGDATABASE;
+psql -h $PGHOST -U $PGUSER -d postgres -tc "SELECT 1 FROM pg_database WHERE datname = '${PGDATABASE}'" | grep -q 1 || psql -h $PGHOST -U $PGUSER -d postgres -c "CREATE DATABASE ${PGDATABASE};"
+
+if [ -z "$PGSCHEMA" ]; then
+  tern migrate -m /migrations/$PGDATABASE
+else
+  migrations_dir=$(mktemp -d)
+  trap 'rm -rf "$migrations_dir"' EXIT
+  cp -r /migrations/$PGSCHEMA/. "$migrations_dir"
+  if [ -d /migrations/${PGSCHEMA}_single ]; then
+    cp /migrations/${PGSCHEMA}_single/*.sql "$migrations_dir"
+  fi
+  psql -h $PGHOST -U $PGUSER -d $PGDATABASE -v ON_ERROR_STOP=1 -c "CREATE SCHEMA IF NOT EXISTS ${PGSCHEMA};"
+  PGOPTIONS="-c search_path=${PGSCHEMA}" tern migrate -m "$migrations_dir" --version-table ${PGSCHEMA}.schema_version
+fi